@@ -0,0 +1,74 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/kroulersama/goProject/models"
+	"github.com/kroulersama/goProject/storage"
+)
+
+// check [--repair] [--json]; возвращает число неисправленных находок
+func runCheck(config *storage.Config, args []string) (int, error) {
+	flags := flag.NewFlagSet("check", flag.ExitOnError)
+	repair := flags.Bool("repair", false, "apply safe fixes")
+	asJSON := flags.Bool("json", false, "print the report as JSON")
+	flags.Parse(args)
+
+	policy, err := models.ParseUniquenessPolicy(os.Getenv("DEPARTMENT_NAME_UNIQUENESS"))
+	if err != nil {
+		return 0, err
+	}
+	db, err := storage.NewConnection(config)
+	if err != nil {
+		return 0, err
+	}
+
+	store := storage.NewGormStore(db, policy)
+	report, err := store.CheckIntegrity(context.Background(), *repair)
+	if err != nil {
+		return 0, err
+	}
+
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return report.Remaining(), enc.Encode(report)
+	}
+	return report.Remaining(), printReport(report)
+}
+
+// Таблица находок с предлагаемыми исправлениями
+func printReport(report *models.IntegrityReport) error {
+	fmt.Printf("Checked %d departments and %d employees: %d issues, %d repaired\n",
+		report.Departments, report.Employees, len(report.Issues), report.Repaired)
+	if len(report.Issues) == 0 {
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "CHECK\tENTITY\tIDS\tSTATE\tMESSAGE")
+	for _, issue := range report.Issues {
+		ids := make([]string, len(issue.IDs))
+		for i, id := range issue.IDs {
+			ids[i] = fmt.Sprint(id)
+		}
+		state := "manual"
+		switch {
+		case issue.Repaired:
+			state = "repaired"
+		case issue.Repairable:
+			state = "repairable"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", issue.Check, issue.Entity, strings.Join(ids, ","), state, issue.Message)
+		for _, fix := range issue.Fixes {
+			fmt.Fprintf(w, "\t\t\t\t  fix: %s\n", fix)
+		}
+	}
+	return w.Flush()
+}
@@ -0,0 +1,80 @@
+package handler
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/kroulersama/goProject/models"
+)
+
+// EmployeeHistory периоды работы сотрудника по подразделениям и должностям
+func (r *Repository) EmployeeHistory(w http.ResponseWriter, req *http.Request) {
+	if !checkMethod(w, req, http.MethodGet) {
+		return
+	}
+
+	employeeID, ok := r.employeeID(w, req, "id")
+	if !ok {
+		return
+	}
+
+	history, err := r.Assignments.EmployeeHistory(req.Context(), employeeID)
+	if err != nil {
+		r.Log.Error("Failed get employee history", err, "id", employeeID)
+		r.writeError(w, req, err)
+		return
+	}
+	if history == nil {
+		history = []models.EmployeeAssignment{}
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"data": history})
+}
+
+// DepartmentMovements кто пришел в подразделение и кто ушел, ?since= и ?until=
+func (r *Repository) DepartmentMovements(w http.ResponseWriter, req *http.Request) {
+	if !checkMethod(w, req, http.MethodGet) {
+		return
+	}
+
+	// Подразделение из query: id, code:... или ext:...:...
+	departmentID, ok := r.queryDepartmentID(w, req, "department_id", models.ErrDepartmentNotFound)
+	if !ok {
+		return
+	}
+	if departmentID == nil {
+		writeFieldProblem(w, req, "department_id", "department_id is required")
+		return
+	}
+
+	// Период ленты
+	var filter models.MovementFilter
+	if filter.Since, ok = queryTime(w, req, "since"); !ok {
+		return
+	}
+	if filter.Until, ok = queryTime(w, req, "until"); !ok {
+		return
+	}
+
+	movements, err := r.Assignments.GetDepartmentMovements(req.Context(), *departmentID, &filter)
+	if err != nil {
+		r.Log.Error("Failed get department movements", err, "department_id", *departmentID)
+		r.writeError(w, req, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"data": movements})
+}
+
+// Необязательная дата RFC 3339 из query
+func queryTime(w http.ResponseWriter, req *http.Request, name string) (*time.Time, bool) {
+	raw := req.URL.Query().Get(name)
+	if raw == "" {
+		return nil, true
+	}
+	t, err := time.Parse(time.RFC3339, raw)
+	if err != nil {
+		writeFieldProblem(w, req, name, name+" must be an RFC 3339 timestamp")
+		return nil, false
+	}
+	t = t.UTC()
+	return &t, true
+}
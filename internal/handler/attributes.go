@@ -0,0 +1,297 @@
+package handler
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/kroulersama/goProject/models"
+)
+
+// Префикс query-параметров фильтра по атрибутам: attr.<имя>=<значение>
+const attributeQueryPrefix = "attr."
+
+// CreateAttributeDefinition создание описания атрибута
+func (r *Repository) CreateAttributeDefinition(w http.ResponseWriter, req *http.Request) {
+	r.Log.Info("Creating attribute definition", "method", req.Method)
+
+	// Проверка метода
+	if !checkMethod(w, req, http.MethodPost) {
+		return
+	}
+
+	// Декодирование JSON
+	var defReq models.AttributeDefinitionRequest
+	if !r.decodeBody(w, req, &defReq) {
+		return
+	}
+
+	// Обработка в модуле
+	def, err := r.Attributes.CreateAttributeDefinition(req.Context(), &defReq)
+	if err != nil {
+		r.Log.Error("Failed to create attribute definition", err, "entity", defReq.Entity, "name", defReq.Name)
+		r.writeError(w, req, err)
+		return
+	}
+	r.Log.Info("Attribute definition created", "id", def.ID, "entity", def.Entity, "name", def.Name)
+
+	writeJSONWithETag(w, req, http.StatusCreated, def.Version, map[string]interface{}{
+		"message": "attribute definition created successfully",
+		"data":    def,
+	})
+}
+
+// ListAttributeDefinitions список описаний, ?entity= - по сущности
+func (r *Repository) ListAttributeDefinitions(w http.ResponseWriter, req *http.Request) {
+	if !checkMethod(w, req, http.MethodGet) {
+		return
+	}
+
+	defs, err := r.Attributes.ListAttributeDefinitions(req.Context(), req.URL.Query().Get("entity"))
+	if err != nil {
+		r.Log.Error("Failed list attribute definitions", err)
+		r.writeError(w, req, err)
+		return
+	}
+	if defs == nil {
+		defs = []models.AttributeDefinition{}
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"data": defs})
+}
+
+// GetAttributeDefinition описание по id
+func (r *Repository) GetAttributeDefinition(w http.ResponseWriter, req *http.Request) {
+	if !checkMethod(w, req, http.MethodGet) {
+		return
+	}
+
+	defID, ok := pathID(w, req, "id")
+	if !ok {
+		return
+	}
+
+	def, err := r.Attributes.GetAttributeDefinition(req.Context(), defID)
+	if err != nil {
+		r.Log.Error("Failed get attribute definition", err, "id", defID)
+		r.writeError(w, req, err)
+		return
+	}
+
+	// Ответ, 304 если If-None-Match совпал
+	writeJSONWithETag(w, req, http.StatusOK, def.Version, def)
+}
+
+// UpdateAttributeDefinition изменение описания атрибута
+func (r *Repository) UpdateAttributeDefinition(w http.ResponseWriter, req *http.Request) {
+	r.Log.Info("Updating attribute definition", "method", req.Method)
+
+	// Проверка метода
+	if !checkMethod(w, req, http.MethodPatch) {
+		return
+	}
+
+	// Получение id
+	defID, ok := pathID(w, req, "id")
+	if !ok {
+		return
+	}
+
+	// Версия из If-Match
+	version, ok := ifMatchVersion(w, req)
+	if !ok {
+		return
+	}
+
+	// Декодирование JSON
+	var update models.AttributeDefinitionUpdate
+	if !r.decodeBody(w, req, &update) {
+		return
+	}
+
+	def, err := r.Attributes.UpdateAttributeDefinition(req.Context(), defID, version, &update)
+	if err != nil {
+		r.Log.Error("Failed update attribute definition", err, "id", defID)
+		r.writeError(w, req, err)
+		return
+	}
+	r.Log.Info("Attribute definition updated", "id", def.ID, "name", def.Name)
+
+	writeJSONWithETag(w, req, http.StatusOK, def.Version, map[string]interface{}{
+		"message": "attribute definition updated successfully",
+		"data":    def,
+	})
+}
+
+// DeleteAttributeDefinition удаление описания и значений атрибута
+func (r *Repository) DeleteAttributeDefinition(w http.ResponseWriter, req *http.Request) {
+	r.Log.Info("del attribute definition", "method", req.Method)
+
+	// Проверка метода
+	if !checkMethod(w, req, http.MethodDelete) {
+		return
+	}
+
+	// Получаем Id
+	defID, ok := pathID(w, req, "id")
+	if !ok {
+		return
+	}
+
+	// Версия из If-Match
+	version, ok := ifMatchVersion(w, req)
+	if !ok {
+		return
+	}
+
+	if err := r.Attributes.DeleteAttributeDefinition(req.Context(), defID, version); err != nil {
+		r.Log.Error("Failed del attribute definition", err, "id", defID)
+		r.writeError(w, req, err)
+		return
+	}
+	r.Log.Info("Attribute definition del", "id", defID)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ListDepartments список подразделений с фильтром по атрибутам
+func (r *Repository) ListDepartments(w http.ResponseWriter, req *http.Request) {
+	if !checkMethod(w, req, http.MethodGet) {
+		return
+	}
+
+	filter := &models.DepartmentFilter{Attributes: attributeQuery(req)}
+	departments, err := r.Departments.ListDepartments(req.Context(), filter)
+	if err != nil {
+		r.Log.Error("Failed list departments", err)
+		r.writeError(w, req, err)
+		return
+	}
+	if departments == nil {
+		departments = []models.Department{}
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"data": departments})
+}
+
+// ListEmployees сотрудники подразделения с сортировкой, фильтром по атрибутам и статусу
+func (r *Repository) ListEmployees(w http.ResponseWriter, req *http.Request) {
+	if !checkMethod(w, req, http.MethodGet) {
+		return
+	}
+
+	// Подразделение из query: id, code:... или ext:...:...
+	departmentID, ok := r.queryDepartmentID(w, req, "department_id", models.ErrDepartmentNotFound)
+	if !ok {
+		return
+	}
+	if departmentID == nil {
+		writeFieldProblem(w, req, "department_id", "department_id is required")
+		return
+	}
+
+	// Сортировка
+	sortBy := req.URL.Query().Get("sort")
+	if sortBy != "" && sortBy != "name" && sortBy != "created" {
+		writeFieldProblem(w, req, "sort", "sort must be name or created")
+		return
+	}
+
+	// Фильтр по статусу
+	filter := &models.EmployeeFilter{SortBy: sortBy, Attributes: attributeQuery(req)}
+	if !employeeStatusQuery(w, req, filter) {
+		return
+	}
+
+	// Проверка существования подразделения
+	if _, err := r.Departments.GetWithTree(req.Context(), *departmentID, 0, nil); err != nil {
+		r.writeError(w, req, err)
+		return
+	}
+
+	employees, err := r.Employees.GetEmployeesByDepartment(req.Context(), *departmentID, filter)
+	if err != nil {
+		r.Log.Error("Failed list employees", err, "department_id", *departmentID)
+		r.writeError(w, req, err)
+		return
+	}
+	if employees == nil {
+		employees = []models.Employee{}
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"data": employees})
+}
+
+// GetEmployee сотрудник по id или табельному номеру (pn:...)
+func (r *Repository) GetEmployee(w http.ResponseWriter, req *http.Request) {
+	if !checkMethod(w, req, http.MethodGet) {
+		return
+	}
+
+	employeeID, ok := r.employeeID(w, req, "id")
+	if !ok {
+		return
+	}
+
+	employee, err := r.Employees.GetEmployee(req.Context(), employeeID)
+	if err != nil {
+		r.Log.Error("Failed get employee", err, "id", employeeID)
+		r.writeError(w, req, err)
+		return
+	}
+
+	// Ответ, 304 если If-None-Match совпал
+	writeJSONWithETag(w, req, http.StatusOK, employee.Version, employee)
+}
+
+// UpdateEmployeeAttributes изменение атрибутов сотрудника
+func (r *Repository) UpdateEmployeeAttributes(w http.ResponseWriter, req *http.Request) {
+	r.Log.Info("Updating employee attributes", "method", req.Method)
+
+	// Проверка метода
+	if !checkMethod(w, req, http.MethodPatch) {
+		return
+	}
+
+	// Получение id
+	employeeID, ok := r.employeeID(w, req, "id")
+	if !ok {
+		return
+	}
+
+	// Версия из If-Match
+	version, ok := ifMatchVersion(w, req)
+	if !ok {
+		return
+	}
+
+	// Декодирование JSON
+	var attrReq models.EmployeeAttributesRequest
+	if !r.decodeBody(w, req, &attrReq) {
+		return
+	}
+	if attrReq.Attributes == nil {
+		writeFieldProblem(w, req, "attributes", "attributes is required")
+		return
+	}
+
+	employee, err := r.Employees.UpdateEmployeeAttributes(req.Context(), employeeID, version, &attrReq)
+	if err != nil {
+		r.Log.Error("Failed update employee attributes", err, "id", employeeID)
+		r.writeError(w, req, err)
+		return
+	}
+	r.Log.Info("Employee attributes updated", "id", employee.ID)
+
+	writeJSONWithETag(w, req, http.StatusOK, employee.Version, map[string]interface{}{
+		"message": "employee attributes updated successfully",
+		"data":    employee,
+	})
+}
+
+// Фильтр по атрибутам из query: attr.<имя>=<значение>
+func attributeQuery(req *http.Request) map[string]string {
+	filter := map[string]string{}
+	for key, values := range req.URL.Query() {
+		if name, ok := strings.CutPrefix(key, attributeQueryPrefix); ok && len(values) > 0 {
+			filter[name] = values[0]
+		}
+	}
+	return filter
+}
@@ -0,0 +1,102 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/kroulersama/goProject/models"
+)
+
+// Дедлайны запросов к базе по маршрутам
+type Timeouts struct {
+	Default time.Duration
+	Routes  map[string]time.Duration
+}
+
+// ParseTimeouts разбирает дедлайн по умолчанию и переопределения
+// вида "GET /departments/{id}=10s,DELETE /departments/{id}=30s"
+func ParseTimeouts(def, overrides string) (*Timeouts, error) {
+	t := &Timeouts{
+		Default: 5 * time.Second,
+		Routes:  make(map[string]time.Duration),
+	}
+
+	if def != "" {
+		d, err := time.ParseDuration(def)
+		if err != nil {
+			return nil, fmt.Errorf("invalid default timeout %q: %w", def, err)
+		}
+		t.Default = d
+	}
+
+	for _, item := range strings.Split(overrides, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		route, value, ok := strings.Cut(item, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid route timeout %q, want ROUTE=DURATION", item)
+		}
+		d, err := time.ParseDuration(strings.TrimSpace(value))
+		if err != nil {
+			return nil, fmt.Errorf("invalid timeout for %q: %w", route, err)
+		}
+		t.Routes[strings.TrimSpace(route)] = d
+	}
+
+	return t, nil
+}
+
+// Дедлайн для маршрута
+func (t *Timeouts) For(route string) time.Duration {
+	if d, ok := t.Routes[route]; ok {
+		return d
+	}
+	return t.Default
+}
+
+// Ключ контекста с дедлайном маршрута
+type routeTimeoutKey struct{}
+
+// WithTimeout ограничивает время обработки запроса; 0 - без ограничения
+func WithTimeout(d time.Duration, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		// Дедлайн маршрута нужен Idempotent, чтобы не считать брошенным еще идущий запрос
+		ctx := context.WithValue(req.Context(), routeTimeoutKey{}, d)
+		if d <= 0 {
+			next(w, req.WithContext(ctx))
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(ctx, d)
+		defer cancel()
+
+		next(w, req.WithContext(ctx))
+	}
+}
+
+// Дедлайн маршрута из WithTimeout; ok false - запрос прошел без WithTimeout
+func routeTimeout(ctx context.Context) (d time.Duration, ok bool) {
+	d, ok = ctx.Value(routeTimeoutKey{}).(time.Duration)
+	return d, ok
+}
+
+// WithActor передает автора изменений из X-Actor в историю назначений
+func WithActor(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		actor := strings.TrimSpace(req.Header.Get("X-Actor"))
+		if actor == "" {
+			next(w, req)
+			return
+		}
+		if len(actor) > 100 {
+			writeFieldProblem(w, req, "X-Actor", "X-Actor too long (max 100)")
+			return
+		}
+		next(w, req.WithContext(models.WithActor(req.Context(), actor)))
+	}
+}
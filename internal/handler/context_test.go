@@ -0,0 +1,95 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/kroulersama/goProject/models"
+)
+
+// Хранилище, которое отвечает только после завершения контекста запроса
+type blockingStore struct {
+	models.DepartmentStore
+	err error // ошибка вместо ctx.Err(), как у драйвера базы
+}
+
+func (s *blockingStore) GetWithTree(ctx context.Context, id uint, depth int, employees *models.EmployeeFilter) (*models.DepartmentResponse, error) {
+	<-ctx.Done()
+	if s.err != nil {
+		return nil, s.err
+	}
+	return nil, ctx.Err()
+}
+
+func TestWithTimeoutDeadline(t *testing.T) {
+	for _, storeErr := range []error{nil, errors.New("driver: bad connection")} {
+		r, store := newTestRepository()
+		r.Departments = &blockingStore{DepartmentStore: store, err: storeErr}
+
+		rec := serve("GET /departments/{id}", WithTimeout(10*time.Millisecond, r.GetDepartment), newRequest("GET", "/departments/1", ""))
+		expectProblem(t, rec, http.StatusGatewayTimeout, CodeRequestTimeout)
+	}
+}
+
+func TestWithTimeoutCanceled(t *testing.T) {
+	r, store := newTestRepository()
+	r.Departments = &blockingStore{DepartmentStore: store}
+
+	// Клиент отключается, пока запрос ждет базу
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(10*time.Millisecond, cancel)
+	req := newRequest("GET", "/departments/1", "").WithContext(ctx)
+
+	rec := serve("GET /departments/{id}", WithTimeout(time.Minute, r.GetDepartment), req)
+	problem := expectProblem(t, rec, StatusClientClosedRequest, CodeRequestCanceled)
+	if problem.Title != "Client Closed Request" {
+		t.Fatalf("unexpected title: %q", problem.Title)
+	}
+}
+
+func TestWithTimeoutUnlimited(t *testing.T) {
+	var hasDeadline bool
+	WithTimeout(0, func(w http.ResponseWriter, req *http.Request) {
+		_, hasDeadline = req.Context().Deadline()
+	})(httptest.NewRecorder(), newRequest("GET", "/departments", ""))
+	if hasDeadline {
+		t.Fatal("zero timeout must not set a deadline")
+	}
+}
+
+func TestParseTimeouts(t *testing.T) {
+	timeouts, err := ParseTimeouts("", "")
+	if err != nil || timeouts.Default != 5*time.Second || len(timeouts.Routes) != 0 {
+		t.Fatalf("defaults: %+v, %v", timeouts, err)
+	}
+
+	timeouts, err = ParseTimeouts("2s", " GET /departments/{id}=10s , ,DELETE /departments/{id} = 1m ")
+	if err != nil {
+		t.Fatalf("ParseTimeouts: %v", err)
+	}
+	for route, want := range map[string]time.Duration{
+		"GET /departments/{id}":    10 * time.Second,
+		"DELETE /departments/{id}": time.Minute,
+		"POST /departments":        2 * time.Second,
+	} {
+		if got := timeouts.For(route); got != want {
+			t.Errorf("For(%q) = %v, want %v", route, got, want)
+		}
+	}
+
+	for _, tt := range []struct{ def, overrides string }{
+		{"fast", ""},
+		{"5", ""},
+		{"", "GET /departments"},
+		{"", "GET /departments=soon"},
+		{"", "GET /departments=10s,POST /departments"},
+	} {
+		if _, err := ParseTimeouts(tt.def, tt.overrides); err == nil {
+			t.Errorf("ParseTimeouts(%q, %q) accepted", tt.def, tt.overrides)
+		}
+	}
+}
@@ -0,0 +1,152 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/kroulersama/goProject/models"
+)
+
+// Фильтр по статусу из query: status и include_terminated
+func employeeStatusQuery(w http.ResponseWriter, req *http.Request, filter *models.EmployeeFilter) bool {
+	query := req.URL.Query()
+
+	filter.Status = query.Get("status")
+	if !models.ValidEmployeeStatus(filter.Status) {
+		writeFieldProblem(w, req, "status", "status must be active, on_leave or terminated")
+		return false
+	}
+
+	if includeStr := query.Get("include_terminated"); includeStr != "" {
+		b, err := strconv.ParseBool(includeStr)
+		if err != nil {
+			writeFieldProblem(w, req, "include_terminated", "include_terminated must be true or false")
+			return false
+		}
+		filter.IncludeTerminated = b
+	}
+	return true
+}
+
+// TerminateEmployee увольнение сотрудника с датой и причиной
+func (r *Repository) TerminateEmployee(w http.ResponseWriter, req *http.Request) {
+	r.Log.Info("terminating employee", "method", req.Method)
+
+	// Проверка метода
+	if !checkMethod(w, req, http.MethodPost) {
+		return
+	}
+
+	// Получение id
+	employeeID, ok := r.employeeID(w, req, "id")
+	if !ok {
+		return
+	}
+
+	// Версия из If-Match
+	version, ok := ifMatchVersion(w, req)
+	if !ok {
+		return
+	}
+
+	// Декодирование JSON
+	var termReq models.TerminationRequest
+	if !r.decodeBody(w, req, &termReq) {
+		return
+	}
+
+	employee, err := r.Employees.TerminateEmployee(req.Context(), employeeID, version, &termReq)
+	if err != nil {
+		r.Log.Error("Failed terminate employee", err, "id", employeeID)
+		r.writeError(w, req, err)
+		return
+	}
+	r.Log.Info("Employee terminated", "id", employeeID, "terminated_at", employee.TerminatedAt)
+
+	writeJSONWithETag(w, req, http.StatusOK, employee.Version, map[string]interface{}{
+		"message": "employee terminated successfully",
+		"data":    employee,
+	})
+}
+
+// RehireEmployee повторный прием уволенного сотрудника
+func (r *Repository) RehireEmployee(w http.ResponseWriter, req *http.Request) {
+	r.Log.Info("rehiring employee", "method", req.Method)
+
+	// Проверка метода
+	if !checkMethod(w, req, http.MethodPost) {
+		return
+	}
+
+	// Получение id
+	employeeID, ok := r.employeeID(w, req, "id")
+	if !ok {
+		return
+	}
+
+	// Версия из If-Match
+	version, ok := ifMatchVersion(w, req)
+	if !ok {
+		return
+	}
+
+	// Декодирование JSON
+	var rehireReq models.RehireRequest
+	if !r.decodeBody(w, req, &rehireReq) {
+		return
+	}
+
+	employee, err := r.Employees.RehireEmployee(req.Context(), employeeID, version, &rehireReq)
+	if err != nil {
+		r.Log.Error("Failed rehire employee", err, "id", employeeID)
+		r.writeError(w, req, err)
+		return
+	}
+	r.Log.Info("Employee rehired", "id", employeeID, "department_id", employee.DepartmentId)
+
+	writeJSONWithETag(w, req, http.StatusOK, employee.Version, map[string]interface{}{
+		"message": "employee rehired successfully",
+		"data":    employee,
+	})
+}
+
+// SetEmployeeStatus смена статуса: active или on_leave
+func (r *Repository) SetEmployeeStatus(w http.ResponseWriter, req *http.Request) {
+	r.Log.Info("changing employee status", "method", req.Method)
+
+	// Проверка метода
+	if !checkMethod(w, req, http.MethodPatch) {
+		return
+	}
+
+	// Получение id
+	employeeID, ok := r.employeeID(w, req, "id")
+	if !ok {
+		return
+	}
+
+	// Версия из If-Match
+	version, ok := ifMatchVersion(w, req)
+	if !ok {
+		return
+	}
+
+	// Декодирование JSON
+	var statusReq models.EmployeeStatusRequest
+	if !r.decodeBody(w, req, &statusReq) {
+		return
+	}
+
+	employee, err := r.Employees.SetEmployeeStatus(req.Context(), employeeID, version, &statusReq)
+	if err != nil {
+		r.Log.Error("Failed change employee status", err, "id", employeeID)
+		r.writeError(w, req, err)
+		return
+	}
+	r.Log.Info("Employee status changed", "id", employeeID, "status", employee.Status)
+
+	writeJSONWithETag(w, req, http.StatusOK, employee.Version, map[string]interface{}{
+		"message": "employee status updated successfully",
+		"data":    employee,
+	})
+}
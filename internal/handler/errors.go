@@ -0,0 +1,309 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/kroulersama/goProject/models"
+)
+
+// Клиент закрыл соединение (nginx)
+const StatusClientClosedRequest = 499
+
+// Префикс type для problem+json
+const problemTypeBase = "urn:goproject:problem:"
+
+// Коды ошибок уровня HTTP
+const (
+	CodeMethodNotAllowed = "method_not_allowed"
+	CodeNotFound         = "not_found"
+	CodeInvalidParameter = "invalid_parameter"
+	CodeMalformedBody    = "malformed_body"
+	CodeRequestTimeout   = "request_timeout"
+	CodeRequestCanceled  = "request_canceled"
+	CodeValidation       = "validation_failed"
+	CodeInternal         = "internal_error"
+	CodePlanInvalid      = "plan_invalid"
+)
+
+// Ответ об ошибке по RFC 9457
+type Problem struct {
+	Type     string       `json:"type"`
+	Title    string       `json:"title"`
+	Status   int          `json:"status"`
+	Detail   string       `json:"detail,omitempty"`
+	Instance string       `json:"instance,omitempty"`
+	Code     string       `json:"code"`
+	Errors   []FieldError `json:"errors,omitempty"`
+}
+
+// Ошибка конкретного поля запроса
+type FieldError struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// Описание ошибки модели
+type problemDef struct {
+	err    error
+	status int
+	code   string
+	title  string
+	field  string
+}
+
+// Таблица соответствия ошибок models кодам и статусам
+var problemDefs = []problemDef{
+	{models.ErrDepartmentNotFound, http.StatusNotFound, "department_not_found", "Department not found", ""},
+	{models.ErrTargetNotFound, http.StatusNotFound, "target_department_not_found", "Target department not found", "reassign_to_department_id"},
+	{models.ErrUnknownDepartment, http.StatusNotFound, "target_department_not_found", "Target department not found", "department_id"},
+	{models.ErrParentNotFound, http.StatusBadRequest, "parent_not_found", "Parent department not found", "parent_id"},
+	{models.ErrNameEmpty, http.StatusBadRequest, "department_name_empty", "Validation failed", "name"},
+	{models.ErrNameTooLong, http.StatusBadRequest, "department_name_too_long", "Validation failed", "name"},
+	{models.ErrInvalidMode, http.StatusBadRequest, "invalid_delete_mode", "Invalid delete mode", "mode"},
+	{models.ErrReassignRequired, http.StatusBadRequest, "reassign_target_required", "Validation failed", "reassign_to_department_id"},
+	{models.ErrReassignToSame, http.StatusBadRequest, "reassign_to_same", "Validation failed", "reassign_to_department_id"},
+	{models.ErrSelfParent, http.StatusConflict, "department_self_parent", "Invalid hierarchy", "parent_id"},
+	{models.ErrCycleDetected, http.StatusConflict, "department_cycle", "Invalid hierarchy", "parent_id"},
+	{models.ErrNameExists, http.StatusConflict, "department_name_exists", "Name conflict", "name"},
+	{models.ErrInvalidPosition, http.StatusBadRequest, "invalid_position", "Validation failed", "position"},
+	{models.ErrInvalidChildOrder, http.StatusBadRequest, "invalid_child_order", "Validation failed", "ids"},
+	{models.ErrInvalidMergeStrategy, http.StatusBadRequest, "invalid_merge_strategy", "Invalid merge strategy", "strategy"},
+	{models.ErrMergeIntoSelf, http.StatusBadRequest, "merge_into_self", "Validation failed", "target"},
+	{models.ErrMergeIntoDescendant, http.StatusConflict, "merge_into_descendant", "Invalid hierarchy", "target"},
+	{models.ErrMergeConflict, http.StatusConflict, "merge_name_conflict", "Name conflict", "strategy"},
+	{models.ErrEmployeeNotInDepartment, http.StatusBadRequest, "employee_not_in_department", "Validation failed", "employee_ids"},
+	{models.ErrNotChildDepartment, http.StatusBadRequest, "not_child_department", "Validation failed", "child_ids"},
+	{models.ErrPlanNotFound, http.StatusNotFound, "plan_not_found", "Plan not found", ""},
+	{models.ErrPlanNameEmpty, http.StatusBadRequest, "plan_name_empty", "Validation failed", "name"},
+	{models.ErrPlanNameTooLong, http.StatusBadRequest, "plan_name_too_long", "Validation failed", "name"},
+	{models.ErrPlanApplied, http.StatusConflict, "plan_already_applied", "Plan already applied", ""},
+	{models.ErrPlanStale, http.StatusConflict, "plan_stale", "Plan is stale", ""},
+	{models.ErrInvalidPlanOperation, http.StatusBadRequest, "invalid_plan_operation", "Validation failed", "operations"},
+	{models.ErrEmployeeNotFound, http.StatusNotFound, "employee_not_found", "Employee not found", ""},
+	{models.ErrScheduledChangeNotFound, http.StatusNotFound, "scheduled_change_not_found", "Scheduled change not found", ""},
+	{models.ErrChangeNotPending, http.StatusConflict, "scheduled_change_not_pending", "Scheduled change is not pending", ""},
+	{models.ErrInvalidChangeStatus, http.StatusBadRequest, "invalid_change_status", "Validation failed", "status"},
+	{models.ErrEmptyChange, http.StatusBadRequest, "empty_change", "Validation failed", ""},
+	{models.ErrCodeExists, http.StatusConflict, "department_code_exists", "Identifier conflict", "code"},
+	{models.ErrInvalidCode, http.StatusBadRequest, "invalid_code", "Validation failed", "code"},
+	{models.ErrExternalIDExists, http.StatusConflict, "external_id_exists", "Identifier conflict", "external_ids"},
+	{models.ErrInvalidExternalID, http.StatusBadRequest, "invalid_external_id", "Validation failed", "external_ids"},
+	{models.ErrPersonnelNumberExists, http.StatusConflict, "personnel_number_exists", "Identifier conflict", "personnel_number"},
+	{models.ErrInvalidPersonnelNum, http.StatusBadRequest, "invalid_personnel_number", "Validation failed", "personnel_number"},
+	{models.ErrInvalidDepartmentRef, http.StatusBadRequest, "invalid_department_ref", "Invalid parameter", ""},
+	{models.ErrInvalidEmployeeRef, http.StatusBadRequest, "invalid_employee_ref", "Invalid parameter", ""},
+	{models.ErrNotSchedulable, http.StatusBadRequest, "not_schedulable", "Validation failed", "effective_at"},
+	{models.ErrAttributeDefinitionNotFound, http.StatusNotFound, "attribute_definition_not_found", "Attribute definition not found", ""},
+	{models.ErrAttributeExists, http.StatusConflict, "attribute_exists", "Name conflict", "name"},
+	{models.ErrInvalidAttributeEntity, http.StatusBadRequest, "invalid_attribute_entity", "Validation failed", "entity"},
+	{models.ErrInvalidAttributeName, http.StatusBadRequest, "invalid_attribute_name", "Validation failed", "name"},
+	{models.ErrInvalidAttributeType, http.StatusBadRequest, "invalid_attribute_type", "Validation failed", "type"},
+	{models.ErrInvalidEnumValues, http.StatusBadRequest, "invalid_enum_values", "Validation failed", "enum_values"},
+	{models.ErrInvalidAttributePattern, http.StatusBadRequest, "invalid_attribute_pattern", "Validation failed", "pattern"},
+	{models.ErrAttributeDescriptionTooLong, http.StatusBadRequest, "attribute_description_too_long", "Validation failed", "description"},
+	{models.ErrUnknownAttribute, http.StatusBadRequest, "unknown_attribute", "Validation failed", "attributes"},
+	{models.ErrAttributeRequired, http.StatusBadRequest, "attribute_required", "Validation failed", "attributes"},
+	{models.ErrAttributeType, http.StatusBadRequest, "attribute_type_mismatch", "Validation failed", "attributes"},
+	{models.ErrAttributeValueTooLong, http.StatusBadRequest, "attribute_value_too_long", "Validation failed", "attributes"},
+	{models.ErrAttributeNotAllowed, http.StatusBadRequest, "attribute_value_not_allowed", "Validation failed", "attributes"},
+	{models.ErrAttributePattern, http.StatusBadRequest, "attribute_pattern_mismatch", "Validation failed", "attributes"},
+	{models.ErrInvalidDepartmentType, http.StatusBadRequest, "invalid_department_type", "Validation failed", "type"},
+	{models.ErrInvalidHierarchyRules, http.StatusBadRequest, "invalid_hierarchy_rules", "Validation failed", "rules"},
+	{models.ErrUnknownDepartmentType, http.StatusBadRequest, "unknown_department_type", "Validation failed", "type"},
+	{models.ErrNamePatternMismatch, http.StatusBadRequest, "name_pattern_mismatch", "Validation failed", "name"},
+	{models.ErrTypeNotAllowed, http.StatusConflict, "type_not_allowed", "Invalid hierarchy", "type"},
+	{models.ErrMaxDepthExceeded, http.StatusConflict, "max_depth_exceeded", "Invalid hierarchy", "parent_id"},
+	{models.ErrMaxChildrenExceeded, http.StatusConflict, "max_children_exceeded", "Invalid hierarchy", "parent_id"},
+	{models.ErrPositionNotFound, http.StatusNotFound, "position_not_found", "Position not found", ""},
+	{models.ErrUnknownPosition, http.StatusBadRequest, "unknown_position", "Validation failed", "position_id"},
+	{models.ErrPositionMismatch, http.StatusBadRequest, "position_mismatch", "Validation failed", "position"},
+	{models.ErrPositionExists, http.StatusConflict, "position_exists", "Name conflict", "title"},
+	{models.ErrPositionInUse, http.StatusConflict, "position_in_use", "Position in use", ""},
+	{models.ErrPositionTitleEmpty, http.StatusBadRequest, "position_title_empty", "Validation failed", "title"},
+	{models.ErrPositionTitleTooLong, http.StatusBadRequest, "position_title_too_long", "Validation failed", "title"},
+	{models.ErrGradeTooLong, http.StatusBadRequest, "grade_too_long", "Validation failed", "grade"},
+	{models.ErrJobFamilyTooLong, http.StatusBadRequest, "job_family_too_long", "Validation failed", "job_family"},
+	{models.ErrIdempotencyMismatch, http.StatusUnprocessableEntity, "idempotency_key_reused", "Idempotency key reused", "Idempotency-Key"},
+	{models.ErrIdempotencyInProgress, http.StatusConflict, "idempotency_key_in_progress", "Request in progress", "Idempotency-Key"},
+	{models.ErrVersionMismatch, http.StatusPreconditionFailed, CodePreconditionFailed, "Precondition failed", ""},
+	{models.ErrFullNameEmpty, http.StatusBadRequest, "full_name_empty", "Validation failed", "full_name"},
+	{models.ErrFullNameTooLong, http.StatusBadRequest, "full_name_too_long", "Validation failed", "full_name"},
+	{models.ErrPositionEmpty, http.StatusBadRequest, "position_empty", "Validation failed", "position"},
+	{models.ErrPositionTooLong, http.StatusBadRequest, "position_too_long", "Validation failed", "position"},
+	{models.ErrHiredAtFuture, http.StatusBadRequest, "hired_at_future", "Validation failed", "hired_at"},
+	{models.ErrInvalidEmployeeStatus, http.StatusBadRequest, "invalid_employee_status", "Validation failed", "status"},
+	{models.ErrTerminationReasonEmpty, http.StatusBadRequest, "termination_reason_empty", "Validation failed", "reason"},
+	{models.ErrTerminationReasonTooLong, http.StatusBadRequest, "termination_reason_too_long", "Validation failed", "reason"},
+	{models.ErrTerminatedAtFuture, http.StatusBadRequest, "terminated_at_future", "Validation failed", "terminated_at"},
+	{models.ErrTerminatedBeforeHired, http.StatusBadRequest, "terminated_before_hired", "Validation failed", "terminated_at"},
+	{models.ErrRehireBeforeTermination, http.StatusBadRequest, "rehire_before_termination", "Validation failed", "hired_at"},
+	{models.ErrEmployeeTerminated, http.StatusConflict, "employee_terminated", "Employee terminated", ""},
+	{models.ErrEmployeeNotTerminated, http.StatusConflict, "employee_not_terminated", "Employee not terminated", ""},
+	{models.ErrSecondaryNotFound, http.StatusNotFound, "secondary_assignment_not_found", "Secondary assignment not found", ""},
+	{models.ErrSecondaryExists, http.StatusConflict, "secondary_assignment_exists", "Secondary assignment exists", "department_id"},
+	{models.ErrSecondaryInPrimary, http.StatusBadRequest, "secondary_in_primary_department", "Validation failed", "department_id"},
+	{models.ErrRoleEmpty, http.StatusBadRequest, "role_empty", "Validation failed", "role"},
+	{models.ErrRoleTooLong, http.StatusBadRequest, "role_too_long", "Validation failed", "role"},
+	{models.ErrInvalidAllocation, http.StatusBadRequest, "invalid_allocation", "Validation failed", "allocation"},
+	{models.ErrAllocationExceeded, http.StatusConflict, "allocation_exceeded", "Allocation exceeded", "allocation"},
+	{models.ErrManagerNotFound, http.StatusBadRequest, "manager_not_found", "Validation failed", "manager_id"},
+	{models.ErrSelfManager, http.StatusConflict, "self_manager", "Invalid reporting line", "manager_id"},
+	{models.ErrManagerCycle, http.StatusConflict, "manager_cycle", "Invalid reporting line", "manager_id"},
+	{models.ErrManagerTerminated, http.StatusConflict, "manager_terminated", "Invalid reporting line", "manager_id"},
+	{models.ErrHeadcountPlanNotFound, http.StatusNotFound, "headcount_plan_not_found", "Headcount plan not found", ""},
+	{models.ErrHeadcountPlanExists, http.StatusConflict, "headcount_plan_exists", "Headcount plan exists", "period"},
+	{models.ErrInvalidPeriod, http.StatusBadRequest, "invalid_period", "Validation failed", "period"},
+	{models.ErrInvalidPlanned, http.StatusBadRequest, "invalid_planned", "Validation failed", "planned"},
+	{models.ErrVacancyNotFound, http.StatusNotFound, "vacancy_not_found", "Vacancy not found", ""},
+	{models.ErrInvalidVacancyStatus, http.StatusBadRequest, "invalid_vacancy_status", "Validation failed", "status"},
+	{models.ErrVacancyNotOpen, http.StatusConflict, "vacancy_not_open", "Vacancy not open", "status"},
+	{models.ErrVacancyFilled, http.StatusConflict, "vacancy_filled", "Vacancy filled", "status"},
+}
+
+// Поиск описания для одиночной ошибки
+func lookupProblem(err error) (problemDef, bool) {
+	for _, def := range problemDefs {
+		if errors.Is(err, def.err) {
+			return def, true
+		}
+	}
+	return problemDef{}, false
+}
+
+// writeError превращает ошибку в problem+json; внутренние ошибки клиенту не показываются
+func (r *Repository) writeError(w http.ResponseWriter, req *http.Request, err error) {
+	ctxErr := req.Context().Err()
+	switch {
+	case errors.Is(err, context.DeadlineExceeded) || errors.Is(ctxErr, context.DeadlineExceeded):
+		writeProblem(w, req, http.StatusGatewayTimeout, CodeRequestTimeout, "request timed out")
+		return
+
+	case errors.Is(err, context.Canceled) || errors.Is(ctxErr, context.Canceled):
+		writeProblem(w, req, StatusClientClosedRequest, CodeRequestCanceled, "request canceled")
+		return
+	}
+
+	// Ошибки плана привязываются к номерам операций
+	var invalidPlan *models.PlanValidationError
+	if errors.As(err, &invalidPlan) {
+		writePlanProblem(w, req, invalidPlan)
+		return
+	}
+
+	// Валидация может вернуть несколько ошибок через errors.Join
+	errs := []error{err}
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		errs = joined.Unwrap()
+	}
+
+	var problem *Problem
+	for _, e := range errs {
+		def, ok := lookupProblem(e)
+		if !ok {
+			continue
+		}
+		if problem == nil {
+			problem = &Problem{
+				Type:     problemTypeBase + def.code,
+				Title:    def.title,
+				Status:   def.status,
+				Detail:   e.Error(),
+				Instance: req.URL.Path,
+				Code:     def.code,
+			}
+		}
+		// Ошибка атрибута указывает на конкретное поле
+		field := def.field
+		var attrErr *models.AttributeError
+		if errors.As(e, &attrErr) {
+			field = attrErr.Field
+		}
+		if field != "" {
+			problem.Errors = append(problem.Errors, FieldError{
+				Field:   field,
+				Code:    def.code,
+				Message: e.Error(),
+			})
+		}
+	}
+
+	if problem == nil {
+		r.Log.Error("Internal error", err, "method", req.Method, "path", req.URL.Path)
+		writeProblem(w, req, http.StatusInternalServerError, CodeInternal, "internal server error")
+		return
+	}
+
+	// Несколько ошибок полей сводятся к общему коду
+	if len(problem.Errors) > 1 {
+		problem.Type = problemTypeBase + CodeValidation
+		problem.Title = "Validation failed"
+		problem.Code = CodeValidation
+		problem.Detail = "request validation failed"
+	}
+	renderProblem(w, problem)
+}
+
+// writePlanProblem ошибки операций плана, по одной на операцию
+func writePlanProblem(w http.ResponseWriter, req *http.Request, invalid *models.PlanValidationError) {
+	problem := &Problem{
+		Type:     problemTypeBase + CodePlanInvalid,
+		Title:    "Plan is invalid",
+		Status:   http.StatusConflict,
+		Detail:   invalid.Error(),
+		Instance: req.URL.Path,
+		Code:     CodePlanInvalid,
+	}
+	for _, opErr := range invalid.Errors {
+		code := CodeValidation
+		if def, ok := lookupProblem(opErr.Err); ok {
+			code = def.code
+		}
+		problem.Errors = append(problem.Errors, FieldError{
+			Field:   fmt.Sprintf("operations[%d]", opErr.Index),
+			Code:    code,
+			Message: opErr.Err.Error(),
+		})
+	}
+	renderProblem(w, problem)
+}
+
+// writeProblem ошибка уровня HTTP без привязки к models
+func writeProblem(w http.ResponseWriter, req *http.Request, status int, code, detail string) {
+	title := http.StatusText(status)
+	if status == StatusClientClosedRequest {
+		title = "Client Closed Request"
+	}
+
+	renderProblem(w, &Problem{
+		Type:     problemTypeBase + code,
+		Title:    title,
+		Status:   status,
+		Detail:   detail,
+		Instance: req.URL.Path,
+		Code:     code,
+	})
+}
+
+// writeFieldProblem ошибка параметра запроса
+func writeFieldProblem(w http.ResponseWriter, req *http.Request, field, detail string) {
+	renderProblem(w, &Problem{
+		Type:     problemTypeBase + CodeInvalidParameter,
+		Title:    "Invalid parameter",
+		Status:   http.StatusBadRequest,
+		Detail:   detail,
+		Instance: req.URL.Path,
+		Code:     CodeInvalidParameter,
+		Errors:   []FieldError{{Field: field, Code: CodeInvalidParameter, Message: detail}},
+	})
+}
+
+func renderProblem(w http.ResponseWriter, problem *Problem) {
+	w.Header().Set("Content-Type", "application/problem+json")
+	w.WriteHeader(problem.Status)
+	json.NewEncoder(w).Encode(problem)
+}
@@ -0,0 +1,113 @@
+package handler
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/kroulersama/goProject/models"
+	"github.com/kroulersama/goProject/pkg/logger"
+)
+
+func TestWriteErrorProblemDefs(t *testing.T) {
+	r, _ := newTestRepository()
+	for _, def := range problemDefs {
+		t.Run(def.code, func(t *testing.T) {
+			// Ошибки из models приходят и обернутыми
+			for _, err := range []error{def.err, fmt.Errorf("%w: detail", def.err)} {
+				rec := httptest.NewRecorder()
+				r.writeError(rec, newRequest("POST", "/departments/1", ""), err)
+
+				problem := expectProblem(t, rec, def.status, def.code)
+				if problem.Title != def.title || problem.Detail != err.Error() || problem.Instance != "/departments/1" {
+					t.Fatalf("unexpected problem for %v: %+v", err, problem)
+				}
+				switch {
+				case def.field == "" && len(problem.Errors) != 0:
+					t.Fatalf("unexpected field errors for %v: %+v", err, problem.Errors)
+				case def.field != "" && (len(problem.Errors) != 1 || problem.Errors[0] != FieldError{Field: def.field, Code: def.code, Message: err.Error()}):
+					t.Fatalf("unexpected field errors for %v: %+v", err, problem.Errors)
+				}
+			}
+		})
+	}
+}
+
+func TestWriteErrorFields(t *testing.T) {
+	r, _ := newTestRepository()
+	write := func(err error) Problem {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		r.writeError(rec, newRequest("POST", "/employees", ""), err)
+		return expectProblem(t, rec, http.StatusBadRequest, CodeValidation)
+	}
+
+	// Несколько ошибок валидации - общий код и ошибка на каждое поле
+	problem := write(errors.Join(models.ErrFullNameEmpty, models.ErrPositionTooLong))
+	want := []FieldError{
+		{Field: "full_name", Code: "full_name_empty", Message: models.ErrFullNameEmpty.Error()},
+		{Field: "position", Code: "position_too_long", Message: models.ErrPositionTooLong.Error()},
+	}
+	if problem.Title != "Validation failed" || len(problem.Errors) != 2 || problem.Errors[0] != want[0] || problem.Errors[1] != want[1] {
+		t.Fatalf("unexpected joined problem: %+v", problem)
+	}
+
+	// Ошибка атрибута указывает на сам атрибут
+	attrErr := &models.AttributeError{Field: "attributes.region", Err: models.ErrAttributeRequired}
+	problem = write(errors.Join(models.ErrFullNameEmpty, attrErr))
+	if len(problem.Errors) != 2 || problem.Errors[1].Field != "attributes.region" || problem.Errors[1].Code != "attribute_required" {
+		t.Fatalf("unexpected attribute problem: %+v", problem)
+	}
+
+	// Ошибки плана - по номерам операций
+	rec := httptest.NewRecorder()
+	r.writeError(rec, newRequest("POST", "/plans/1/apply", ""), &models.PlanValidationError{Errors: []models.PlanOperationError{
+		{Index: 0, Op: models.OpMove, Err: models.ErrCycleDetected},
+		{Index: 2, Op: models.OpRename, Err: errors.New("something odd")},
+	}})
+	problem = expectProblem(t, rec, http.StatusConflict, CodePlanInvalid)
+	if len(problem.Errors) != 2 ||
+		problem.Errors[0] != (FieldError{Field: "operations[0]", Code: "department_cycle", Message: models.ErrCycleDetected.Error()}) ||
+		problem.Errors[1] != (FieldError{Field: "operations[2]", Code: CodeValidation, Message: "something odd"}) {
+		t.Fatalf("unexpected plan problem: %+v", problem)
+	}
+}
+
+func TestWriteErrorInternal(t *testing.T) {
+	r, _ := newTestRepository()
+	var logged bytes.Buffer
+	r.Log = logger.NewTo(io.Discard, &logged)
+
+	// Текст внутренней ошибки пишется в лог, но не клиенту
+	rec := httptest.NewRecorder()
+	r.writeError(rec, newRequest("GET", "/departments/1", ""), errors.New("pq: password authentication failed"))
+
+	problem := expectProblem(t, rec, http.StatusInternalServerError, CodeInternal)
+	if strings.Contains(rec.Body.String(), "password") || problem.Detail != "internal server error" || len(problem.Errors) != 0 {
+		t.Fatalf("internal error leaked: %s", rec.Body)
+	}
+	if !strings.Contains(logged.String(), "pq: password authentication failed") || !strings.Contains(logged.String(), "/departments/1") {
+		t.Fatalf("internal error not logged: %q", logged.String())
+	}
+}
+
+func TestWriteProblemHelpers(t *testing.T) {
+	rec := httptest.NewRecorder()
+	writeFieldProblem(rec, newRequest("GET", "/departments?depth=9", ""), "depth", "depth must be between 1 and 5")
+	problem := expectProblem(t, rec, http.StatusBadRequest, CodeInvalidParameter)
+	if problem.Title != "Invalid parameter" || problem.Instance != "/departments" ||
+		len(problem.Errors) != 1 || problem.Errors[0].Field != "depth" {
+		t.Fatalf("unexpected field problem: %+v", problem)
+	}
+
+	rec = httptest.NewRecorder()
+	writeProblem(rec, newRequest("POST", "/departments", ""), http.StatusUnprocessableEntity, CodeMalformedBody, "bad json")
+	if problem = expectProblem(t, rec, http.StatusUnprocessableEntity, CodeMalformedBody); problem.Title != http.StatusText(http.StatusUnprocessableEntity) || problem.Detail != "bad json" {
+		t.Fatalf("unexpected problem: %+v", problem)
+	}
+}
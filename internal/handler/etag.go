@@ -0,0 +1,81 @@
+package handler
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// Коды ошибок предусловий
+const (
+	CodePreconditionRequired = "precondition_required"
+	CodePreconditionFailed   = "version_mismatch"
+)
+
+// ETag вида "<version>-<hash>": версия строки и отпечаток представления
+func makeETag(version int, body []byte) string {
+	sum := sha256.Sum256(body)
+	return `"` + strconv.Itoa(version) + "-" + hex.EncodeToString(sum[:6]) + `"`
+}
+
+// Версия из If-Match; "*" дает 0 (любая версия). false - ответ уже записан
+func ifMatchVersion(w http.ResponseWriter, req *http.Request) (int, bool) {
+	header := strings.TrimSpace(req.Header.Get("If-Match"))
+	if header == "" {
+		writeProblem(w, req, http.StatusPreconditionRequired, CodePreconditionRequired, "If-Match header is required")
+		return 0, false
+	}
+	if header == "*" {
+		return 0, true
+	}
+
+	// Слабые ETag для If-Match не подходят
+	tag := strings.Trim(strings.TrimSpace(strings.Split(header, ",")[0]), `"`)
+	versionStr, _, _ := strings.Cut(tag, "-")
+	version, err := strconv.Atoi(versionStr)
+	if strings.HasPrefix(header, "W/") || err != nil || version <= 0 {
+		writeProblem(w, req, http.StatusPreconditionFailed, CodePreconditionFailed, "If-Match does not match current version")
+		return 0, false
+	}
+	return version, true
+}
+
+// Совпадает ли If-None-Match с ETag (слабое сравнение)
+func ifNoneMatch(req *http.Request, etag string) bool {
+	header := req.Header.Get("If-None-Match")
+	if header == "" {
+		return false
+	}
+
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+		if tag == "*" || tag == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// writeJSONWithETag успешный ответ с ETag; для GET учитывает If-None-Match
+func writeJSONWithETag(w http.ResponseWriter, req *http.Request, status, version int, v interface{}) {
+	body, err := json.Marshal(v)
+	if err != nil {
+		writeProblem(w, req, http.StatusInternalServerError, CodeInternal, "internal server error")
+		return
+	}
+
+	etag := makeETag(version, body)
+	w.Header().Set("ETag", etag)
+
+	if req.Method == http.MethodGet && ifNoneMatch(req, etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(append(body, '\n'))
+}
@@ -0,0 +1,118 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+
+	"github.com/kroulersama/goProject/models"
+)
+
+func TestMakeETag(t *testing.T) {
+	etag := makeETag(7, []byte(`{"id":1}`))
+	if !regexp.MustCompile(`^"7-[0-9a-f]{12}"$`).MatchString(etag) {
+		t.Fatalf("unexpected ETag format: %s", etag)
+	}
+	if makeETag(7, []byte(`{"id":1}`)) != etag || makeETag(7, []byte(`{"id":2}`)) == etag || makeETag(8, []byte(`{"id":1}`)) == etag {
+		t.Fatal("ETag must depend on version and body only")
+	}
+}
+
+func TestIfMatchVersion(t *testing.T) {
+	tests := []struct {
+		header  string
+		version int
+		status  int
+	}{
+		{`"3-0a1b2c3d4e5f"`, 3, 0},
+		{`"12-x"`, 12, 0},
+		{` "3-abc" `, 3, 0},
+		{`"4-a", "5-b"`, 4, 0},
+		{`*`, 0, 0},
+		{``, 0, http.StatusPreconditionRequired},
+		{`W/"3-abc"`, 0, http.StatusPreconditionFailed},
+		{`"abc"`, 0, http.StatusPreconditionFailed},
+		{`"0-abc"`, 0, http.StatusPreconditionFailed},
+		{`"-3-abc"`, 0, http.StatusPreconditionFailed},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		version, ok := ifMatchVersion(rec, newRequest("PATCH", "/departments/1", "", "If-Match", tt.header))
+		if tt.status == 0 {
+			if !ok || version != tt.version {
+				t.Errorf("If-Match %q: got %d, %v, want %d", tt.header, version, ok, tt.version)
+			}
+			continue
+		}
+		if ok {
+			t.Errorf("If-Match %q accepted as %d", tt.header, version)
+			continue
+		}
+		code := CodePreconditionFailed
+		if tt.status == http.StatusPreconditionRequired {
+			code = CodePreconditionRequired
+		}
+		expectProblem(t, rec, tt.status, code)
+	}
+}
+
+func TestDepartmentPreconditions(t *testing.T) {
+	r, store := newTestRepository()
+	dept, err := store.CreateDepartment(context.Background(), &models.DepartmentRequest{Name: "Sales"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	get := func(header ...string) *httptest.ResponseRecorder {
+		return serve("GET /departments/{id}", r.GetDepartment, newRequest("GET", "/departments/1", "", header...))
+	}
+	patch := func(body string, header ...string) *httptest.ResponseRecorder {
+		return serve("PATCH /departments/{id}", r.MoveDepartment, newRequest("PATCH", "/departments/1", body, header...))
+	}
+
+	rec := get()
+	etag := rec.Header().Get("ETag")
+	if rec.Code != http.StatusOK || etag != makeETag(dept.Version, trimNewline(rec.Body.Bytes())) {
+		t.Fatalf("GET: %d, ETag %q", rec.Code, etag)
+	}
+
+	// If-None-Match: совпадение дает 304 без тела, в том числе слабое и *
+	for _, header := range []string{etag, "W/" + etag, `"9-zzz", ` + etag, "*"} {
+		rec = get("If-None-Match", header)
+		if rec.Code != http.StatusNotModified || rec.Body.Len() != 0 || rec.Header().Get("ETag") != etag {
+			t.Fatalf("If-None-Match %s: %d %q", header, rec.Code, rec.Body)
+		}
+	}
+	if rec = get("If-None-Match", `"9-zzz"`); rec.Code != http.StatusOK {
+		t.Fatalf("If-None-Match mismatch: %d", rec.Code)
+	}
+
+	// Изменение без If-Match, с устаревшим и с кривым
+	expectProblem(t, patch(`{"name": "Sales 2"}`), http.StatusPreconditionRequired, CodePreconditionRequired)
+	expectProblem(t, patch(`{"name": "Sales 2"}`, "If-Match", `"9-zzz"`), http.StatusPreconditionFailed, CodePreconditionFailed)
+	expectProblem(t, patch(`{"name": "Sales 2"}`, "If-Match", "garbage"), http.StatusPreconditionFailed, CodePreconditionFailed)
+
+	// Текущий ETag проходит и дает новый; старый после этого устарел
+	rec = patch(`{"name": "Sales 2"}`, "If-Match", etag)
+	if rec.Code != http.StatusOK || rec.Header().Get("ETag") == "" || rec.Header().Get("ETag") == etag {
+		t.Fatalf("PATCH with current ETag: %d, ETag %q", rec.Code, rec.Header().Get("ETag"))
+	}
+	expectProblem(t, patch(`{"name": "Sales 3"}`, "If-Match", etag), http.StatusPreconditionFailed, CodePreconditionFailed)
+
+	// If-None-Match учитывается только для GET
+	rec = patch(`{"name": "Sales 3"}`, "If-Match", "*", "If-None-Match", "*")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("PATCH with If-None-Match: %d", rec.Code)
+	}
+	if rec = get("If-None-Match", etag); rec.Code != http.StatusOK {
+		t.Fatalf("old ETag still matches: %d", rec.Code)
+	}
+}
+
+func trimNewline(b []byte) []byte {
+	if n := len(b); n > 0 && b[n-1] == '\n' {
+		return b[:n-1]
+	}
+	return b
+}
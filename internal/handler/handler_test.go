@@ -0,0 +1,74 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kroulersama/goProject/models"
+	"github.com/kroulersama/goProject/pkg/logger"
+	"github.com/kroulersama/goProject/storage/memory"
+)
+
+// Repository на хранилище в памяти
+func newTestRepository() (*Repository, *memory.Store) {
+	store := memory.New(models.DefaultUniquenessPolicy)
+	return &Repository{
+		Departments:    store,
+		Employees:      store,
+		Attributes:     store,
+		Positions:      store,
+		Assignments:    store,
+		Secondary:      store,
+		Reporting:      store,
+		Stats:          store,
+		Staffing:       store,
+		Rules:          store,
+		Integrity:      store,
+		Idempotency:    store,
+		Plans:          store,
+		Schedule:       store,
+		IdempotencyTTL: time.Hour,
+		Log:            logger.New(),
+	}, store
+}
+
+// Запрос; header - пары имя, значение
+func newRequest(method, target, body string, header ...string) *http.Request {
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	for i := 0; i+1 < len(header); i += 2 {
+		req.Header.Set(header[i], header[i+1])
+	}
+	return req
+}
+
+// Запрос через маршрут pattern, чтобы работали PathValue
+func serve(pattern string, h http.HandlerFunc, req *http.Request) *httptest.ResponseRecorder {
+	mux := http.NewServeMux()
+	mux.HandleFunc(pattern, h)
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+	return rec
+}
+
+// Ответ problem+json с ожидаемым статусом и кодом
+func expectProblem(t *testing.T, rec *httptest.ResponseRecorder, status int, code string) Problem {
+	t.Helper()
+	if rec.Code != status {
+		t.Fatalf("status %d, want %d: %s", rec.Code, status, rec.Body)
+	}
+	if ct := rec.Header().Get("Content-Type"); ct != "application/problem+json" {
+		t.Fatalf("Content-Type %q", ct)
+	}
+	var problem Problem
+	if err := json.Unmarshal(rec.Body.Bytes(), &problem); err != nil {
+		t.Fatalf("decode problem: %v: %s", err, rec.Body)
+	}
+	if problem.Status != status || problem.Code != code || problem.Type != problemTypeBase+code || problem.Title == "" {
+		t.Fatalf("unexpected problem: %+v", problem)
+	}
+	return problem
+}
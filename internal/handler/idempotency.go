@@ -0,0 +1,167 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/kroulersama/goProject/models"
+)
+
+// Через сколько незавершенный запрос считается брошенным, если дедлайн маршрута короче
+const idempotencyStaleAfter = time.Minute
+
+// Запоминает ответ, продолжая писать клиенту
+type responseRecorder struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (rec *responseRecorder) WriteHeader(status int) {
+	rec.status = status
+	rec.ResponseWriter.WriteHeader(status)
+}
+
+func (rec *responseRecorder) Write(b []byte) (int, error) {
+	rec.body.Write(b)
+	return rec.ResponseWriter.Write(b)
+}
+
+// Idempotent повторяет сохраненный ответ для запроса с тем же Idempotency-Key
+func (r *Repository) Idempotent(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		key := strings.TrimSpace(req.Header.Get("Idempotency-Key"))
+		if key == "" || r.Idempotency == nil {
+			next(w, req)
+			return
+		}
+		if len(key) > 255 {
+			writeFieldProblem(w, req, "Idempotency-Key", "Idempotency-Key must be at most 255 characters")
+			return
+		}
+
+		// Тело читается целиком: оно входит в отпечаток запроса
+		body, err := io.ReadAll(req.Body)
+		if err != nil {
+			writeProblem(w, req, http.StatusUnprocessableEntity, CodeMalformedBody, "could not read request body")
+			return
+		}
+		req.Body = io.NopCloser(bytes.NewReader(body))
+
+		now := time.Now().UTC()
+		record := &models.IdempotencyKey{
+			Key:         key,
+			Method:      req.Method,
+			Path:        req.URL.Path,
+			RequestHash: requestHash(req, body),
+			CreatedAt:   now,
+			ExpiresAt:   now.Add(r.IdempotencyTTL),
+		}
+
+		existing, err := r.Idempotency.ReserveIdempotencyKey(req.Context(), record, r.staleAfter(req.Context()))
+		if err != nil {
+			r.Log.Error("Failed to reserve idempotency key", err, "key", key)
+			r.writeError(w, req, err)
+			return
+		}
+
+		// Ключ уже использовался
+		if existing != nil {
+			switch {
+			case existing.RequestHash != record.RequestHash:
+				r.writeError(w, req, models.ErrIdempotencyMismatch)
+			case existing.Pending():
+				r.writeError(w, req, models.ErrIdempotencyInProgress)
+			default:
+				replay(w, existing)
+			}
+			return
+		}
+
+		rec := &responseRecorder{ResponseWriter: w, status: http.StatusOK}
+		next(rec, req)
+
+		// Ответ сохраняется даже если клиент уже отключился
+		ctx := context.WithoutCancel(req.Context())
+
+		// Сбои сервера не запоминаются, запрос можно повторить
+		if rec.status >= http.StatusInternalServerError || rec.status == StatusClientClosedRequest {
+			if err := r.Idempotency.ReleaseIdempotencyKey(ctx, key); err != nil {
+				r.Log.Error("Failed to release idempotency key", err, "key", key)
+			}
+			return
+		}
+
+		record.StatusCode = rec.status
+		record.ContentType = rec.Header().Get("Content-Type")
+		record.ETag = rec.Header().Get("ETag")
+		record.Body = rec.body.Bytes()
+		if err := r.Idempotency.CompleteIdempotencyKey(ctx, record); err != nil {
+			r.Log.Error("Failed to store idempotent response", err, "key", key)
+		}
+	}
+}
+
+// PurgeIdempotencyKeys периодически удаляет просроченные ключи до отмены ctx
+func (r *Repository) PurgeIdempotencyKeys(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			purged, err := r.Idempotency.PurgeExpiredIdempotencyKeys(ctx, time.Now().UTC())
+			if err != nil {
+				r.Log.Error("Failed to purge idempotency keys", err)
+				continue
+			}
+			if purged > 0 {
+				r.Log.Info("Idempotency keys purged", "count", purged)
+			}
+		}
+	}
+}
+
+// staleAfter через сколько ключ без ответа можно перехватить: не раньше дедлайна
+// маршрута, иначе повтор выполнит запрос второй раз, пока первый еще идет. Маршрут без
+// дедлайна держит ключ до истечения IdempotencyTTL
+func (r *Repository) staleAfter(ctx context.Context) time.Duration {
+	d, ok := routeTimeout(ctx)
+	switch {
+	case !ok:
+		return idempotencyStaleAfter
+	case d <= 0:
+		return max(r.IdempotencyTTL, idempotencyStaleAfter)
+	default:
+		return max(d, idempotencyStaleAfter)
+	}
+}
+
+// Отпечаток запроса: метод, путь и тело
+func requestHash(req *http.Request, body []byte) string {
+	h := sha256.New()
+	h.Write([]byte(req.Method + " " + req.URL.Path + "\n"))
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// Повтор сохраненного ответа
+func replay(w http.ResponseWriter, record *models.IdempotencyKey) {
+	if record.ContentType != "" {
+		w.Header().Set("Content-Type", record.ContentType)
+	}
+	if record.ETag != "" {
+		w.Header().Set("ETag", record.ETag)
+	}
+	w.Header().Set("Idempotent-Replayed", "true")
+	w.WriteHeader(record.StatusCode)
+	w.Write(record.Body)
+}
@@ -0,0 +1,199 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/kroulersama/goProject/models"
+)
+
+func TestIdempotentReplay(t *testing.T) {
+	r, _ := newTestRepository()
+	h := r.Idempotent(r.CreateDepartment)
+	body := `{"name": "Sales"}`
+
+	first := serve("POST /departments", h, newRequest("POST", "/departments", body, "Idempotency-Key", "k1"))
+	if first.Code != http.StatusCreated {
+		t.Fatalf("first request: %d %s", first.Code, first.Body)
+	}
+
+	// Повтор не создает второе подразделение, а возвращает тот же ответ
+	second := serve("POST /departments", h, newRequest("POST", "/departments", body, "Idempotency-Key", "k1"))
+	if second.Code != http.StatusCreated || second.Header().Get("Idempotent-Replayed") != "true" {
+		t.Fatalf("replay: %d %v", second.Code, second.Header())
+	}
+	if second.Body.String() != first.Body.String() || second.Header().Get("Content-Type") != first.Header().Get("Content-Type") {
+		t.Fatalf("replayed response differs: %s vs %s", second.Body, first.Body)
+	}
+	if list, err := r.Departments.ListDepartments(context.Background(), &models.DepartmentFilter{}); err != nil || len(list) != 1 {
+		t.Fatalf("departments after replay: %+v, %v", list, err)
+	}
+
+	// Тот же ключ с другим телом
+	rec := serve("POST /departments", h, newRequest("POST", "/departments", `{"name": "Other"}`, "Idempotency-Key", "k1"))
+	expectProblem(t, rec, http.StatusUnprocessableEntity, "idempotency_key_reused")
+
+	// Без ключа запрос выполняется как обычно
+	rec = serve("POST /departments", h, newRequest("POST", "/departments", `{"name": "Plain"}`))
+	if rec.Code != http.StatusCreated || rec.Header().Get("Idempotent-Replayed") != "" {
+		t.Fatalf("request without key: %d %v", rec.Code, rec.Header())
+	}
+
+	long := make([]byte, 256)
+	for i := range long {
+		long[i] = 'k'
+	}
+	rec = serve("POST /departments", h, newRequest("POST", "/departments", body, "Idempotency-Key", string(long)))
+	expectProblem(t, rec, http.StatusBadRequest, CodeInvalidParameter)
+}
+
+func TestIdempotentInProgress(t *testing.T) {
+	r, _ := newTestRepository()
+
+	// Повтор приходит, пока первый запрос еще выполняется
+	var nested *httptest.ResponseRecorder
+	var h http.HandlerFunc
+	h = r.Idempotent(func(w http.ResponseWriter, req *http.Request) {
+		nested = serve("POST /jobs", h, newRequest("POST", "/jobs", "{}", "Idempotency-Key", "k1"))
+		writeJSON(w, http.StatusOK, map[string]string{"status": "done"})
+	})
+
+	rec := serve("POST /jobs", h, newRequest("POST", "/jobs", "{}", "Idempotency-Key", "k1"))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("first request: %d %s", rec.Code, rec.Body)
+	}
+	expectProblem(t, nested, http.StatusConflict, "idempotency_key_in_progress")
+}
+
+func TestIdempotentReleasesFailures(t *testing.T) {
+	for _, status := range []int{http.StatusInternalServerError, http.StatusGatewayTimeout, StatusClientClosedRequest} {
+		r, _ := newTestRepository()
+		var calls atomic.Int32
+		h := r.Idempotent(func(w http.ResponseWriter, req *http.Request) {
+			if calls.Add(1) == 1 {
+				writeProblem(w, req, status, CodeInternal, "failed")
+				return
+			}
+			writeJSON(w, http.StatusOK, map[string]string{"status": "done"})
+		})
+
+		// Сбой не запоминается, повтор выполняет запрос заново
+		if rec := serve("POST /jobs", h, newRequest("POST", "/jobs", "{}", "Idempotency-Key", "k1")); rec.Code != status {
+			t.Fatalf("first request: %d, want %d", rec.Code, status)
+		}
+		rec := serve("POST /jobs", h, newRequest("POST", "/jobs", "{}", "Idempotency-Key", "k1"))
+		if rec.Code != http.StatusOK || rec.Header().Get("Idempotent-Replayed") != "" || calls.Load() != 2 {
+			t.Fatalf("retry after %d: %d, %d calls", status, rec.Code, calls.Load())
+		}
+	}
+}
+
+func TestIdempotentStaleAfterRouteTimeout(t *testing.T) {
+	r, store := newTestRepository()
+
+	tests := []struct {
+		name    string
+		timeout *time.Duration
+		want    time.Duration
+	}{
+		{"no route timeout", nil, idempotencyStaleAfter},
+		{"short route", durationPtr(5 * time.Second), idempotencyStaleAfter},
+		{"long route", durationPtr(5 * time.Minute), 5 * time.Minute},
+		{"unlimited route", durationPtr(0), r.IdempotencyTTL},
+	}
+	for _, tt := range tests {
+		var got time.Duration
+		h := func(w http.ResponseWriter, req *http.Request) { got = r.staleAfter(req.Context()) }
+		if tt.timeout != nil {
+			h = WithTimeout(*tt.timeout, h)
+		}
+		h(httptest.NewRecorder(), newRequest("POST", "/jobs", "{}"))
+		if got != tt.want {
+			t.Errorf("%s: staleAfter %v, want %v", tt.name, got, tt.want)
+		}
+	}
+
+	// Запрос с дедлайном 5 минут начат 2 минуты назад: повтор не перехватывает ключ
+	req := newRequest("POST", "/jobs", "{}", "Idempotency-Key", "k1")
+	now := time.Now().UTC()
+	_, err := store.ReserveIdempotencyKey(context.Background(), &models.IdempotencyKey{
+		Key:         "k1",
+		Method:      "POST",
+		Path:        "/jobs",
+		RequestHash: requestHash(req, []byte("{}")),
+		CreatedAt:   now.Add(-2 * time.Minute),
+		ExpiresAt:   now.Add(time.Hour),
+	}, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var calls atomic.Int32
+	job := r.Idempotent(func(w http.ResponseWriter, req *http.Request) {
+		calls.Add(1)
+		writeJSON(w, http.StatusOK, map[string]string{"status": "done"})
+	})
+	rec := serve("POST /jobs", WithTimeout(5*time.Minute, job), req)
+	expectProblem(t, rec, http.StatusConflict, "idempotency_key_in_progress")
+
+	// С дедлайном короче минуты запрос давно брошен
+	rec = serve("POST /jobs", WithTimeout(5*time.Second, job), newRequest("POST", "/jobs", "{}", "Idempotency-Key", "k1"))
+	if rec.Code != http.StatusOK || calls.Load() != 1 {
+		t.Fatalf("abandoned key not taken over: %d, %d calls", rec.Code, calls.Load())
+	}
+}
+
+// Хранилище, сообщающее о каждой очистке
+type purgeSignal struct {
+	models.IdempotencyStore
+	purged chan int64
+}
+
+func (p *purgeSignal) PurgeExpiredIdempotencyKeys(ctx context.Context, now time.Time) (int64, error) {
+	n, err := p.IdempotencyStore.PurgeExpiredIdempotencyKeys(ctx, now)
+	p.purged <- n
+	return n, err
+}
+
+func TestPurgeIdempotencyKeys(t *testing.T) {
+	r, store := newTestRepository()
+	now := time.Now().UTC()
+	for key, expiresAt := range map[string]time.Time{"old": now.Add(-time.Minute), "live": now.Add(time.Hour)} {
+		if _, err := store.ReserveIdempotencyKey(context.Background(), &models.IdempotencyKey{Key: key, CreatedAt: now, ExpiresAt: expiresAt}, time.Minute); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	signal := &purgeSignal{IdempotencyStore: store, purged: make(chan int64)}
+	r.Idempotency = signal
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		r.PurgeIdempotencyKeys(ctx, time.Millisecond)
+		close(done)
+	}()
+
+	if n := <-signal.purged; n != 1 {
+		t.Fatalf("first purge removed %d keys, want 1", n)
+	}
+	if n := <-signal.purged; n != 0 {
+		t.Fatalf("second purge removed %d keys, want 0", n)
+	}
+	cancel()
+	// Очистка могла начаться до отмены
+	for {
+		select {
+		case <-signal.purged:
+		case <-done:
+			return
+		}
+	}
+}
+
+func durationPtr(d time.Duration) *time.Duration {
+	return &d
+}
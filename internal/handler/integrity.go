@@ -0,0 +1,21 @@
+package handler
+
+import (
+	"net/http"
+)
+
+// CheckIntegrity отчет о нарушениях целостности; только чтение,
+// исправления применяет команда check --repair
+func (r *Repository) CheckIntegrity(w http.ResponseWriter, req *http.Request) {
+	if !checkMethod(w, req, http.MethodGet) {
+		return
+	}
+
+	report, err := r.Integrity.CheckIntegrity(req.Context(), false)
+	if err != nil {
+		r.Log.Error("Failed check integrity", err)
+		r.writeError(w, req, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, report)
+}
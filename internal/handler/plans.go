@@ -0,0 +1,215 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/kroulersama/goProject/models"
+)
+
+// Тело запроса на добавление операций
+type addPlanOperationsRequest struct {
+	Operations []models.PlanOperation `json:"operations"`
+}
+
+// CreatePlan создание черновика плана
+func (r *Repository) CreatePlan(w http.ResponseWriter, req *http.Request) {
+	r.Log.Info("Creating plan", "method", req.Method)
+
+	// Проверка метода
+	if !checkMethod(w, req, http.MethodPost) {
+		return
+	}
+
+	// Декодирование JSON
+	var planReq models.PlanRequest
+	if !r.decodeBody(w, req, &planReq) {
+		return
+	}
+
+	// Обработка в модуле
+	plan, err := r.Plans.CreatePlan(req.Context(), &planReq)
+	if err != nil {
+		r.Log.Error("Failed to create plan", err, "name", planReq.Name)
+		r.writeError(w, req, err)
+		return
+	}
+	r.Log.Info("Plan created", "id", plan.ID, "operations", len(plan.Operations))
+
+	writeJSONWithETag(w, req, http.StatusCreated, plan.Version, map[string]interface{}{
+		"message": "plan created successfully",
+		"data":    plan,
+	})
+}
+
+// ListPlans список планов
+func (r *Repository) ListPlans(w http.ResponseWriter, req *http.Request) {
+	if !checkMethod(w, req, http.MethodGet) {
+		return
+	}
+
+	plans, err := r.Plans.ListPlans(req.Context())
+	if err != nil {
+		r.Log.Error("Failed list plans", err)
+		r.writeError(w, req, err)
+		return
+	}
+	if plans == nil {
+		plans = []models.Plan{}
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"data": plans})
+}
+
+// GetPlan план по id
+func (r *Repository) GetPlan(w http.ResponseWriter, req *http.Request) {
+	if !checkMethod(w, req, http.MethodGet) {
+		return
+	}
+
+	planID, ok := pathID(w, req, "id")
+	if !ok {
+		return
+	}
+
+	plan, err := r.Plans.GetPlan(req.Context(), planID)
+	if err != nil {
+		r.Log.Error("Failed get plan", err, "id", planID)
+		r.writeError(w, req, err)
+		return
+	}
+
+	// Ответ, 304 если If-None-Match совпал
+	writeJSONWithETag(w, req, http.StatusOK, plan.Version, plan)
+}
+
+// AddPlanOperations добавление операций в черновик
+func (r *Repository) AddPlanOperations(w http.ResponseWriter, req *http.Request) {
+	r.Log.Info("Adding plan operations", "method", req.Method)
+
+	// Проверка метода
+	if !checkMethod(w, req, http.MethodPost) {
+		return
+	}
+
+	// Получение id
+	planID, ok := pathID(w, req, "id")
+	if !ok {
+		return
+	}
+
+	// Версия из If-Match
+	version, ok := ifMatchVersion(w, req)
+	if !ok {
+		return
+	}
+
+	// Декодирование JSON
+	var opsReq addPlanOperationsRequest
+	if !r.decodeBody(w, req, &opsReq) {
+		return
+	}
+	if len(opsReq.Operations) == 0 {
+		writeFieldProblem(w, req, "operations", "operations must not be empty")
+		return
+	}
+
+	// Логика в модели
+	plan, err := r.Plans.AddPlanOperations(req.Context(), planID, version, opsReq.Operations)
+	if err != nil {
+		r.Log.Error("Failed add plan operations", err, "id", planID)
+		r.writeError(w, req, err)
+		return
+	}
+	r.Log.Info("Plan operations added", "id", planID, "operations", len(plan.Operations))
+
+	writeJSONWithETag(w, req, http.StatusOK, plan.Version, map[string]interface{}{
+		"message": "operations added successfully",
+		"data":    plan,
+	})
+}
+
+// DeletePlan удаление плана
+func (r *Repository) DeletePlan(w http.ResponseWriter, req *http.Request) {
+	r.Log.Info("del plan", "method", req.Method)
+
+	// Проверка метода
+	if !checkMethod(w, req, http.MethodDelete) {
+		return
+	}
+
+	// Получаем Id
+	planID, ok := pathID(w, req, "id")
+	if !ok {
+		return
+	}
+
+	// Версия из If-Match
+	version, ok := ifMatchVersion(w, req)
+	if !ok {
+		return
+	}
+
+	if err := r.Plans.DeletePlan(req.Context(), planID, version); err != nil {
+		r.Log.Error("Failed del plan", err, "id", planID)
+		r.writeError(w, req, err)
+		return
+	}
+	r.Log.Info("Plan del", "id", planID)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// DiffPlan изменения дерева, которые внесет план
+func (r *Repository) DiffPlan(w http.ResponseWriter, req *http.Request) {
+	if !checkMethod(w, req, http.MethodGet) {
+		return
+	}
+
+	planID, ok := pathID(w, req, "id")
+	if !ok {
+		return
+	}
+
+	diff, err := r.Plans.DiffPlan(req.Context(), planID)
+	if err != nil {
+		r.Log.Error("Failed diff plan", err, "id", planID)
+		r.writeError(w, req, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, diff)
+}
+
+// ApplyPlan атомарное выполнение плана
+func (r *Repository) ApplyPlan(w http.ResponseWriter, req *http.Request) {
+	r.Log.Info("Applying plan", "method", req.Method)
+
+	// Проверка метода
+	if !checkMethod(w, req, http.MethodPost) {
+		return
+	}
+
+	// Получение id
+	planID, ok := pathID(w, req, "id")
+	if !ok {
+		return
+	}
+
+	// Версия из If-Match
+	version, ok := ifMatchVersion(w, req)
+	if !ok {
+		return
+	}
+
+	// Логика в модели
+	plan, err := r.Plans.ApplyPlan(req.Context(), planID, version)
+	if err != nil {
+		r.Log.Error("Failed apply plan", err, "id", planID)
+		r.writeError(w, req, err)
+		return
+	}
+	r.Log.Info("Plan applied", "id", planID, "operations", len(plan.Operations))
+
+	writeJSONWithETag(w, req, http.StatusOK, plan.Version, map[string]interface{}{
+		"message": "plan applied successfully",
+		"data":    plan,
+	})
+}
@@ -0,0 +1,165 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/kroulersama/goProject/models"
+)
+
+// CreatePosition добавление должности в каталог
+func (r *Repository) CreatePosition(w http.ResponseWriter, req *http.Request) {
+	r.Log.Info("Creating position", "method", req.Method)
+
+	// Проверка метода
+	if !checkMethod(w, req, http.MethodPost) {
+		return
+	}
+
+	// Декодирование JSON
+	var posReq models.PositionRequest
+	if !r.decodeBody(w, req, &posReq) {
+		return
+	}
+
+	// Обработка в модуле
+	position, err := r.Positions.CreatePosition(req.Context(), &posReq)
+	if err != nil {
+		r.Log.Error("Failed to create position", err, "title", posReq.Title)
+		r.writeError(w, req, err)
+		return
+	}
+	r.Log.Info("Position created", "id", position.ID, "title", position.Title)
+
+	writeJSONWithETag(w, req, http.StatusCreated, position.Version, map[string]interface{}{
+		"message": "position created successfully",
+		"data":    position,
+	})
+}
+
+// ListPositions каталог должностей, ?job_family= - по семейству
+func (r *Repository) ListPositions(w http.ResponseWriter, req *http.Request) {
+	if !checkMethod(w, req, http.MethodGet) {
+		return
+	}
+
+	positions, err := r.Positions.ListPositions(req.Context(), req.URL.Query().Get("job_family"))
+	if err != nil {
+		r.Log.Error("Failed list positions", err)
+		r.writeError(w, req, err)
+		return
+	}
+	if positions == nil {
+		positions = []models.Position{}
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"data": positions})
+}
+
+// GetPosition должность по id
+func (r *Repository) GetPosition(w http.ResponseWriter, req *http.Request) {
+	if !checkMethod(w, req, http.MethodGet) {
+		return
+	}
+
+	positionID, ok := pathID(w, req, "id")
+	if !ok {
+		return
+	}
+
+	position, err := r.Positions.GetPosition(req.Context(), positionID)
+	if err != nil {
+		r.Log.Error("Failed get position", err, "id", positionID)
+		r.writeError(w, req, err)
+		return
+	}
+
+	// Ответ, 304 если If-None-Match совпал
+	writeJSONWithETag(w, req, http.StatusOK, position.Version, position)
+}
+
+// UpdatePosition изменение должности
+func (r *Repository) UpdatePosition(w http.ResponseWriter, req *http.Request) {
+	r.Log.Info("Updating position", "method", req.Method)
+
+	// Проверка метода
+	if !checkMethod(w, req, http.MethodPatch) {
+		return
+	}
+
+	// Получение id
+	positionID, ok := pathID(w, req, "id")
+	if !ok {
+		return
+	}
+
+	// Версия из If-Match
+	version, ok := ifMatchVersion(w, req)
+	if !ok {
+		return
+	}
+
+	// Декодирование JSON
+	var update models.PositionUpdate
+	if !r.decodeBody(w, req, &update) {
+		return
+	}
+
+	position, err := r.Positions.UpdatePosition(req.Context(), positionID, version, &update)
+	if err != nil {
+		r.Log.Error("Failed update position", err, "id", positionID)
+		r.writeError(w, req, err)
+		return
+	}
+	r.Log.Info("Position updated", "id", position.ID, "title", position.Title)
+
+	writeJSONWithETag(w, req, http.StatusOK, position.Version, map[string]interface{}{
+		"message": "position updated successfully",
+		"data":    position,
+	})
+}
+
+// DeletePosition удаление должности без сотрудников
+func (r *Repository) DeletePosition(w http.ResponseWriter, req *http.Request) {
+	r.Log.Info("del position", "method", req.Method)
+
+	// Проверка метода
+	if !checkMethod(w, req, http.MethodDelete) {
+		return
+	}
+
+	// Получаем Id
+	positionID, ok := pathID(w, req, "id")
+	if !ok {
+		return
+	}
+
+	// Версия из If-Match
+	version, ok := ifMatchVersion(w, req)
+	if !ok {
+		return
+	}
+
+	if err := r.Positions.DeletePosition(req.Context(), positionID, version); err != nil {
+		r.Log.Error("Failed del position", err, "id", positionID)
+		r.writeError(w, req, err)
+		return
+	}
+	r.Log.Info("Position del", "id", positionID)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// PositionClusters предложение группировки текста должностей в записи каталога;
+// применяет его команда positions cluster --apply
+func (r *Repository) PositionClusters(w http.ResponseWriter, req *http.Request) {
+	if !checkMethod(w, req, http.MethodGet) {
+		return
+	}
+
+	clustering, err := r.Positions.ClusterPositionTitles(req.Context(), false)
+	if err != nil {
+		r.Log.Error("Failed cluster positions", err)
+		r.writeError(w, req, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, clustering)
+}
@@ -0,0 +1,261 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/kroulersama/goProject/models"
+)
+
+// Поле тела, где вместо id подразделения можно передать "code:..." или "ext:...:...",
+// и ошибка для ненайденной ссылки
+type bodyRefField struct {
+	name     string
+	notFound error
+}
+
+// Ссылки верхнего уровня тела
+var bodyRefFields = []bodyRefField{
+	{"parent_id", models.ErrParentNotFound},
+	{"department_id", models.ErrUnknownDepartment},
+	{"child_ids", models.ErrNotChildDepartment},
+}
+
+// Ссылки в операциях плана (operations[])
+var planOperationRefFields = []bodyRefField{
+	{"department_id", models.ErrDepartmentNotFound},
+	{"parent_id", models.ErrParentNotFound},
+	{"to_department_id", models.ErrTargetNotFound},
+}
+
+// GetDepartmentByCode подразделение по коду
+func (r *Repository) GetDepartmentByCode(w http.ResponseWriter, req *http.Request) {
+	r.Log.Info("Getting department by code", "method", req.Method)
+
+	if !checkMethod(w, req, http.MethodGet) {
+		return
+	}
+
+	departmentID, ok := r.resolveDepartment(w, req, "code", models.DepartmentRef{Code: req.PathValue("code")}, models.ErrDepartmentNotFound)
+	if !ok {
+		return
+	}
+	r.getDepartment(w, req, departmentID)
+}
+
+// GetDepartmentByExternalID подразделение по id во внешней системе
+func (r *Repository) GetDepartmentByExternalID(w http.ResponseWriter, req *http.Request) {
+	r.Log.Info("Getting department by external id", "method", req.Method)
+
+	if !checkMethod(w, req, http.MethodGet) {
+		return
+	}
+
+	ref := models.DepartmentRef{System: req.PathValue("system"), ExternalID: req.PathValue("external_id")}
+	departmentID, ok := r.resolveDepartment(w, req, "external_id", ref, models.ErrDepartmentNotFound)
+	if !ok {
+		return
+	}
+	r.getDepartment(w, req, departmentID)
+}
+
+// GetEmployeeByPersonnelNumber сотрудник по табельному номеру
+func (r *Repository) GetEmployeeByPersonnelNumber(w http.ResponseWriter, req *http.Request) {
+	r.Log.Info("Getting employee by personnel number", "method", req.Method)
+
+	if !checkMethod(w, req, http.MethodGet) {
+		return
+	}
+
+	ref := models.EmployeeRef{PersonnelNumber: req.PathValue("number")}
+	employeeID, err := r.Employees.ResolveEmployee(req.Context(), ref)
+	if err != nil {
+		r.writeRefError(w, req, "number", err)
+		return
+	}
+
+	employee, err := r.Employees.GetEmployee(req.Context(), employeeID)
+	if err != nil {
+		r.Log.Error("Failed get employee", err, "id", employeeID)
+		r.writeError(w, req, err)
+		return
+	}
+
+	writeJSONWithETag(w, req, http.StatusOK, employee.Version, employee)
+}
+
+// departmentID id подразделения из пути: число, "code:<code>" или "ext:<system>:<id>"
+func (r *Repository) departmentID(w http.ResponseWriter, req *http.Request, name string, notFound error) (uint, bool) {
+	raw := req.PathValue(name)
+	if raw == "" {
+		writeFieldProblem(w, req, name, name+" is required")
+		return 0, false
+	}
+
+	ref, err := models.ParseDepartmentRef(raw)
+	if err != nil {
+		r.writeRefError(w, req, name, err)
+		return 0, false
+	}
+	return r.resolveDepartment(w, req, name, ref, notFound)
+}
+
+// employeeID id сотрудника из пути: число или "pn:<табельный номер>"
+func (r *Repository) employeeID(w http.ResponseWriter, req *http.Request, name string) (uint, bool) {
+	raw := req.PathValue(name)
+	if raw == "" {
+		writeFieldProblem(w, req, name, name+" is required")
+		return 0, false
+	}
+
+	ref, err := models.ParseEmployeeRef(raw)
+	if err == nil {
+		var id uint
+		if id, err = r.Employees.ResolveEmployee(req.Context(), ref); err == nil {
+			return id, true
+		}
+	}
+	r.writeRefError(w, req, name, err)
+	return 0, false
+}
+
+// queryDepartmentID необязательный id подразделения из query
+func (r *Repository) queryDepartmentID(w http.ResponseWriter, req *http.Request, name string, notFound error) (*uint, bool) {
+	raw := req.URL.Query().Get(name)
+	if raw == "" {
+		return nil, true
+	}
+
+	ref, err := models.ParseDepartmentRef(raw)
+	if err != nil {
+		r.writeRefError(w, req, name, err)
+		return nil, false
+	}
+	id, ok := r.resolveDepartment(w, req, name, ref, notFound)
+	if !ok {
+		return nil, false
+	}
+	return &id, true
+}
+
+// Поиск подразделения по ссылке; ненайденная ссылка - notFound
+func (r *Repository) resolveDepartment(w http.ResponseWriter, req *http.Request, field string, ref models.DepartmentRef, notFound error) (uint, bool) {
+	id, err := r.Departments.ResolveDepartment(req.Context(), ref)
+	if errors.Is(err, models.ErrDepartmentNotFound) {
+		err = notFound
+	}
+	if err != nil {
+		r.writeRefError(w, req, field, err)
+		return 0, false
+	}
+	return id, true
+}
+
+// decodeBody декодирует тело, заменяя строковые ссылки на подразделения их id
+func (r *Repository) decodeBody(w http.ResponseWriter, req *http.Request, v interface{}) bool {
+	var raw interface{}
+	decoder := json.NewDecoder(req.Body)
+	decoder.UseNumber()
+	if err := decoder.Decode(&raw); err != nil {
+		writeProblem(w, req, http.StatusUnprocessableEntity, CodeMalformedBody, "invalid request format: "+err.Error())
+		return false
+	}
+
+	if !r.resolveBodyRefs(w, req, raw) {
+		return false
+	}
+
+	data, err := json.Marshal(raw)
+	if err == nil {
+		decoder = json.NewDecoder(bytes.NewReader(data))
+		err = decoder.Decode(v)
+	}
+	if err != nil {
+		writeProblem(w, req, http.StatusUnprocessableEntity, CodeMalformedBody, "invalid request format: "+err.Error())
+		return false
+	}
+	return true
+}
+
+// Замена ссылок на id в полях верхнего уровня и в операциях плана;
+// attributes и другие вложенные объекты не просматриваются
+func (r *Repository) resolveBodyRefs(w http.ResponseWriter, req *http.Request, raw interface{}) bool {
+	body, ok := raw.(map[string]interface{})
+	if !ok {
+		return true
+	}
+	if !r.resolveRefFields(w, req, "", body, bodyRefFields) {
+		return false
+	}
+
+	operations, _ := body["operations"].([]interface{})
+	for i, item := range operations {
+		op, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if !r.resolveRefFields(w, req, fmt.Sprintf("operations[%d].", i), op, planOperationRefFields) {
+			return false
+		}
+	}
+	return true
+}
+
+// Ссылки в полях fields объекта obj; prefix - путь объекта для ошибки
+func (r *Repository) resolveRefFields(w http.ResponseWriter, req *http.Request, prefix string, obj map[string]interface{}, fields []bodyRefField) bool {
+	for _, f := range fields {
+		field := prefix + f.name
+		switch v := obj[f.name].(type) {
+		case string:
+			id, ok := r.resolveBodyRef(w, req, field, v, f.notFound)
+			if !ok {
+				return false
+			}
+			obj[f.name] = id
+		case []interface{}:
+			for i, item := range v {
+				raw, isRef := item.(string)
+				if !isRef {
+					continue
+				}
+				id, ok := r.resolveBodyRef(w, req, field, raw, f.notFound)
+				if !ok {
+					return false
+				}
+				v[i] = id
+			}
+		}
+	}
+	return true
+}
+
+// Строковая ссылка на подразделение из тела
+func (r *Repository) resolveBodyRef(w http.ResponseWriter, req *http.Request, field, raw string, notFound error) (uint, bool) {
+	ref, err := models.ParseDepartmentRef(raw)
+	if err != nil {
+		r.writeRefError(w, req, field, err)
+		return 0, false
+	}
+	return r.resolveDepartment(w, req, field, ref, notFound)
+}
+
+// Ошибка ссылки с привязкой к полю, где она передана
+func (r *Repository) writeRefError(w http.ResponseWriter, req *http.Request, field string, err error) {
+	def, ok := lookupProblem(err)
+	if !ok {
+		r.writeError(w, req, err)
+		return
+	}
+	renderProblem(w, &Problem{
+		Type:     problemTypeBase + def.code,
+		Title:    def.title,
+		Status:   def.status,
+		Detail:   err.Error(),
+		Instance: req.URL.Path,
+		Code:     def.code,
+		Errors:   []FieldError{{Field: field, Code: def.code, Message: err.Error()}},
+	})
+}
@@ -0,0 +1,130 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/kroulersama/goProject/models"
+)
+
+// SetManager назначение или снятие руководителя сотрудника
+func (r *Repository) SetManager(w http.ResponseWriter, req *http.Request) {
+	r.Log.Info("setting manager", "method", req.Method)
+
+	// Проверка метода
+	if !checkMethod(w, req, http.MethodPatch) {
+		return
+	}
+
+	// Получение id
+	employeeID, ok := r.employeeID(w, req, "id")
+	if !ok {
+		return
+	}
+
+	// Версия из If-Match
+	version, ok := ifMatchVersion(w, req)
+	if !ok {
+		return
+	}
+
+	// Декодирование JSON
+	var managerReq models.ManagerRequest
+	if !r.decodeBody(w, req, &managerReq) {
+		return
+	}
+
+	employee, err := r.Reporting.SetManager(req.Context(), employeeID, version, &managerReq)
+	if err != nil {
+		r.Log.Error("Failed set manager", err, "id", employeeID)
+		r.writeError(w, req, err)
+		return
+	}
+	r.Log.Info("Manager set", "id", employeeID, "manager_id", employee.ManagerID)
+
+	writeJSONWithETag(w, req, http.StatusOK, employee.Version, map[string]interface{}{
+		"message": "manager updated successfully",
+		"data":    employee,
+	})
+}
+
+// DirectReports прямые подчиненные сотрудника, фильтр по статусу как в списке
+func (r *Repository) DirectReports(w http.ResponseWriter, req *http.Request) {
+	if !checkMethod(w, req, http.MethodGet) {
+		return
+	}
+
+	employeeID, ok := r.employeeID(w, req, "id")
+	if !ok {
+		return
+	}
+	var filter models.EmployeeFilter
+	if !employeeStatusQuery(w, req, &filter) {
+		return
+	}
+
+	reports, err := r.Reporting.DirectReports(req.Context(), employeeID, &filter)
+	if err != nil {
+		r.Log.Error("Failed get direct reports", err, "id", employeeID)
+		r.writeError(w, req, err)
+		return
+	}
+	if reports == nil {
+		reports = []models.Employee{}
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"data": reports})
+}
+
+// ReportingChain цепочка руководителей до верхнего
+func (r *Repository) ReportingChain(w http.ResponseWriter, req *http.Request) {
+	if !checkMethod(w, req, http.MethodGet) {
+		return
+	}
+
+	employeeID, ok := r.employeeID(w, req, "id")
+	if !ok {
+		return
+	}
+
+	chain, err := r.Reporting.ReportingChain(req.Context(), employeeID)
+	if err != nil {
+		r.Log.Error("Failed get reporting chain", err, "id", employeeID)
+		r.writeError(w, req, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"data": chain})
+}
+
+// SpanOfControl число подчиненных сотрудника: прямых и всех уровней
+func (r *Repository) SpanOfControl(w http.ResponseWriter, req *http.Request) {
+	if !checkMethod(w, req, http.MethodGet) {
+		return
+	}
+
+	employeeID, ok := r.employeeID(w, req, "id")
+	if !ok {
+		return
+	}
+
+	span, err := r.Reporting.GetSpanOfControl(req.Context(), employeeID)
+	if err != nil {
+		r.Log.Error("Failed get span of control", err, "id", employeeID)
+		r.writeError(w, req, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, span)
+}
+
+// ReportingIssues руководители вне ветки подразделения сотрудника и уволенные руководители
+func (r *Repository) ReportingIssues(w http.ResponseWriter, req *http.Request) {
+	if !checkMethod(w, req, http.MethodGet) {
+		return
+	}
+
+	issues, err := r.Reporting.GetReportingIssues(req.Context())
+	if err != nil {
+		r.Log.Error("Failed check reporting lines", err)
+		r.writeError(w, req, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"data": issues})
+}
@@ -11,36 +11,15 @@ import (
 
 	"github.com/kroulersama/goProject/models"
 	"github.com/kroulersama/goProject/pkg/logger"
-	"github.com/pressly/goose/v3"
-	"gorm.io/gorm"
+	"github.com/kroulersama/goProject/storage"
 )
 
-// Применяем миграции
-func RunMigrations(dsn string) error {
-	db, err := sql.Open("postgres", dsn)
-	if err != nil {
-		return err
-	}
-	defer db.Close()
-
-	if err := goose.SetDialect("postgres"); err != nil {
-		return err
-	}
-
-	if err := goose.Up(db, "migrations"); err != nil {
-		return err
-	}
-
-	log.Println("Migrations applied successfully")
-	return nil
-}
-
 // Ожидание готовности базы
-func WaitForDB(dsn string) {
+func WaitForDB(config *storage.Config) {
 	log.Println("Waiting for database...")
 
 	for i := 0; i < 30; i++ {
-		db, err := sql.Open("postgres", dsn)
+		db, err := sql.Open(config.GetDriver(), config.GetDSN())
 		if err == nil {
 			if err = db.Ping(); err == nil {
 				db.Close()
@@ -57,8 +36,22 @@ func WaitForDB(dsn string) {
 }
 
 type Repository struct {
-	DB  *gorm.DB
-	Log *logger.Logger
+	Departments    models.DepartmentStore
+	Employees      models.EmployeeStore
+	Attributes     models.AttributeStore
+	Positions      models.PositionStore
+	Assignments    models.AssignmentStore
+	Secondary      models.SecondaryStore
+	Reporting      models.ReportingStore
+	Stats          models.StatsStore
+	Staffing       models.StaffingStore
+	Rules          models.RulesStore
+	Integrity      models.IntegrityStore
+	Idempotency    models.IdempotencyStore
+	Plans          models.PlanStore
+	Schedule       models.ScheduleStore
+	IdempotencyTTL time.Duration
+	Log            *logger.Logger
 }
 
 // Тип для запроса подразделения
@@ -69,53 +62,28 @@ func (r *Repository) CreateDepartment(w http.ResponseWriter, req *http.Request)
 	r.Log.Info("Creating department", "method", req.Method)
 
 	// Проверка метода
-	if req.Method != http.MethodPost {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	if !checkMethod(w, req, http.MethodPost) {
 		return
 	}
 
 	// Декодирование JSON
 	var deptReq models.DepartmentRequest
-	if err := json.NewDecoder(req.Body).Decode(&deptReq); err != nil {
-		w.WriteHeader(http.StatusUnprocessableEntity)
-		json.NewEncoder(w).Encode(map[string]string{
-			"message": "request failed",
-			"error":   err.Error(),
-		})
+	if !r.decodeBody(w, req, &deptReq) {
 		return
 	}
 
 	// Обработка в модуле
-	department, err := models.CreateDepartment(r.DB, &deptReq)
+	department, err := r.Departments.CreateDepartment(req.Context(), &deptReq)
 	if err != nil {
 		r.Log.Error("Failed to create department", err, "name", deptReq.Name)
-
-		switch {
-		case errors.Is(err, models.ErrNameEmpty) ||
-			errors.Is(err, models.ErrNameTooLong) ||
-			errors.Is(err, models.ErrParentNotFound):
-			w.WriteHeader(http.StatusBadRequest)
-			json.NewEncoder(w).Encode(map[string]string{"message": err.Error()})
-
-		case errors.Is(err, models.ErrNameExists):
-			w.WriteHeader(http.StatusConflict)
-			json.NewEncoder(w).Encode(map[string]string{"message": err.Error()})
-
-		default:
-			w.WriteHeader(http.StatusInternalServerError)
-			json.NewEncoder(w).Encode(map[string]string{
-				"message": "could not create department",
-				"error":   err.Error(),
-			})
-		}
+		r.writeError(w, req, err)
 		return
 	}
 
 	r.Log.Info("Department created", "id", department.Id, "name", department.Name)
 
 	// Успешный ответ
-	w.WriteHeader(http.StatusCreated)
-	json.NewEncoder(w).Encode(map[string]interface{}{
+	writeJSONWithETag(w, req, http.StatusCreated, department.Version, map[string]interface{}{
 		"message": "department created successfully",
 		"data":    department,
 	})
@@ -129,68 +97,34 @@ func (r *Repository) CreateEmployeeInDepartment(w http.ResponseWriter, req *http
 	r.Log.Info("Creating employee", "method", req.Method)
 
 	// Проверка метода
-	if req.Method != http.MethodPost {
-		http.Error(w, `{"message": "method not allowed"}`, http.StatusMethodNotAllowed)
+	if !checkMethod(w, req, http.MethodPost) {
 		return
 	}
 
 	// Получение id
-	departmentIDStr := req.PathValue("id")
-	if departmentIDStr == "" {
-		http.Error(w, `{"message": "department id is required"}`, http.StatusBadRequest)
-		return
-	}
-
-	departmentID, err := strconv.ParseUint(departmentIDStr, 10, 32)
-	if err != nil {
-		http.Error(w, `{"message": "invalid department id"}`, http.StatusBadRequest)
+	departmentID, ok := r.departmentID(w, req, "id", models.ErrDepartmentNotFound)
+	if !ok {
 		return
 	}
 
 	// обработка запроса
 	var empReq models.EmployeeRequest
-	if err := json.NewDecoder(req.Body).Decode(&empReq); err != nil {
-		w.WriteHeader(http.StatusUnprocessableEntity)
-		json.NewEncoder(w).Encode(map[string]string{
-			"message": "invalid request format",
-			"error":   err.Error(),
-		})
+	if !r.decodeBody(w, req, &empReq) {
 		return
 	}
 
 	// Вычисления из модуля
-	employee, err := models.CreateEmployee(r.DB, uint(departmentID), &empReq)
+	employee, err := r.Employees.CreateEmployee(req.Context(), departmentID, &empReq)
 	if err != nil {
 		r.Log.Error("Failed to create employee", err, "name", empReq.FullName)
-
-		switch {
-		case errors.Is(err, models.ErrDepartmentNotFound):
-			w.WriteHeader(http.StatusNotFound)
-			json.NewEncoder(w).Encode(map[string]string{"message": err.Error()})
-
-		case errors.Is(err, models.ErrFullNameEmpty),
-			errors.Is(err, models.ErrFullNameTooLong),
-			errors.Is(err, models.ErrPositionEmpty),
-			errors.Is(err, models.ErrPositionTooLong),
-			errors.Is(err, models.ErrHiredAtFuture):
-			w.WriteHeader(http.StatusBadRequest)
-			json.NewEncoder(w).Encode(map[string]string{"message": err.Error()})
-
-		default:
-			w.WriteHeader(http.StatusInternalServerError)
-			json.NewEncoder(w).Encode(map[string]string{
-				"message": "could not create employee",
-				"error":   err.Error(),
-			})
-		}
+		r.writeError(w, req, err)
 		return
 	}
 
 	r.Log.Info("employee created", "id", employee.ID, "full_name", employee.FullName)
 
 	// Ответ
-	w.WriteHeader(http.StatusCreated)
-	json.NewEncoder(w).Encode(map[string]interface{}{
+	writeJSONWithETag(w, req, http.StatusCreated, employee.Version, map[string]interface{}{
 		"message": "employee created successfully",
 		"data":    employee,
 	})
@@ -201,24 +135,21 @@ func (r *Repository) GetDepartment(w http.ResponseWriter, req *http.Request) {
 	r.Log.Info("Getting department", "method", req.Method)
 
 	// Проверка метода
-	if req.Method != http.MethodGet {
-		http.Error(w, `{"message": "method not allowed"}`, http.StatusMethodNotAllowed)
+	if !checkMethod(w, req, http.MethodGet) {
 		return
 	}
 
 	// Получение id
-	idStr := req.PathValue("id")
-	if idStr == "" {
-		http.Error(w, `{"message": "department id is required"}`, http.StatusBadRequest)
-		return
-	}
-
-	departmentID, err := strconv.ParseUint(idStr, 10, 32)
-	if err != nil {
-		http.Error(w, `{"message": "invalid department id"}`, http.StatusBadRequest)
+	departmentID, ok := r.departmentID(w, req, "id", models.ErrDepartmentNotFound)
+	if !ok {
 		return
 	}
+	r.getDepartment(w, req, departmentID)
+}
 
+// Ответ с подразделением и поддеревом по параметрам depth, include_employees,
+// status и include_terminated
+func (r *Repository) getDepartment(w http.ResponseWriter, req *http.Request, departmentID uint) {
 	// Обработка заявки
 	depth := 1
 	depthStr := req.URL.Query().Get("depth")
@@ -226,7 +157,7 @@ func (r *Repository) GetDepartment(w http.ResponseWriter, req *http.Request) {
 		if d, err := strconv.Atoi(depthStr); err == nil && d >= 1 && d <= 5 {
 			depth = d
 		} else {
-			http.Error(w, `{"message": "depth must be between 1 and 5"}`, http.StatusBadRequest)
+			writeFieldProblem(w, req, "depth", "depth must be between 1 and 5")
 			return
 		}
 	}
@@ -237,30 +168,42 @@ func (r *Repository) GetDepartment(w http.ResponseWriter, req *http.Request) {
 		if b, err := strconv.ParseBool(includeStr); err == nil {
 			includeEmployees = b
 		} else {
-			http.Error(w, `{"message": "include_employees must be true or false"}`, http.StatusBadRequest)
+			writeFieldProblem(w, req, "include_employees", "include_employees must be true or false")
 			return
 		}
 	}
 
-	// Вычисления из модуля
-	var dept models.Department
-	response, err := dept.GetWithTree(r.DB, uint(departmentID), depth, includeEmployees)
-	if err != nil {
-		r.Log.Error("Failed get department", err, "name", dept.Name)
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			http.Error(w, `{"message": "department not found"}`, http.StatusNotFound)
+	includeSecondary := false
+	if secondaryStr := req.URL.Query().Get("include_secondary"); secondaryStr != "" {
+		if b, err := strconv.ParseBool(secondaryStr); err == nil {
+			includeSecondary = b
+		} else {
+			writeFieldProblem(w, req, "include_secondary", "include_secondary must be true or false")
+			return
+		}
+	}
+
+	// Фильтр сотрудников по статусу
+	var employees *models.EmployeeFilter
+	if includeEmployees {
+		employees = &models.EmployeeFilter{Secondary: includeSecondary}
+		if !employeeStatusQuery(w, req, employees) {
 			return
 		}
-		http.Error(w, `{"message": "database error"}`, http.StatusInternalServerError)
+	}
+
+	// Вычисления из модуля
+	response, err := r.Departments.GetWithTree(req.Context(), departmentID, depth, employees)
+	if err != nil {
+		r.Log.Error("Failed get department", err, "id", departmentID)
+		r.writeError(w, req, err)
 		return
 	}
 
-	r.Log.Info("Department", "id", dept.Id, "name", dept.Name)
+	r.Log.Info("Department", "id", response.Id, "name", response.Name)
 
-	// Ответ
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(response)
+	// Ответ, 304 если If-None-Match совпал
+	writeJSONWithETag(w, req, http.StatusOK, response.Version, response)
 }
 
 // MoveDepartment перемещение подразделения с изменением родителя
@@ -268,71 +211,54 @@ func (r *Repository) MoveDepartment(w http.ResponseWriter, req *http.Request) {
 	r.Log.Info("moving department", "method", req.Method)
 
 	// Проверка метода
-	if req.Method != http.MethodPatch {
-		http.Error(w, `{"message": "method not allowed"}`, http.StatusMethodNotAllowed)
+	if !checkMethod(w, req, http.MethodPatch) {
 		return
 	}
 
 	// Вычленение Id
-	idStr := req.PathValue("id")
-	if idStr == "" {
-		http.Error(w, `{"message": "department id is required"}`, http.StatusBadRequest)
+	departmentID, ok := r.departmentID(w, req, "id", models.ErrDepartmentNotFound)
+	if !ok {
 		return
 	}
 
-	departmentID, err := strconv.ParseUint(idStr, 10, 32)
-	if err != nil {
-		http.Error(w, `{"message": "invalid department id"}`, http.StatusBadRequest)
+	// Версия из If-Match
+	version, ok := ifMatchVersion(w, req)
+	if !ok {
 		return
 	}
 
 	// Обработка запроса
-	var deptReq models.DepartmentRequest
-	if err := json.NewDecoder(req.Body).Decode(&deptReq); err != nil {
-		w.WriteHeader(http.StatusUnprocessableEntity)
-		json.NewEncoder(w).Encode(map[string]string{
-			"message": "invalid request format",
-			"error":   err.Error(),
-		})
+	var body scheduledDepartmentRequest
+	if !r.decodeBody(w, req, &body) {
+		return
+	}
+	deptReq := body.DepartmentRequest
+
+	// Изменение с датой в будущем откладывается
+	if body.EffectiveAt != nil && body.EffectiveAt.After(time.Now()) {
+		change, err := r.Schedule.ScheduleDepartmentChange(req.Context(), departmentID, version, &deptReq, *body.EffectiveAt)
+		if err != nil {
+			r.Log.Error("Failed schedule department change", err, "id", departmentID)
+			r.writeError(w, req, err)
+			return
+		}
+		r.Log.Info("Department change scheduled", "id", departmentID, "change_id", change.ID, "effective_at", change.EffectiveAt)
+		writeScheduled(w, change)
 		return
 	}
 
 	// Логика в модуле
-	updatedDepartment, err := models.UpdateDepartment(r.DB, uint(departmentID), &deptReq)
+	updatedDepartment, err := r.Departments.UpdateDepartment(req.Context(), departmentID, version, &deptReq)
 	if err != nil {
 		r.Log.Error("Failed move department", err, "name", deptReq.Name)
-		switch {
-		case errors.Is(err, models.ErrDepartmentNotFound):
-			w.WriteHeader(http.StatusNotFound)
-			json.NewEncoder(w).Encode(map[string]string{"message": err.Error()})
-
-		case errors.Is(err, models.ErrNameTooLong),
-			errors.Is(err, models.ErrNameEmpty),
-			errors.Is(err, models.ErrParentNotFound):
-			w.WriteHeader(http.StatusBadRequest)
-			json.NewEncoder(w).Encode(map[string]string{"message": err.Error()})
-
-		case errors.Is(err, models.ErrSelfParent),
-			errors.Is(err, models.ErrCycleDetected),
-			errors.Is(err, models.ErrNameExists):
-			w.WriteHeader(http.StatusConflict)
-			json.NewEncoder(w).Encode(map[string]string{"message": err.Error()})
-
-		default:
-			w.WriteHeader(http.StatusInternalServerError)
-			json.NewEncoder(w).Encode(map[string]string{
-				"message": "could not update department",
-				"error":   err.Error(),
-			})
-		}
+		r.writeError(w, req, err)
 		return
 	}
 
 	r.Log.Info("Department move", "parent_id", deptReq.ParentID, "name", deptReq.Name)
 
 	// Успешный ответ
-	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(map[string]interface{}{
+	writeJSONWithETag(w, req, http.StatusOK, updatedDepartment.Version, map[string]interface{}{
 		"message": "department updated successfully",
 		"data":    updatedDepartment,
 	})
@@ -342,71 +268,48 @@ func (r *Repository) DeleteDepartment(w http.ResponseWriter, req *http.Request)
 	r.Log.Info("del department", "method", req.Method)
 
 	// Проверка метода
-	if req.Method != http.MethodDelete {
-		http.Error(w, `{"message": "method not allowed"}`, http.StatusMethodNotAllowed)
+	if !checkMethod(w, req, http.MethodDelete) {
 		return
 	}
 
 	// Получаем Id
-	idStr := req.PathValue("id")
-	if idStr == "" {
-		http.Error(w, `{"message": "department id is required"}`, http.StatusBadRequest)
+	departmentID, ok := r.departmentID(w, req, "id", models.ErrDepartmentNotFound)
+	if !ok {
 		return
 	}
 
-	departmentID, err := strconv.ParseUint(idStr, 10, 32)
-	if err != nil {
-		http.Error(w, `{"message": "invalid department id"}`, http.StatusBadRequest)
+	// Версия из If-Match
+	version, ok := ifMatchVersion(w, req)
+	if !ok {
 		return
 	}
 
 	// Параметры
 	mode := req.URL.Query().Get("mode")
 	if mode == "" {
-		http.Error(w, `{"message": "mode parameter is required (cascade or reassign)"}`, http.StatusBadRequest)
+		writeFieldProblem(w, req, "mode", "mode parameter is required (cascade or reassign)")
 		return
 	}
 
 	var reassignToID *uint
 	if mode == "reassign" {
-		reassignStr := req.URL.Query().Get("reassign_to_department_id")
-		if reassignStr == "" {
-			http.Error(w, `{"message": "reassign_to_department_id is required for reassign mode"}`, http.StatusBadRequest)
+		if req.URL.Query().Get("reassign_to_department_id") == "" {
+			r.writeError(w, req, models.ErrReassignRequired)
 			return
 		}
 
-		reassignID, err := strconv.ParseUint(reassignStr, 10, 32)
-		if err != nil {
-			http.Error(w, `{"message": "invalid reassign_to_department_id"}`, http.StatusBadRequest)
+		// id или ссылка на подразделение
+		reassignToID, ok = r.queryDepartmentID(w, req, "reassign_to_department_id", models.ErrTargetNotFound)
+		if !ok {
 			return
 		}
-		reassignIDUint := uint(reassignID)
-		reassignToID = &reassignIDUint
 	}
 
 	// Логика в  модели
-	err = models.DeleteDepartment(r.DB, uint(departmentID), mode, reassignToID)
+	err := r.Departments.DeleteDepartment(req.Context(), departmentID, version, mode, reassignToID)
 	if err != nil {
 		r.Log.Error("Failed del department", err, "id", departmentID, "mode", mode)
-		switch {
-		case errors.Is(err, models.ErrDepartmentNotFound):
-			http.Error(w, `{"message": "department not found"}`, http.StatusNotFound)
-
-		case errors.Is(err, models.ErrTargetNotFound):
-			http.Error(w, `{"message": "target department not found"}`, http.StatusNotFound)
-
-		case errors.Is(err, models.ErrReassignToSame),
-			errors.Is(err, models.ErrInvalidMode):
-			w.WriteHeader(http.StatusBadRequest)
-			json.NewEncoder(w).Encode(map[string]string{"message": err.Error()})
-
-		default:
-			w.WriteHeader(http.StatusInternalServerError)
-			json.NewEncoder(w).Encode(map[string]string{
-				"message": "could not delete department",
-				"error":   err.Error(),
-			})
-		}
+		r.writeError(w, req, err)
 		return
 	}
 	r.Log.Info("Department del", "departmentID", departmentID, "mode", mode)
@@ -414,3 +317,211 @@ func (r *Repository) DeleteDepartment(w http.ResponseWriter, req *http.Request)
 	// Успешное удаление
 	w.WriteHeader(http.StatusNoContent)
 }
+
+// MergeDepartment слияние подразделения с другим
+func (r *Repository) MergeDepartment(w http.ResponseWriter, req *http.Request) {
+	r.Log.Info("merging department", "method", req.Method)
+
+	// Проверка метода
+	if !checkMethod(w, req, http.MethodPost) {
+		return
+	}
+
+	// Источник и цель
+	sourceID, ok := r.departmentID(w, req, "id", models.ErrDepartmentNotFound)
+	if !ok {
+		return
+	}
+	targetID, ok := r.departmentID(w, req, "target", models.ErrTargetNotFound)
+	if !ok {
+		return
+	}
+
+	// Версия источника из If-Match
+	version, ok := ifMatchVersion(w, req)
+	if !ok {
+		return
+	}
+
+	// Стратегия при совпадении имен, по умолчанию fail
+	strategy := req.URL.Query().Get("strategy")
+
+	// Логика в модели
+	summary, err := r.Departments.MergeDepartment(req.Context(), sourceID, targetID, version, strategy)
+	if err != nil {
+		r.Log.Error("Failed merge department", err, "id", sourceID, "target", targetID, "strategy", strategy)
+		if errors.Is(err, models.ErrTargetNotFound) {
+			r.writeRefError(w, req, "target", err)
+			return
+		}
+		r.writeError(w, req, err)
+		return
+	}
+	r.Log.Info("Department merged", "id", sourceID, "target", targetID,
+		"employees", summary.EmployeesMoved, "departments", len(summary.DepartmentsMoved))
+
+	// Итог слияния
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"message": "department merged successfully",
+		"data":    summary,
+	})
+}
+
+// SplitDepartment выделение части подразделения в новое соседнее
+func (r *Repository) SplitDepartment(w http.ResponseWriter, req *http.Request) {
+	r.Log.Info("splitting department", "method", req.Method)
+
+	// Проверка метода
+	if !checkMethod(w, req, http.MethodPost) {
+		return
+	}
+
+	// Получение id
+	departmentID, ok := r.departmentID(w, req, "id", models.ErrDepartmentNotFound)
+	if !ok {
+		return
+	}
+
+	// Версия из If-Match
+	version, ok := ifMatchVersion(w, req)
+	if !ok {
+		return
+	}
+
+	// Обработка запроса
+	var splitReq models.SplitRequest
+	if !r.decodeBody(w, req, &splitReq) {
+		return
+	}
+
+	// Логика в модели
+	result, err := r.Departments.SplitDepartment(req.Context(), departmentID, version, &splitReq)
+	if err != nil {
+		r.Log.Error("Failed split department", err, "id", departmentID, "name", splitReq.Name)
+		if errors.Is(err, models.ErrEmployeeNotFound) {
+			r.writeRefError(w, req, "employee_ids", err)
+			return
+		}
+		r.writeError(w, req, err)
+		return
+	}
+	r.Log.Info("Department split", "id", departmentID, "new_id", result.Department.Id,
+		"employees", len(result.EmployeesMoved), "departments", len(result.DepartmentsMoved))
+
+	// Ответ
+	writeJSONWithETag(w, req, http.StatusCreated, result.Department.Version, map[string]interface{}{
+		"message": "department split successfully",
+		"data":    result,
+	})
+}
+
+// CopyDepartment копирование структуры поддерева
+func (r *Repository) CopyDepartment(w http.ResponseWriter, req *http.Request) {
+	r.Log.Info("copying department", "method", req.Method)
+
+	// Проверка метода
+	if !checkMethod(w, req, http.MethodPost) {
+		return
+	}
+
+	// Получение id
+	departmentID, ok := r.departmentID(w, req, "id", models.ErrDepartmentNotFound)
+	if !ok {
+		return
+	}
+
+	// Обработка запроса
+	var copyReq models.CopyRequest
+	if !r.decodeBody(w, req, &copyReq) {
+		return
+	}
+
+	// Логика в модели
+	result, err := r.Departments.CopyDepartment(req.Context(), departmentID, &copyReq)
+	if err != nil {
+		r.Log.Error("Failed copy department", err, "id", departmentID, "parent_id", copyReq.ParentID)
+		r.writeError(w, req, err)
+		return
+	}
+	r.Log.Info("Department copied", "id", departmentID, "root_id", result.Root.Id, "departments", len(result.Departments))
+
+	// Ответ
+	writeJSONWithETag(w, req, http.StatusCreated, result.Root.Version, map[string]interface{}{
+		"message": "department copied successfully",
+		"data":    result,
+	})
+}
+
+// ReorderChildren порядок дочерних подразделений
+func (r *Repository) ReorderChildren(w http.ResponseWriter, req *http.Request) {
+	r.Log.Info("reordering children", "method", req.Method)
+
+	// Проверка метода
+	if !checkMethod(w, req, http.MethodPut) {
+		return
+	}
+
+	// Получение id
+	departmentID, ok := r.departmentID(w, req, "id", models.ErrDepartmentNotFound)
+	if !ok {
+		return
+	}
+
+	// Версия из If-Match
+	version, ok := ifMatchVersion(w, req)
+	if !ok {
+		return
+	}
+
+	// Обработка запроса
+	var orderReq models.ReorderRequest
+	if !r.decodeBody(w, req, &orderReq) {
+		return
+	}
+
+	// Логика в модели
+	response, err := r.Departments.ReorderChildren(req.Context(), departmentID, version, &orderReq)
+	if err != nil {
+		r.Log.Error("Failed reorder children", err, "id", departmentID)
+		r.writeError(w, req, err)
+		return
+	}
+	r.Log.Info("Children reordered", "id", departmentID, "children", len(orderReq.IDs))
+
+	writeJSONWithETag(w, req, http.StatusOK, response.Version, map[string]interface{}{
+		"message": "children reordered successfully",
+		"data":    response,
+	})
+}
+
+// Проверка метода запроса
+func checkMethod(w http.ResponseWriter, req *http.Request, method string) bool {
+	if req.Method != method {
+		writeProblem(w, req, http.StatusMethodNotAllowed, CodeMethodNotAllowed, "method not allowed")
+		return false
+	}
+	return true
+}
+
+// Числовой id из пути
+func pathID(w http.ResponseWriter, req *http.Request, name string) (uint, bool) {
+	idStr := req.PathValue(name)
+	if idStr == "" {
+		writeFieldProblem(w, req, name, name+" is required")
+		return 0, false
+	}
+
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		writeFieldProblem(w, req, name, "invalid "+name)
+		return 0, false
+	}
+	return uint(id), true
+}
+
+// Ответ JSON без ETag
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,77 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/kroulersama/goProject/models"
+)
+
+// GetHierarchyRules текущие правила иерархии
+func (r *Repository) GetHierarchyRules(w http.ResponseWriter, req *http.Request) {
+	if !checkMethod(w, req, http.MethodGet) {
+		return
+	}
+
+	set, err := r.Rules.GetHierarchyRules(req.Context())
+	if err != nil {
+		r.Log.Error("Failed get hierarchy rules", err)
+		r.writeError(w, req, err)
+		return
+	}
+
+	// Ответ, 304 если If-None-Match совпал
+	writeJSONWithETag(w, req, http.StatusOK, set.Version, set)
+}
+
+// UpdateHierarchyRules замена правил иерархии
+func (r *Repository) UpdateHierarchyRules(w http.ResponseWriter, req *http.Request) {
+	r.Log.Info("Updating hierarchy rules", "method", req.Method)
+
+	// Проверка метода
+	if !checkMethod(w, req, http.MethodPut) {
+		return
+	}
+
+	// Версия из If-Match
+	version, ok := ifMatchVersion(w, req)
+	if !ok {
+		return
+	}
+
+	// Декодирование JSON
+	var rules models.HierarchyRules
+	if !r.decodeBody(w, req, &rules) {
+		return
+	}
+
+	set, err := r.Rules.UpdateHierarchyRules(req.Context(), version, &rules)
+	if err != nil {
+		r.Log.Error("Failed update hierarchy rules", err)
+		r.writeError(w, req, err)
+		return
+	}
+	r.Log.Info("Hierarchy rules updated", "version", set.Version, "types", len(set.Rules.Types))
+
+	writeJSONWithETag(w, req, http.StatusOK, set.Version, map[string]interface{}{
+		"message": "hierarchy rules updated successfully",
+		"data":    set,
+	})
+}
+
+// CheckHierarchy нарушения правил в текущем дереве
+func (r *Repository) CheckHierarchy(w http.ResponseWriter, req *http.Request) {
+	if !checkMethod(w, req, http.MethodGet) {
+		return
+	}
+
+	violations, err := r.Rules.CheckHierarchy(req.Context())
+	if err != nil {
+		r.Log.Error("Failed check hierarchy", err)
+		r.writeError(w, req, err)
+		return
+	}
+	if violations == nil {
+		violations = []models.RuleViolation{}
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"data": violations})
+}
@@ -0,0 +1,193 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/kroulersama/goProject/models"
+)
+
+// PATCH подразделения с необязательной датой вступления в силу
+type scheduledDepartmentRequest struct {
+	models.DepartmentRequest
+	EffectiveAt *time.Time `json:"effective_at"`
+}
+
+// Перевод сотрудника с необязательной датой вступления в силу
+type scheduledTransferRequest struct {
+	models.TransferRequest
+	EffectiveAt *time.Time `json:"effective_at"`
+}
+
+// TransferEmployee перевод сотрудника в другой отдел, сразу или с effective_at
+func (r *Repository) TransferEmployee(w http.ResponseWriter, req *http.Request) {
+	r.Log.Info("transferring employee", "method", req.Method)
+
+	// Проверка метода
+	if !checkMethod(w, req, http.MethodPost) {
+		return
+	}
+
+	// Получение id
+	employeeID, ok := r.employeeID(w, req, "id")
+	if !ok {
+		return
+	}
+
+	// Версия из If-Match
+	version, ok := ifMatchVersion(w, req)
+	if !ok {
+		return
+	}
+
+	// Обработка запроса
+	var body scheduledTransferRequest
+	if !r.decodeBody(w, req, &body) {
+		return
+	}
+
+	// Изменение с датой в будущем откладывается
+	if body.EffectiveAt != nil && body.EffectiveAt.After(time.Now()) {
+		change, err := r.Schedule.ScheduleTransfer(req.Context(), employeeID, version, &body.TransferRequest, *body.EffectiveAt)
+		if err != nil {
+			r.Log.Error("Failed schedule transfer", err, "id", employeeID)
+			r.writeError(w, req, err)
+			return
+		}
+		r.Log.Info("Transfer scheduled", "id", employeeID, "change_id", change.ID, "effective_at", change.EffectiveAt)
+		writeScheduled(w, change)
+		return
+	}
+
+	// Логика в модели
+	employee, err := r.Employees.TransferEmployee(req.Context(), employeeID, version, &body.TransferRequest)
+	if err != nil {
+		r.Log.Error("Failed transfer employee", err, "id", employeeID)
+		r.writeError(w, req, err)
+		return
+	}
+	r.Log.Info("Employee transferred", "id", employeeID, "department_id", employee.DepartmentId)
+
+	writeJSONWithETag(w, req, http.StatusOK, employee.Version, map[string]interface{}{
+		"message": "employee transferred successfully",
+		"data":    employee,
+	})
+}
+
+// ListScheduledChanges список отложенных изменений
+func (r *Repository) ListScheduledChanges(w http.ResponseWriter, req *http.Request) {
+	if !checkMethod(w, req, http.MethodGet) {
+		return
+	}
+
+	status := req.URL.Query().Get("status")
+	if !models.ValidChangeStatus(status) {
+		writeFieldProblem(w, req, "status", models.ErrInvalidChangeStatus.Error())
+		return
+	}
+
+	changes, err := r.Schedule.ListScheduledChanges(req.Context(), status)
+	if err != nil {
+		r.Log.Error("Failed list scheduled changes", err, "status", status)
+		r.writeError(w, req, err)
+		return
+	}
+	if changes == nil {
+		changes = []models.ScheduledChange{}
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"data": changes})
+}
+
+// GetScheduledChange отложенное изменение по id
+func (r *Repository) GetScheduledChange(w http.ResponseWriter, req *http.Request) {
+	if !checkMethod(w, req, http.MethodGet) {
+		return
+	}
+
+	changeID, ok := pathID(w, req, "id")
+	if !ok {
+		return
+	}
+
+	change, err := r.Schedule.GetScheduledChange(req.Context(), changeID)
+	if err != nil {
+		r.Log.Error("Failed get scheduled change", err, "id", changeID)
+		r.writeError(w, req, err)
+		return
+	}
+
+	// Ответ, 304 если If-None-Match совпал
+	writeJSONWithETag(w, req, http.StatusOK, change.Version, change)
+}
+
+// CancelScheduledChange отмена отложенного изменения
+func (r *Repository) CancelScheduledChange(w http.ResponseWriter, req *http.Request) {
+	r.Log.Info("canceling scheduled change", "method", req.Method)
+
+	// Проверка метода
+	if !checkMethod(w, req, http.MethodPost) {
+		return
+	}
+
+	// Получение id
+	changeID, ok := pathID(w, req, "id")
+	if !ok {
+		return
+	}
+
+	// Версия из If-Match
+	version, ok := ifMatchVersion(w, req)
+	if !ok {
+		return
+	}
+
+	change, err := r.Schedule.CancelScheduledChange(req.Context(), changeID, version)
+	if err != nil {
+		r.Log.Error("Failed cancel scheduled change", err, "id", changeID)
+		r.writeError(w, req, err)
+		return
+	}
+	r.Log.Info("Scheduled change canceled", "id", changeID)
+
+	writeJSONWithETag(w, req, http.StatusOK, change.Version, map[string]interface{}{
+		"message": "scheduled change canceled successfully",
+		"data":    change,
+	})
+}
+
+// RunScheduler периодически применяет наступившие изменения до отмены ctx.
+// Между экземплярами работу выполняет только лидер, см. models.ApplyDueChanges
+func (r *Repository) RunScheduler(ctx context.Context, interval time.Duration) {
+	// Изменения планировщика попадают в историю от его имени
+	ctx = models.WithActor(ctx, "scheduler")
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			applied, err := r.Schedule.ApplyDueChanges(ctx, time.Now().UTC())
+			if err != nil {
+				r.Log.Error("Failed to apply scheduled changes", err)
+				continue
+			}
+			if applied > 0 {
+				r.Log.Info("Scheduled changes applied", "count", applied)
+			}
+		}
+	}
+}
+
+// Ответ 202 на отложенное изменение
+func writeScheduled(w http.ResponseWriter, change *models.ScheduledChange) {
+	w.Header().Set("Location", fmt.Sprintf("/scheduled-changes/%d", change.ID))
+	writeJSON(w, http.StatusAccepted, map[string]interface{}{
+		"message": "change scheduled successfully",
+		"data":    change,
+	})
+}
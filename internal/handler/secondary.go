@@ -0,0 +1,166 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/kroulersama/goProject/models"
+)
+
+// ListSecondaryAssignments совмещения сотрудника в других подразделениях
+func (r *Repository) ListSecondaryAssignments(w http.ResponseWriter, req *http.Request) {
+	if !checkMethod(w, req, http.MethodGet) {
+		return
+	}
+
+	employeeID, ok := r.employeeID(w, req, "id")
+	if !ok {
+		return
+	}
+
+	assignments, err := r.Secondary.ListSecondaryAssignments(req.Context(), employeeID)
+	if err != nil {
+		r.Log.Error("Failed list secondary assignments", err, "id", employeeID)
+		r.writeError(w, req, err)
+		return
+	}
+	if assignments == nil {
+		assignments = []models.SecondaryAssignment{}
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"data": assignments})
+}
+
+// AddSecondaryAssignment назначение по совмещению с ролью и долей ставки
+func (r *Repository) AddSecondaryAssignment(w http.ResponseWriter, req *http.Request) {
+	r.Log.Info("adding secondary assignment", "method", req.Method)
+
+	// Проверка метода
+	if !checkMethod(w, req, http.MethodPost) {
+		return
+	}
+
+	// Получение id
+	employeeID, ok := r.employeeID(w, req, "id")
+	if !ok {
+		return
+	}
+
+	// Декодирование JSON
+	var assignReq models.SecondaryAssignmentRequest
+	if !r.decodeBody(w, req, &assignReq) {
+		return
+	}
+
+	assignment, err := r.Secondary.AddSecondaryAssignment(req.Context(), employeeID, &assignReq)
+	if err != nil {
+		r.Log.Error("Failed add secondary assignment", err, "id", employeeID)
+		r.writeError(w, req, err)
+		return
+	}
+	r.Log.Info("Secondary assignment added", "id", assignment.ID, "employee_id", employeeID,
+		"department_id", assignment.DepartmentID, "allocation", assignment.Allocation)
+
+	writeJSONWithETag(w, req, http.StatusCreated, assignment.Version, map[string]interface{}{
+		"message": "secondary assignment added successfully",
+		"data":    assignment,
+	})
+}
+
+// UpdateSecondaryAssignment изменение роли или доли ставки совмещения
+func (r *Repository) UpdateSecondaryAssignment(w http.ResponseWriter, req *http.Request) {
+	r.Log.Info("updating secondary assignment", "method", req.Method)
+
+	// Проверка метода
+	if !checkMethod(w, req, http.MethodPatch) {
+		return
+	}
+
+	// Получение id
+	employeeID, ok := r.employeeID(w, req, "id")
+	if !ok {
+		return
+	}
+	assignmentID, ok := pathID(w, req, "assignment")
+	if !ok {
+		return
+	}
+
+	// Версия из If-Match
+	version, ok := ifMatchVersion(w, req)
+	if !ok {
+		return
+	}
+
+	// Декодирование JSON
+	var update models.SecondaryAssignmentUpdate
+	if !r.decodeBody(w, req, &update) {
+		return
+	}
+
+	assignment, err := r.Secondary.UpdateSecondaryAssignment(req.Context(), employeeID, assignmentID, version, &update)
+	if err != nil {
+		r.Log.Error("Failed update secondary assignment", err, "id", assignmentID)
+		r.writeError(w, req, err)
+		return
+	}
+	r.Log.Info("Secondary assignment updated", "id", assignment.ID, "allocation", assignment.Allocation)
+
+	writeJSONWithETag(w, req, http.StatusOK, assignment.Version, map[string]interface{}{
+		"message": "secondary assignment updated successfully",
+		"data":    assignment,
+	})
+}
+
+// RemoveSecondaryAssignment снятие совмещения
+func (r *Repository) RemoveSecondaryAssignment(w http.ResponseWriter, req *http.Request) {
+	r.Log.Info("removing secondary assignment", "method", req.Method)
+
+	// Проверка метода
+	if !checkMethod(w, req, http.MethodDelete) {
+		return
+	}
+
+	// Получение id
+	employeeID, ok := r.employeeID(w, req, "id")
+	if !ok {
+		return
+	}
+	assignmentID, ok := pathID(w, req, "assignment")
+	if !ok {
+		return
+	}
+
+	// Версия из If-Match
+	version, ok := ifMatchVersion(w, req)
+	if !ok {
+		return
+	}
+
+	if err := r.Secondary.RemoveSecondaryAssignment(req.Context(), employeeID, assignmentID, version); err != nil {
+		r.Log.Error("Failed remove secondary assignment", err, "id", assignmentID)
+		r.writeError(w, req, err)
+		return
+	}
+	r.Log.Info("Secondary assignment removed", "id", assignmentID)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// DepartmentHeadcount численность подразделения: люди и ставки с учетом совмещений
+func (r *Repository) DepartmentHeadcount(w http.ResponseWriter, req *http.Request) {
+	if !checkMethod(w, req, http.MethodGet) {
+		return
+	}
+
+	departmentID, ok := r.departmentID(w, req, "id", models.ErrDepartmentNotFound)
+	if !ok {
+		return
+	}
+
+	headcount, err := r.Secondary.GetHeadcount(req.Context(), departmentID)
+	if err != nil {
+		r.Log.Error("Failed get headcount", err, "id", departmentID)
+		r.writeError(w, req, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, headcount)
+}
@@ -0,0 +1,314 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/kroulersama/goProject/models"
+)
+
+// CreateHeadcountPlan плановая численность подразделения на месяц
+func (r *Repository) CreateHeadcountPlan(w http.ResponseWriter, req *http.Request) {
+	r.Log.Info("creating headcount plan", "method", req.Method)
+
+	// Проверка метода
+	if !checkMethod(w, req, http.MethodPost) {
+		return
+	}
+
+	// Декодирование JSON
+	var planReq models.HeadcountPlanRequest
+	if !r.decodeBody(w, req, &planReq) {
+		return
+	}
+
+	plan, err := r.Staffing.CreateHeadcountPlan(req.Context(), &planReq)
+	if err != nil {
+		r.Log.Error("Failed create headcount plan", err, "department_id", planReq.DepartmentID)
+		r.writeError(w, req, err)
+		return
+	}
+	r.Log.Info("Headcount plan created", "id", plan.ID, "department_id", plan.DepartmentID, "period", plan.Period)
+
+	writeJSONWithETag(w, req, http.StatusCreated, plan.Version, map[string]interface{}{
+		"message": "headcount plan created successfully",
+		"data":    plan,
+	})
+}
+
+// ListHeadcountPlans планы, ?department_id= и ?period= - фильтры
+func (r *Repository) ListHeadcountPlans(w http.ResponseWriter, req *http.Request) {
+	if !checkMethod(w, req, http.MethodGet) {
+		return
+	}
+
+	departmentID, ok := r.queryDepartmentID(w, req, "department_id", models.ErrDepartmentNotFound)
+	if !ok {
+		return
+	}
+	filter := models.HeadcountPlanFilter{DepartmentID: departmentID, Period: req.URL.Query().Get("period")}
+
+	plans, err := r.Staffing.ListHeadcountPlans(req.Context(), &filter)
+	if err != nil {
+		r.Log.Error("Failed list headcount plans", err)
+		r.writeError(w, req, err)
+		return
+	}
+	if plans == nil {
+		plans = []models.HeadcountPlan{}
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"data": plans})
+}
+
+// UpdateHeadcountPlan изменение плановой численности
+func (r *Repository) UpdateHeadcountPlan(w http.ResponseWriter, req *http.Request) {
+	r.Log.Info("updating headcount plan", "method", req.Method)
+
+	// Проверка метода
+	if !checkMethod(w, req, http.MethodPatch) {
+		return
+	}
+
+	// Получение id
+	planID, ok := pathID(w, req, "id")
+	if !ok {
+		return
+	}
+
+	// Версия из If-Match
+	version, ok := ifMatchVersion(w, req)
+	if !ok {
+		return
+	}
+
+	// Декодирование JSON
+	var update models.HeadcountPlanUpdate
+	if !r.decodeBody(w, req, &update) {
+		return
+	}
+
+	plan, err := r.Staffing.UpdateHeadcountPlan(req.Context(), planID, version, &update)
+	if err != nil {
+		r.Log.Error("Failed update headcount plan", err, "id", planID)
+		r.writeError(w, req, err)
+		return
+	}
+	r.Log.Info("Headcount plan updated", "id", plan.ID, "planned", plan.Planned)
+
+	writeJSONWithETag(w, req, http.StatusOK, plan.Version, map[string]interface{}{
+		"message": "headcount plan updated successfully",
+		"data":    plan,
+	})
+}
+
+// DeleteHeadcountPlan удаление плана
+func (r *Repository) DeleteHeadcountPlan(w http.ResponseWriter, req *http.Request) {
+	r.Log.Info("del headcount plan", "method", req.Method)
+
+	// Проверка метода
+	if !checkMethod(w, req, http.MethodDelete) {
+		return
+	}
+
+	// Получаем Id
+	planID, ok := pathID(w, req, "id")
+	if !ok {
+		return
+	}
+
+	// Версия из If-Match
+	version, ok := ifMatchVersion(w, req)
+	if !ok {
+		return
+	}
+
+	if err := r.Staffing.DeleteHeadcountPlan(req.Context(), planID, version); err != nil {
+		r.Log.Error("Failed del headcount plan", err, "id", planID)
+		r.writeError(w, req, err)
+		return
+	}
+	r.Log.Info("Headcount plan del", "id", planID)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// CreateVacancy открытие вакансии в подразделении
+func (r *Repository) CreateVacancy(w http.ResponseWriter, req *http.Request) {
+	r.Log.Info("creating vacancy", "method", req.Method)
+
+	// Проверка метода
+	if !checkMethod(w, req, http.MethodPost) {
+		return
+	}
+
+	// Декодирование JSON
+	var vacancyReq models.VacancyRequest
+	if !r.decodeBody(w, req, &vacancyReq) {
+		return
+	}
+
+	vacancy, err := r.Staffing.CreateVacancy(req.Context(), &vacancyReq)
+	if err != nil {
+		r.Log.Error("Failed create vacancy", err, "department_id", vacancyReq.DepartmentID)
+		r.writeError(w, req, err)
+		return
+	}
+	r.Log.Info("Vacancy created", "id", vacancy.ID, "department_id", vacancy.DepartmentID, "position", vacancy.Position)
+
+	writeJSONWithETag(w, req, http.StatusCreated, vacancy.Version, map[string]interface{}{
+		"message": "vacancy created successfully",
+		"data":    vacancy,
+	})
+}
+
+// ListVacancies вакансии, ?department_id= и ?status= - фильтры
+func (r *Repository) ListVacancies(w http.ResponseWriter, req *http.Request) {
+	if !checkMethod(w, req, http.MethodGet) {
+		return
+	}
+
+	departmentID, ok := r.queryDepartmentID(w, req, "department_id", models.ErrDepartmentNotFound)
+	if !ok {
+		return
+	}
+	filter := models.VacancyFilter{DepartmentID: departmentID, Status: req.URL.Query().Get("status")}
+	if filter.Status != "" && !models.ValidVacancyStatus(filter.Status) {
+		writeFieldProblem(w, req, "status", "status must be open, filled or canceled")
+		return
+	}
+
+	vacancies, err := r.Staffing.ListVacancies(req.Context(), &filter)
+	if err != nil {
+		r.Log.Error("Failed list vacancies", err)
+		r.writeError(w, req, err)
+		return
+	}
+	if vacancies == nil {
+		vacancies = []models.Vacancy{}
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"data": vacancies})
+}
+
+// GetVacancy вакансия по id
+func (r *Repository) GetVacancy(w http.ResponseWriter, req *http.Request) {
+	if !checkMethod(w, req, http.MethodGet) {
+		return
+	}
+
+	vacancyID, ok := pathID(w, req, "id")
+	if !ok {
+		return
+	}
+
+	vacancy, err := r.Staffing.GetVacancy(req.Context(), vacancyID)
+	if err != nil {
+		r.Log.Error("Failed get vacancy", err, "id", vacancyID)
+		r.writeError(w, req, err)
+		return
+	}
+	writeJSONWithETag(w, req, http.StatusOK, vacancy.Version, vacancy)
+}
+
+// UpdateVacancy изменение должности, срока или статуса вакансии
+func (r *Repository) UpdateVacancy(w http.ResponseWriter, req *http.Request) {
+	r.Log.Info("updating vacancy", "method", req.Method)
+
+	// Проверка метода
+	if !checkMethod(w, req, http.MethodPatch) {
+		return
+	}
+
+	// Получение id
+	vacancyID, ok := pathID(w, req, "id")
+	if !ok {
+		return
+	}
+
+	// Версия из If-Match
+	version, ok := ifMatchVersion(w, req)
+	if !ok {
+		return
+	}
+
+	// Декодирование JSON
+	var update models.VacancyUpdate
+	if !r.decodeBody(w, req, &update) {
+		return
+	}
+
+	vacancy, err := r.Staffing.UpdateVacancy(req.Context(), vacancyID, version, &update)
+	if err != nil {
+		r.Log.Error("Failed update vacancy", err, "id", vacancyID)
+		r.writeError(w, req, err)
+		return
+	}
+	r.Log.Info("Vacancy updated", "id", vacancy.ID, "status", vacancy.Status)
+
+	writeJSONWithETag(w, req, http.StatusOK, vacancy.Version, map[string]interface{}{
+		"message": "vacancy updated successfully",
+		"data":    vacancy,
+	})
+}
+
+// FillVacancy закрытие вакансии: тело как при создании сотрудника, должность
+// по умолчанию из вакансии
+func (r *Repository) FillVacancy(w http.ResponseWriter, req *http.Request) {
+	r.Log.Info("filling vacancy", "method", req.Method)
+
+	// Проверка метода
+	if !checkMethod(w, req, http.MethodPost) {
+		return
+	}
+
+	// Получение id
+	vacancyID, ok := pathID(w, req, "id")
+	if !ok {
+		return
+	}
+
+	// Версия из If-Match
+	version, ok := ifMatchVersion(w, req)
+	if !ok {
+		return
+	}
+
+	// Декодирование JSON
+	var empReq models.EmployeeRequest
+	if !r.decodeBody(w, req, &empReq) {
+		return
+	}
+
+	vacancy, employee, err := r.Staffing.FillVacancy(req.Context(), vacancyID, version, &empReq)
+	if err != nil {
+		r.Log.Error("Failed fill vacancy", err, "id", vacancyID)
+		r.writeError(w, req, err)
+		return
+	}
+	r.Log.Info("Vacancy filled", "id", vacancy.ID, "employee_id", employee.ID)
+
+	writeJSONWithETag(w, req, http.StatusOK, vacancy.Version, map[string]interface{}{
+		"message":  "vacancy filled successfully",
+		"data":     vacancy,
+		"employee": employee,
+	})
+}
+
+// DepartmentStaffing план, факт и открытые вакансии по поддереву; ?period=YYYY-MM,
+// по умолчанию текущий месяц
+func (r *Repository) DepartmentStaffing(w http.ResponseWriter, req *http.Request) {
+	if !checkMethod(w, req, http.MethodGet) {
+		return
+	}
+
+	departmentID, ok := r.departmentID(w, req, "id", models.ErrDepartmentNotFound)
+	if !ok {
+		return
+	}
+
+	rollup, err := r.Staffing.GetStaffing(req.Context(), departmentID, req.URL.Query().Get("period"))
+	if err != nil {
+		r.Log.Error("Failed get staffing", err, "id", departmentID)
+		r.writeError(w, req, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, rollup)
+}
@@ -0,0 +1,27 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/kroulersama/goProject/models"
+)
+
+// DepartmentStats численность, найм, стаж, должности и форма поддерева подразделения
+func (r *Repository) DepartmentStats(w http.ResponseWriter, req *http.Request) {
+	if !checkMethod(w, req, http.MethodGet) {
+		return
+	}
+
+	departmentID, ok := r.departmentID(w, req, "id", models.ErrDepartmentNotFound)
+	if !ok {
+		return
+	}
+
+	stats, err := r.Stats.GetDepartmentStats(req.Context(), departmentID)
+	if err != nil {
+		r.Log.Error("Failed get department stats", err, "id", departmentID)
+		r.writeError(w, req, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, stats)
+}
@@ -0,0 +1,38 @@
+package handler
+
+import "net/http"
+
+// EmployeeView чтение вложенных ресурсов сотрудника: /employees/{id}/{view}.
+// Отдельные маршруты вида /employees/{id}/history конфликтуют в ServeMux
+// с /employees/by-personnel-number/{number}
+func (r *Repository) EmployeeView(w http.ResponseWriter, req *http.Request) {
+	switch req.PathValue("view") {
+	case "history":
+		r.EmployeeHistory(w, req)
+	case "assignments":
+		r.ListSecondaryAssignments(w, req)
+	case "reports":
+		r.DirectReports(w, req)
+	case "chain":
+		r.ReportingChain(w, req)
+	case "span":
+		r.SpanOfControl(w, req)
+	default:
+		writeProblem(w, req, http.StatusNotFound, CodeNotFound, "unknown employee resource "+req.PathValue("view"))
+	}
+}
+
+// DepartmentView чтение вложенных ресурсов подразделения: /departments/{id}/{view};
+// причина та же, конфликт с /departments/by-code/{code}
+func (r *Repository) DepartmentView(w http.ResponseWriter, req *http.Request) {
+	switch req.PathValue("view") {
+	case "headcount":
+		r.DepartmentHeadcount(w, req)
+	case "stats":
+		r.DepartmentStats(w, req)
+	case "staffing":
+		r.DepartmentStaffing(w, req)
+	default:
+		writeProblem(w, req, http.StatusNotFound, CodeNotFound, "unknown department resource "+req.PathValue("view"))
+	}
+}
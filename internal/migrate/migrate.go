@@ -0,0 +1,80 @@
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"io/fs"
+
+	"github.com/kroulersama/goProject/migrations"
+	"github.com/kroulersama/goProject/storage"
+	"github.com/pressly/goose/v3"
+	"github.com/pressly/goose/v3/lock"
+)
+
+// Migrator применяет встроенные миграции
+type Migrator struct {
+	*goose.Provider
+}
+
+// New открывает базу и готовит goose провайдер для диалекта
+func New(config *storage.Config) (*Migrator, error) {
+	fsys, err := fs.Sub(migrations.FS, config.GetDriver())
+	if err != nil {
+		return nil, err
+	}
+
+	db, err := sql.Open(config.GetDriver(), config.GetDSN())
+	if err != nil {
+		return nil, err
+	}
+
+	dialect := goose.DialectPostgres
+	var opts []goose.ProviderOption
+	if config.GetDriver() == storage.DriverSQLite {
+		dialect = goose.DialectSQLite3
+	} else {
+		// Advisory lock: реплики не применяют миграции одновременно
+		locker, err := lock.NewPostgresSessionLocker()
+		if err != nil {
+			db.Close()
+			return nil, err
+		}
+		opts = append(opts, goose.WithSessionLocker(locker))
+	}
+
+	provider, err := goose.NewProvider(dialect, db, fsys, opts...)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return &Migrator{Provider: provider}, nil
+}
+
+// Redo откатывает и заново применяет последнюю миграцию
+func (m *Migrator) Redo(ctx context.Context) ([]*goose.MigrationResult, error) {
+	down, err := m.Down(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	up, err := m.ApplyVersion(ctx, down.Source.Version, true)
+	if err != nil {
+		return nil, err
+	}
+
+	return []*goose.MigrationResult{down, up}, nil
+}
+
+// To приводит схему к указанной версии в любую сторону
+func (m *Migrator) To(ctx context.Context, version int64) ([]*goose.MigrationResult, error) {
+	current, err := m.GetDBVersion(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if version < current {
+		return m.DownTo(ctx, version)
+	}
+	return m.UpTo(ctx, version)
+}
@@ -1,11 +1,16 @@
 package main
 
 import (
+	"context"
+	"flag"
+	"fmt"
 	"net/http"
 	"os"
+	"strings"
+	"time"
 
 	"github.com/kroulersama/goProject/internal/handler"
-
+	"github.com/kroulersama/goProject/models"
 	"github.com/kroulersama/goProject/pkg/logger"
 	"github.com/kroulersama/goProject/storage"
 	_ "github.com/lib/pq"
@@ -15,11 +20,10 @@ func main() {
 	// Инициализация логгера
 	log := logger.New()
 
-	// Логируем запуск
-	log.Info("Starting server...")
-
-	//Инициализация базы с goose миграциями
+	// Конфигурация базы из окружения
 	config := &storage.Config{
+		Driver:   os.Getenv("DB_DRIVER"),
+		Path:     os.Getenv("DB_PATH"),
 		Host:     os.Getenv("DB_HOST"),
 		Port:     os.Getenv("DB_PORT"),
 		Password: os.Getenv("DB_PASSWORD"),
@@ -28,16 +32,57 @@ func main() {
 		DBName:   os.Getenv("DB_NAME"),
 	}
 
-	dsn := config.GetDSN()
+	// Подкоманда, по умолчанию serve
+	cmd, args := "serve", os.Args[1:]
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		cmd, args = args[0], args[1:]
+	}
+
+	switch cmd {
+	case "serve":
+		serve(log, config, args)
+	case "migrate":
+		if err := runMigrate(log, config, args); err != nil {
+			log.Fatal("Migration failed", err)
+		}
+	case "positions":
+		if err := runPositions(config, args); err != nil {
+			log.Fatal("Positions command failed", err)
+		}
+	case "check":
+		remaining, err := runCheck(config, args)
+		if err != nil {
+			log.Fatal("Integrity check failed", err)
+		}
+		if remaining > 0 {
+			os.Exit(1)
+		}
+	default:
+		log.Fatal("Unknown command", fmt.Errorf("%q, use serve, migrate, check or positions", cmd))
+	}
+}
+
+func serve(log *logger.Logger, config *storage.Config, args []string) {
+	flags := flag.NewFlagSet("serve", flag.ExitOnError)
+	noMigrate := flags.Bool("no-migrate", false, "do not apply migrations on startup")
+	flags.Parse(args)
+
+	// Логируем запуск
+	log.Info("Starting server...")
 
 	log.Info("Waiting for database...")
-	handler.WaitForDB(dsn)
+	handler.WaitForDB(config)
 	log.Info("Database connected")
 
-	if err := handler.RunMigrations(dsn); err != nil {
-		log.Fatal("Failed to run migrations", err)
+	//Миграции goose
+	if *noMigrate {
+		log.Info("Migrations skipped (--no-migrate)")
+	} else {
+		if err := runMigrate(log, config, []string{"up"}); err != nil {
+			log.Fatal("Failed to run migrations", err)
+		}
+		log.Info("Migrations applied successfully")
 	}
-	log.Info("Migrations applied successfully")
 
 	//Инициализация GORM
 	db, err := storage.NewConnection(config)
@@ -45,18 +90,129 @@ func main() {
 		log.Fatal("Could not load the database", err)
 	}
 	log.Info("GORM initialized")
+	// Срок хранения ключей идемпотентности
+	idempotencyTTL := 24 * time.Hour
+	if ttl := os.Getenv("IDEMPOTENCY_TTL"); ttl != "" {
+		if idempotencyTTL, err = time.ParseDuration(ttl); err != nil {
+			log.Fatal("Invalid IDEMPOTENCY_TTL", err)
+		}
+	}
+
+	// Политика уникальности имен подразделений
+	policy, err := models.ParseUniquenessPolicy(os.Getenv("DEPARTMENT_NAME_UNIQUENESS"))
+	if err != nil {
+		log.Fatal("Invalid DEPARTMENT_NAME_UNIQUENESS", err)
+	}
+
+	store := storage.NewGormStore(db, policy)
+	if err := store.ApplyUniquenessPolicy(context.Background()); err != nil {
+		log.Fatal("Department or position names violate uniqueness policy", err)
+	}
+	log.Info("Uniqueness policy applied", "policy", policy)
 	repo := &handler.Repository{
-		DB:  db,
-		Log: log,
+		Departments:    store,
+		Employees:      store,
+		Attributes:     store,
+		Positions:      store,
+		Assignments:    store,
+		Secondary:      store,
+		Reporting:      store,
+		Stats:          store,
+		Staffing:       store,
+		Rules:          store,
+		Integrity:      store,
+		Idempotency:    store,
+		Plans:          store,
+		Schedule:       store,
+		IdempotencyTTL: idempotencyTTL,
+		Log:            log,
+	}
+	go repo.PurgeIdempotencyKeys(context.Background(), time.Hour)
+
+	// Планировщик отложенных изменений, 0 - отключен
+	schedulerInterval := 30 * time.Second
+	if interval := os.Getenv("SCHEDULER_INTERVAL"); interval != "" {
+		if schedulerInterval, err = time.ParseDuration(interval); err != nil {
+			log.Fatal("Invalid SCHEDULER_INTERVAL", err)
+		}
+	}
+	if schedulerInterval > 0 {
+		go repo.RunScheduler(context.Background(), schedulerInterval)
+	}
+
+	// Дедлайны запросов к базе
+	timeouts, err := handler.ParseTimeouts(os.Getenv("QUERY_TIMEOUT"), os.Getenv("QUERY_TIMEOUTS"))
+	if err != nil {
+		log.Fatal("Invalid query timeouts", err)
 	}
 
 	//Инициализация Путей
 	mux := http.NewServeMux()
-	mux.HandleFunc("POST /departments", log.Middleware(repo.CreateDepartment))
-	mux.HandleFunc("POST /departments/{id}/employees", log.Middleware(repo.CreateEmployeeInDepartment))
-	mux.HandleFunc("GET /departments/{id}", log.Middleware(repo.GetDepartment))
-	mux.HandleFunc("PATCH /departments/{id}", log.Middleware(repo.MoveDepartment))
-	mux.HandleFunc("DELETE /departments/{id}", log.Middleware(repo.DeleteDepartment))
+	route := func(pattern string, h http.HandlerFunc) {
+		mux.HandleFunc(pattern, log.Middleware(handler.WithTimeout(timeouts.For(pattern), handler.WithActor(h))))
+	}
+	route("POST /departments", repo.Idempotent(repo.CreateDepartment))
+	route("GET /departments", repo.ListDepartments)
+	route("POST /departments/{id}/employees", repo.Idempotent(repo.CreateEmployeeInDepartment))
+	route("GET /departments/{id}", repo.GetDepartment)
+	route("GET /departments/by-code/{code}", repo.GetDepartmentByCode)
+	route("GET /departments/by-external-id/{system}/{external_id}", repo.GetDepartmentByExternalID)
+	route("PATCH /departments/{id}", repo.Idempotent(repo.MoveDepartment))
+	route("DELETE /departments/{id}", repo.DeleteDepartment)
+	route("POST /departments/{id}/merge-into/{target}", repo.Idempotent(repo.MergeDepartment))
+	route("POST /departments/{id}/split", repo.Idempotent(repo.SplitDepartment))
+	route("POST /departments/{id}/copy", repo.Idempotent(repo.CopyDepartment))
+	route("PUT /departments/{id}/children/order", repo.ReorderChildren)
+	route("GET /departments/{id}/{view}", repo.DepartmentView)
+	route("GET /employees/by-personnel-number/{number}", repo.GetEmployeeByPersonnelNumber)
+	route("GET /employees", repo.ListEmployees)
+	route("GET /employees/{id}", repo.GetEmployee)
+	route("PATCH /employees/{id}/attributes", repo.Idempotent(repo.UpdateEmployeeAttributes))
+	route("POST /employees/{id}/transfer", repo.Idempotent(repo.TransferEmployee))
+	route("POST /employees/{id}/terminate", repo.Idempotent(repo.TerminateEmployee))
+	route("POST /employees/{id}/rehire", repo.Idempotent(repo.RehireEmployee))
+	route("PATCH /employees/{id}/status", repo.Idempotent(repo.SetEmployeeStatus))
+	route("POST /employees/{id}/assignments", repo.Idempotent(repo.AddSecondaryAssignment))
+	route("PATCH /employees/{id}/assignments/{assignment}", repo.Idempotent(repo.UpdateSecondaryAssignment))
+	route("DELETE /employees/{id}/assignments/{assignment}", repo.RemoveSecondaryAssignment)
+	route("PATCH /employees/{id}/manager", repo.Idempotent(repo.SetManager))
+	route("GET /employees/{id}/{view}", repo.EmployeeView)
+	route("GET /department-movements", repo.DepartmentMovements)
+	route("GET /reporting-lines/issues", repo.ReportingIssues)
+	route("POST /attributes", repo.Idempotent(repo.CreateAttributeDefinition))
+	route("GET /attributes", repo.ListAttributeDefinitions)
+	route("GET /attributes/{id}", repo.GetAttributeDefinition)
+	route("PATCH /attributes/{id}", repo.Idempotent(repo.UpdateAttributeDefinition))
+	route("DELETE /attributes/{id}", repo.DeleteAttributeDefinition)
+	route("POST /positions", repo.Idempotent(repo.CreatePosition))
+	route("GET /positions", repo.ListPositions)
+	route("GET /positions/clusters", repo.PositionClusters)
+	route("GET /positions/{id}", repo.GetPosition)
+	route("PATCH /positions/{id}", repo.Idempotent(repo.UpdatePosition))
+	route("DELETE /positions/{id}", repo.DeletePosition)
+	route("POST /headcount-plans", repo.Idempotent(repo.CreateHeadcountPlan))
+	route("GET /headcount-plans", repo.ListHeadcountPlans)
+	route("PATCH /headcount-plans/{id}", repo.Idempotent(repo.UpdateHeadcountPlan))
+	route("DELETE /headcount-plans/{id}", repo.DeleteHeadcountPlan)
+	route("POST /vacancies", repo.Idempotent(repo.CreateVacancy))
+	route("GET /vacancies", repo.ListVacancies)
+	route("GET /vacancies/{id}", repo.GetVacancy)
+	route("PATCH /vacancies/{id}", repo.Idempotent(repo.UpdateVacancy))
+	route("POST /vacancies/{id}/fill", repo.Idempotent(repo.FillVacancy))
+	route("GET /admin/integrity", repo.CheckIntegrity)
+	route("GET /hierarchy-rules", repo.GetHierarchyRules)
+	route("PUT /hierarchy-rules", repo.UpdateHierarchyRules)
+	route("GET /hierarchy-rules/violations", repo.CheckHierarchy)
+	route("GET /scheduled-changes", repo.ListScheduledChanges)
+	route("GET /scheduled-changes/{id}", repo.GetScheduledChange)
+	route("POST /scheduled-changes/{id}/cancel", repo.Idempotent(repo.CancelScheduledChange))
+	route("POST /plans", repo.Idempotent(repo.CreatePlan))
+	route("GET /plans", repo.ListPlans)
+	route("GET /plans/{id}", repo.GetPlan)
+	route("DELETE /plans/{id}", repo.DeletePlan)
+	route("POST /plans/{id}/operations", repo.Idempotent(repo.AddPlanOperations))
+	route("GET /plans/{id}/diff", repo.DiffPlan)
+	route("POST /plans/{id}/apply", repo.Idempotent(repo.ApplyPlan))
 
 	log.Info("Server started on :8080")
 	if err := http.ListenAndServe(":8080", mux); err != nil {
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"text/tabwriter"
+
+	"github.com/kroulersama/goProject/internal/migrate"
+	"github.com/kroulersama/goProject/pkg/logger"
+	"github.com/kroulersama/goProject/storage"
+	"github.com/pressly/goose/v3"
+)
+
+const migrateUsage = "usage: migrate up|down|status|redo|version N"
+
+// migrate up|down|status|redo|version N
+func runMigrate(log *logger.Logger, config *storage.Config, args []string) error {
+	if len(args) == 0 {
+		return errors.New(migrateUsage)
+	}
+
+	m, err := migrate.New(config)
+	if err != nil {
+		return err
+	}
+	defer m.Close()
+
+	ctx := context.Background()
+
+	var results []*goose.MigrationResult
+	switch args[0] {
+	case "up":
+		results, err = m.Up(ctx)
+
+	case "down":
+		var result *goose.MigrationResult
+		result, err = m.Down(ctx)
+		if result != nil {
+			results = append(results, result)
+		}
+
+	case "redo":
+		results, err = m.Redo(ctx)
+
+	case "version":
+		if len(args) < 2 {
+			return errors.New(migrateUsage)
+		}
+		version, parseErr := strconv.ParseInt(args[1], 10, 64)
+		if parseErr != nil {
+			return fmt.Errorf("invalid version %q", args[1])
+		}
+		results, err = m.To(ctx, version)
+
+	case "status":
+		return printStatus(ctx, m)
+
+	default:
+		return errors.New(migrateUsage)
+	}
+
+	for _, result := range results {
+		log.Info("Migration", "version", result.Source.Version, "direction", result.Direction, "duration", result.Duration)
+	}
+	return err
+}
+
+// Таблица состояния миграций
+func printStatus(ctx context.Context, m *migrate.Migrator) error {
+	statuses, err := m.Status(ctx)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "VERSION\tSTATE\tAPPLIED AT\tFILE")
+	for _, s := range statuses {
+		appliedAt := "-"
+		if !s.AppliedAt.IsZero() {
+			appliedAt = s.AppliedAt.Format("2006-01-02 15:04:05")
+		}
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\n", s.Source.Version, s.State, appliedAt, s.Source.Path)
+	}
+	return w.Flush()
+}
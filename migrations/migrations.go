@@ -0,0 +1,8 @@
+package migrations
+
+import "embed"
+
+// SQL миграции для каждого диалекта встраиваются в бинарник
+//
+//go:embed postgres/*.sql sqlite/*.sql
+var FS embed.FS
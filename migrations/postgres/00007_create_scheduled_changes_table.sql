@@ -0,0 +1,26 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS scheduled_changes (
+    id SERIAL PRIMARY KEY,
+    kind VARCHAR(20) NOT NULL,
+    department_id INTEGER NULL,
+    employee_id INTEGER NULL,
+    name VARCHAR(200) NOT NULL DEFAULT '',
+    parent_id INTEGER NULL,
+    to_department_id INTEGER NULL,
+    effective_at TIMESTAMP NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending',
+    error TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    applied_at TIMESTAMP NULL,
+    version INT NOT NULL DEFAULT 1
+);
+
+-- Индекс для выборки изменений, которые пора применить
+CREATE INDEX idx_scheduled_changes_due ON scheduled_changes(status, effective_at);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS scheduled_changes;
+-- +goose StatementEnd
\ No newline at end of file
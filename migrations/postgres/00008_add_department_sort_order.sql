@@ -0,0 +1,21 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Порядок среди соседей; существующие нумеруются по id внутри родителя
+ALTER TABLE departments ADD COLUMN sort_order INT NOT NULL DEFAULT 0;
+UPDATE departments SET sort_order = (
+    SELECT COUNT(*) FROM departments d
+    WHERE COALESCE(d.parent_id, 0) = COALESCE(departments.parent_id, 0) AND d.id < departments.id
+);
+CREATE INDEX idx_departments_parent_sort ON departments(parent_id, sort_order);
+
+-- Позиция для отложенного переноса
+ALTER TABLE scheduled_changes ADD COLUMN position INT NULL;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE scheduled_changes DROP COLUMN position;
+
+DROP INDEX idx_departments_parent_sort;
+ALTER TABLE departments DROP COLUMN sort_order;
+-- +goose StatementEnd
\ No newline at end of file
@@ -0,0 +1,37 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Код подразделения (центр затрат) и идентификаторы во внешних системах
+ALTER TABLE departments ADD COLUMN code VARCHAR(50) NULL;
+ALTER TABLE departments ADD COLUMN external_ids TEXT NOT NULL DEFAULT '{}';
+CREATE UNIQUE INDEX idx_departments_code ON departments(code);
+
+-- Копия external_ids для поиска и уникальности пары (система, id)
+CREATE TABLE IF NOT EXISTS department_external_ids (
+    department_id INTEGER NOT NULL,
+    system VARCHAR(50) NOT NULL,
+    external_id VARCHAR(100) NOT NULL,
+
+    PRIMARY KEY (department_id, system),
+    CONSTRAINT fk_department_external_ids_department
+        FOREIGN KEY (department_id)
+        REFERENCES departments(id)
+        ON DELETE CASCADE
+);
+CREATE UNIQUE INDEX idx_department_external_ids_lookup ON department_external_ids(system, external_id);
+
+-- Табельный номер сотрудника
+ALTER TABLE employees ADD COLUMN personnel_number VARCHAR(50) NULL;
+CREATE UNIQUE INDEX idx_employees_personnel_number ON employees(personnel_number);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX idx_employees_personnel_number;
+ALTER TABLE employees DROP COLUMN personnel_number;
+
+DROP TABLE IF EXISTS department_external_ids;
+
+DROP INDEX idx_departments_code;
+ALTER TABLE departments DROP COLUMN external_ids;
+ALTER TABLE departments DROP COLUMN code;
+-- +goose StatementEnd
\ No newline at end of file
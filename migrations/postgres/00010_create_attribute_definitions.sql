@@ -0,0 +1,33 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Описания пользовательских атрибутов подразделений и сотрудников
+CREATE TABLE IF NOT EXISTS attribute_definitions (
+    id SERIAL PRIMARY KEY,
+    entity VARCHAR(20) NOT NULL,
+    name VARCHAR(50) NOT NULL,
+    type VARCHAR(20) NOT NULL,
+    required BOOLEAN NOT NULL DEFAULT FALSE,
+    enum_values TEXT NOT NULL DEFAULT '[]',
+    pattern VARCHAR(500) NOT NULL DEFAULT '',
+    description VARCHAR(500) NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    version INT NOT NULL DEFAULT 1
+);
+CREATE UNIQUE INDEX idx_attribute_definitions_name ON attribute_definitions(entity, name);
+
+-- Значения атрибутов
+ALTER TABLE departments ADD COLUMN attributes JSONB NOT NULL DEFAULT '{}';
+ALTER TABLE employees ADD COLUMN attributes JSONB NOT NULL DEFAULT '{}';
+CREATE INDEX idx_departments_attributes ON departments USING GIN (attributes);
+CREATE INDEX idx_employees_attributes ON employees USING GIN (attributes);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_employees_attributes;
+DROP INDEX IF EXISTS idx_departments_attributes;
+ALTER TABLE employees DROP COLUMN attributes;
+ALTER TABLE departments DROP COLUMN attributes;
+
+DROP TABLE IF EXISTS attribute_definitions;
+-- +goose StatementEnd
\ No newline at end of file
@@ -0,0 +1,16 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Статус занятости: уволенные остаются в базе с датой и причиной
+ALTER TABLE employees ADD COLUMN status VARCHAR(20) NOT NULL DEFAULT 'active';
+ALTER TABLE employees ADD COLUMN terminated_at TIMESTAMP NULL;
+ALTER TABLE employees ADD COLUMN termination_reason VARCHAR(500) NOT NULL DEFAULT '';
+CREATE INDEX idx_employees_status ON employees(status);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_employees_status;
+ALTER TABLE employees DROP COLUMN termination_reason;
+ALTER TABLE employees DROP COLUMN terminated_at;
+ALTER TABLE employees DROP COLUMN status;
+-- +goose StatementEnd
\ No newline at end of file
@@ -0,0 +1,27 @@
+-- +goose Up
+-- +goose StatementBegin
+-- История назначений: периоды работы сотрудника в подразделении на должности
+CREATE TABLE IF NOT EXISTS employee_assignments (
+    id SERIAL PRIMARY KEY,
+    employee_id INT NOT NULL REFERENCES employees(id) ON DELETE CASCADE,
+    department_id INT NOT NULL,
+    position VARCHAR(200) NOT NULL,
+    started_at TIMESTAMP NOT NULL,
+    ended_at TIMESTAMP NULL,
+    actor VARCHAR(100) NOT NULL DEFAULT '',
+    closed_by VARCHAR(100) NOT NULL DEFAULT ''
+);
+CREATE INDEX idx_employee_assignments_employee ON employee_assignments(employee_id, started_at);
+CREATE INDEX idx_employee_assignments_department ON employee_assignments(department_id, started_at);
+
+-- Текущие назначения существующих сотрудников
+INSERT INTO employee_assignments (employee_id, department_id, position, started_at, ended_at, actor, closed_by)
+SELECT id, department_id, position, COALESCE(hired_at, created_at), terminated_at, 'migration',
+       CASE WHEN terminated_at IS NULL THEN '' ELSE 'migration' END
+FROM employees;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS employee_assignments;
+-- +goose StatementEnd
\ No newline at end of file
@@ -0,0 +1,12 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Линии подчинения: руководитель сотрудника
+ALTER TABLE employees ADD COLUMN manager_id INT NULL REFERENCES employees(id) ON DELETE SET NULL;
+CREATE INDEX idx_employees_manager_id ON employees(manager_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_employees_manager_id;
+ALTER TABLE employees DROP COLUMN manager_id;
+-- +goose StatementEnd
\ No newline at end of file
@@ -0,0 +1,34 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Плановая численность подразделений по месяцам
+CREATE TABLE IF NOT EXISTS headcount_plans (
+    id SERIAL PRIMARY KEY,
+    department_id INT NOT NULL REFERENCES departments(id) ON DELETE CASCADE,
+    period VARCHAR(7) NOT NULL,
+    planned INT NOT NULL CHECK (planned >= 0),
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    version INT NOT NULL DEFAULT 1
+);
+CREATE UNIQUE INDEX idx_headcount_plans_department_period ON headcount_plans(department_id, period);
+
+-- Вакансии; закрытая вакансия ссылается на принятого сотрудника
+CREATE TABLE IF NOT EXISTS vacancies (
+    id SERIAL PRIMARY KEY,
+    department_id INT NOT NULL REFERENCES departments(id) ON DELETE CASCADE,
+    position VARCHAR(200) NOT NULL,
+    position_id INT NULL REFERENCES positions(id) ON DELETE SET NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'open',
+    target_date TIMESTAMP NULL,
+    employee_id INT NULL REFERENCES employees(id) ON DELETE SET NULL,
+    filled_at TIMESTAMP NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    version INT NOT NULL DEFAULT 1
+);
+CREATE INDEX idx_vacancies_department_status ON vacancies(department_id, status);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS vacancies;
+DROP TABLE IF EXISTS headcount_plans;
+-- +goose StatementEnd
\ No newline at end of file
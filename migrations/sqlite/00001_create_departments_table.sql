@@ -0,0 +1,24 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS departments (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    name VARCHAR(200) NOT NULL,
+    parent_id INTEGER NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    
+    CONSTRAINT fk_departments_parent 
+        FOREIGN KEY (parent_id) 
+        REFERENCES departments(id) 
+        ON DELETE CASCADE
+);
+
+-- Индекс для поиска по parent_id
+CREATE INDEX idx_departments_parent_id ON departments(parent_id);
+CREATE UNIQUE INDEX idx_departments_name_parent 
+ON departments(name, COALESCE(parent_id, 0));
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS departments;
+-- +goose StatementEnd
\ No newline at end of file
@@ -0,0 +1,25 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS employees (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    department_id INTEGER NOT NULL,
+    full_name VARCHAR(200) NOT NULL,
+    position VARCHAR(200) NOT NULL,
+    hired_at DATE NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    
+    CONSTRAINT fk_employees_department 
+        FOREIGN KEY (department_id) 
+        REFERENCES departments(id) 
+        ON DELETE CASCADE
+);
+
+-- Индекс для поиска по department_id
+CREATE INDEX idx_employees_department_id ON employees(department_id);
+CREATE INDEX idx_employees_full_name ON employees(full_name);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS employees;
+-- +goose StatementEnd
\ No newline at end of file
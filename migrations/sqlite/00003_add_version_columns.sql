@@ -0,0 +1,11 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE departments ADD COLUMN version INT NOT NULL DEFAULT 1;
+ALTER TABLE employees ADD COLUMN version INT NOT NULL DEFAULT 1;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE employees DROP COLUMN version;
+ALTER TABLE departments DROP COLUMN version;
+-- +goose StatementEnd
\ No newline at end of file
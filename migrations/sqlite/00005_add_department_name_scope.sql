@@ -0,0 +1,21 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Ключ имени (NFKC + case folding) и область уникальности по политике;
+-- точные значения пересчитывает приложение при запуске
+ALTER TABLE departments ADD COLUMN name_key TEXT NOT NULL DEFAULT '';
+ALTER TABLE departments ADD COLUMN name_scope TEXT NOT NULL DEFAULT '';
+UPDATE departments SET name_key = name, name_scope = 's:' || COALESCE(parent_id, 0);
+
+DROP INDEX idx_departments_name_parent;
+CREATE UNIQUE INDEX idx_departments_name_scope ON departments(name_scope, name_key);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX idx_departments_name_scope;
+CREATE UNIQUE INDEX idx_departments_name_parent 
+ON departments(name, COALESCE(parent_id, 0));
+
+ALTER TABLE departments DROP COLUMN name_scope;
+ALTER TABLE departments DROP COLUMN name_key;
+-- +goose StatementEnd
\ No newline at end of file
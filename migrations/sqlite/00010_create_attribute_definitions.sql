@@ -0,0 +1,29 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Описания пользовательских атрибутов подразделений и сотрудников
+CREATE TABLE IF NOT EXISTS attribute_definitions (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    entity VARCHAR(20) NOT NULL,
+    name VARCHAR(50) NOT NULL,
+    type VARCHAR(20) NOT NULL,
+    required BOOLEAN NOT NULL DEFAULT FALSE,
+    enum_values TEXT NOT NULL DEFAULT '[]',
+    pattern VARCHAR(500) NOT NULL DEFAULT '',
+    description VARCHAR(500) NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    version INT NOT NULL DEFAULT 1
+);
+CREATE UNIQUE INDEX idx_attribute_definitions_name ON attribute_definitions(entity, name);
+
+-- Значения атрибутов, JSON-текст
+ALTER TABLE departments ADD COLUMN attributes TEXT NOT NULL DEFAULT '{}';
+ALTER TABLE employees ADD COLUMN attributes TEXT NOT NULL DEFAULT '{}';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE employees DROP COLUMN attributes;
+ALTER TABLE departments DROP COLUMN attributes;
+
+DROP TABLE IF EXISTS attribute_definitions;
+-- +goose StatementEnd
\ No newline at end of file
@@ -0,0 +1,22 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Тип подразделения и правила иерархии (одна запись)
+ALTER TABLE departments ADD COLUMN type VARCHAR(50) NOT NULL DEFAULT '';
+CREATE INDEX idx_departments_type ON departments(type);
+
+CREATE TABLE IF NOT EXISTS hierarchy_rules (
+    id INTEGER PRIMARY KEY,
+    rules TEXT NOT NULL DEFAULT '{}',
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    version INT NOT NULL DEFAULT 1
+);
+INSERT INTO hierarchy_rules (id) VALUES (1);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS hierarchy_rules;
+
+DROP INDEX IF EXISTS idx_departments_type;
+ALTER TABLE departments DROP COLUMN type;
+-- +goose StatementEnd
\ No newline at end of file
@@ -0,0 +1,27 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Каталог должностей
+CREATE TABLE IF NOT EXISTS positions (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    title VARCHAR(200) NOT NULL,
+    title_key VARCHAR(200) NOT NULL,
+    grade VARCHAR(50) NOT NULL DEFAULT '',
+    job_family VARCHAR(100) NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    version INT NOT NULL DEFAULT 1
+);
+CREATE UNIQUE INDEX idx_positions_title_key ON positions(title_key);
+CREATE INDEX idx_positions_job_family ON positions(job_family);
+
+-- Ссылка сотрудника на каталог; текст должности остается на время перехода
+ALTER TABLE employees ADD COLUMN position_id INTEGER NULL REFERENCES positions(id);
+CREATE INDEX idx_employees_position_id ON employees(position_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_employees_position_id;
+ALTER TABLE employees DROP COLUMN position_id;
+
+DROP TABLE IF EXISTS positions;
+-- +goose StatementEnd
\ No newline at end of file
@@ -0,0 +1,20 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Совмещения: работа сотрудника в других подразделениях на часть ставки
+CREATE TABLE IF NOT EXISTS secondary_assignments (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    employee_id INTEGER NOT NULL REFERENCES employees(id) ON DELETE CASCADE,
+    department_id INTEGER NOT NULL REFERENCES departments(id) ON DELETE CASCADE,
+    role VARCHAR(100) NOT NULL,
+    allocation INT NOT NULL CHECK (allocation BETWEEN 1 AND 100),
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    version INT NOT NULL DEFAULT 1
+);
+CREATE UNIQUE INDEX idx_secondary_assignments_employee_department ON secondary_assignments(employee_id, department_id);
+CREATE INDEX idx_secondary_assignments_department ON secondary_assignments(department_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS secondary_assignments;
+-- +goose StatementEnd
\ No newline at end of file
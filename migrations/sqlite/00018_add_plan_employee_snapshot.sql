@@ -0,0 +1,10 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Версии сотрудников, на которых ссылается план
+ALTER TABLE reorg_plans ADD COLUMN employee_snapshot TEXT NOT NULL DEFAULT '{}';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE reorg_plans DROP COLUMN employee_snapshot;
+-- +goose StatementEnd
@@ -0,0 +1,303 @@
+package models
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// События ленты подразделения
+const (
+	MovementJoined = "joined"
+	MovementLeft   = "left"
+)
+
+// Автор изменений без X-Actor
+const DefaultActor = "system"
+
+// Период работы сотрудника в подразделении на должности; To nil - текущий
+type EmployeeAssignment struct {
+	ID           uint       `json:"id" gorm:"primaryKey"`
+	EmployeeID   uint       `json:"employee_id" gorm:"column:employee_id;not null"`
+	DepartmentID uint       `json:"department_id" gorm:"column:department_id;not null"`
+	Position     string     `json:"position" gorm:"column:position;not null;size:200"`
+	From         time.Time  `json:"from" gorm:"column:started_at;not null"`
+	To           *time.Time `json:"to" gorm:"column:ended_at"`
+	Actor        string     `json:"actor" gorm:"column:actor;not null;size:100"`
+	ClosedBy     string     `json:"closed_by,omitempty" gorm:"column:closed_by;not null;size:100"`
+}
+
+// Имя для таблицы
+func (EmployeeAssignment) TableName() string {
+	return "employee_assignments"
+}
+
+// Приход или уход сотрудника из подразделения
+type Movement struct {
+	Type             string    `json:"type"`
+	At               time.Time `json:"at"`
+	EmployeeID       uint      `json:"employee_id"`
+	FullName         string    `json:"full_name"`
+	Position         string    `json:"position"`
+	FromDepartmentID *uint     `json:"from_department_id"` // nil - прием на работу
+	ToDepartmentID   *uint     `json:"to_department_id"`   // nil - увольнение
+	Actor            string    `json:"actor"`
+}
+
+// Период ленты: Since включительно, Until не включая; nil - без границы
+type MovementFilter struct {
+	Since *time.Time
+	Until *time.Time
+}
+
+// Попадает ли момент at в период
+func (f *MovementFilter) Includes(at time.Time) bool {
+	return (f.Since == nil || !at.Before(*f.Since)) && (f.Until == nil || at.Before(*f.Until))
+}
+
+type actorKey struct{}
+
+// WithActor автор изменений для истории назначений
+func WithActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, actorKey{}, strings.TrimSpace(actor))
+}
+
+// ActorFromContext автор изменений, по умолчанию DefaultActor
+func ActorFromContext(ctx context.Context) string {
+	if actor, _ := ctx.Value(actorKey{}).(string); actor != "" {
+		return actor
+	}
+	return DefaultActor
+}
+
+// DepartmentMovements лента подразделения по его периодам own; соседние периоды
+// тех же сотрудников related показывают, откуда пришел и куда ушел сотрудник.
+// Смена должности внутри подразделения в ленту не попадает
+func DepartmentMovements(departmentID uint, own, related []EmployeeAssignment, names map[uint]string, filter *MovementFilter) []Movement {
+	// Соседний период того же сотрудника, начатый или законченный в момент at
+	adjacent := func(a EmployeeAssignment, at time.Time, next bool) *EmployeeAssignment {
+		for i := range related {
+			r := &related[i]
+			if r.ID == a.ID || r.EmployeeID != a.EmployeeID {
+				continue
+			}
+			if next && r.From.Equal(at) || !next && r.To != nil && r.To.Equal(at) {
+				return r
+			}
+		}
+		return nil
+	}
+
+	movements := []Movement{}
+	for _, a := range own {
+		if filter.Includes(a.From) {
+			prev := adjacent(a, a.From, false)
+			if prev == nil || prev.DepartmentID != departmentID {
+				movement := Movement{
+					Type: MovementJoined, At: a.From, EmployeeID: a.EmployeeID, FullName: names[a.EmployeeID],
+					Position: a.Position, ToDepartmentID: &departmentID, Actor: a.Actor,
+				}
+				if prev != nil {
+					movement.FromDepartmentID = &prev.DepartmentID
+				}
+				movements = append(movements, movement)
+			}
+		}
+
+		if a.To != nil && filter.Includes(*a.To) {
+			next := adjacent(a, *a.To, true)
+			if next == nil || next.DepartmentID != departmentID {
+				movement := Movement{
+					Type: MovementLeft, At: *a.To, EmployeeID: a.EmployeeID, FullName: names[a.EmployeeID],
+					Position: a.Position, FromDepartmentID: &departmentID, Actor: a.ClosedBy,
+				}
+				if next != nil {
+					movement.ToDepartmentID = &next.DepartmentID
+					movement.Actor = next.Actor
+				}
+				movements = append(movements, movement)
+			}
+		}
+	}
+
+	// Новые события первыми
+	slices.SortStableFunc(movements, func(a, b Movement) int {
+		if c := b.At.Compare(a.At); c != 0 {
+			return c
+		}
+		if c := cmp.Compare(a.EmployeeID, b.EmployeeID); c != 0 {
+			return c
+		}
+		return cmp.Compare(b.Type, a.Type)
+	})
+	return movements
+}
+
+// EmployeeHistory периоды сотрудника от первого к последнему
+func EmployeeHistory(ctx context.Context, db *gorm.DB, id uint) ([]EmployeeAssignment, error) {
+	db = db.WithContext(ctx)
+
+	if _, err := GetEmployee(ctx, db, id); err != nil {
+		return nil, err
+	}
+	var history []EmployeeAssignment
+	if err := db.Where("employee_id = ?", id).Order("started_at, id").Find(&history).Error; err != nil {
+		return nil, err
+	}
+	return history, nil
+}
+
+// GetDepartmentMovements кто пришел в подразделение и кто из него ушел
+func GetDepartmentMovements(ctx context.Context, db *gorm.DB, departmentID uint, filter *MovementFilter) ([]Movement, error) {
+	db = db.WithContext(ctx)
+
+	// Проверка отдела
+	if err := db.Select("id").First(&Department{}, departmentID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrDepartmentNotFound
+		}
+		return nil, err
+	}
+
+	// Периоды, начатые или законченные в интервале
+	query := db.Where("department_id = ?", departmentID)
+	if filter.Since != nil {
+		query = query.Where("started_at >= ? OR ended_at >= ?", *filter.Since, *filter.Since)
+	}
+	if filter.Until != nil {
+		query = query.Where("started_at < ?", *filter.Until)
+	}
+	var own []EmployeeAssignment
+	if err := query.Find(&own).Error; err != nil {
+		return nil, err
+	}
+	if len(own) == 0 {
+		return []Movement{}, nil
+	}
+
+	employeeIDs := make([]uint, 0, len(own))
+	for _, a := range own {
+		employeeIDs = append(employeeIDs, a.EmployeeID)
+	}
+	employeeIDs = uniqueIDs(employeeIDs)
+
+	var related []EmployeeAssignment
+	if err := db.Where("employee_id IN ?", employeeIDs).Find(&related).Error; err != nil {
+		return nil, err
+	}
+	var employees []Employee
+	if err := db.Select("id", "full_name").Where("id IN ?", employeeIDs).Find(&employees).Error; err != nil {
+		return nil, err
+	}
+	names := make(map[uint]string, len(employees))
+	for _, emp := range employees {
+		names[emp.ID] = emp.FullName
+	}
+
+	return DepartmentMovements(departmentID, own, related, names, filter), nil
+}
+
+// Открывает период с текущими отделом и должностью сотрудника, закрывая прежний;
+// если они не изменились или сотрудник уволен, ничего не делает
+func recordAssignment(tx *gorm.DB, employee *Employee, at time.Time) error {
+	if employee.Status == EmployeeTerminated {
+		return nil
+	}
+
+	open, err := openAssignments(tx, employee.ID)
+	if err != nil {
+		return err
+	}
+	if len(open) == 1 && open[0].DepartmentID == employee.DepartmentId && open[0].Position == employee.Position {
+		return nil
+	}
+	if err := closeAssignments(tx, open, at); err != nil {
+		return err
+	}
+
+	return tx.Create(&EmployeeAssignment{
+		EmployeeID:   employee.ID,
+		DepartmentID: employee.DepartmentId,
+		Position:     employee.Position,
+		From:         at.UTC(),
+		Actor:        ActorFromContext(tx.Statement.Context),
+	}).Error
+}
+
+// Закрывает текущий период сотрудника моментом at
+func closeAssignment(tx *gorm.DB, employeeID uint, at time.Time) error {
+	open, err := openAssignments(tx, employeeID)
+	if err != nil {
+		return err
+	}
+	return closeAssignments(tx, open, at)
+}
+
+func openAssignments(tx *gorm.DB, employeeID uint) ([]EmployeeAssignment, error) {
+	var open []EmployeeAssignment
+	err := tx.Where("employee_id = ? AND ended_at IS NULL", employeeID).Find(&open).Error
+	return open, err
+}
+
+// Период не заканчивается раньше своего начала
+func closeAssignments(tx *gorm.DB, open []EmployeeAssignment, at time.Time) error {
+	for _, a := range open {
+		end := at.UTC()
+		if end.Before(a.From) {
+			end = a.From
+		}
+		err := tx.Model(&EmployeeAssignment{}).
+			Where("id = ?", a.ID).
+			Updates(map[string]interface{}{
+				"ended_at":  end,
+				"closed_by": ActorFromContext(tx.Statement.Context),
+			}).Error
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Перевод сотрудников, выбранных условием, в отдел toDeptID с записью в историю;
+// возвращает число переведенных
+func reassignEmployees(tx *gorm.DB, toDeptID uint, query string, args ...interface{}) (int64, error) {
+	var employees []Employee
+	if err := tx.Where(query, args...).Order("id").Find(&employees).Error; err != nil {
+		return 0, err
+	}
+	if len(employees) == 0 {
+		return 0, nil
+	}
+	ids := make([]uint, len(employees))
+	for i, emp := range employees {
+		ids[i] = emp.ID
+	}
+
+	result := tx.Model(&Employee{}).
+		Where("id IN ?", ids).
+		Updates(map[string]interface{}{
+			"department_id": toDeptID,
+			"version":       gorm.Expr("version + 1"),
+		})
+	if result.Error != nil {
+		return 0, result.Error
+	}
+	if err := dropSecondaryInPrimary(tx, toDeptID, ids...); err != nil {
+		return 0, err
+	}
+
+	at := time.Now().UTC()
+	for i := range employees {
+		employees[i].DepartmentId = toDeptID
+		if err := recordAssignment(tx, &employees[i], at); err != nil {
+			return 0, err
+		}
+	}
+	return result.RowsAffected, nil
+}
@@ -0,0 +1,570 @@
+package models
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Сущности с пользовательскими атрибутами
+const (
+	EntityDepartment = "department"
+	EntityEmployee   = "employee"
+)
+
+// Типы атрибутов
+const (
+	AttrString  = "string"
+	AttrNumber  = "number"
+	AttrBoolean = "boolean"
+	AttrDate    = "date" // YYYY-MM-DD
+)
+
+// Ограничения описаний и значений
+const (
+	maxEnumValues           = 100
+	maxPatternLength        = 500
+	maxDescriptionLength    = 500
+	maxAttributeValueLength = 1000
+)
+
+// Имя атрибута: латиница в нижнем регистре, цифры и _
+var attributeNamePattern = regexp.MustCompile(`^[a-z][a-z0-9_]{0,49}$`)
+
+// Описание пользовательского атрибута
+type AttributeDefinition struct {
+	ID          uint      `json:"id" gorm:"primaryKey"`
+	Entity      string    `json:"entity" gorm:"column:entity;not null;size:20"`
+	Name        string    `json:"name" gorm:"column:name;not null;size:50"`
+	Type        string    `json:"type" gorm:"column:type;not null;size:20"`
+	Required    bool      `json:"required" gorm:"column:required;not null"`
+	EnumValues  []string  `json:"enum_values,omitempty" gorm:"column:enum_values;serializer:json;not null"`
+	Pattern     string    `json:"pattern,omitempty" gorm:"column:pattern;not null"`
+	Description string    `json:"description,omitempty" gorm:"column:description;not null"`
+	CreatedAt   time.Time `json:"created_at" gorm:"column:created_at"`
+	Version     int       `json:"version" gorm:"column:version;not null;default:1"`
+}
+
+// Имя для таблицы
+func (AttributeDefinition) TableName() string {
+	return "attribute_definitions"
+}
+
+// Запрос на создание описания атрибута
+type AttributeDefinitionRequest struct {
+	Entity      string   `json:"entity"`
+	Name        string   `json:"name"`
+	Type        string   `json:"type"`
+	Required    bool     `json:"required"`
+	EnumValues  []string `json:"enum_values"`
+	Pattern     string   `json:"pattern"` // регулярное выражение для всего значения, только для string
+	Description string   `json:"description"`
+}
+
+// Изменение описания: сущность, имя и тип не меняются
+type AttributeDefinitionUpdate struct {
+	Required    *bool     `json:"required"`
+	EnumValues  *[]string `json:"enum_values"`
+	Pattern     *string   `json:"pattern"`
+	Description *string   `json:"description"`
+}
+
+// Ошибка значения конкретного атрибута; Field - "attributes.<имя>" или "attr.<имя>"
+type AttributeError struct {
+	Field string
+	Err   error
+}
+
+func (e *AttributeError) Error() string {
+	return e.Field + ": " + e.Err.Error()
+}
+
+func (e *AttributeError) Unwrap() error {
+	return e.Err
+}
+
+// Проверка сущности; пустая - все сущности
+func ValidAttributeEntity(entity string) bool {
+	switch entity {
+	case "", EntityDepartment, EntityEmployee:
+		return true
+	}
+	return false
+}
+
+// Валидация описания, возвращает все ошибки полей сразу
+func (r *AttributeDefinitionRequest) Validate() error {
+	r.Name = strings.TrimSpace(r.Name)
+	r.Description = strings.TrimSpace(r.Description)
+
+	var errs []error
+	if r.Entity == "" || !ValidAttributeEntity(r.Entity) {
+		errs = append(errs, ErrInvalidAttributeEntity)
+	}
+	if !attributeNamePattern.MatchString(r.Name) {
+		errs = append(errs, ErrInvalidAttributeName)
+	}
+
+	switch r.Type {
+	case AttrString:
+		if err := validateEnumValues(r.EnumValues); err != nil {
+			errs = append(errs, err)
+		}
+		if len(r.Pattern) > maxPatternLength {
+			errs = append(errs, ErrInvalidAttributePattern)
+		} else if _, err := regexp.Compile(r.Pattern); err != nil {
+			errs = append(errs, fmt.Errorf("%w: %v", ErrInvalidAttributePattern, err))
+		}
+	case AttrNumber, AttrBoolean, AttrDate:
+		// Перечень и шаблон имеют смысл только для строк
+		if len(r.EnumValues) > 0 {
+			errs = append(errs, ErrInvalidEnumValues)
+		}
+		if r.Pattern != "" {
+			errs = append(errs, ErrInvalidAttributePattern)
+		}
+	default:
+		errs = append(errs, ErrInvalidAttributeType)
+	}
+
+	if len(r.Description) > maxDescriptionLength {
+		errs = append(errs, ErrAttributeDescriptionTooLong)
+	}
+	return errors.Join(errs...)
+}
+
+// Допустимые значения: непустые, без повторов
+func validateEnumValues(values []string) error {
+	if len(values) > maxEnumValues {
+		return ErrInvalidEnumValues
+	}
+	seen := make(map[string]bool, len(values))
+	for _, v := range values {
+		if strings.TrimSpace(v) == "" || seen[v] {
+			return ErrInvalidEnumValues
+		}
+		seen[v] = true
+	}
+	return nil
+}
+
+// WithUpdate запрос с текущими полями описания и изменениями из update
+func (d *AttributeDefinition) WithUpdate(update *AttributeDefinitionUpdate) *AttributeDefinitionRequest {
+	req := &AttributeDefinitionRequest{
+		Entity:      d.Entity,
+		Name:        d.Name,
+		Type:        d.Type,
+		Required:    d.Required,
+		EnumValues:  d.EnumValues,
+		Pattern:     d.Pattern,
+		Description: d.Description,
+	}
+	if update.Required != nil {
+		req.Required = *update.Required
+	}
+	if update.EnumValues != nil {
+		req.EnumValues = *update.EnumValues
+	}
+	if update.Pattern != nil {
+		req.Pattern = *update.Pattern
+	}
+	if update.Description != nil {
+		req.Description = *update.Description
+	}
+	return req
+}
+
+// Набор описаний атрибутов одной сущности
+type AttributeSchema []AttributeDefinition
+
+func (s AttributeSchema) find(name string) (*AttributeDefinition, bool) {
+	for i := range s {
+		if s[i].Name == name {
+			return &s[i], true
+		}
+	}
+	return nil, false
+}
+
+// Validate проверяет значения и приводит их к типу атрибута. requireAll -
+// проверка обязательных атрибутов целиком (создание); иначе это частичное
+// изменение, где nil удаляет значение, и обязательный атрибут удалить нельзя
+func (s AttributeSchema) Validate(attrs map[string]any, requireAll bool) (map[string]any, error) {
+	normalized, errs := s.validate(attrs, requireAll)
+	return normalized, errors.Join(errs...)
+}
+
+func (s AttributeSchema) validate(attrs map[string]any, requireAll bool) (map[string]any, []error) {
+	names := make([]string, 0, len(attrs))
+	for name := range attrs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var errs []error
+	normalized := make(map[string]any, len(attrs))
+	for _, name := range names {
+		field := "attributes." + name
+		def, ok := s.find(name)
+		if !ok {
+			errs = append(errs, &AttributeError{Field: field, Err: ErrUnknownAttribute})
+			continue
+		}
+		if attrs[name] == nil {
+			if def.Required {
+				errs = append(errs, &AttributeError{Field: field, Err: ErrAttributeRequired})
+			} else if !requireAll {
+				normalized[name] = nil
+			}
+			continue
+		}
+		value, err := def.normalize(attrs[name])
+		if err != nil {
+			errs = append(errs, &AttributeError{Field: field, Err: err})
+			continue
+		}
+		normalized[name] = value
+	}
+
+	if requireAll {
+		for _, def := range s {
+			if _, ok := attrs[def.Name]; def.Required && !ok {
+				errs = append(errs, &AttributeError{Field: "attributes." + def.Name, Err: ErrAttributeRequired})
+			}
+		}
+	}
+	return normalized, errs
+}
+
+// Значение, приведенное к типу атрибута
+func (d *AttributeDefinition) normalize(value any) (any, error) {
+	switch d.Type {
+	case AttrString:
+		s, ok := value.(string)
+		if !ok {
+			return nil, fmt.Errorf("%w: expected string", ErrAttributeType)
+		}
+		s = strings.TrimSpace(s)
+		if len(s) > maxAttributeValueLength {
+			return nil, ErrAttributeValueTooLong
+		}
+		if len(d.EnumValues) > 0 && !slices.Contains(d.EnumValues, s) {
+			return nil, fmt.Errorf("%w: use one of %s", ErrAttributeNotAllowed, strings.Join(d.EnumValues, ", "))
+		}
+		if d.Pattern != "" {
+			re, err := regexp.Compile(`^(?:` + d.Pattern + `)$`)
+			if err != nil || !re.MatchString(s) {
+				return nil, fmt.Errorf("%w: %s", ErrAttributePattern, d.Pattern)
+			}
+		}
+		return s, nil
+
+	case AttrNumber:
+		var f float64
+		switch v := value.(type) {
+		case float64:
+			f = v
+		case int:
+			f = float64(v)
+		case json.Number:
+			var err error
+			if f, err = v.Float64(); err != nil {
+				return nil, fmt.Errorf("%w: expected number", ErrAttributeType)
+			}
+		default:
+			return nil, fmt.Errorf("%w: expected number", ErrAttributeType)
+		}
+		if math.IsNaN(f) || math.IsInf(f, 0) {
+			return nil, fmt.Errorf("%w: expected number", ErrAttributeType)
+		}
+		return f, nil
+
+	case AttrBoolean:
+		b, ok := value.(bool)
+		if !ok {
+			return nil, fmt.Errorf("%w: expected boolean", ErrAttributeType)
+		}
+		return b, nil
+
+	case AttrDate:
+		s, ok := value.(string)
+		if !ok {
+			return nil, fmt.Errorf("%w: expected date YYYY-MM-DD", ErrAttributeType)
+		}
+		date, err := time.Parse(time.DateOnly, s)
+		if err != nil {
+			return nil, fmt.Errorf("%w: expected date YYYY-MM-DD", ErrAttributeType)
+		}
+		return date.Format(time.DateOnly), nil
+	}
+	return nil, ErrInvalidAttributeType
+}
+
+// MergeAttributes новые значения поверх старых; nil удаляет атрибут
+func MergeAttributes(base, patch map[string]any) map[string]any {
+	merged := make(map[string]any, len(base)+len(patch))
+	for name, value := range base {
+		merged[name] = value
+	}
+	for name, value := range patch {
+		if value == nil {
+			delete(merged, name)
+		} else {
+			merged[name] = value
+		}
+	}
+	return merged
+}
+
+// Фильтр списка по значениям атрибутов, все условия через AND
+type AttributeFilter map[string]any
+
+// ParseFilter разбирает значения из query (attr.<имя>=<значение>) по типам атрибутов
+func (s AttributeSchema) ParseFilter(raw map[string]string) (AttributeFilter, error) {
+	var errs []error
+	filter := make(AttributeFilter, len(raw))
+	for name, value := range raw {
+		field := "attr." + name
+		def, ok := s.find(name)
+		if !ok {
+			errs = append(errs, &AttributeError{Field: field, Err: ErrUnknownAttribute})
+			continue
+		}
+
+		var parsed any
+		var err error
+		switch def.Type {
+		case AttrNumber:
+			parsed, err = strconv.ParseFloat(value, 64)
+		case AttrBoolean:
+			parsed, err = strconv.ParseBool(value)
+		case AttrDate:
+			var date time.Time
+			date, err = time.Parse(time.DateOnly, value)
+			parsed = date.Format(time.DateOnly)
+		default:
+			parsed = value
+		}
+		if err != nil {
+			errs = append(errs, &AttributeError{Field: field, Err: fmt.Errorf("%w: expected %s", ErrAttributeType, def.Type)})
+			continue
+		}
+		filter[name] = parsed
+	}
+	return filter, errors.Join(errs...)
+}
+
+// Match подходят ли значения под фильтр
+func (f AttributeFilter) Match(attrs map[string]any) bool {
+	for name, want := range f {
+		got, ok := attrs[name]
+		if !ok {
+			return false
+		}
+		if n, ok := want.(float64); ok {
+			if v, ok := got.(float64); !ok || v != n {
+				return false
+			}
+			continue
+		}
+		if got != want {
+			return false
+		}
+	}
+	return true
+}
+
+// Условия фильтра в запросе: на Postgres - содержит ли jsonb пару (индекс GIN),
+// на SQLite - json_extract
+func whereAttributes(db *gorm.DB, filter AttributeFilter) *gorm.DB {
+	for name, value := range filter {
+		if db.Dialector.Name() == "postgres" {
+			db = db.Where("attributes @> ?::jsonb", jsonColumn(map[string]any{name: value}))
+		} else {
+			db = db.Where("json_extract(attributes, ?) = ?", "$."+name, value)
+		}
+	}
+	return db
+}
+
+// Значение json-колонки для Updates с map, где сериализатор не вызывается
+func jsonColumn(v any) string {
+	data, err := json.Marshal(v)
+	if err != nil || string(data) == "null" {
+		return "{}"
+	}
+	return string(data)
+}
+
+// Описания атрибутов сущности
+func loadAttributeSchema(db *gorm.DB, entity string) (AttributeSchema, error) {
+	var schema AttributeSchema
+	err := db.Where("entity = ?", entity).Order("name").Find(&schema).Error
+	return schema, err
+}
+
+// Фильтр из query по описаниям атрибутов сущности
+func loadAttributeFilter(db *gorm.DB, entity string, raw map[string]string) (AttributeFilter, error) {
+	if len(raw) == 0 {
+		return nil, nil
+	}
+	schema, err := loadAttributeSchema(db, entity)
+	if err != nil {
+		return nil, err
+	}
+	return schema.ParseFilter(raw)
+}
+
+// CreateAttributeDefinition добавляет описание атрибута
+func CreateAttributeDefinition(ctx context.Context, db *gorm.DB, req *AttributeDefinitionRequest) (*AttributeDefinition, error) {
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+	db = db.WithContext(ctx)
+
+	var count int64
+	if err := db.Model(&AttributeDefinition{}).Where("entity = ? AND name = ?", req.Entity, req.Name).Count(&count).Error; err != nil {
+		return nil, err
+	}
+	if count > 0 {
+		return nil, ErrAttributeExists
+	}
+
+	def := &AttributeDefinition{
+		Entity:      req.Entity,
+		Name:        req.Name,
+		Type:        req.Type,
+		Required:    req.Required,
+		EnumValues:  req.EnumValues,
+		Pattern:     req.Pattern,
+		Description: req.Description,
+		CreatedAt:   time.Now().UTC(),
+		Version:     1,
+	}
+	if def.EnumValues == nil {
+		def.EnumValues = []string{}
+	}
+	if err := db.Create(def).Error; err != nil {
+		if isUniqueViolation(err) {
+			return nil, ErrAttributeExists
+		}
+		return nil, err
+	}
+	return def, nil
+}
+
+// ListAttributeDefinitions описания по сущности; пустая - все
+func ListAttributeDefinitions(ctx context.Context, db *gorm.DB, entity string) ([]AttributeDefinition, error) {
+	if !ValidAttributeEntity(entity) {
+		return nil, ErrInvalidAttributeEntity
+	}
+
+	query := db.WithContext(ctx).Order("entity, name")
+	if entity != "" {
+		query = query.Where("entity = ?", entity)
+	}
+
+	var defs []AttributeDefinition
+	err := query.Find(&defs).Error
+	return defs, err
+}
+
+// GetAttributeDefinition описание по id
+func GetAttributeDefinition(ctx context.Context, db *gorm.DB, id uint) (*AttributeDefinition, error) {
+	var def AttributeDefinition
+	if err := db.WithContext(ctx).First(&def, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrAttributeDefinitionNotFound
+		}
+		return nil, err
+	}
+	return &def, nil
+}
+
+// UpdateAttributeDefinition меняет обязательность, перечень, шаблон и описание.
+// Сохраненные значения не перепроверяются
+func UpdateAttributeDefinition(ctx context.Context, db *gorm.DB, id uint, version int, update *AttributeDefinitionUpdate) (*AttributeDefinition, error) {
+	def, err := GetAttributeDefinition(ctx, db, id)
+	if err != nil {
+		return nil, err
+	}
+	if version != 0 && def.Version != version {
+		return nil, ErrVersionMismatch
+	}
+
+	req := def.WithUpdate(update)
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+	if req.EnumValues == nil {
+		req.EnumValues = []string{}
+	}
+
+	result := db.WithContext(ctx).Model(&AttributeDefinition{}).
+		Where("id = ? AND version = ?", id, def.Version).
+		Updates(map[string]interface{}{
+			"required":    req.Required,
+			"enum_values": jsonColumn(req.EnumValues),
+			"pattern":     req.Pattern,
+			"description": req.Description,
+			"version":     gorm.Expr("version + 1"),
+		})
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, ErrVersionMismatch
+	}
+
+	def.Required = req.Required
+	def.EnumValues = req.EnumValues
+	def.Pattern = req.Pattern
+	def.Description = req.Description
+	def.Version++
+	return def, nil
+}
+
+// DeleteAttributeDefinition удаляет описание вместе со значениями атрибута
+func DeleteAttributeDefinition(ctx context.Context, db *gorm.DB, id uint, version int) error {
+	return db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var def AttributeDefinition
+		if err := tx.First(&def, id).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrAttributeDefinitionNotFound
+			}
+			return err
+		}
+
+		if version != 0 && def.Version != version {
+			return ErrVersionMismatch
+		}
+
+		result := tx.Where("version = ?", def.Version).Delete(&AttributeDefinition{ID: def.ID})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrVersionMismatch
+		}
+
+		// Значения удаленного атрибута больше не прошли бы валидацию
+		table := Department{}.TableName()
+		if def.Entity == EntityEmployee {
+			table = Employee{}.TableName()
+		}
+		if tx.Dialector.Name() == "postgres" {
+			return tx.Exec("UPDATE "+table+" SET attributes = attributes - ?::text", def.Name).Error
+		}
+		return tx.Exec("UPDATE "+table+" SET attributes = json_remove(attributes, ?)", "$."+def.Name).Error
+	})
+}
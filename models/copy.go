@@ -0,0 +1,186 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Запрос на копирование поддерева
+type CopyRequest struct {
+	ParentID         *uint  `json:"parent_id"`
+	Name             string `json:"name"`
+	IncludePositions bool   `json:"include_positions"`
+}
+
+// Скопированное подразделение
+type CopiedDepartment struct {
+	SourceID  uint      `json:"source_id"`
+	ID        uint      `json:"id"`
+	ParentID  *uint     `json:"parent_id"`
+	Name      string    `json:"name"`
+	Vacancies []Vacancy `json:"vacancies,omitempty"`
+}
+
+// Итог копирования; первым идет корень копии
+type CopyResult struct {
+	Root        *Department         `json:"root"`
+	Departments []CopiedDepartment  `json:"departments"`
+	Renamed     []RenamedDepartment `json:"renamed"`
+}
+
+// CopyDepartment копирует структуру поддерева id под req.ParentID (nil - в корень).
+// Копия, чье имя занято по политике уникальности, получает имя "Имя (N)", как при
+// слиянии; явно заданное req.Name не меняется. Сотрудники не копируются; с
+// IncludePositions места исходного подразделения (работающие сотрудники и открытые
+// вакансии) становятся открытыми вакансиями копии
+func CopyDepartment(ctx context.Context, db *gorm.DB, policy UniquenessPolicy, id uint, req *CopyRequest) (*CopyResult, error) {
+	var result *CopyResult
+	err := withTreeLock(ctx, db, func(tx *gorm.DB) error {
+		// Снимок поддерева до создания копий: копия может оказаться внутри него
+		subtree, err := loadSubtree(tx, id)
+		if err != nil {
+			return err
+		}
+
+		seats := map[uint][]VacancyRequest{}
+		if req.IncludePositions {
+			if seats, err = seatsByDepartment(tx, subtree); err != nil {
+				return err
+			}
+		}
+
+		result = &CopyResult{Renamed: []RenamedDepartment{}}
+		copies := make(map[uint]uint, len(subtree))
+		for i, dept := range subtree {
+			create := &DepartmentRequest{Name: dept.Name, Attributes: dept.Attributes, Type: &dept.Type}
+			if i == 0 {
+				create.ParentID = req.ParentID
+			} else {
+				parentID := copies[*dept.ParentId]
+				create.ParentID = &parentID
+			}
+
+			// Явное имя корня не меняется, остальные при совпадении получают суффикс
+			suffixed := false
+			if i == 0 && req.Name != "" {
+				create.Name = req.Name
+			} else {
+				if create.Name, err = freeCopyName(tx, policy, dept.Name, create.ParentID); err != nil {
+					return err
+				}
+				suffixed = create.Name != dept.Name
+			}
+
+			department, err := createDepartment(tx, policy, create)
+			if err != nil {
+				return err
+			}
+			copies[dept.Id] = department.Id
+			if suffixed {
+				result.Renamed = append(result.Renamed, RenamedDepartment{ID: department.Id, From: dept.Name, To: department.Name})
+			}
+
+			copied := CopiedDepartment{
+				SourceID: dept.Id,
+				ID:       department.Id,
+				ParentID: department.ParentId,
+				Name:     department.Name,
+			}
+			for _, seat := range seats[dept.Id] {
+				vacancy := &Vacancy{
+					DepartmentID: department.Id,
+					Position:     seat.Position,
+					PositionID:   seat.PositionID,
+					Status:       VacancyOpen,
+					TargetDate:   seat.TargetDate,
+					CreatedAt:    time.Now(),
+					Version:      1,
+				}
+				if err := tx.Create(vacancy).Error; err != nil {
+					return err
+				}
+				copied.Vacancies = append(copied.Vacancies, *vacancy)
+			}
+
+			if i == 0 {
+				result.Root = department
+			}
+			result.Departments = append(result.Departments, copied)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// Имя копии: исходное, если свободно под parentID, иначе "Имя (N)"
+func freeCopyName(tx *gorm.DB, policy UniquenessPolicy, name string, parentID *uint) (string, error) {
+	key, scope, err := nameScope(tx, policy, name, parentID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return "", ErrParentNotFound
+	}
+	if err != nil {
+		return "", err
+	}
+	taken, err := nameTaken(tx, key, scope)
+	if err != nil || !taken {
+		return name, err
+	}
+	return freeSuffixName(tx, policy, name, parentID)
+}
+
+// loadSubtree подразделение и все потомки; родитель всегда раньше детей
+func loadSubtree(db *gorm.DB, id uint) ([]Department, error) {
+	var root Department
+	if err := db.First(&root, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrDepartmentNotFound
+		}
+		return nil, err
+	}
+
+	subtree := []Department{root}
+	for i := 0; i < len(subtree); i++ {
+		var children []Department
+		if err := db.Where("parent_id = ?", subtree[i].Id).Order("sort_order, id").Find(&children).Error; err != nil {
+			return nil, err
+		}
+		subtree = append(subtree, children...)
+	}
+	return subtree, nil
+}
+
+// Места подразделений для копии: по одному на работающего сотрудника и на открытую
+// вакансию, в порядке id
+func seatsByDepartment(db *gorm.DB, departments []Department) (map[uint][]VacancyRequest, error) {
+	ids := make([]uint, len(departments))
+	for i, dept := range departments {
+		ids[i] = dept.Id
+	}
+
+	var employees []Employee
+	if err := db.Select("id", "department_id", "position", "position_id").
+		Where("department_id IN ? AND status <> ?", ids, EmployeeTerminated).
+		Order("id").Find(&employees).Error; err != nil {
+		return nil, err
+	}
+	var vacancies []Vacancy
+	if err := db.Where("department_id IN ? AND status = ?", ids, VacancyOpen).Order("id").Find(&vacancies).Error; err != nil {
+		return nil, err
+	}
+
+	seats := make(map[uint][]VacancyRequest)
+	for _, emp := range employees {
+		seats[emp.DepartmentId] = append(seats[emp.DepartmentId], VacancyRequest{Position: emp.Position, PositionID: emp.PositionID})
+	}
+	for _, vacancy := range vacancies {
+		seats[vacancy.DepartmentID] = append(seats[vacancy.DepartmentID],
+			VacancyRequest{Position: vacancy.Position, PositionID: vacancy.PositionID, TargetDate: vacancy.TargetDate})
+	}
+	return seats, nil
+}
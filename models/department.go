@@ -1,6 +1,7 @@
 package models
 
 import (
+	"context"
 	"errors"
 	"strings"
 	"time"
@@ -10,24 +11,43 @@ import (
 
 // Подразделение
 type Department struct {
-	Id        uint         `json:"id" gorm:"primaryKey;autoIncrement"`
-	Name      string       `json:"name" gorm:"column:name;not null;size:200"`
-	ParentId  *uint        `json:"parent_id" gorm:"column:parent_id"`
-	Parent    *Department  `json:"parent,omitempty" gorm:"foreignKey:ParentId"`
-	Children  []Department `json:"children,omitempty" gorm:"foreignKey:ParentId"`
-	CreatedAt time.Time    `json:"created_at" gorm:"column:created_at;default:CURRENT_TIMESTAMP"`
+	Id          uint              `json:"id" gorm:"primaryKey;autoIncrement"`
+	Name        string            `json:"name" gorm:"column:name;not null;size:200"`
+	ParentId    *uint             `json:"parent_id" gorm:"column:parent_id"`
+	Parent      *Department       `json:"parent,omitempty" gorm:"foreignKey:ParentId"`
+	Children    []Department      `json:"children,omitempty" gorm:"foreignKey:ParentId"`
+	CreatedAt   time.Time         `json:"created_at" gorm:"column:created_at;default:CURRENT_TIMESTAMP"`
+	Version     int               `json:"version" gorm:"column:version;not null;default:1"`
+	SortOrder   int               `json:"sort_order" gorm:"column:sort_order;not null;default:0"`
+	Code        *string           `json:"code,omitempty" gorm:"column:code"`
+	ExternalIDs map[string]string `json:"external_ids,omitempty" gorm:"column:external_ids;serializer:json"`
+	Attributes  map[string]any    `json:"attributes,omitempty" gorm:"column:attributes;serializer:json"`
+	Type        string            `json:"type,omitempty" gorm:"column:type;not null;default:''"`
+	NameKey     string            `json:"-" gorm:"column:name_key;not null"`
+	NameScope   string            `json:"-" gorm:"column:name_scope;not null"`
 }
 
 // Структура для создания/обновления отдела
 type DepartmentRequest struct {
-	Name     string `json:"name"`
-	ParentID *uint  `json:"parent_id"`
+	Name        string            `json:"name"`
+	ParentID    *uint             `json:"parent_id"`
+	Position    *int              `json:"position,omitempty"`     // место среди соседей, с нуля
+	Code        *string           `json:"code,omitempty"`         // nil - без изменений, "" - снять код
+	ExternalIDs map[string]string `json:"external_ids,omitempty"` // система -> id; nil - без изменений, иначе заменяет весь набор
+	Attributes  map[string]any    `json:"attributes,omitempty"`   // при изменении дополняет текущие, null удаляет
+	Type        *string           `json:"type,omitempty"`         // тип из правил иерархии; nil - без изменений, "" - снять тип
+}
+
+// Фильтр списка подразделений
+type DepartmentFilter struct {
+	Attributes map[string]string // attr.<имя>=<значение> из query
 }
 
 // Структура для ответа API
 type DepartmentResponse struct {
 	Department
 	Employees []Employee           `json:"employees,omitempty"`
+	Secondary []SecondaryMember    `json:"secondary_employees,omitempty"`
 	Children  []DepartmentResponse `json:"children,omitempty"`
 }
 
@@ -36,8 +56,21 @@ func (Department) TableName() string {
 	return "departments"
 }
 
-// Валидация
-func (d *DepartmentRequest) Validate() error {
+// HierarchyNode подразделение для проверки правил иерархии
+func (d *Department) HierarchyNode() HierarchyNode {
+	return HierarchyNode{ID: d.Id, ParentID: d.ParentId, Name: d.Name, Type: d.Type}
+}
+
+// Тип из запроса; nil - без типа
+func (d *DepartmentRequest) TypeName() string {
+	if d.Type == nil {
+		return ""
+	}
+	return strings.TrimSpace(*d.Type)
+}
+
+// Валидация, атрибуты проверяются по schema
+func (d *DepartmentRequest) Validate(schema AttributeSchema) error {
 	// Пробелы
 	d.Name = strings.TrimSpace(d.Name)
 
@@ -46,16 +79,62 @@ func (d *DepartmentRequest) Validate() error {
 		return ErrNameEmpty
 	}
 	if len(d.Name) > 200 {
-		return ErrFullNameTooLong
+		return ErrNameTooLong
+	}
+	if d.Position != nil && *d.Position < 0 {
+		return ErrInvalidPosition
+	}
+	if err := d.ValidateIdentifiers(); err != nil {
+		return err
 	}
 
+	// Пользовательские атрибуты
+	attrs, err := schema.Validate(d.Attributes, true)
+	if err != nil {
+		return err
+	}
+	d.Attributes = attrs
+	return nil
+}
+
+// ValidateIdentifiers проверка кода и внешних id, "" в коде остается как снятие
+func (d *DepartmentRequest) ValidateIdentifiers() error {
+	if d.Code != nil {
+		code, err := NormalizeCode(d.Code)
+		if err != nil {
+			return err
+		}
+		if code == nil {
+			code = new(string)
+		}
+		d.Code = code
+	}
+	ids, err := NormalizeExternalIDs(d.ExternalIDs)
+	if err != nil {
+		return err
+	}
+	d.ExternalIDs = ids
 	return nil
 }
 
 // Создает новый подразделения
-func CreateDepartment(db *gorm.DB, req *DepartmentRequest) (*Department, error) {
+func CreateDepartment(ctx context.Context, db *gorm.DB, policy UniquenessPolicy, req *DepartmentRequest) (*Department, error) {
+	var department *Department
+	err := withTreeLock(ctx, db, func(tx *gorm.DB) error {
+		var err error
+		department, err = createDepartment(tx, policy, req)
+		return err
+	})
+	return department, err
+}
+
+func createDepartment(db *gorm.DB, policy UniquenessPolicy, req *DepartmentRequest) (*Department, error) {
 	// Валидация
-	if err := req.Validate(); err != nil {
+	schema, err := loadAttributeSchema(db, EntityDepartment)
+	if err != nil {
+		return nil, err
+	}
+	if err := req.Validate(schema); err != nil {
 		return nil, err
 	}
 
@@ -70,36 +149,106 @@ func CreateDepartment(db *gorm.DB, req *DepartmentRequest) (*Department, error)
 		}
 	}
 
-	// Проверка уникальность имени в ветке
-	unique, err := CheckNameUniqueInBranch(db, req.Name, req.ParentID)
+	// Правила иерархии
+	typ := req.TypeName()
+	if err := checkHierarchy(db, HierarchyNode{ParentID: req.ParentID, Name: req.Name, Type: typ}); err != nil {
+		return nil, err
+	}
+
+	// Проверка уникальности имени по политике
+	key, scope, err := nameScope(db, policy, req.Name, req.ParentID)
+	if err != nil {
+		return nil, err
+	}
+	taken, err := nameTaken(db, key, scope)
 	if err != nil {
 		return nil, err
 	}
-	if !unique {
+	if taken {
 		return nil, ErrNameExists
 	}
 
+	// Проверка кода и внешних id
+	code := emptyToNil(req.Code)
+	if code != nil {
+		if err := codeTaken(db, *code, 0); err != nil {
+			return nil, err
+		}
+	}
+	if err := externalIDsTaken(db, req.ExternalIDs, 0); err != nil {
+		return nil, err
+	}
+	externalIDs := req.ExternalIDs
+	if externalIDs == nil {
+		externalIDs = map[string]string{}
+	}
+
+	// Новое подразделение встает в конец списка соседей
+	sortOrder, err := nextSortOrder(db, req.ParentID)
+	if err != nil {
+		return nil, err
+	}
+
 	// Создание подразделения
 	department := &Department{
 		Name:      req.Name,
 		ParentId:  req.ParentID,
 		CreatedAt: time.Now(),
+		Version:   1,
+		SortOrder: sortOrder,
+		Code:      code,
+		Type:      typ,
+		NameKey:   key,
+		NameScope: scope,
+
+		ExternalIDs: externalIDs,
+		Attributes:  MergeAttributes(nil, req.Attributes),
 	}
 
 	if err := db.Create(department).Error; err != nil {
-		// Проверка Имени
-		if strings.Contains(err.Error(), "duplicate key") ||
-			strings.Contains(err.Error(), "unique constraint") {
-			return nil, ErrNameExists
-		}
+		// Проверка имени и кода
+		return nil, departmentUniqueError(err)
+	}
+	if err := saveExternalIDs(db, department.Id, externalIDs); err != nil {
 		return nil, err
 	}
 
+	// Вставка на заданное место
+	if req.Position != nil {
+		if err := placeAt(db, department, *req.Position); err != nil {
+			return nil, err
+		}
+	}
+
 	return department, nil
 }
 
-// Обновляет существующее подразделения
-func UpdateDepartment(db *gorm.DB, id uint, req *DepartmentRequest) (*Department, error) {
+// ListDepartments подразделения без дерева, отфильтрованные по атрибутам
+func ListDepartments(ctx context.Context, db *gorm.DB, filter *DepartmentFilter) ([]Department, error) {
+	db = db.WithContext(ctx)
+
+	attrs, err := loadAttributeFilter(db, EntityDepartment, filter.Attributes)
+	if err != nil {
+		return nil, err
+	}
+
+	var departments []Department
+	err = whereAttributes(db, attrs).Order("parent_id NULLS FIRST, sort_order, id").Find(&departments).Error
+	return departments, err
+}
+
+// Обновляет существующее подразделения; version 0 - без проверки версии
+func UpdateDepartment(ctx context.Context, db *gorm.DB, policy UniquenessPolicy, id uint, version int, req *DepartmentRequest) (*Department, error) {
+	var department *Department
+	err := withTreeLock(ctx, db, func(tx *gorm.DB) error {
+		var err error
+		department, err = updateDepartment(tx, policy, id, version, req)
+		return err
+	})
+	return department, err
+}
+
+func updateDepartment(db *gorm.DB, policy UniquenessPolicy, id uint, version int, req *DepartmentRequest) (*Department, error) {
 	// Проверка существования
 	var department Department
 	if err := db.First(&department, id).Error; err != nil {
@@ -109,6 +258,11 @@ func UpdateDepartment(db *gorm.DB, id uint, req *DepartmentRequest) (*Department
 		return nil, err
 	}
 
+	// Проверка версии
+	if version != 0 && department.Version != version {
+		return nil, ErrVersionMismatch
+	}
+
 	// Валидация имени
 	if req.Name != "" {
 		req.Name = strings.TrimSpace(req.Name)
@@ -117,6 +271,41 @@ func UpdateDepartment(db *gorm.DB, id uint, req *DepartmentRequest) (*Department
 		}
 		department.Name = req.Name
 	}
+	if req.Position != nil && *req.Position < 0 {
+		return nil, ErrInvalidPosition
+	}
+
+	// Код и внешние id
+	if err := req.ValidateIdentifiers(); err != nil {
+		return nil, err
+	}
+	if req.Code != nil {
+		department.Code = emptyToNil(req.Code)
+		if department.Code != nil {
+			if err := codeTaken(db, *department.Code, id); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if req.ExternalIDs != nil {
+		if err := externalIDsTaken(db, req.ExternalIDs, id); err != nil {
+			return nil, err
+		}
+		department.ExternalIDs = req.ExternalIDs
+	}
+
+	// Атрибуты дополняют текущие
+	if req.Attributes != nil {
+		schema, err := loadAttributeSchema(db, EntityDepartment)
+		if err != nil {
+			return nil, err
+		}
+		attrs, err := schema.Validate(req.Attributes, false)
+		if err != nil {
+			return nil, err
+		}
+		department.Attributes = MergeAttributes(department.Attributes, attrs)
+	}
 
 	// Обновление parent_id
 	if req.ParentID != nil {
@@ -134,22 +323,76 @@ func UpdateDepartment(db *gorm.DB, id uint, req *DepartmentRequest) (*Department
 			return nil, err
 		}
 
+		// У нового родителя - в конец списка, если позиция не задана
+		if department.ParentId == nil || *department.ParentId != *req.ParentID {
+			sortOrder, err := nextSortOrder(db, req.ParentID)
+			if err != nil {
+				return nil, err
+			}
+			department.SortOrder = sortOrder
+		}
 		department.ParentId = req.ParentID
 	}
 
-	// Сохранение
-	if err := db.Save(&department).Error; err != nil {
-		if strings.Contains(err.Error(), "duplicate key") {
-			return nil, ErrNameExists
+	// Правила иерархии для нового имени, типа и места
+	if req.Type != nil {
+		department.Type = req.TypeName()
+	}
+	if req.Name != "" || req.ParentID != nil || req.Type != nil {
+		if err := checkHierarchy(db, department.HierarchyNode()); err != nil {
+			return nil, err
+		}
+	}
+
+	// Сохранение, только если версию никто не изменил
+	result := db.Model(&Department{}).
+		Where("id = ? AND version = ?", id, department.Version).
+		Updates(map[string]interface{}{
+			"name":         department.Name,
+			"parent_id":    department.ParentId,
+			"sort_order":   department.SortOrder,
+			"code":         department.Code,
+			"external_ids": jsonColumn(department.ExternalIDs),
+			"attributes":   jsonColumn(department.Attributes),
+			"type":         department.Type,
+			"version":      gorm.Expr("version + 1"),
+		})
+	if result.Error != nil {
+		return nil, departmentUniqueError(result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return nil, ErrVersionMismatch
+	}
+	department.Version++
+	if req.ExternalIDs != nil {
+		if err := saveExternalIDs(db, id, req.ExternalIDs); err != nil {
+			return nil, err
 		}
+	}
+
+	// Уникальность имени проверяет индекс (name_scope, name_key)
+	if err := syncNameScope(db, policy, &department); err != nil {
 		return nil, err
 	}
 
+	// Перестановка среди соседей
+	if req.Position != nil {
+		if err := placeAt(db, &department, *req.Position); err != nil {
+			return nil, err
+		}
+	}
+
 	return &department, nil
 }
 
-// Удалить подразделение с переводом сотрудников
-func DeleteDepartment(db *gorm.DB, id uint, mode string, reassignToID *uint) error {
+// Удалить подразделение с переводом сотрудников; version 0 - без проверки версии
+func DeleteDepartment(ctx context.Context, db *gorm.DB, id uint, version int, mode string, reassignToID *uint) error {
+	return withTreeLock(ctx, db, func(tx *gorm.DB) error {
+		return deleteDepartment(ctx, tx, id, version, mode, reassignToID)
+	})
+}
+
+func deleteDepartment(ctx context.Context, db *gorm.DB, id uint, version int, mode string, reassignToID *uint) error {
 	// Проверка существования
 	var department Department
 	if err := db.First(&department, id).Error; err != nil {
@@ -159,16 +402,21 @@ func DeleteDepartment(db *gorm.DB, id uint, mode string, reassignToID *uint) err
 		return err
 	}
 
+	// Проверка версии
+	if version != 0 && department.Version != version {
+		return ErrVersionMismatch
+	}
+
 	// 2. Обрабатываем режимы
 	switch mode {
 	case "cascade":
 		// Каскадное удаление
-		return db.Delete(&department).Error
+		return deleteVersioned(db, &department)
 
 	case "reassign":
 		// С переводом
 		if reassignToID == nil {
-			return errors.New("reassign_to_department_id is required for reassign mode")
+			return ErrReassignRequired
 		}
 
 		if *reassignToID == id {
@@ -184,40 +432,52 @@ func DeleteDepartment(db *gorm.DB, id uint, mode string, reassignToID *uint) err
 			return err
 		}
 
-		return db.Transaction(func(tx *gorm.DB) error {
-			// Переводим сотрудников
-			if err := tx.Model(&Employee{}).
-				Where("department_id = ?", id).
-				Update("department_id", *reassignToID).Error; err != nil {
-				return err
-			}
+		// Переводим сотрудников
+		if err := MoveEmployees(ctx, db, id, *reassignToID); err != nil {
+			return err
+		}
 
-			// Получаем все дочерние
-			var children []Department
-			if err := tx.Where("parent_id = ?", id).Find(&children).Error; err != nil {
-				return err
-			}
+		// Совмещения, планы численности и открытые вакансии
+		if err := moveSecondary(db, id, *reassignToID); err != nil {
+			return err
+		}
+		if err := moveStaffing(db, id, *reassignToID); err != nil {
+			return err
+		}
 
-			// Удаление дочерних с сотрудниками
-			for _, child := range children {
-				if err := tx.Delete(&child).Error; err != nil {
-					return err
-				}
-			}
+		// Получаем все дочерние
+		var children []Department
+		if err := db.Where("parent_id = ?", id).Find(&children).Error; err != nil {
+			return err
+		}
 
-			// Удаляем подразделение
-			if err := tx.Delete(&department).Error; err != nil {
+		// Удаление дочерних с сотрудниками
+		for _, child := range children {
+			if err := db.Delete(&child).Error; err != nil {
 				return err
 			}
+		}
 
-			return nil
-		})
+		// Удаляем подразделение
+		return deleteVersioned(db, &department)
 
 	default:
 		return ErrInvalidMode
 	}
 }
 
+// Удаление с проверкой, что версия не изменилась
+func deleteVersioned(db *gorm.DB, department *Department) error {
+	result := db.Where("version = ?", department.Version).Delete(department)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrVersionMismatch
+	}
+	return nil
+}
+
 // Проверяет нового parent_id
 func checkCycle(db *gorm.DB, deptID, newParentID uint) error {
 	if deptID == newParentID {
@@ -257,7 +517,10 @@ func getChildIDs(db *gorm.DB, parentID uint, ids *[]uint) error {
 }
 
 // Get - Получить подразделение
-func (d *Department) GetWithTree(db *gorm.DB, id uint, depth int, includeEmployees bool) (*DepartmentResponse, error) {
+// employees - фильтр сотрудников, nil - без сотрудников
+func (d *Department) GetWithTree(ctx context.Context, db *gorm.DB, id uint, depth int, employees *EmployeeFilter) (*DepartmentResponse, error) {
+	db = db.WithContext(ctx)
+
 	// Получаем сам отдел
 	if err := db.First(d, id).Error; err != nil {
 		return nil, err
@@ -268,25 +531,30 @@ func (d *Department) GetWithTree(db *gorm.DB, id uint, depth int, includeEmploye
 	}
 
 	// Загружаем сотрудников
-	if includeEmployees {
-		var employees []Employee
-		if err := db.Where("department_id = ?", id).
+	if employees != nil {
+		if err := employees.whereStatus(db.Where("department_id = ?", id)).
 			Order("created_at DESC, full_name ASC").
-			Find(&employees).Error; err != nil {
+			Find(&response.Employees).Error; err != nil {
 			return nil, err
 		}
-		response.Employees = employees
+		if employees.Secondary {
+			members, err := secondaryMembers(db, id, employees)
+			if err != nil {
+				return nil, err
+			}
+			response.Secondary = members
+		}
 	}
 
 	// Загружаем потомков
 	if depth > 0 {
 		var children []Department
-		if err := db.Where("parent_id = ?", id).Find(&children).Error; err != nil {
+		if err := db.Where("parent_id = ?", id).Order("sort_order, id").Find(&children).Error; err != nil {
 			return nil, err
 		}
 
 		for _, child := range children {
-			childResponse, err := child.GetWithTree(db, child.Id, depth-1, includeEmployees)
+			childResponse, err := child.GetWithTree(ctx, db, child.Id, depth-1, employees)
 			if err != nil {
 				return nil, err
 			}
@@ -298,7 +566,9 @@ func (d *Department) GetWithTree(db *gorm.DB, id uint, depth int, includeEmploye
 }
 
 // Валидация родства
-func (d *Department) ValidateParent(db *gorm.DB) error {
+func (d *Department) ValidateParent(ctx context.Context, db *gorm.DB) error {
+	db = db.WithContext(ctx)
+
 	if d.ParentId == nil {
 		return nil
 	}
@@ -338,47 +608,3 @@ func (d *Department) getChildIDs(db *gorm.DB, parentID uint, ids *[]uint) error
 	}
 	return nil
 }
-
-// Проверяет уникальность имени в ветке
-func CheckNameUniqueInBranch(db *gorm.DB, name string, parentID *uint) (bool, error) {
-	var count int64
-
-	if parentID == nil {
-		// Проверяем все корневые
-		err := db.Model(&Department{}).
-			Where("name = ? AND parent_id IS NULL", name).
-			Count(&count).Error
-		return count == 0, err
-	}
-
-	// Получаем всех потомков
-	var allIDs []uint
-	err := getBranchIDs(db, *parentID, &allIDs)
-	if err != nil {
-		return false, err
-	}
-	allIDs = append(allIDs, *parentID)
-
-	// Проверяем уникальность
-	err = db.Model(&Department{}).
-		Where("name = ? AND id IN ?", name, allIDs).
-		Count(&count).Error
-
-	return count == 0, err
-}
-
-// Собирает ID всех потомков
-func getBranchIDs(db *gorm.DB, parentID uint, ids *[]uint) error {
-	var children []Department
-	if err := db.Where("parent_id = ?", parentID).Find(&children).Error; err != nil {
-		return err
-	}
-
-	for _, child := range children {
-		*ids = append(*ids, child.Id)
-		if err := getBranchIDs(db, child.Id, ids); err != nil {
-			return err
-		}
-	}
-	return nil
-}
@@ -1,6 +1,7 @@
 package models
 
 import (
+	"context"
 	"errors"
 	"strings"
 	"time"
@@ -10,19 +11,49 @@ import (
 
 // Сотрудник
 type Employee struct {
-	ID           uint       `json:"id" gorm:"primaryKey"`
-	DepartmentId uint       `json:"department_id" gorm:"column:department_id;not null"`
-	FullName     string     `json:"full_name" gorm:"column:full_name;not null;size:200"`
-	Position     string     `json:"position" gorm:"column:position;not null;size:200"`
-	HiredAt      *time.Time `json:"hired_at" gorm:"column:hired_at"`
-	CreatedAt    time.Time  `json:"created_at" gorm:"column:created_at;default:CURRENT_TIMESTAMP"`
+	ID                uint           `json:"id" gorm:"primaryKey"`
+	DepartmentId      uint           `json:"department_id" gorm:"column:department_id;not null"`
+	FullName          string         `json:"full_name" gorm:"column:full_name;not null;size:200"`
+	Position          string         `json:"position" gorm:"column:position;not null;size:200"`
+	PositionID        *uint          `json:"position_id,omitempty" gorm:"column:position_id"`
+	ManagerID         *uint          `json:"manager_id,omitempty" gorm:"column:manager_id"`
+	HiredAt           *time.Time     `json:"hired_at" gorm:"column:hired_at"`
+	PersonnelNumber   *string        `json:"personnel_number,omitempty" gorm:"column:personnel_number"`
+	Attributes        map[string]any `json:"attributes,omitempty" gorm:"column:attributes;serializer:json"`
+	Status            string         `json:"status" gorm:"column:status;not null;size:20;default:active"`
+	TerminatedAt      *time.Time     `json:"terminated_at,omitempty" gorm:"column:terminated_at"`
+	TerminationReason string         `json:"termination_reason,omitempty" gorm:"column:termination_reason;not null;size:500"`
+	CreatedAt         time.Time      `json:"created_at" gorm:"column:created_at;default:CURRENT_TIMESTAMP"`
+	Version           int            `json:"version" gorm:"column:version;not null;default:1"`
 }
 
 // Структура для создания сотрудника
 type EmployeeRequest struct {
-	FullName string     `json:"full_name"`
-	Position string     `json:"position"`
-	HiredAt  *time.Time `json:"hired_at"`
+	FullName        string         `json:"full_name"`
+	Position        string         `json:"position"`              // свободный текст; с position_id можно не указывать
+	PositionID      *uint          `json:"position_id,omitempty"` // должность из каталога
+	HiredAt         *time.Time     `json:"hired_at"`
+	PersonnelNumber *string        `json:"personnel_number,omitempty"` // табельный номер, уникальный
+	Attributes      map[string]any `json:"attributes,omitempty"`
+}
+
+// Изменение атрибутов сотрудника: дополняет текущие, null удаляет
+type EmployeeAttributesRequest struct {
+	Attributes map[string]any `json:"attributes"`
+}
+
+// Фильтр и сортировка списка сотрудников
+type EmployeeFilter struct {
+	SortBy            string            // name или created
+	Attributes        map[string]string // attr.<имя>=<значение> из query
+	Status            string            // только сотрудники с этим статусом
+	IncludeTerminated bool              // без Status уволенные по умолчанию скрыты
+	Secondary         bool              // вместе с совместителями (только дерево)
+}
+
+// Запрос на перевод сотрудника
+type TransferRequest struct {
+	DepartmentID uint `json:"department_id"`
 }
 
 // Структура ответа
@@ -36,38 +67,73 @@ func (Employee) TableName() string {
 	return "employees"
 }
 
-// Валидация Сотрудника
-func (e *EmployeeRequest) Validate() error {
+// Includes попадает ли сотрудник со статусом status в выборку
+func (f *EmployeeFilter) Includes(status string) bool {
+	if f.Status != "" {
+		return status == f.Status
+	}
+	return f.IncludeTerminated || status != EmployeeTerminated
+}
+
+// Условие фильтра по статусу для запроса
+func (f *EmployeeFilter) whereStatus(query *gorm.DB) *gorm.DB {
+	if f.Status != "" {
+		return query.Where("status = ?", f.Status)
+	}
+	if !f.IncludeTerminated {
+		return query.Where("status <> ?", EmployeeTerminated)
+	}
+	return query
+}
+
+// Валидация Сотрудника, возвращает все ошибки полей сразу; атрибуты по schema
+func (e *EmployeeRequest) Validate(schema AttributeSchema) error {
 	// Пробелов
 	e.FullName = strings.TrimSpace(e.FullName)
 	e.Position = strings.TrimSpace(e.Position)
 
+	var errs []error
+
 	// Проверка имени
 	if e.FullName == "" {
-		return ErrFullNameEmpty
-	}
-	if len(e.FullName) > 200 {
-		return ErrFullNameTooLong
+		errs = append(errs, ErrFullNameEmpty)
+	} else if len(e.FullName) > 200 {
+		errs = append(errs, ErrFullNameTooLong)
 	}
 
 	// Проверка должности
 	if e.Position == "" {
-		return ErrPositionEmpty
-	}
-	if len(e.Position) > 200 {
-		return ErrPositionTooLong
+		errs = append(errs, ErrPositionEmpty)
+	} else if len(e.Position) > 200 {
+		errs = append(errs, ErrPositionTooLong)
 	}
 
 	// Проверка даты найма
 	if e.HiredAt != nil && e.HiredAt.After(time.Now()) {
-		return ErrHiredAtFuture
+		errs = append(errs, ErrHiredAtFuture)
 	}
 
-	return nil
+	// Проверка табельного номера
+	if e.PersonnelNumber != nil {
+		number := strings.TrimSpace(*e.PersonnelNumber)
+		if len(number) > 50 || strings.Contains(number, "/") {
+			errs = append(errs, ErrInvalidPersonnelNum)
+		}
+		e.PersonnelNumber = emptyToNil(&number)
+	}
+
+	// Пользовательские атрибуты
+	attrs, attrErrs := schema.validate(e.Attributes, true)
+	errs = append(errs, attrErrs...)
+	e.Attributes = attrs
+
+	return errors.Join(errs...)
 }
 
 // CreateEmployee создает нового сотрудника в указанном отделе
-func CreateEmployee(db *gorm.DB, departmentID uint, req *EmployeeRequest) (*Employee, error) {
+func CreateEmployee(ctx context.Context, db *gorm.DB, departmentID uint, req *EmployeeRequest) (*Employee, error) {
+	db = db.WithContext(ctx)
+
 	// Проверка отдела
 	var department Department
 	if err := db.First(&department, departmentID).Error; err != nil {
@@ -77,35 +143,138 @@ func CreateEmployee(db *gorm.DB, departmentID uint, req *EmployeeRequest) (*Empl
 		return nil, err
 	}
 
+	// Должность из каталога
+	if req.PositionID != nil {
+		position, err := findPosition(db, *req.PositionID, ErrUnknownPosition)
+		if err != nil {
+			return nil, err
+		}
+		if err := req.UsePosition(position); err != nil {
+			return nil, err
+		}
+	}
+
 	// Вызов валидации
-	if err := req.Validate(); err != nil {
+	schema, err := loadAttributeSchema(db, EntityEmployee)
+	if err != nil {
+		return nil, err
+	}
+	if err := req.Validate(schema); err != nil {
 		return nil, err
 	}
 
+	// Проверка табельного номера
+	if req.PersonnelNumber != nil {
+		var count int64
+		if err := db.Model(&Employee{}).Where("personnel_number = ?", *req.PersonnelNumber).Count(&count).Error; err != nil {
+			return nil, err
+		}
+		if count > 0 {
+			return nil, ErrPersonnelNumberExists
+		}
+	}
+
 	// Создание
 	employee := &Employee{
-		DepartmentId: departmentID,
-		FullName:     req.FullName,
-		Position:     req.Position,
-		HiredAt:      req.HiredAt,
-		CreatedAt:    time.Now(),
+		DepartmentId:    departmentID,
+		FullName:        req.FullName,
+		Position:        req.Position,
+		PositionID:      req.PositionID,
+		HiredAt:         req.HiredAt,
+		PersonnelNumber: req.PersonnelNumber,
+		Attributes:      MergeAttributes(nil, req.Attributes),
+		Status:          EmployeeActive,
+		CreatedAt:       time.Now(),
+		Version:         1,
 	}
 
-	if err := db.Create(employee).Error; err != nil {
+	// Первый период истории начинается с даты найма
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(employee).Error; err != nil {
+			return err
+		}
+		from := employee.CreatedAt
+		if employee.HiredAt != nil {
+			from = *employee.HiredAt
+		}
+		return recordAssignment(tx, employee, from)
+	})
+	if err != nil {
+		if isUniqueViolation(err) {
+			return nil, ErrPersonnelNumberExists
+		}
 		return nil, err
 	}
 
 	return employee, nil
 }
 
+// GetEmployee сотрудник по id
+func GetEmployee(ctx context.Context, db *gorm.DB, id uint) (*Employee, error) {
+	var employee Employee
+	if err := db.WithContext(ctx).First(&employee, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrEmployeeNotFound
+		}
+		return nil, err
+	}
+	return &employee, nil
+}
+
+// UpdateEmployeeAttributes дополняет атрибуты сотрудника; version 0 - без проверки версии
+func UpdateEmployeeAttributes(ctx context.Context, db *gorm.DB, id uint, version int, req *EmployeeAttributesRequest) (*Employee, error) {
+	db = db.WithContext(ctx)
+
+	employee, err := GetEmployee(ctx, db, id)
+	if err != nil {
+		return nil, err
+	}
+	if version != 0 && employee.Version != version {
+		return nil, ErrVersionMismatch
+	}
+
+	schema, err := loadAttributeSchema(db, EntityEmployee)
+	if err != nil {
+		return nil, err
+	}
+	attrs, err := schema.Validate(req.Attributes, false)
+	if err != nil {
+		return nil, err
+	}
+	employee.Attributes = MergeAttributes(employee.Attributes, attrs)
+
+	// Сохранение, только если версию никто не изменил
+	result := db.Model(&Employee{}).
+		Where("id = ? AND version = ?", id, employee.Version).
+		Updates(map[string]interface{}{
+			"attributes": jsonColumn(employee.Attributes),
+			"version":    gorm.Expr("version + 1"),
+		})
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, ErrVersionMismatch
+	}
+	employee.Version++
+	return employee, nil
+}
+
 // Get - всех сотрудников в отделе
-func GetEmployeesByDepartment(db *gorm.DB, departmentID uint, sortBy string) ([]Employee, error) {
+func GetEmployeesByDepartment(ctx context.Context, db *gorm.DB, departmentID uint, filter *EmployeeFilter) ([]Employee, error) {
 	var employees []Employee
+	db = db.WithContext(ctx)
 
-	query := db.Where("department_id = ?", departmentID)
+	// Фильтр по атрибутам
+	attrs, err := loadAttributeFilter(db, EntityEmployee, filter.Attributes)
+	if err != nil {
+		return nil, err
+	}
+	query := whereAttributes(db.Where("department_id = ?", departmentID), attrs)
+	query = filter.whereStatus(query)
 
 	// Сортировка
-	switch sortBy {
+	switch filter.SortBy {
 	case "name":
 		query = query.Order("full_name ASC")
 	case "created":
@@ -122,8 +291,70 @@ func GetEmployeesByDepartment(db *gorm.DB, departmentID uint, sortBy string) ([]
 }
 
 // Перемещение сотрудника между отделами
-func MoveEmployees(db *gorm.DB, fromDeptID, toDeptID uint) error {
-	return db.Model(&Employee{}).
-		Where("department_id = ?", fromDeptID).
-		Update("department_id", toDeptID).Error
+func MoveEmployees(ctx context.Context, db *gorm.DB, fromDeptID, toDeptID uint) error {
+	return db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		_, err := reassignEmployees(tx, toDeptID, "department_id = ?", fromDeptID)
+		return err
+	})
+}
+
+// TransferEmployee переводит сотрудника в другой отдел
+func TransferEmployee(ctx context.Context, db *gorm.DB, id uint, version int, req *TransferRequest) (*Employee, error) {
+	var employee *Employee
+	err := db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var err error
+		employee, err = transferEmployee(tx, id, version, req.DepartmentID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return employee, nil
+}
+
+func transferEmployee(db *gorm.DB, id uint, version int, toDeptID uint) (*Employee, error) {
+	var employee Employee
+	if err := db.First(&employee, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrEmployeeNotFound
+		}
+		return nil, err
+	}
+	if version != 0 && employee.Version != version {
+		return nil, ErrVersionMismatch
+	}
+	if employee.Status == EmployeeTerminated {
+		return nil, ErrEmployeeTerminated
+	}
+
+	// Проверка отдела
+	if err := db.Select("id").First(&Department{}, toDeptID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrUnknownDepartment
+		}
+		return nil, err
+	}
+
+	// Сохранение, только если версию никто не изменил
+	result := db.Model(&Employee{}).
+		Where("id = ? AND version = ?", id, employee.Version).
+		Updates(map[string]interface{}{
+			"department_id": toDeptID,
+			"version":       gorm.Expr("version + 1"),
+		})
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, ErrVersionMismatch
+	}
+	employee.DepartmentId = toDeptID
+	employee.Version++
+	if err := dropSecondaryInPrimary(db, toDeptID, id); err != nil {
+		return nil, err
+	}
+	if err := recordAssignment(db, &employee, time.Now()); err != nil {
+		return nil, err
+	}
+	return &employee, nil
 }
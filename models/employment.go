@@ -0,0 +1,224 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Статусы занятости
+const (
+	EmployeeActive     = "active"
+	EmployeeOnLeave    = "on_leave"
+	EmployeeTerminated = "terminated"
+)
+
+// Увольнение сотрудника
+type TerminationRequest struct {
+	TerminatedAt *time.Time `json:"terminated_at"` // nil - сейчас
+	Reason       string     `json:"reason"`
+}
+
+// Повторный прием уволенного сотрудника
+type RehireRequest struct {
+	HiredAt      *time.Time `json:"hired_at"`                // nil - сейчас
+	DepartmentID *uint      `json:"department_id,omitempty"` // nil - прежний отдел
+}
+
+// Смена статуса работающего сотрудника
+type EmployeeStatusRequest struct {
+	Status string `json:"status"`
+}
+
+// Проверка фильтра по статусу; пустой - без фильтра
+func ValidEmployeeStatus(status string) bool {
+	switch status {
+	case "", EmployeeActive, EmployeeOnLeave, EmployeeTerminated:
+		return true
+	}
+	return false
+}
+
+// Валидация увольнения сотрудника e; дата не раньше приема и не в будущем
+func (r *TerminationRequest) Validate(e *Employee) error {
+	r.Reason = strings.TrimSpace(r.Reason)
+	if r.TerminatedAt == nil {
+		now := time.Now().UTC()
+		r.TerminatedAt = &now
+	}
+
+	var errs []error
+	if r.Reason == "" {
+		errs = append(errs, ErrTerminationReasonEmpty)
+	} else if len(r.Reason) > 500 {
+		errs = append(errs, ErrTerminationReasonTooLong)
+	}
+	if r.TerminatedAt.After(time.Now()) {
+		errs = append(errs, ErrTerminatedAtFuture)
+	} else if e.HiredAt != nil && r.TerminatedAt.Before(*e.HiredAt) {
+		errs = append(errs, ErrTerminatedBeforeHired)
+	}
+	return errors.Join(errs...)
+}
+
+// Валидация повторного приема сотрудника e; дата не раньше увольнения
+func (r *RehireRequest) Validate(e *Employee) error {
+	if r.HiredAt == nil {
+		now := time.Now().UTC()
+		r.HiredAt = &now
+	}
+	if r.HiredAt.After(time.Now()) {
+		return ErrHiredAtFuture
+	}
+	if e.TerminatedAt != nil && r.HiredAt.Before(*e.TerminatedAt) {
+		return ErrRehireBeforeTermination
+	}
+	return nil
+}
+
+// Валидация статуса; уволить можно только через terminate
+func (r *EmployeeStatusRequest) Validate() error {
+	r.Status = strings.TrimSpace(r.Status)
+	if r.Status != EmployeeActive && r.Status != EmployeeOnLeave {
+		return ErrInvalidEmployeeStatus
+	}
+	return nil
+}
+
+// TerminateEmployee увольняет сотрудника; запись остается с датой и причиной.
+// Идет под блокировкой дерева, как и SetManager: иначе уволенного можно
+// назначить руководителем между проверкой и увольнением
+func TerminateEmployee(ctx context.Context, db *gorm.DB, id uint, version int, req *TerminationRequest) (*Employee, error) {
+	var employee *Employee
+	err := withTreeLock(ctx, db, func(tx *gorm.DB) error {
+		var err error
+		if employee, err = employeeForUpdate(tx, id, version); err != nil {
+			return err
+		}
+		if employee.Status == EmployeeTerminated {
+			return ErrEmployeeTerminated
+		}
+		if err := req.Validate(employee); err != nil {
+			return err
+		}
+
+		employee.Status = EmployeeTerminated
+		employee.TerminatedAt = req.TerminatedAt
+		employee.TerminationReason = req.Reason
+		if err := saveEmployeeStatus(tx, employee); err != nil {
+			return err
+		}
+		if err := releaseReports(tx, employee.ID); err != nil {
+			return err
+		}
+		return closeAssignment(tx, employee.ID, *employee.TerminatedAt)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return employee, nil
+}
+
+// RehireEmployee возвращает уволенного сотрудника с новой датой приема
+func RehireEmployee(ctx context.Context, db *gorm.DB, id uint, version int, req *RehireRequest) (*Employee, error) {
+	db = db.WithContext(ctx)
+
+	employee, err := employeeForUpdate(db, id, version)
+	if err != nil {
+		return nil, err
+	}
+	if employee.Status != EmployeeTerminated {
+		return nil, ErrEmployeeNotTerminated
+	}
+	if err := req.Validate(employee); err != nil {
+		return nil, err
+	}
+
+	// Проверка отдела
+	if req.DepartmentID != nil {
+		if err := db.Select("id").First(&Department{}, *req.DepartmentID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil, ErrUnknownDepartment
+			}
+			return nil, err
+		}
+		employee.DepartmentId = *req.DepartmentID
+	}
+
+	employee.Status = EmployeeActive
+	employee.HiredAt = req.HiredAt
+	employee.TerminatedAt = nil
+	employee.TerminationReason = ""
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if err := saveEmployeeStatus(tx, employee); err != nil {
+			return err
+		}
+		if err := dropSecondaryInPrimary(tx, employee.DepartmentId, employee.ID); err != nil {
+			return err
+		}
+		return recordAssignment(tx, employee, *employee.HiredAt)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return employee, nil
+}
+
+// SetEmployeeStatus переводит работающего сотрудника в отпуск и обратно
+func SetEmployeeStatus(ctx context.Context, db *gorm.DB, id uint, version int, req *EmployeeStatusRequest) (*Employee, error) {
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+	db = db.WithContext(ctx)
+
+	employee, err := employeeForUpdate(db, id, version)
+	if err != nil {
+		return nil, err
+	}
+	if employee.Status == EmployeeTerminated {
+		return nil, ErrEmployeeTerminated
+	}
+
+	employee.Status = req.Status
+	if err := saveEmployeeStatus(db, employee); err != nil {
+		return nil, err
+	}
+	return employee, nil
+}
+
+// Сотрудник с проверкой версии; version 0 - без проверки
+func employeeForUpdate(db *gorm.DB, id uint, version int) (*Employee, error) {
+	employee, err := GetEmployee(db.Statement.Context, db, id)
+	if err != nil {
+		return nil, err
+	}
+	if version != 0 && employee.Version != version {
+		return nil, ErrVersionMismatch
+	}
+	return employee, nil
+}
+
+// Сохранение полей занятости, только если версию никто не изменил
+func saveEmployeeStatus(db *gorm.DB, employee *Employee) error {
+	result := db.Model(&Employee{}).
+		Where("id = ? AND version = ?", employee.ID, employee.Version).
+		Updates(map[string]interface{}{
+			"department_id":      employee.DepartmentId,
+			"status":             employee.Status,
+			"hired_at":           employee.HiredAt,
+			"terminated_at":      employee.TerminatedAt,
+			"termination_reason": employee.TerminationReason,
+			"version":            gorm.Expr("version + 1"),
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrVersionMismatch
+	}
+	employee.Version++
+	return nil
+}
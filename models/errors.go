@@ -1,6 +1,9 @@
 package models
 
-import "errors"
+import (
+	"errors"
+	"strings"
+)
 
 // Errors как переменные (для возврата)
 var (
@@ -8,19 +11,169 @@ var (
 	ErrCycleDetected      = errors.New("cannot move department to its own child (cycle detected)")
 	ErrDepartmentNotFound = errors.New("department not found")
 	ErrTargetNotFound     = errors.New("target department not found")
+	ErrUnknownDepartment  = errors.New("department_id refers to a missing department")
 	ErrNameEmpty          = errors.New("department name cannot be empty")
 	ErrNameTooLong        = errors.New("department name too long (max 200)")
 	ErrParentNotFound     = errors.New("parent department not found")
-	ErrNameExists         = errors.New("department with this name already exists in this parent")
+	ErrNameExists         = errors.New("department with this name already exists")
 	ErrInvalidMode        = errors.New("invalid mode, use 'cascade' or 'reassign'")
 	ErrReassignToSame     = errors.New("cannot reassign to the same department")
+	ErrReassignRequired   = errors.New("reassign_to_department_id is required for reassign mode")
+	ErrVersionMismatch    = errors.New("resource was modified by another request")
+	ErrInvalidPosition    = errors.New("position cannot be negative")
+	ErrInvalidChildOrder  = errors.New("ids must list every child department exactly once")
+)
+
+// Для слияния подразделений
+var (
+	ErrInvalidMergeStrategy = errors.New("invalid strategy, use 'fail', 'suffix' or 'merge'")
+	ErrMergeIntoSelf        = errors.New("cannot merge department into itself")
+	ErrMergeIntoDescendant  = errors.New("cannot merge department into its own child")
+	ErrMergeConflict        = errors.New("child department with the same name already exists in target")
+)
+
+// Для выделения подразделений
+var (
+	ErrEmployeeNotInDepartment = errors.New("employee does not belong to the department")
+	ErrNotChildDepartment      = errors.New("department is not a direct child of the split department")
+)
+
+// Для планов реорганизации
+var (
+	ErrPlanNotFound         = errors.New("plan not found")
+	ErrPlanNameEmpty        = errors.New("plan name cannot be empty")
+	ErrPlanNameTooLong      = errors.New("plan name too long (max 200)")
+	ErrPlanApplied          = errors.New("plan is already applied")
+	ErrPlanStale            = errors.New("departments in the plan were changed after it was drafted")
+	ErrPlanInvalid          = errors.New("plan is invalid")
+	ErrInvalidPlanOperation = errors.New("invalid plan operation")
+)
+
+// Для отложенных изменений
+var (
+	ErrScheduledChangeNotFound = errors.New("scheduled change not found")
+	ErrChangeNotPending        = errors.New("scheduled change is not pending")
+	ErrInvalidChangeStatus     = errors.New("invalid status, use 'pending', 'applied', 'canceled' or 'failed'")
+	ErrEmptyChange             = errors.New("nothing to change, set name, parent_id or position")
+	ErrNotSchedulable          = errors.New("code, external_ids, attributes and type cannot be scheduled, change them without effective_at")
+)
+
+// Для кодов и внешних идентификаторов
+var (
+	ErrCodeExists            = errors.New("department with this code already exists")
+	ErrInvalidCode           = errors.New("invalid code (max 50 characters, no ':' or '/')")
+	ErrInvalidExternalID     = errors.New("invalid external id (system max 50 characters without ':' or '/', id max 100 characters)")
+	ErrExternalIDExists      = errors.New("external id is already assigned to another department")
+	ErrPersonnelNumberExists = errors.New("employee with this personnel number already exists")
+	ErrInvalidPersonnelNum   = errors.New("invalid personnel number (max 50 characters, no '/')")
+	ErrInvalidDepartmentRef  = errors.New("invalid department reference, use id, 'code:<code>' or 'ext:<system>:<id>'")
+	ErrInvalidEmployeeRef    = errors.New("invalid employee reference, use id or 'pn:<personnel number>'")
+)
+
+// Для пользовательских атрибутов
+var (
+	ErrAttributeDefinitionNotFound = errors.New("attribute definition not found")
+	ErrAttributeExists             = errors.New("attribute with this name already exists")
+	ErrInvalidAttributeEntity      = errors.New("invalid entity, use 'department' or 'employee'")
+	ErrInvalidAttributeName        = errors.New("invalid attribute name (lowercase latin letters, digits and '_', max 50)")
+	ErrInvalidAttributeType        = errors.New("invalid attribute type, use 'string', 'number', 'boolean' or 'date'")
+	ErrInvalidEnumValues           = errors.New("enum_values must be unique non-empty strings (max 100), only for string attributes")
+	ErrInvalidAttributePattern     = errors.New("pattern must be a valid regular expression (max 500), only for string attributes")
+	ErrAttributeDescriptionTooLong = errors.New("attribute description too long (max 500)")
+	ErrUnknownAttribute            = errors.New("attribute is not defined")
+	ErrAttributeRequired           = errors.New("attribute is required")
+	ErrAttributeType               = errors.New("attribute value has wrong type")
+	ErrAttributeValueTooLong       = errors.New("attribute value too long (max 1000)")
+	ErrAttributeNotAllowed         = errors.New("attribute value is not allowed")
+	ErrAttributePattern            = errors.New("attribute value does not match pattern")
+)
+
+// Для типов подразделений и правил иерархии
+var (
+	ErrInvalidDepartmentType = errors.New("invalid department type (lowercase latin letters, digits and '_', max 50)")
+	ErrInvalidHierarchyRules = errors.New("invalid hierarchy rules")
+	ErrUnknownDepartmentType = errors.New("department type is not defined in hierarchy rules")
+	ErrTypeNotAllowed        = errors.New("department type is not allowed under the parent type")
+	ErrMaxDepthExceeded      = errors.New("hierarchy depth limit exceeded")
+	ErrMaxChildrenExceeded   = errors.New("direct children limit exceeded")
+	ErrNamePatternMismatch   = errors.New("department name does not match the pattern of its type")
+)
+
+// Для каталога должностей
+var (
+	ErrPositionNotFound     = errors.New("position not found")
+	ErrUnknownPosition      = errors.New("position_id refers to a missing position")
+	ErrPositionMismatch     = errors.New("position does not match the title of position_id")
+	ErrPositionExists       = errors.New("position with this title already exists")
+	ErrPositionInUse        = errors.New("position is assigned to employees")
+	ErrPositionTitleEmpty   = errors.New("position title cannot be empty")
+	ErrPositionTitleTooLong = errors.New("position title too long (max 200)")
+	ErrGradeTooLong         = errors.New("grade too long (max 50)")
+	ErrJobFamilyTooLong     = errors.New("job family too long (max 100)")
+)
+
+// Для Idempotency-Key
+var (
+	ErrIdempotencyInProgress = errors.New("request with this idempotency key is still in progress")
+	ErrIdempotencyMismatch   = errors.New("idempotency key was already used with a different request")
 )
 
 // Для Employee
 var (
-	ErrFullNameEmpty   = errors.New("full name cannot be empty")
-	ErrFullNameTooLong = errors.New("full name too long (max 200 characters)")
-	ErrPositionEmpty   = errors.New("position cannot be empty")
-	ErrPositionTooLong = errors.New("position too long (max 200 characters)")
-	ErrHiredAtFuture   = errors.New("hired_at cannot be in the future")
+	ErrEmployeeNotFound = errors.New("employee not found")
+	ErrFullNameEmpty    = errors.New("full name cannot be empty")
+	ErrFullNameTooLong  = errors.New("full name too long (max 200 characters)")
+	ErrPositionEmpty    = errors.New("position cannot be empty")
+	ErrPositionTooLong  = errors.New("position too long (max 200 characters)")
+	ErrHiredAtFuture    = errors.New("hired_at cannot be in the future")
+)
+
+// Для статуса занятости
+var (
+	ErrInvalidEmployeeStatus    = errors.New("invalid status, use 'active' or 'on_leave'; terminated only via terminate")
+	ErrTerminationReasonEmpty   = errors.New("termination reason cannot be empty")
+	ErrTerminationReasonTooLong = errors.New("termination reason too long (max 500)")
+	ErrTerminatedAtFuture       = errors.New("terminated_at cannot be in the future")
+	ErrTerminatedBeforeHired    = errors.New("terminated_at cannot be before hired_at")
+	ErrRehireBeforeTermination  = errors.New("hired_at cannot be before terminated_at")
+	ErrEmployeeTerminated       = errors.New("employee is terminated")
+	ErrEmployeeNotTerminated    = errors.New("employee is not terminated")
 )
+
+// Для совмещений
+var (
+	ErrSecondaryNotFound  = errors.New("secondary assignment not found")
+	ErrSecondaryExists    = errors.New("employee already has a secondary assignment in this department")
+	ErrSecondaryInPrimary = errors.New("secondary assignment cannot be in the primary department")
+	ErrRoleEmpty          = errors.New("role cannot be empty")
+	ErrRoleTooLong        = errors.New("role too long (max 100)")
+	ErrInvalidAllocation  = errors.New("allocation must be between 1 and 100 percent")
+	ErrAllocationExceeded = errors.New("total allocation of secondary assignments exceeds 100 percent")
+)
+
+// Для линий подчинения
+var (
+	ErrManagerNotFound   = errors.New("manager not found")
+	ErrSelfManager       = errors.New("employee cannot be their own manager")
+	ErrManagerCycle      = errors.New("manager cannot report to the employee (cycle detected)")
+	ErrManagerTerminated = errors.New("manager is terminated")
+)
+
+// Для плановой численности и вакансий
+var (
+	ErrHeadcountPlanNotFound = errors.New("headcount plan not found")
+	ErrHeadcountPlanExists   = errors.New("headcount plan for this department and period already exists")
+	ErrInvalidPeriod         = errors.New("invalid period, use YYYY-MM")
+	ErrInvalidPlanned        = errors.New("planned headcount cannot be negative")
+	ErrVacancyNotFound       = errors.New("vacancy not found")
+	ErrInvalidVacancyStatus  = errors.New("invalid status, use 'open' or 'canceled'; filled only via fill")
+	ErrVacancyNotOpen        = errors.New("vacancy is not open")
+	ErrVacancyFilled         = errors.New("vacancy is already filled")
+)
+
+// Нарушение уникального индекса (Postgres и SQLite)
+func isUniqueViolation(err error) bool {
+	msg := strings.ToLower(err.Error())
+	return strings.Contains(msg, "duplicate key") ||
+		strings.Contains(msg, "unique constraint")
+}
@@ -0,0 +1,100 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Сохраненный ответ на запрос с Idempotency-Key
+type IdempotencyKey struct {
+	Key         string    `gorm:"column:idempotency_key;primaryKey;size:255"`
+	Method      string    `gorm:"column:method;not null;size:10"`
+	Path        string    `gorm:"column:path;not null;size:500"`
+	RequestHash string    `gorm:"column:request_hash;not null;size:64"`
+	StatusCode  int       `gorm:"column:status_code;not null;default:0"`
+	ContentType string    `gorm:"column:content_type;not null;default:''"`
+	ETag        string    `gorm:"column:etag;not null;default:''"`
+	Body        []byte    `gorm:"column:body"`
+	CreatedAt   time.Time `gorm:"column:created_at"`
+	ExpiresAt   time.Time `gorm:"column:expires_at;not null"`
+}
+
+// Имя для таблицы
+func (IdempotencyKey) TableName() string {
+	return "idempotency_keys"
+}
+
+// Запрос еще выполняется, ответа нет
+func (k *IdempotencyKey) Pending() bool {
+	return k.StatusCode == 0
+}
+
+// ReserveIdempotencyKey занимает ключ. Если ключ уже есть, возвращает
+// сохраненную запись; просроченные и зависшие (дольше staleAfter) записи заменяются
+func ReserveIdempotencyKey(ctx context.Context, db *gorm.DB, key *IdempotencyKey, staleAfter time.Duration) (*IdempotencyKey, error) {
+	db = db.WithContext(ctx)
+
+	for attempt := 0; attempt < 2; attempt++ {
+		err := db.Create(key).Error
+		if err == nil {
+			return nil, nil
+		}
+		if !isUniqueViolation(err) {
+			return nil, err
+		}
+
+		// Ключ уже занят
+		var existing IdempotencyKey
+		if err := db.Where("idempotency_key = ?", key.Key).First(&existing).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				continue
+			}
+			return nil, err
+		}
+
+		now := time.Now().UTC()
+		expired := now.After(existing.ExpiresAt)
+		abandoned := existing.Pending() && now.Sub(existing.CreatedAt) > staleAfter
+		if !expired && !abandoned {
+			return &existing, nil
+		}
+
+		if err := db.Where("idempotency_key = ? AND (expires_at < ? OR (status_code = 0 AND created_at < ?))",
+			existing.Key, now, now.Add(-staleAfter)).
+			Delete(&IdempotencyKey{}).Error; err != nil {
+			return nil, err
+		}
+	}
+
+	return nil, ErrIdempotencyInProgress
+}
+
+// CompleteIdempotencyKey сохраняет ответ
+func CompleteIdempotencyKey(ctx context.Context, db *gorm.DB, key *IdempotencyKey) error {
+	return db.WithContext(ctx).Model(&IdempotencyKey{}).
+		Where("idempotency_key = ?", key.Key).
+		Updates(map[string]interface{}{
+			"status_code":  key.StatusCode,
+			"content_type": key.ContentType,
+			"etag":         key.ETag,
+			"body":         key.Body,
+		}).Error
+}
+
+// ReleaseIdempotencyKey освобождает ключ, чтобы запрос можно было повторить
+func ReleaseIdempotencyKey(ctx context.Context, db *gorm.DB, key string) error {
+	return db.WithContext(ctx).
+		Where("idempotency_key = ?", key).
+		Delete(&IdempotencyKey{}).Error
+}
+
+// PurgeExpiredIdempotencyKeys удаляет просроченные ключи
+func PurgeExpiredIdempotencyKeys(ctx context.Context, db *gorm.DB, now time.Time) (int64, error) {
+	result := db.WithContext(ctx).
+		Where("expires_at < ?", now).
+		Delete(&IdempotencyKey{})
+	return result.RowsAffected, result.Error
+}
@@ -0,0 +1,223 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// Префиксы ссылок вместо числового id: "code:FIN-01", "ext:sap:1000", "pn:00042"
+const (
+	refCodePrefix            = "code:"
+	refExternalPrefix        = "ext:"
+	refPersonnelNumberPrefix = "pn:"
+)
+
+// Ограничения длины идентификаторов
+const (
+	maxCodeLength       = 50
+	maxSystemLength     = 50
+	maxExternalIDLength = 100
+)
+
+// Строка department_external_ids
+type DepartmentExternalID struct {
+	DepartmentID uint   `gorm:"column:department_id;primaryKey"`
+	System       string `gorm:"column:system;primaryKey"`
+	ExternalID   string `gorm:"column:external_id"`
+}
+
+// Имя для таблицы
+func (DepartmentExternalID) TableName() string {
+	return "department_external_ids"
+}
+
+// Ссылка на подразделение: id, код или id во внешней системе
+type DepartmentRef struct {
+	ID         uint
+	Code       string
+	System     string
+	ExternalID string
+}
+
+// ParseDepartmentRef разбирает "42", "code:FIN-01" или "ext:sap:1000"
+func ParseDepartmentRef(s string) (DepartmentRef, error) {
+	switch {
+	case strings.HasPrefix(s, refCodePrefix):
+		if code := strings.TrimPrefix(s, refCodePrefix); code != "" {
+			return DepartmentRef{Code: code}, nil
+		}
+	case strings.HasPrefix(s, refExternalPrefix):
+		system, id, ok := strings.Cut(strings.TrimPrefix(s, refExternalPrefix), ":")
+		if ok && system != "" && id != "" {
+			return DepartmentRef{System: system, ExternalID: id}, nil
+		}
+	default:
+		if id, err := strconv.ParseUint(s, 10, 32); err == nil && id > 0 {
+			return DepartmentRef{ID: uint(id)}, nil
+		}
+	}
+	return DepartmentRef{}, fmt.Errorf("%w: %q", ErrInvalidDepartmentRef, s)
+}
+
+// Ссылка на сотрудника: id или табельный номер
+type EmployeeRef struct {
+	ID              uint
+	PersonnelNumber string
+}
+
+// ParseEmployeeRef разбирает "42" или "pn:00042"
+func ParseEmployeeRef(s string) (EmployeeRef, error) {
+	if number, ok := strings.CutPrefix(s, refPersonnelNumberPrefix); ok {
+		if number != "" {
+			return EmployeeRef{PersonnelNumber: number}, nil
+		}
+	} else if id, err := strconv.ParseUint(s, 10, 32); err == nil && id > 0 {
+		return EmployeeRef{ID: uint(id)}, nil
+	}
+	return EmployeeRef{}, fmt.Errorf("%w: %q", ErrInvalidEmployeeRef, s)
+}
+
+// ResolveDepartment id подразделения по ссылке. Числовой id возвращается
+// без запроса: его существование проверит сама операция
+func ResolveDepartment(ctx context.Context, db *gorm.DB, ref DepartmentRef) (uint, error) {
+	db = db.WithContext(ctx)
+
+	var id uint
+	var err error
+	switch {
+	case ref.ID != 0:
+		return ref.ID, nil
+	case ref.Code != "":
+		err = db.Model(&Department{}).Select("id").Where("code = ?", ref.Code).Take(&id).Error
+	default:
+		err = db.Model(&DepartmentExternalID{}).Select("department_id").
+			Where("system = ? AND external_id = ?", ref.System, ref.ExternalID).Take(&id).Error
+	}
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return 0, ErrDepartmentNotFound
+	}
+	return id, err
+}
+
+// ResolveEmployee id сотрудника по ссылке
+func ResolveEmployee(ctx context.Context, db *gorm.DB, ref EmployeeRef) (uint, error) {
+	if ref.ID != 0 {
+		return ref.ID, nil
+	}
+
+	var id uint
+	err := db.WithContext(ctx).Model(&Employee{}).Select("id").
+		Where("personnel_number = ?", ref.PersonnelNumber).Take(&id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return 0, ErrEmployeeNotFound
+	}
+	return id, err
+}
+
+// NormalizeCode пробелы по краям убираются, пустой код - nil
+func NormalizeCode(code *string) (*string, error) {
+	if code == nil {
+		return nil, nil
+	}
+	trimmed := strings.TrimSpace(*code)
+	if trimmed == "" {
+		return nil, nil
+	}
+	if len(trimmed) > maxCodeLength || strings.ContainsAny(trimmed, ":/") {
+		return nil, ErrInvalidCode
+	}
+	return &trimmed, nil
+}
+
+// NormalizeExternalIDs проверяет пары система -> id; nil остается nil
+func NormalizeExternalIDs(ids map[string]string) (map[string]string, error) {
+	if ids == nil {
+		return nil, nil
+	}
+	normalized := make(map[string]string, len(ids))
+	for system, id := range ids {
+		system, id = strings.TrimSpace(system), strings.TrimSpace(id)
+		switch {
+		case system == "" || len(system) > maxSystemLength || strings.ContainsAny(system, ":/"):
+			return nil, fmt.Errorf("%w: system %q", ErrInvalidExternalID, system)
+		case id == "" || len(id) > maxExternalIDLength || strings.Contains(id, "/"):
+			return nil, fmt.Errorf("%w: %s id %q", ErrInvalidExternalID, system, id)
+		}
+		normalized[system] = id
+	}
+	return normalized, nil
+}
+
+// Занят ли код другим подразделением
+func codeTaken(db *gorm.DB, code string, exceptID uint) error {
+	var count int64
+	if err := db.Model(&Department{}).Where("code = ? AND id <> ?", code, exceptID).Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return fmt.Errorf("%w: %q", ErrCodeExists, code)
+	}
+	return nil
+}
+
+// Заняты ли внешние id другими подразделениями
+func externalIDsTaken(db *gorm.DB, ids map[string]string, exceptID uint) error {
+	for system, id := range ids {
+		var count int64
+		err := db.Model(&DepartmentExternalID{}).
+			Where("system = ? AND external_id = ? AND department_id <> ?", system, id, exceptID).
+			Count(&count).Error
+		if err != nil {
+			return err
+		}
+		if count > 0 {
+			return fmt.Errorf("%w: %s %q", ErrExternalIDExists, system, id)
+		}
+	}
+	return nil
+}
+
+// Замена строк department_external_ids подразделения
+func saveExternalIDs(db *gorm.DB, departmentID uint, ids map[string]string) error {
+	if err := db.Where("department_id = ?", departmentID).Delete(&DepartmentExternalID{}).Error; err != nil {
+		return err
+	}
+	for system, id := range ids {
+		row := DepartmentExternalID{DepartmentID: departmentID, System: system, ExternalID: id}
+		if err := db.Create(&row).Error; err != nil {
+			return departmentUniqueError(err)
+		}
+	}
+	return nil
+}
+
+// Нарушение уникального индекса при гонке: какое ограничение сработало
+func departmentUniqueError(err error) error {
+	if !isUniqueViolation(err) {
+		return err
+	}
+	msg := strings.ToLower(err.Error())
+	switch {
+	case strings.Contains(msg, "idx_departments_code") || strings.Contains(msg, "departments.code"):
+		return ErrCodeExists
+	case strings.Contains(msg, "department_external_ids"):
+		return ErrExternalIDExists
+	case strings.Contains(msg, "personnel_number"):
+		return ErrPersonnelNumberExists
+	default:
+		return ErrNameExists
+	}
+}
+
+// Пустая строка запроса означает "снять значение"
+func emptyToNil(s *string) *string {
+	if s == nil || *s == "" {
+		return nil
+	}
+	return s
+}
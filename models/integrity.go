@@ -0,0 +1,358 @@
+package models
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+	"strconv"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Виды находок проверки целостности
+const (
+	IssueCycle            = "cycle"
+	IssueDanglingParent   = "dangling_parent"
+	IssueDanglingEmployee = "dangling_department"
+	IssueNameConflict     = "name_conflict"
+	IssueNameKeyStale     = "name_key_stale"
+	IssueNameEmpty        = "name_empty"
+	IssueNameTooLong      = "name_too_long"
+	IssueHiredAtFuture    = "hired_at_future"
+	IssueTerminatedBefore = "terminated_before_hired"
+)
+
+// Находка проверки целостности
+type IntegrityIssue struct {
+	Check      string   `json:"check"`
+	Entity     string   `json:"entity"` // department или employee
+	IDs        []uint   `json:"ids"`
+	Message    string   `json:"message"`
+	Fixes      []string `json:"fixes"`      // предлагаемые исправления, безопасное - первым
+	Repairable bool     `json:"repairable"` // исправляется автоматически (check --repair)
+	Repaired   bool     `json:"repaired"`
+}
+
+// Итог проверки
+type IntegrityReport struct {
+	Departments int              `json:"departments"`
+	Employees   int              `json:"employees"`
+	Issues      []IntegrityIssue `json:"issues"`
+	Repaired    int              `json:"repaired"`
+}
+
+// Remaining число неисправленных находок
+func (r *IntegrityReport) Remaining() int {
+	n := 0
+	for _, issue := range r.Issues {
+		if !issue.Repaired {
+			n++
+		}
+	}
+	return n
+}
+
+// InspectIntegrity ищет нарушения в загруженных строках и возвращает безопасные
+// исправления: подразделения с новыми parent_id, sort_order, name, name_key и name_scope.
+// Имена проверяются на дереве после исправления циклов и висячих parent_id
+func InspectIntegrity(policy UniquenessPolicy, departments []Department, employees []Employee, now time.Time) (*IntegrityReport, []Department) {
+	report := &IntegrityReport{Departments: len(departments), Employees: len(employees), Issues: []IntegrityIssue{}}
+
+	departments = slices.Clone(departments)
+	slices.SortFunc(departments, func(a, b Department) int { return cmp.Compare(a.Id, b.Id) })
+	byID := make(map[uint]Department, len(departments))
+	for _, dept := range departments {
+		byID[dept.Id] = dept
+	}
+	changed := make(map[uint]bool)
+
+	// Следующий sort_order среди корней
+	nextRootOrder := 0
+	for _, dept := range departments {
+		if dept.ParentId == nil {
+			nextRootOrder = max(nextRootOrder, dept.SortOrder+1)
+		}
+	}
+	toRoot := func(id uint) {
+		dept := byID[id]
+		dept.ParentId = nil
+		dept.SortOrder = nextRootOrder
+		nextRootOrder++
+		byID[id] = dept
+		changed[id] = true
+	}
+
+	// Висячие parent_id
+	for _, dept := range departments {
+		if dept.ParentId == nil {
+			continue
+		}
+		if _, ok := byID[*dept.ParentId]; !ok {
+			report.Issues = append(report.Issues, IntegrityIssue{
+				Check:   IssueDanglingParent,
+				Entity:  EntityDepartment,
+				IDs:     []uint{dept.Id},
+				Message: fmt.Sprintf("department %d refers to missing parent %d", dept.Id, *dept.ParentId),
+				Fixes: []string{
+					fmt.Sprintf("move department %d to the root", dept.Id),
+					"set parent_id to an existing department",
+				},
+				Repairable: true,
+			})
+			toRoot(dept.Id)
+		}
+	}
+
+	// Циклы: обход по родителям, цикл разрывается на меньшем id
+	state := make(map[uint]int, len(departments)) // 1 - в обходе, 2 - проверено
+	for _, dept := range departments {
+		var path []uint
+		id := dept.Id
+		for state[id] == 0 {
+			state[id] = 1
+			path = append(path, id)
+			parentID := byID[id].ParentId
+			if parentID == nil {
+				break
+			}
+			id = *parentID
+		}
+		if state[id] == 1 && byID[id].ParentId != nil {
+			cycle := slices.Clone(path[slices.Index(path, id):])
+			slices.Sort(cycle)
+			report.Issues = append(report.Issues, IntegrityIssue{
+				Check:   IssueCycle,
+				Entity:  EntityDepartment,
+				IDs:     cycle,
+				Message: fmt.Sprintf("departments %v form a parent_id cycle", cycle),
+				Fixes: []string{
+					fmt.Sprintf("move department %d to the root", cycle[0]),
+					"move any department of the cycle under a department outside it",
+				},
+				Repairable: true,
+			})
+			toRoot(cycle[0])
+		}
+		for _, seen := range path {
+			state[seen] = 2
+		}
+	}
+
+	// Имена и уникальность по политике; область считается до переименований
+	groups := make(map[[2]string][]uint)
+	scopes := make(map[uint]string, len(departments))
+	for _, dept := range departments {
+		dept = byID[dept.Id]
+		switch {
+		case len(dept.Name) == 0:
+			report.Issues = append(report.Issues, IntegrityIssue{
+				Check: IssueNameEmpty, Entity: EntityDepartment, IDs: []uint{dept.Id},
+				Message: fmt.Sprintf("department %d has an empty name", dept.Id),
+				Fixes:   []string{"rename the department"},
+			})
+		case len(dept.Name) > 200:
+			report.Issues = append(report.Issues, IntegrityIssue{
+				Check: IssueNameTooLong, Entity: EntityDepartment, IDs: []uint{dept.Id},
+				Message: fmt.Sprintf("department %d name is %d bytes long (max 200)", dept.Id, len(dept.Name)),
+				Fixes:   []string{"rename the department to at most 200 bytes"},
+			})
+		}
+		scopes[dept.Id] = policy.NameScope(dept.ParentId, rootKeyOf(byID, dept))
+		name := [2]string{scopes[dept.Id], NameKey(dept.Name)}
+		groups[name] = append(groups[name], dept.Id)
+	}
+
+	taken := make(map[[2]string]bool, len(groups))
+	for name := range groups {
+		taken[name] = true
+	}
+	for _, dept := range departments {
+		// Имя из исходной строки: переименованные в этом цикле остаются в своей группе
+		scope := scopes[dept.Id]
+		ids := groups[[2]string{scope, NameKey(dept.Name)}]
+		if ids[0] != dept.Id {
+			continue
+		}
+
+		// Первое (старшее) подразделение сохраняет имя, остальные получают суффикс
+		if len(ids) > 1 {
+			issue := IntegrityIssue{
+				Check:      IssueNameConflict,
+				Entity:     EntityDepartment,
+				IDs:        ids,
+				Message:    fmt.Sprintf("departments %v share the name %q under the %s policy", ids, dept.Name, policy),
+				Repairable: true,
+			}
+			for _, id := range ids[1:] {
+				other := byID[id]
+				renamed := ""
+				for n := 2; n <= maxMergeSuffix && renamed == ""; n++ {
+					candidate := fmt.Sprintf("%s (%d)", other.Name, n)
+					if !taken[[2]string{scope, NameKey(candidate)}] && len(candidate) <= 200 {
+						renamed = candidate
+					}
+				}
+				if renamed == "" {
+					issue.Repairable = false
+					continue
+				}
+				taken[[2]string{scope, NameKey(renamed)}] = true
+				issue.Fixes = append(issue.Fixes, fmt.Sprintf("rename department %d to %q", id, renamed))
+				other.Name = renamed
+				byID[id] = other
+				changed[id] = true
+			}
+			issue.Fixes = append(issue.Fixes, "rename or merge the departments manually")
+			report.Issues = append(report.Issues, issue)
+		}
+	}
+
+	// Служебные колонки имен после всех исправлений
+	var stale []uint
+	for _, dept := range departments {
+		fixed := byID[dept.Id]
+		fixed.NameKey = NameKey(fixed.Name)
+		fixed.NameScope = policy.NameScope(fixed.ParentId, rootKeyOf(byID, fixed))
+		if fixed.NameKey != dept.NameKey || fixed.NameScope != dept.NameScope {
+			if !changed[dept.Id] {
+				stale = append(stale, dept.Id)
+			}
+			changed[dept.Id] = true
+		}
+		byID[dept.Id] = fixed
+	}
+	if len(stale) > 0 {
+		report.Issues = append(report.Issues, IntegrityIssue{
+			Check:      IssueNameKeyStale,
+			Entity:     EntityDepartment,
+			IDs:        stale,
+			Message:    fmt.Sprintf("departments %v have name_key or name_scope out of date", stale),
+			Fixes:      []string{"recompute name_key and name_scope"},
+			Repairable: true,
+		})
+	}
+
+	// Сотрудники
+	employees = slices.Clone(employees)
+	slices.SortFunc(employees, func(a, b Employee) int { return cmp.Compare(a.ID, b.ID) })
+	for _, emp := range employees {
+		if _, ok := byID[emp.DepartmentId]; !ok {
+			report.Issues = append(report.Issues, IntegrityIssue{
+				Check: IssueDanglingEmployee, Entity: EntityEmployee, IDs: []uint{emp.ID},
+				Message: fmt.Sprintf("employee %d refers to missing department %d", emp.ID, emp.DepartmentId),
+				Fixes: []string{
+					fmt.Sprintf("transfer employee %d to an existing department", emp.ID),
+					"delete the employee",
+				},
+			})
+		}
+		switch {
+		case len(emp.FullName) == 0:
+			report.Issues = append(report.Issues, IntegrityIssue{
+				Check: IssueNameEmpty, Entity: EntityEmployee, IDs: []uint{emp.ID},
+				Message: fmt.Sprintf("employee %d has an empty full_name", emp.ID),
+				Fixes:   []string{"set the employee full_name"},
+			})
+		case len(emp.FullName) > 200:
+			report.Issues = append(report.Issues, IntegrityIssue{
+				Check: IssueNameTooLong, Entity: EntityEmployee, IDs: []uint{emp.ID},
+				Message: fmt.Sprintf("employee %d full_name is %d bytes long (max 200)", emp.ID, len(emp.FullName)),
+				Fixes:   []string{"shorten the employee full_name to at most 200 bytes"},
+			})
+		}
+		if emp.HiredAt != nil && emp.HiredAt.After(now) {
+			report.Issues = append(report.Issues, IntegrityIssue{
+				Check: IssueHiredAtFuture, Entity: EntityEmployee, IDs: []uint{emp.ID},
+				Message: fmt.Sprintf("employee %d hired_at %s is in the future", emp.ID, emp.HiredAt.Format(time.RFC3339)),
+				Fixes:   []string{"set hired_at to the actual hire date", "clear hired_at"},
+			})
+		}
+		if emp.HiredAt != nil && emp.TerminatedAt != nil && emp.TerminatedAt.Before(*emp.HiredAt) {
+			report.Issues = append(report.Issues, IntegrityIssue{
+				Check: IssueTerminatedBefore, Entity: EntityEmployee, IDs: []uint{emp.ID},
+				Message: fmt.Sprintf("employee %d terminated_at %s is before hired_at %s", emp.ID,
+					emp.TerminatedAt.Format(time.RFC3339), emp.HiredAt.Format(time.RFC3339)),
+				Fixes: []string{"set terminated_at to the actual termination date", "correct hired_at"},
+			})
+		}
+	}
+
+	var repairs []Department
+	for _, dept := range departments {
+		if changed[dept.Id] {
+			repairs = append(repairs, byID[dept.Id])
+		}
+	}
+	return report, repairs
+}
+
+// MarkRepaired отмечает исправимые находки исправленными
+func (r *IntegrityReport) MarkRepaired() {
+	for i := range r.Issues {
+		if r.Issues[i].Repairable {
+			r.Issues[i].Repaired = true
+			r.Repaired++
+		}
+	}
+}
+
+// CheckIntegrity проверяет подразделения и сотрудников; с repair применяет
+// безопасные исправления в одной транзакции под блокировкой дерева
+func CheckIntegrity(ctx context.Context, db *gorm.DB, policy UniquenessPolicy, repair bool, now time.Time) (*IntegrityReport, error) {
+	inspect := func(tx *gorm.DB) (*IntegrityReport, []Department, error) {
+		var departments []Department
+		if err := tx.Select("id", "parent_id", "name", "name_key", "name_scope", "sort_order").Find(&departments).Error; err != nil {
+			return nil, nil, err
+		}
+		var employees []Employee
+		if err := tx.Select("id", "department_id", "full_name", "hired_at", "terminated_at").Find(&employees).Error; err != nil {
+			return nil, nil, err
+		}
+		report, repairs := InspectIntegrity(policy, departments, employees, now)
+		return report, repairs, nil
+	}
+
+	if !repair {
+		report, _, err := inspect(db.WithContext(ctx))
+		return report, err
+	}
+
+	var report *IntegrityReport
+	err := withTreeLock(ctx, db, func(tx *gorm.DB) error {
+		var repairs []Department
+		var err error
+		if report, repairs, err = inspect(tx); err != nil {
+			return err
+		}
+
+		// Сначала во временную область, чтобы не нарушать уникальный индекс
+		for _, dept := range repairs {
+			if err := tx.Model(&Department{}).Where("id = ?", dept.Id).
+				Update("name_scope", "tmp:"+strconv.FormatUint(uint64(dept.Id), 10)).Error; err != nil {
+				return err
+			}
+		}
+		for _, dept := range repairs {
+			err := tx.Model(&Department{}).Where("id = ?", dept.Id).
+				Updates(map[string]interface{}{
+					"parent_id":  dept.ParentId,
+					"sort_order": dept.SortOrder,
+					"name":       dept.Name,
+					"name_key":   dept.NameKey,
+					"name_scope": dept.NameScope,
+					"version":    gorm.Expr("version + 1"),
+				}).Error
+			if err != nil {
+				return err
+			}
+		}
+		report.MarkRepaired()
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return report, nil
+}
@@ -0,0 +1,58 @@
+package models
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+)
+
+// Ключи advisory lock: изменения структуры подразделений и лидер планировщика
+const (
+	treeLockKey      int64 = 0x6f72675f74726565
+	schedulerLockKey int64 = 0x7363686564756c65
+)
+
+// withTreeLock выполняет fn в транзакции, сериализуя изменения дерева.
+// На Postgres берется pg_advisory_xact_lock, который снимается при COMMIT/ROLLBACK;
+// SQLite работает через одно соединение, поэтому транзакции и так идут по очереди
+func withTreeLock(ctx context.Context, db *gorm.DB, fn func(tx *gorm.DB) error) error {
+	return db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if tx.Dialector.Name() == "postgres" {
+			if err := tx.Exec("SELECT pg_advisory_xact_lock(?)", treeLockKey).Error; err != nil {
+				return err
+			}
+		}
+		return fn(tx)
+	})
+}
+
+// lockEmployee блокирует строку сотрудника до конца транзакции tx: проверки по его
+// совмещениям не расходятся с записью. SQLite работает через одно соединение
+func lockEmployee(tx *gorm.DB, id uint) error {
+	if tx.Dialector.Name() != "postgres" {
+		return nil
+	}
+	return tx.Exec("SELECT id FROM employees WHERE id = ? FOR UPDATE", id).Error
+}
+
+// withLeaderLock выполняет fn, только если этот экземпляр стал лидером.
+// На Postgres лидер держит сессионный pg_try_advisory_lock на отдельном соединении
+// до конца fn, остальные экземпляры пропускают проход. SQLite обслуживает один процесс
+func withLeaderLock(ctx context.Context, db *gorm.DB, fn func(conn *gorm.DB) error) error {
+	db = db.WithContext(ctx)
+	if db.Dialector.Name() != "postgres" {
+		return fn(db)
+	}
+	return db.Connection(func(conn *gorm.DB) error {
+		var leader bool
+		if err := conn.Raw("SELECT pg_try_advisory_lock(?)", schedulerLockKey).Scan(&leader).Error; err != nil {
+			return err
+		}
+		if !leader {
+			return nil
+		}
+		// Снимаем даже при отмене ctx, иначе блокировка уйдет в пул вместе с соединением
+		defer conn.WithContext(context.Background()).Exec("SELECT pg_advisory_unlock(?)", schedulerLockKey)
+		return fn(conn)
+	})
+}
@@ -0,0 +1,298 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+// Стратегии при совпадении имен дочерних подразделений
+const (
+	MergeFail      = "fail"   // отменить слияние
+	MergeSuffix    = "suffix" // переименовать переносимое: "Имя (2)"
+	MergeRecursive = "merge"  // слить одноименные подразделения рекурсивно
+)
+
+// Итог слияния
+type MergeSummary struct {
+	SourceID         uint                `json:"source_id"`
+	TargetID         uint                `json:"target_id"`
+	Strategy         string              `json:"strategy"`
+	EmployeesMoved   int64               `json:"employees_moved"`
+	DepartmentsMoved []uint              `json:"departments_moved"`
+	Renamed          []RenamedDepartment `json:"renamed"`
+	Merged           []MergedDepartment  `json:"merged"`
+}
+
+// NewMergeSummary пустой итог; списки не nil, чтобы в JSON были []
+func NewMergeSummary(sourceID, targetID uint, strategy string) *MergeSummary {
+	return &MergeSummary{
+		SourceID:         sourceID,
+		TargetID:         targetID,
+		Strategy:         strategy,
+		DepartmentsMoved: []uint{},
+		Renamed:          []RenamedDepartment{},
+		Merged:           []MergedDepartment{},
+	}
+}
+
+// Подразделение, переименованное при переносе
+type RenamedDepartment struct {
+	ID   uint   `json:"id"`
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// Пара подразделений, слитых рекурсивно
+type MergedDepartment struct {
+	SourceID uint `json:"source_id"`
+	TargetID uint `json:"target_id"`
+}
+
+// Сколько суффиксов перебирать до отказа
+const maxMergeSuffix = 100
+
+// MergeDepartment переносит сотрудников и дочерние подразделения source в target
+// и удаляет source; все в одной транзакции. version 0 - без проверки версии source
+func MergeDepartment(ctx context.Context, db *gorm.DB, policy UniquenessPolicy, sourceID, targetID uint, version int, strategy string) (*MergeSummary, error) {
+	if strategy == "" {
+		strategy = MergeFail
+	}
+	if strategy != MergeFail && strategy != MergeSuffix && strategy != MergeRecursive {
+		return nil, ErrInvalidMergeStrategy
+	}
+	if sourceID == targetID {
+		return nil, ErrMergeIntoSelf
+	}
+
+	summary := NewMergeSummary(sourceID, targetID, strategy)
+	err := withTreeLock(ctx, db, func(tx *gorm.DB) error {
+		// Проверка существования
+		var source Department
+		if err := tx.First(&source, sourceID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrDepartmentNotFound
+			}
+			return err
+		}
+		if version != 0 && source.Version != version {
+			return ErrVersionMismatch
+		}
+
+		var target Department
+		if err := tx.First(&target, targetID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrTargetNotFound
+			}
+			return err
+		}
+
+		// Нельзя сливать в собственного потомка
+		var childIDs []uint
+		if err := getChildIDs(tx, sourceID, &childIDs); err != nil {
+			return err
+		}
+		for _, id := range childIDs {
+			if id == targetID {
+				return ErrMergeIntoDescendant
+			}
+		}
+
+		return mergeInto(tx, policy, &source, &target, summary)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return summary, nil
+}
+
+// mergeInto переносит содержимое source в target и удаляет source
+func mergeInto(tx *gorm.DB, policy UniquenessPolicy, source, target *Department, summary *MergeSummary) error {
+	// Сотрудники
+	moved, err := reassignEmployees(tx, target.Id, "department_id = ?", source.Id)
+	if err != nil {
+		return err
+	}
+	summary.EmployeesMoved += moved
+
+	// Совмещения, планы численности и открытые вакансии
+	if err := moveSecondary(tx, source.Id, target.Id); err != nil {
+		return err
+	}
+	if err := moveStaffing(tx, source.Id, target.Id); err != nil {
+		return err
+	}
+
+	// Дочерние подразделения
+	var children []Department
+	if err := tx.Where("parent_id = ?", source.Id).Order("sort_order, id").Find(&children).Error; err != nil {
+		return err
+	}
+
+	for i := range children {
+		child := &children[i]
+
+		// Совпадение ищется в области уникальности, которую подразделение получит у target:
+		// при branch и global это не только соседи
+		subtree := []uint{child.Id}
+		if err := getChildIDs(tx, child.Id, &subtree); err != nil {
+			return err
+		}
+		key, scope, err := nameScope(tx, policy, child.Name, &target.Id)
+		if err != nil {
+			return err
+		}
+		same, err := nameOwner(tx, key, scope, subtree)
+		if err != nil {
+			return err
+		}
+		if same == nil {
+			// Совпадения нет, просто переносим
+			if err := resolveSubtreeNames(tx, policy, child, target.Id, summary); err != nil {
+				return err
+			}
+			if _, err := updateDepartment(tx, policy, child.Id, 0, &DepartmentRequest{ParentID: &target.Id}); err != nil {
+				return err
+			}
+			summary.DepartmentsMoved = append(summary.DepartmentsMoved, child.Id)
+			continue
+		}
+
+		switch summary.Strategy {
+		case MergeSuffix:
+			name, err := freeSuffixName(tx, policy, child.Name, &target.Id)
+			if err != nil {
+				return err
+			}
+			if err := resolveSubtreeNames(tx, policy, child, target.Id, summary); err != nil {
+				return err
+			}
+			if _, err := updateDepartment(tx, policy, child.Id, 0, &DepartmentRequest{Name: name, ParentID: &target.Id}); err != nil {
+				return err
+			}
+			summary.DepartmentsMoved = append(summary.DepartmentsMoved, child.Id)
+			summary.Renamed = append(summary.Renamed, RenamedDepartment{ID: child.Id, From: child.Name, To: name})
+
+		case MergeRecursive:
+			if err := mergeInto(tx, policy, child, same, summary); err != nil {
+				return err
+			}
+			summary.Merged = append(summary.Merged, MergedDepartment{SourceID: child.Id, TargetID: same.Id})
+
+		default:
+			return fmt.Errorf("%w: %q", ErrMergeConflict, child.Name)
+		}
+	}
+
+	// Источник пуст, удаляем
+	return deleteVersioned(tx, source)
+}
+
+// resolveSubtreeNames при политике branch: поддерево child переходит в ветку targetID
+// целиком, совпадения его потомков с ней решаются по стратегии до переноса
+func resolveSubtreeNames(tx *gorm.DB, policy UniquenessPolicy, child *Department, targetID uint, summary *MergeSummary) error {
+	if policy != UniqueInBranch {
+		return nil
+	}
+	_, scope, err := nameScope(tx, policy, child.Name, &targetID)
+	if err != nil {
+		return err
+	}
+
+	for {
+		subtree := []uint{child.Id}
+		if err := getChildIDs(tx, child.Id, &subtree); err != nil {
+			return err
+		}
+		var descendants []Department
+		if err := tx.Where("id IN ?", subtree[1:]).Find(&descendants).Error; err != nil {
+			return err
+		}
+		byID := make(map[uint]Department, len(descendants))
+		for _, dept := range descendants {
+			byID[dept.Id] = dept
+		}
+
+		// Родители раньше детей; после слияния поддерево меняется, проход начинается заново
+		merged := false
+		for _, id := range subtree[1:] {
+			dept := byID[id]
+			same, err := nameOwner(tx, dept.NameKey, scope, subtree)
+			if err != nil {
+				return err
+			}
+			if same == nil {
+				continue
+			}
+
+			switch summary.Strategy {
+			case MergeSuffix:
+				// Имя должно быть свободно и на старом месте, и в ветке target
+				name, err := freeSuffixName(tx, policy, dept.Name, dept.ParentId, &targetID)
+				if err != nil {
+					return err
+				}
+				if _, err := updateDepartment(tx, policy, dept.Id, 0, &DepartmentRequest{Name: name}); err != nil {
+					return err
+				}
+				summary.Renamed = append(summary.Renamed, RenamedDepartment{ID: dept.Id, From: dept.Name, To: name})
+
+			case MergeRecursive:
+				if err := mergeInto(tx, policy, &dept, same, summary); err != nil {
+					return err
+				}
+				summary.Merged = append(summary.Merged, MergedDepartment{SourceID: dept.Id, TargetID: same.Id})
+				merged = true
+
+			default:
+				return fmt.Errorf("%w: %q", ErrMergeConflict, dept.Name)
+			}
+			if merged {
+				break
+			}
+		}
+		if !merged {
+			return nil
+		}
+	}
+}
+
+// Подразделение с ключом key в области scope, кроме exclude; nil - имя свободно
+func nameOwner(tx *gorm.DB, key, scope string, exclude []uint) (*Department, error) {
+	var same []Department
+	if err := tx.Where("name_scope = ? AND name_key = ? AND id NOT IN ?", scope, key, exclude).Limit(1).Find(&same).Error; err != nil {
+		return nil, err
+	}
+	if len(same) == 0 {
+		return nil, nil
+	}
+	return &same[0], nil
+}
+
+// Первое свободное имя вида "Имя (N)" под каждым из parentIDs (nil - среди корней)
+func freeSuffixName(tx *gorm.DB, policy UniquenessPolicy, name string, parentIDs ...*uint) (string, error) {
+	for n := 2; n <= maxMergeSuffix; n++ {
+		candidate := fmt.Sprintf("%s (%d)", name, n)
+		if len(candidate) > 200 {
+			break
+		}
+		free := true
+		for _, parentID := range parentIDs {
+			key, scope, err := nameScope(tx, policy, candidate, parentID)
+			if err != nil {
+				return "", err
+			}
+			taken, err := nameTaken(tx, key, scope)
+			if err != nil {
+				return "", err
+			}
+			free = free && !taken
+		}
+		if free {
+			return candidate, nil
+		}
+	}
+	return "", fmt.Errorf("%w: no free name for %q", ErrMergeConflict, name)
+}
@@ -0,0 +1,140 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"slices"
+
+	"gorm.io/gorm"
+)
+
+// Полный новый порядок дочерних подразделений
+type ReorderRequest struct {
+	IDs []uint `json:"ids"`
+}
+
+// ReorderChildren задает порядок дочерних подразделений id. В ids должны быть
+// все дочерние ровно по одному разу; меняется версия родителя
+func ReorderChildren(ctx context.Context, db *gorm.DB, id uint, version int, req *ReorderRequest) (*DepartmentResponse, error) {
+	err := withTreeLock(ctx, db, func(tx *gorm.DB) error {
+		var parent Department
+		if err := tx.First(&parent, id).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrDepartmentNotFound
+			}
+			return err
+		}
+		if version != 0 && parent.Version != version {
+			return ErrVersionMismatch
+		}
+
+		children, err := childrenOf(tx, &id)
+		if err != nil {
+			return err
+		}
+		if !sameIDSet(children, req.IDs) {
+			return ErrInvalidChildOrder
+		}
+		if err := renumber(tx, children, req.IDs); err != nil {
+			return err
+		}
+
+		// Порядок детей - часть представления родителя
+		result := tx.Model(&Department{}).
+			Where("id = ? AND version = ?", id, parent.Version).
+			Update("version", gorm.Expr("version + 1"))
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrVersionMismatch
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return (&Department{}).GetWithTree(ctx, db, id, 1, nil)
+}
+
+// Дочерние подразделения parentID (nil - корни) в порядке вывода
+func childrenOf(db *gorm.DB, parentID *uint) ([]Department, error) {
+	query := db.Select("id", "sort_order").Order("sort_order, id")
+	if parentID == nil {
+		query = query.Where("parent_id IS NULL")
+	} else {
+		query = query.Where("parent_id = ?", *parentID)
+	}
+
+	var children []Department
+	err := query.Find(&children).Error
+	return children, err
+}
+
+// Следующий sort_order в конце списка детей parentID
+func nextSortOrder(db *gorm.DB, parentID *uint) (int, error) {
+	children, err := childrenOf(db, parentID)
+	if err != nil || len(children) == 0 {
+		return 0, err
+	}
+	return children[len(children)-1].SortOrder + 1, nil
+}
+
+// placeAt ставит подразделение на позицию position среди соседей (с нуля; больше
+// числа соседей - в конец) и нумерует соседей подряд. Версии соседей не меняются
+func placeAt(db *gorm.DB, department *Department, position int) error {
+	siblings, err := childrenOf(db, department.ParentId)
+	if err != nil {
+		return err
+	}
+
+	ids := make([]uint, 0, len(siblings))
+	for _, sibling := range siblings {
+		if sibling.Id != department.Id {
+			ids = append(ids, sibling.Id)
+		}
+	}
+	position = min(position, len(ids))
+	ids = slices.Insert(ids, position, department.Id)
+
+	if err := renumber(db, siblings, ids); err != nil {
+		return err
+	}
+	department.SortOrder = position
+	return nil
+}
+
+// Запись sort_order по порядку ids только там, где он изменился
+func renumber(db *gorm.DB, current []Department, ids []uint) error {
+	orders := make(map[uint]int, len(current))
+	for _, dept := range current {
+		orders[dept.Id] = dept.SortOrder
+	}
+	for i, id := range ids {
+		if order, ok := orders[id]; ok && order == i {
+			continue
+		}
+		if err := db.Model(&Department{}).Where("id = ?", id).Update("sort_order", i).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// В ids ровно те же подразделения, что и в children, без повторов
+func sameIDSet(children []Department, ids []uint) bool {
+	if len(ids) != len(children) {
+		return false
+	}
+	pending := make(map[uint]bool, len(children))
+	for _, child := range children {
+		pending[child.Id] = true
+	}
+	for _, id := range ids {
+		if !pending[id] {
+			return false
+		}
+		delete(pending, id)
+	}
+	return true
+}
@@ -0,0 +1,441 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Статусы плана
+const (
+	PlanDraft   = "draft"
+	PlanApplied = "applied"
+)
+
+// Операции плана
+const (
+	OpCreate   = "create"
+	OpMove     = "move"
+	OpRename   = "rename"
+	OpDelete   = "delete"
+	OpTransfer = "transfer"
+)
+
+// План реорганизации: черновик операций над деревом
+type Plan struct {
+	ID               uint            `json:"id" gorm:"primaryKey"`
+	Name             string          `json:"name" gorm:"column:name;not null;size:200"`
+	Status           string          `json:"status" gorm:"column:status;not null;size:20"`
+	Operations       []PlanOperation `json:"operations" gorm:"column:operations;serializer:json;not null"`
+	Snapshot         map[uint]int    `json:"snapshot" gorm:"column:snapshot;serializer:json;not null"`
+	EmployeeSnapshot map[uint]int    `json:"employee_snapshot" gorm:"column:employee_snapshot;serializer:json;not null"`
+	CreatedAt        time.Time       `json:"created_at" gorm:"column:created_at"`
+	AppliedAt        *time.Time      `json:"applied_at" gorm:"column:applied_at"`
+	Version          int             `json:"version" gorm:"column:version;not null;default:1"`
+}
+
+// Операция плана. Подразделения задаются id (существующие) или ref,
+// который create присваивает новому подразделению
+type PlanOperation struct {
+	Op              string         `json:"op"`
+	Ref             string         `json:"ref,omitempty"`
+	DepartmentID    *uint          `json:"department_id,omitempty"`
+	DepartmentRef   string         `json:"department_ref,omitempty"`
+	ParentID        *uint          `json:"parent_id,omitempty"`
+	ParentRef       string         `json:"parent_ref,omitempty"`
+	Name            string         `json:"name,omitempty"`
+	Mode            string         `json:"mode,omitempty"`
+	EmployeeIDs     []uint         `json:"employee_ids,omitempty"`
+	ToDepartmentID  *uint          `json:"to_department_id,omitempty"`
+	ToDepartmentRef string         `json:"to_department_ref,omitempty"`
+	Attributes      map[string]any `json:"attributes,omitempty"` // атрибуты нового подразделения для create
+	Type            string         `json:"type,omitempty"`       // тип нового подразделения для create
+}
+
+// Запрос на создание плана
+type PlanRequest struct {
+	Name       string          `json:"name"`
+	Operations []PlanOperation `json:"operations"`
+}
+
+// Имя для таблицы
+func (Plan) TableName() string {
+	return "reorg_plans"
+}
+
+// Валидация
+func (p *PlanRequest) Validate() error {
+	p.Name = strings.TrimSpace(p.Name)
+	if p.Name == "" {
+		return ErrPlanNameEmpty
+	}
+	if len(p.Name) > 200 {
+		return ErrPlanNameTooLong
+	}
+	return nil
+}
+
+// DepartmentIDs существующие подразделения, на которые ссылаются операции
+func (op *PlanOperation) DepartmentIDs() []uint {
+	var ids []uint
+	for _, id := range []*uint{op.DepartmentID, op.ParentID, op.ToDepartmentID} {
+		if id != nil {
+			ids = append(ids, *id)
+		}
+	}
+	return ids
+}
+
+// Текущие версии подразделений и сотрудников
+type PlanVersions struct {
+	Department func(id uint) (int, bool)
+	Employee   func(id uint) (int, bool)
+}
+
+// RecordSnapshot запоминает версии подразделений и сотрудников, на которые
+// ссылаются ops; уже записанные версии не меняются
+func (p *Plan) RecordSnapshot(ops []PlanOperation, versions PlanVersions) {
+	if p.Snapshot == nil {
+		p.Snapshot = make(map[uint]int)
+	}
+	if p.EmployeeSnapshot == nil {
+		p.EmployeeSnapshot = make(map[uint]int)
+	}
+	for i := range ops {
+		recordVersions(p.Snapshot, ops[i].DepartmentIDs(), versions.Department)
+		recordVersions(p.EmployeeSnapshot, ops[i].EmployeeIDs, versions.Employee)
+	}
+}
+
+func recordVersions(snapshot map[uint]int, ids []uint, versionOf func(id uint) (int, bool)) {
+	for _, id := range ids {
+		if _, ok := snapshot[id]; ok {
+			continue
+		}
+		if version, ok := versionOf(id); ok {
+			snapshot[id] = version
+		}
+	}
+}
+
+// Stale изменилось ли что-то из снимка
+func (p *Plan) Stale(versions PlanVersions) bool {
+	return changedSince(p.Snapshot, versions.Department) || changedSince(p.EmployeeSnapshot, versions.Employee)
+}
+
+func changedSince(snapshot map[uint]int, versionOf func(id uint) (int, bool)) bool {
+	for id, version := range snapshot {
+		if current, ok := versionOf(id); !ok || current != version {
+			return true
+		}
+	}
+	return false
+}
+
+// CreatePlan создает черновик; операции сразу проверяются
+func CreatePlan(ctx context.Context, db *gorm.DB, policy UniquenessPolicy, req *PlanRequest) (*Plan, error) {
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+
+	plan := &Plan{
+		Name:       req.Name,
+		Status:     PlanDraft,
+		Operations: []PlanOperation{},
+		CreatedAt:  time.Now(),
+		Version:    1,
+	}
+	if req.Operations != nil {
+		plan.Operations = req.Operations
+	}
+
+	err := db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		snapshot, versions, err := loadPlanSnapshot(tx)
+		if err != nil {
+			return err
+		}
+		if _, err := CheckPlan(policy, snapshot, plan.Operations); err != nil {
+			return err
+		}
+		plan.RecordSnapshot(plan.Operations, versions)
+		return tx.Create(plan).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return plan, nil
+}
+
+// GetPlan план по id
+func GetPlan(ctx context.Context, db *gorm.DB, id uint) (*Plan, error) {
+	var plan Plan
+	if err := db.WithContext(ctx).First(&plan, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrPlanNotFound
+		}
+		return nil, err
+	}
+	return &plan, nil
+}
+
+// ListPlans все планы, новые первыми
+func ListPlans(ctx context.Context, db *gorm.DB) ([]Plan, error) {
+	var plans []Plan
+	err := db.WithContext(ctx).Order("id DESC").Find(&plans).Error
+	return plans, err
+}
+
+// AddPlanOperations добавляет операции в черновик; план целиком проверяется заново
+func AddPlanOperations(ctx context.Context, db *gorm.DB, policy UniquenessPolicy, id uint, version int, ops []PlanOperation) (*Plan, error) {
+	var plan *Plan
+	err := db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var err error
+		if plan, err = draftPlan(tx, id, version); err != nil {
+			return err
+		}
+
+		snapshot, versions, err := loadPlanSnapshot(tx)
+		if err != nil {
+			return err
+		}
+		operations := append(plan.Operations, ops...)
+		if _, err := CheckPlan(policy, snapshot, operations); err != nil {
+			return err
+		}
+		plan.Operations = operations
+		plan.RecordSnapshot(ops, versions)
+
+		return savePlan(tx, plan)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return plan, nil
+}
+
+// DeletePlan удаляет план
+func DeletePlan(ctx context.Context, db *gorm.DB, id uint, version int) error {
+	return db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		plan, err := GetPlan(ctx, tx, id)
+		if err != nil {
+			return err
+		}
+		if version != 0 && plan.Version != version {
+			return ErrVersionMismatch
+		}
+		result := tx.Where("version = ?", plan.Version).Delete(plan)
+		if result.Error == nil && result.RowsAffected == 0 {
+			return ErrVersionMismatch
+		}
+		return result.Error
+	})
+}
+
+// DiffPlan изменения дерева, которые внесет план, относительно текущего состояния.
+// Для примененного плана сравнивать не с чем: его операции уже в дереве
+func DiffPlan(ctx context.Context, db *gorm.DB, policy UniquenessPolicy, id uint) (*PlanDiff, error) {
+	db = db.WithContext(ctx)
+
+	plan, err := GetPlan(ctx, db, id)
+	if err != nil {
+		return nil, err
+	}
+	if plan.Status != PlanDraft {
+		return nil, ErrPlanApplied
+	}
+	snapshot, _, err := loadPlanSnapshot(db)
+	if err != nil {
+		return nil, err
+	}
+	return CheckPlan(policy, snapshot, plan.Operations)
+}
+
+// ApplyPlan выполняет все операции плана в одной транзакции
+func ApplyPlan(ctx context.Context, db *gorm.DB, policy UniquenessPolicy, id uint, version int) (*Plan, error) {
+	var plan *Plan
+	err := withTreeLock(ctx, db, func(tx *gorm.DB) error {
+		var err error
+		if plan, err = draftPlan(tx, id, version); err != nil {
+			return err
+		}
+
+		// Проверка снимка и плана целиком до первой записи
+		snapshot, versions, err := loadPlanSnapshot(tx)
+		if err != nil {
+			return err
+		}
+		if plan.Stale(versions) {
+			return ErrPlanStale
+		}
+		if _, err := CheckPlan(policy, snapshot, plan.Operations); err != nil {
+			return err
+		}
+
+		// Выполнение через обычные операции над деревом
+		refs := make(map[string]uint)
+		resolve := func(id *uint, ref string) *uint {
+			if id != nil || ref == "" {
+				return id
+			}
+			realID := refs[ref]
+			return &realID
+		}
+		for i := range plan.Operations {
+			if err := applyPlanOperation(ctx, tx, policy, &plan.Operations[i], refs, resolve); err != nil {
+				return fmt.Errorf("operation %d (%s): %w", i, plan.Operations[i].Op, err)
+			}
+		}
+
+		now := time.Now()
+		plan.Status = PlanApplied
+		plan.AppliedAt = &now
+		return savePlan(tx, plan)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return plan, nil
+}
+
+func applyPlanOperation(ctx context.Context, tx *gorm.DB, policy UniquenessPolicy, op *PlanOperation, refs map[string]uint, resolve func(*uint, string) *uint) error {
+	switch op.Op {
+	case OpCreate:
+		department, err := createDepartment(tx, policy, &DepartmentRequest{Name: op.Name, ParentID: resolve(op.ParentID, op.ParentRef), Attributes: op.Attributes, Type: &op.Type})
+		if err != nil {
+			return err
+		}
+		if op.Ref != "" {
+			refs[op.Ref] = department.Id
+		}
+		return nil
+
+	case OpMove:
+		_, err := updateDepartment(tx, policy, *resolve(op.DepartmentID, op.DepartmentRef), 0,
+			&DepartmentRequest{ParentID: resolve(op.ParentID, op.ParentRef)})
+		return err
+
+	case OpRename:
+		_, err := updateDepartment(tx, policy, *resolve(op.DepartmentID, op.DepartmentRef), 0,
+			&DepartmentRequest{Name: op.Name})
+		return err
+
+	case OpDelete:
+		return deleteDepartment(ctx, tx, *resolve(op.DepartmentID, op.DepartmentRef), 0, op.Mode,
+			resolve(op.ToDepartmentID, op.ToDepartmentRef))
+
+	case OpTransfer:
+		ids := uniqueIDs(op.EmployeeIDs)
+		var terminated int64
+		if err := tx.Model(&Employee{}).Where("id IN ? AND status = ?", ids, EmployeeTerminated).Count(&terminated).Error; err != nil {
+			return err
+		}
+		if terminated > 0 {
+			return ErrEmployeeTerminated
+		}
+		moved, err := reassignEmployees(tx, *resolve(op.ToDepartmentID, op.ToDepartmentRef), "id IN ?", ids)
+		if err == nil && moved != int64(len(ids)) {
+			return ErrEmployeeNotFound
+		}
+		return err
+
+	default:
+		return fmt.Errorf("%w: unknown op %q", ErrInvalidPlanOperation, op.Op)
+	}
+}
+
+// Черновик с проверкой версии
+func draftPlan(tx *gorm.DB, id uint, version int) (*Plan, error) {
+	var plan Plan
+	if err := tx.First(&plan, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrPlanNotFound
+		}
+		return nil, err
+	}
+	if version != 0 && plan.Version != version {
+		return nil, ErrVersionMismatch
+	}
+	if plan.Status != PlanDraft {
+		return nil, ErrPlanApplied
+	}
+	return &plan, nil
+}
+
+// Сохранение плана, только если версию никто не изменил
+func savePlan(tx *gorm.DB, plan *Plan) error {
+	result := tx.Model(&Plan{ID: plan.ID}).
+		Where("version = ?", plan.Version).
+		Select("operations", "snapshot", "employee_snapshot", "status", "applied_at", "version").
+		Updates(&Plan{
+			Operations:       plan.Operations,
+			Snapshot:         plan.Snapshot,
+			EmployeeSnapshot: plan.EmployeeSnapshot,
+			Status:           plan.Status,
+			AppliedAt:        plan.AppliedAt,
+			Version:          plan.Version + 1,
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrVersionMismatch
+	}
+	plan.Version++
+	return nil
+}
+
+// Текущее дерево и версии подразделений и сотрудников
+func loadPlanSnapshot(db *gorm.DB) (*PlanSnapshot, PlanVersions, error) {
+	var departments []Department
+	if err := db.Select("id", "name", "parent_id", "type", "version").Order("id").Find(&departments).Error; err != nil {
+		return nil, PlanVersions{}, err
+	}
+
+	var employees []Employee
+	if err := db.Select("id", "department_id", "status", "version").Find(&employees).Error; err != nil {
+		return nil, PlanVersions{}, err
+	}
+
+	schema, err := loadAttributeSchema(db, EntityDepartment)
+	if err != nil {
+		return nil, PlanVersions{}, err
+	}
+
+	rules, err := loadHierarchyRules(db)
+	if err != nil {
+		return nil, PlanVersions{}, err
+	}
+
+	snapshot := &PlanSnapshot{
+		Departments: departments,
+		Employees:   make(map[uint]uint, len(employees)),
+		Terminated:  make(map[uint]bool),
+		Attributes:  schema,
+		Rules:       rules.Rules,
+	}
+	employeeVersions := make(map[uint]int, len(employees))
+	for _, emp := range employees {
+		snapshot.Employees[emp.ID] = emp.DepartmentId
+		if emp.Status == EmployeeTerminated {
+			snapshot.Terminated[emp.ID] = true
+		}
+		employeeVersions[emp.ID] = emp.Version
+	}
+
+	departmentVersions := make(map[uint]int, len(departments))
+	for _, dept := range departments {
+		departmentVersions[dept.Id] = dept.Version
+	}
+	return snapshot, PlanVersions{Department: lookupVersion(departmentVersions), Employee: lookupVersion(employeeVersions)}, nil
+}
+
+func lookupVersion(versions map[uint]int) func(id uint) (int, bool) {
+	return func(id uint) (int, bool) {
+		version, ok := versions[id]
+		return version, ok
+	}
+}
@@ -0,0 +1,450 @@
+package models
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Состояние дерева, против которого проверяется план
+type PlanSnapshot struct {
+	Departments []Department
+	Employees   map[uint]uint   // сотрудник -> подразделение
+	Terminated  map[uint]bool   // уволенные сотрудники
+	Attributes  AttributeSchema // атрибуты подразделений для create
+	Rules       HierarchyRules  // правила иерархии
+}
+
+// Итог проверки плана: изменения дерева до и после
+type PlanDiff struct {
+	Departments []DepartmentChange `json:"departments"`
+	Employees   []EmployeeChange   `json:"employees"`
+}
+
+// Изменение подразделения; для новых ID пустой
+type DepartmentChange struct {
+	ID      *uint            `json:"id"`
+	Ref     string           `json:"ref,omitempty"`
+	Changes []string         `json:"changes"`
+	Before  *DepartmentState `json:"before"`
+	After   *DepartmentState `json:"after"`
+}
+
+// Положение подразделения в дереве
+type DepartmentState struct {
+	Name string `json:"name"`
+	Path string `json:"path"`
+}
+
+// Перевод или удаление сотрудника
+type EmployeeChange struct {
+	ID     uint    `json:"id"`
+	From   string  `json:"from"`
+	To     *string `json:"to"`
+	Before uint    `json:"before_department_id"`
+	After  *uint   `json:"after_department_id"`
+}
+
+// Ошибки проверки по операциям плана
+type PlanValidationError struct {
+	Errors []PlanOperationError
+}
+
+// Ошибка одной операции
+type PlanOperationError struct {
+	Index int
+	Op    string
+	Err   error
+}
+
+func (e *PlanValidationError) Error() string {
+	msgs := make([]string, len(e.Errors))
+	for i, opErr := range e.Errors {
+		msgs[i] = fmt.Sprintf("operation %d (%s): %v", opErr.Index, opErr.Op, opErr.Err)
+	}
+	return "plan is invalid: " + strings.Join(msgs, "; ")
+}
+
+func (e *PlanValidationError) Unwrap() error {
+	return ErrPlanInvalid
+}
+
+// Подразделение в симуляции
+type simDepartment struct {
+	id       uint
+	ref      string
+	name     string
+	parentID *uint
+	typ      string
+	isNew    bool
+	deleted  bool
+}
+
+// planSim применяет операции к копии дерева без записи в базу
+type planSim struct {
+	policy      UniquenessPolicy
+	schema      AttributeSchema
+	rules       HierarchyRules
+	departments map[uint]*simDepartment
+	order       []uint
+	employees   map[uint]uint
+	terminated  map[uint]bool
+	removed     map[uint]bool
+	refs        map[string]uint
+	nextID      uint
+}
+
+func newPlanSim(policy UniquenessPolicy, snapshot *PlanSnapshot) *planSim {
+	sim := &planSim{
+		policy:      policy,
+		schema:      snapshot.Attributes,
+		rules:       snapshot.Rules,
+		departments: make(map[uint]*simDepartment, len(snapshot.Departments)),
+		employees:   make(map[uint]uint, len(snapshot.Employees)),
+		terminated:  snapshot.Terminated,
+		removed:     make(map[uint]bool),
+		refs:        make(map[string]uint),
+	}
+	for _, dept := range snapshot.Departments {
+		sim.departments[dept.Id] = &simDepartment{id: dept.Id, name: dept.Name, parentID: copyUint(dept.ParentId), typ: dept.Type}
+		sim.order = append(sim.order, dept.Id)
+		sim.nextID = max(sim.nextID, dept.Id)
+	}
+	for empID, deptID := range snapshot.Employees {
+		sim.employees[empID] = deptID
+	}
+	return sim
+}
+
+// CheckPlan проверяет все операции вместе и возвращает изменения дерева.
+// Операция с ошибкой пропускается, чтобы проверить остальные
+func CheckPlan(policy UniquenessPolicy, snapshot *PlanSnapshot, ops []PlanOperation) (*PlanDiff, error) {
+	before := newPlanSim(policy, snapshot)
+	after := newPlanSim(policy, snapshot)
+
+	var invalid PlanValidationError
+	for i := range ops {
+		if err := after.apply(&ops[i]); err != nil {
+			invalid.Errors = append(invalid.Errors, PlanOperationError{Index: i, Op: ops[i].Op, Err: err})
+		}
+	}
+	if len(invalid.Errors) > 0 {
+		return nil, &invalid
+	}
+	return diffSims(before, after), nil
+}
+
+func (sim *planSim) apply(op *PlanOperation) error {
+	switch op.Op {
+	case OpCreate:
+		req := &DepartmentRequest{Name: op.Name, Attributes: op.Attributes, Type: &op.Type}
+		if err := req.Validate(sim.schema); err != nil {
+			return err
+		}
+		var parentID *uint
+		if op.ParentID != nil || op.ParentRef != "" {
+			id, err := sim.resolve(op.ParentID, op.ParentRef, ErrParentNotFound)
+			if err != nil {
+				return err
+			}
+			parentID = &id
+		}
+		if op.Ref != "" {
+			if _, ok := sim.refs[op.Ref]; ok {
+				return fmt.Errorf("%w: duplicate ref %q", ErrInvalidPlanOperation, op.Ref)
+			}
+		}
+
+		if err := sim.checkRules(HierarchyNode{ParentID: parentID, Name: req.Name, Type: req.TypeName()}); err != nil {
+			return err
+		}
+
+		sim.nextID++
+		dept := &simDepartment{id: sim.nextID, ref: op.Ref, name: req.Name, parentID: parentID, typ: req.TypeName(), isNew: true}
+		sim.departments[dept.id] = dept
+		sim.order = append(sim.order, dept.id)
+		if !sim.namesUnique() {
+			delete(sim.departments, dept.id)
+			sim.order = sim.order[:len(sim.order)-1]
+			return ErrNameExists
+		}
+		if op.Ref != "" {
+			sim.refs[op.Ref] = dept.id
+		}
+		return nil
+
+	case OpMove:
+		id, err := sim.resolve(op.DepartmentID, op.DepartmentRef, ErrDepartmentNotFound)
+		if err != nil {
+			return err
+		}
+		parentID, err := sim.resolve(op.ParentID, op.ParentRef, ErrParentNotFound)
+		if err != nil {
+			return err
+		}
+		if id == parentID {
+			return ErrSelfParent
+		}
+		if sim.inSubtree(parentID, id) {
+			return ErrCycleDetected
+		}
+
+		dept := sim.departments[id]
+		if err := sim.checkRules(HierarchyNode{ID: id, ParentID: &parentID, Name: dept.name, Type: dept.typ}); err != nil {
+			return err
+		}
+		old := dept.parentID
+		dept.parentID = &parentID
+		if !sim.namesUnique() {
+			dept.parentID = old
+			return ErrNameExists
+		}
+		return nil
+
+	case OpRename:
+		id, err := sim.resolve(op.DepartmentID, op.DepartmentRef, ErrDepartmentNotFound)
+		if err != nil {
+			return err
+		}
+		req := &DepartmentRequest{Name: op.Name}
+		if err := req.Validate(nil); err != nil {
+			return err
+		}
+
+		dept := sim.departments[id]
+		if err := sim.checkRules(HierarchyNode{ID: id, ParentID: dept.parentID, Name: req.Name, Type: dept.typ}); err != nil {
+			return err
+		}
+		old := dept.name
+		dept.name = req.Name
+		if !sim.namesUnique() {
+			dept.name = old
+			return ErrNameExists
+		}
+		return nil
+
+	case OpDelete:
+		id, err := sim.resolve(op.DepartmentID, op.DepartmentRef, ErrDepartmentNotFound)
+		if err != nil {
+			return err
+		}
+
+		switch op.Mode {
+		case "cascade":
+		case "reassign":
+			if op.ToDepartmentID == nil && op.ToDepartmentRef == "" {
+				return ErrReassignRequired
+			}
+			to, err := sim.resolve(op.ToDepartmentID, op.ToDepartmentRef, ErrTargetNotFound)
+			if err != nil {
+				return err
+			}
+			if to == id {
+				return ErrReassignToSame
+			}
+			// Переводятся только сотрудники самого подразделения, как в DeleteDepartment
+			for empID, deptID := range sim.employees {
+				if deptID == id && !sim.removed[empID] {
+					sim.employees[empID] = to
+				}
+			}
+		default:
+			return ErrInvalidMode
+		}
+
+		for _, deptID := range sim.order {
+			if sim.inSubtree(deptID, id) {
+				sim.departments[deptID].deleted = true
+			}
+		}
+		for empID, deptID := range sim.employees {
+			if sim.departments[deptID].deleted {
+				sim.removed[empID] = true
+			}
+		}
+		return nil
+
+	case OpTransfer:
+		if len(op.EmployeeIDs) == 0 {
+			return fmt.Errorf("%w: employee_ids is required", ErrInvalidPlanOperation)
+		}
+		to, err := sim.resolve(op.ToDepartmentID, op.ToDepartmentRef, ErrTargetNotFound)
+		if err != nil {
+			return err
+		}
+		for _, empID := range op.EmployeeIDs {
+			if _, ok := sim.employees[empID]; !ok || sim.removed[empID] {
+				return fmt.Errorf("%w: %d", ErrEmployeeNotFound, empID)
+			}
+			if sim.terminated[empID] {
+				return fmt.Errorf("%w: %d", ErrEmployeeTerminated, empID)
+			}
+		}
+		for _, empID := range op.EmployeeIDs {
+			sim.employees[empID] = to
+		}
+		return nil
+
+	default:
+		return fmt.Errorf("%w: unknown op %q", ErrInvalidPlanOperation, op.Op)
+	}
+}
+
+// resolve подразделение по id (существующее) или ref (созданное в плане)
+func (sim *planSim) resolve(id *uint, ref string, notFound error) (uint, error) {
+	switch {
+	case id != nil && ref != "":
+		return 0, fmt.Errorf("%w: use either id or ref", ErrInvalidPlanOperation)
+	case id != nil:
+		if dept, ok := sim.departments[*id]; ok && !dept.isNew && !dept.deleted {
+			return *id, nil
+		}
+		return 0, notFound
+	case ref != "":
+		if simID, ok := sim.refs[ref]; ok && !sim.departments[simID].deleted {
+			return simID, nil
+		}
+		return 0, fmt.Errorf("%w: ref %q", notFound, ref)
+	default:
+		return 0, fmt.Errorf("%w: department is required", ErrInvalidPlanOperation)
+	}
+}
+
+// Лежит ли id в поддереве rootID (включая сам rootID)
+func (sim *planSim) inSubtree(id, rootID uint) bool {
+	for seen := 0; seen <= len(sim.departments); seen++ {
+		if id == rootID {
+			return true
+		}
+		parentID := sim.departments[id].parentID
+		if parentID == nil {
+			return false
+		}
+		id = *parentID
+	}
+	return false
+}
+
+// Правила иерархии для подразделения после операции
+func (sim *planSim) checkRules(node HierarchyNode) error {
+	var nodes []HierarchyNode
+	for _, id := range sim.order {
+		if dept := sim.departments[id]; !dept.deleted {
+			nodes = append(nodes, HierarchyNode{ID: dept.id, ParentID: dept.parentID, Name: dept.name, Type: dept.typ})
+		}
+	}
+	return sim.rules.CheckChange(nodes, node)
+}
+
+// Пары (область, ключ) живых подразделений различны
+func (sim *planSim) namesUnique() bool {
+	seen := make(map[[2]string]bool, len(sim.departments))
+	for _, id := range sim.order {
+		dept := sim.departments[id]
+		if dept.deleted {
+			continue
+		}
+		name := [2]string{sim.policy.NameScope(dept.parentID, NameKey(sim.root(dept).name)), NameKey(dept.name)}
+		if seen[name] {
+			return false
+		}
+		seen[name] = true
+	}
+	return true
+}
+
+func (sim *planSim) root(dept *simDepartment) *simDepartment {
+	for dept.parentID != nil {
+		dept = sim.departments[*dept.parentID]
+	}
+	return dept
+}
+
+// Путь вида "Root / Sales / East"
+func (sim *planSim) path(dept *simDepartment) string {
+	names := []string{dept.name}
+	for dept.parentID != nil {
+		dept = sim.departments[*dept.parentID]
+		names = append(names, dept.name)
+	}
+	for i, j := 0, len(names)-1; i < j; i, j = i+1, j-1 {
+		names[i], names[j] = names[j], names[i]
+	}
+	return strings.Join(names, " / ")
+}
+
+func (sim *planSim) state(dept *simDepartment) *DepartmentState {
+	return &DepartmentState{Name: dept.name, Path: sim.path(dept)}
+}
+
+// Сравнение состояний до и после
+func diffSims(before, after *planSim) *PlanDiff {
+	diff := &PlanDiff{Departments: []DepartmentChange{}, Employees: []EmployeeChange{}}
+
+	for _, id := range after.order {
+		dept := after.departments[id]
+		switch {
+		case dept.isNew && dept.deleted:
+			continue
+		case dept.isNew:
+			diff.Departments = append(diff.Departments, DepartmentChange{
+				Ref: dept.ref, Changes: []string{OpCreate}, After: after.state(dept),
+			})
+			continue
+		}
+
+		old := before.departments[id]
+		change := DepartmentChange{ID: copyUint(&id), Before: before.state(old)}
+		if dept.deleted {
+			change.Changes = []string{OpDelete}
+		} else {
+			if !sameUint(old.parentID, dept.parentID) {
+				change.Changes = append(change.Changes, OpMove)
+			}
+			if old.name != dept.name {
+				change.Changes = append(change.Changes, OpRename)
+			}
+			change.After = after.state(dept)
+		}
+		if len(change.Changes) > 0 {
+			diff.Departments = append(diff.Departments, change)
+		}
+	}
+
+	for empID, fromID := range before.employees {
+		toID := after.employees[empID]
+		if !after.removed[empID] && toID == fromID {
+			continue
+		}
+		change := EmployeeChange{ID: empID, Before: fromID, From: before.path(before.departments[fromID])}
+		if !after.removed[empID] {
+			to := after.departments[toID]
+			path := after.path(to)
+			change.To = &path
+			if !to.isNew {
+				change.After = copyUint(&toID)
+			}
+		}
+		diff.Employees = append(diff.Employees, change)
+	}
+	slices.SortFunc(diff.Employees, func(a, b EmployeeChange) int {
+		return cmp.Compare(a.ID, b.ID)
+	})
+	return diff
+}
+
+func sameUint(a, b *uint) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return *a == *b
+}
+
+func copyUint(id *uint) *uint {
+	if id == nil {
+		return nil
+	}
+	v := *id
+	return &v
+}
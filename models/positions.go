@@ -0,0 +1,494 @@
+package models
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"gorm.io/gorm"
+)
+
+// Ограничения записи каталога должностей
+const (
+	maxGradeLength     = 50
+	maxJobFamilyLength = 100
+)
+
+// Сокращения в названиях должностей для группировки написаний
+var positionAbbreviations = map[string]string{
+	"sr":    "senior",
+	"snr":   "senior",
+	"jr":    "junior",
+	"jnr":   "junior",
+	"mid":   "middle",
+	"eng":   "engineer",
+	"engr":  "engineer",
+	"dev":   "developer",
+	"mgr":   "manager",
+	"mngr":  "manager",
+	"asst":  "assistant",
+	"assoc": "associate",
+	"dir":   "director",
+	"ст":    "старший",
+	"мл":    "младший",
+	"вед":   "ведущий",
+	"гл":    "главный",
+	"рук":   "руководитель",
+	"нач":   "начальник",
+	"зам":   "заместитель",
+}
+
+// Должность из каталога
+type Position struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	Title     string    `json:"title" gorm:"column:title;not null;size:200"`
+	TitleKey  string    `json:"-" gorm:"column:title_key;not null"`           // PositionKey названия
+	Grade     string    `json:"grade" gorm:"column:grade;not null"`           // грейд или уровень: L3, M2, Senior
+	JobFamily string    `json:"job_family" gorm:"column:job_family;not null"` // профессиональное семейство
+	CreatedAt time.Time `json:"created_at" gorm:"column:created_at"`
+	Version   int       `json:"version" gorm:"column:version;not null;default:1"`
+}
+
+// Имя для таблицы
+func (Position) TableName() string {
+	return "positions"
+}
+
+// Запрос на создание должности
+type PositionRequest struct {
+	Title     string `json:"title"`
+	Grade     string `json:"grade"`
+	JobFamily string `json:"job_family"`
+}
+
+// Изменение должности; при смене названия меняется и текст должности сотрудников
+type PositionUpdate struct {
+	Title     *string `json:"title"`
+	Grade     *string `json:"grade"`
+	JobFamily *string `json:"job_family"`
+}
+
+// Валидация должности, возвращает все ошибки полей сразу
+func (r *PositionRequest) Validate() error {
+	r.Title = strings.TrimSpace(r.Title)
+	r.Grade = strings.TrimSpace(r.Grade)
+	r.JobFamily = strings.TrimSpace(r.JobFamily)
+
+	var errs []error
+	if PositionKey(r.Title) == "" {
+		errs = append(errs, ErrPositionTitleEmpty)
+	} else if len(r.Title) > 200 {
+		errs = append(errs, ErrPositionTitleTooLong)
+	}
+	if len(r.Grade) > maxGradeLength {
+		errs = append(errs, ErrGradeTooLong)
+	}
+	if len(r.JobFamily) > maxJobFamilyLength {
+		errs = append(errs, ErrJobFamilyTooLong)
+	}
+	return errors.Join(errs...)
+}
+
+// WithUpdate запрос с текущими полями должности и изменениями из update
+func (p *Position) WithUpdate(update *PositionUpdate) *PositionRequest {
+	req := &PositionRequest{Title: p.Title, Grade: p.Grade, JobFamily: p.JobFamily}
+	if update.Title != nil {
+		req.Title = *update.Title
+	}
+	if update.Grade != nil {
+		req.Grade = *update.Grade
+	}
+	if update.JobFamily != nil {
+		req.JobFamily = *update.JobFamily
+	}
+	return req
+}
+
+// UsePosition привязка сотрудника к должности каталога; пустой текст должности
+// заполняется названием, заданный должен быть написанием той же должности
+func (e *EmployeeRequest) UsePosition(p *Position) error {
+	return usePosition(&e.Position, p)
+}
+
+func usePosition(text *string, p *Position) error {
+	if strings.TrimSpace(*text) == "" {
+		*text = p.Title
+		return nil
+	}
+	if PositionKey(*text) != p.TitleKey {
+		return ErrPositionMismatch
+	}
+	return nil
+}
+
+// PositionKey ключ для группировки написаний: регистр, пунктуация и сокращения
+// не различаются - "Sr. Engineer" и "senior engineer" дают один ключ
+func PositionKey(title string) string {
+	words := strings.FieldsFunc(NameKey(title), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '+' && r != '#'
+	})
+	for i, word := range words {
+		if full, ok := positionAbbreviations[word]; ok {
+			words[i] = full
+		}
+	}
+	return strings.Join(words, " ")
+}
+
+// Написание должности и число сотрудников с ним
+type PositionVariant struct {
+	Title     string `json:"title" gorm:"column:title"`
+	Employees int    `json:"employees" gorm:"column:employees"`
+}
+
+// Группа написаний одной должности
+type PositionCluster struct {
+	Key        string            `json:"key"`
+	Title      string            `json:"title"` // название записи каталога
+	Variants   []PositionVariant `json:"variants"`
+	Employees  int               `json:"employees"`
+	PositionID uint              `json:"position_id,omitempty"` // существующая запись, 0 - будет создана
+}
+
+// Итог группировки: без apply - только предложение
+type PositionClustering struct {
+	Clusters []PositionCluster `json:"clusters"`
+	Applied  bool              `json:"applied"`
+	Created  int               `json:"created"` // новых записей каталога
+	Linked   int               `json:"linked"`  // сотрудников получили position_id
+}
+
+// ClusterPositions группирует написания по PositionKey и сопоставляет группы
+// с каталогом. Название новой записи - самое частое написание
+func ClusterPositions(variants []PositionVariant, catalog []Position) []PositionCluster {
+	existing := make(map[string]Position, len(catalog))
+	for _, p := range slices.Backward(catalog) {
+		existing[PositionKey(p.Title)] = p // при совпадении ключей - меньший id
+	}
+
+	byKey := make(map[string]*PositionCluster)
+	var keys []string
+	for _, v := range variants {
+		key := PositionKey(v.Title)
+		if key == "" {
+			continue
+		}
+		cluster, ok := byKey[key]
+		if !ok {
+			cluster = &PositionCluster{Key: key}
+			byKey[key] = cluster
+			keys = append(keys, key)
+		}
+		cluster.Variants = append(cluster.Variants, v)
+		cluster.Employees += v.Employees
+	}
+
+	clusters := make([]PositionCluster, 0, len(keys))
+	for _, key := range keys {
+		cluster := byKey[key]
+		slices.SortFunc(cluster.Variants, func(a, b PositionVariant) int {
+			return cmp.Or(cmp.Compare(b.Employees, a.Employees), cmp.Compare(a.Title, b.Title))
+		})
+		cluster.Title = cluster.Variants[0].Title
+		if p, ok := existing[key]; ok {
+			cluster.Title, cluster.PositionID = p.Title, p.ID
+		}
+		clusters = append(clusters, *cluster)
+	}
+	slices.SortFunc(clusters, func(a, b PositionCluster) int {
+		return cmp.Or(cmp.Compare(b.Employees, a.Employees), cmp.Compare(a.Key, b.Key))
+	})
+	return clusters
+}
+
+// Все написания группы
+func (c *PositionCluster) Titles() []string {
+	titles := make([]string, len(c.Variants))
+	for i, v := range c.Variants {
+		titles[i] = v.Title
+	}
+	return titles
+}
+
+// Должность по id; notFound - ошибка, если ее нет
+func findPosition(db *gorm.DB, id uint, notFound error) (*Position, error) {
+	var position Position
+	if err := db.First(&position, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, notFound
+		}
+		return nil, err
+	}
+	return &position, nil
+}
+
+// Свободно ли название, кроме должности exceptID; написания одной должности
+// ("Sr. Engineer" и "Senior Engineer") считаются одним названием
+func positionTitleFree(db *gorm.DB, titleKey string, exceptID uint) error {
+	var count int64
+	if err := db.Model(&Position{}).Where("title_key = ? AND id <> ?", titleKey, exceptID).Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return ErrPositionExists
+	}
+	return nil
+}
+
+// CreatePosition добавляет должность в каталог
+func CreatePosition(ctx context.Context, db *gorm.DB, req *PositionRequest) (*Position, error) {
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+	db = db.WithContext(ctx)
+
+	position := &Position{
+		Title:     req.Title,
+		TitleKey:  PositionKey(req.Title),
+		Grade:     req.Grade,
+		JobFamily: req.JobFamily,
+		CreatedAt: time.Now().UTC(),
+		Version:   1,
+	}
+	if err := positionTitleFree(db, position.TitleKey, 0); err != nil {
+		return nil, err
+	}
+	if err := db.Create(position).Error; err != nil {
+		if isUniqueViolation(err) {
+			return nil, ErrPositionExists
+		}
+		return nil, err
+	}
+	return position, nil
+}
+
+// ListPositions каталог по семейству; пустое - весь
+func ListPositions(ctx context.Context, db *gorm.DB, jobFamily string) ([]Position, error) {
+	query := db.WithContext(ctx).Order("job_family, title, id")
+	if jobFamily != "" {
+		query = query.Where("job_family = ?", jobFamily)
+	}
+
+	var positions []Position
+	err := query.Find(&positions).Error
+	return positions, err
+}
+
+// GetPosition должность по id
+func GetPosition(ctx context.Context, db *gorm.DB, id uint) (*Position, error) {
+	return findPosition(db.WithContext(ctx), id, ErrPositionNotFound)
+}
+
+// UpdatePosition меняет должность; новое название получают и сотрудники с этой должностью
+func UpdatePosition(ctx context.Context, db *gorm.DB, id uint, version int, update *PositionUpdate) (*Position, error) {
+	var position *Position
+	err := db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var err error
+		if position, err = findPosition(tx, id, ErrPositionNotFound); err != nil {
+			return err
+		}
+		if version != 0 && position.Version != version {
+			return ErrVersionMismatch
+		}
+
+		req := position.WithUpdate(update)
+		if err := req.Validate(); err != nil {
+			return err
+		}
+		titleKey := PositionKey(req.Title)
+		if err := positionTitleFree(tx, titleKey, id); err != nil {
+			return err
+		}
+
+		result := tx.Model(&Position{}).
+			Where("id = ? AND version = ?", id, position.Version).
+			Updates(map[string]interface{}{
+				"title":      req.Title,
+				"title_key":  titleKey,
+				"grade":      req.Grade,
+				"job_family": req.JobFamily,
+				"version":    gorm.Expr("version + 1"),
+			})
+		if result.Error != nil {
+			if isUniqueViolation(result.Error) {
+				return ErrPositionExists
+			}
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrVersionMismatch
+		}
+
+		// Новое название - новый период в истории сотрудников
+		if req.Title != position.Title {
+			var employees []Employee
+			if err := tx.Where("position_id = ?", id).Find(&employees).Error; err != nil {
+				return err
+			}
+			err := tx.Model(&Employee{}).
+				Where("position_id = ?", id).
+				Updates(map[string]interface{}{
+					"position": req.Title,
+					"version":  gorm.Expr("version + 1"),
+				}).Error
+			if err != nil {
+				return err
+			}
+			now := time.Now()
+			for i := range employees {
+				employees[i].Position = req.Title
+				if err := recordAssignment(tx, &employees[i], now); err != nil {
+					return err
+				}
+			}
+		}
+
+		position.Title = req.Title
+		position.TitleKey = titleKey
+		position.Grade = req.Grade
+		position.JobFamily = req.JobFamily
+		position.Version++
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return position, nil
+}
+
+// DeletePosition удаляет должность, на которую не ссылается ни один сотрудник
+func DeletePosition(ctx context.Context, db *gorm.DB, id uint, version int) error {
+	return db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		position, err := findPosition(tx, id, ErrPositionNotFound)
+		if err != nil {
+			return err
+		}
+		if version != 0 && position.Version != version {
+			return ErrVersionMismatch
+		}
+
+		var count int64
+		if err := tx.Model(&Employee{}).Where("position_id = ?", id).Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			return ErrPositionInUse
+		}
+
+		result := tx.Where("version = ?", position.Version).Delete(&Position{ID: id})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrVersionMismatch
+		}
+		return nil
+	})
+}
+
+// ClusterPositionTitles группирует текст должностей сотрудников без position_id.
+// С apply создает недостающие записи каталога и привязывает к ним сотрудников
+func ClusterPositionTitles(ctx context.Context, db *gorm.DB, apply bool) (*PositionClustering, error) {
+	var result *PositionClustering
+	err := db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var variants []PositionVariant
+		err := tx.Model(&Employee{}).
+			Select("position AS title, COUNT(*) AS employees").
+			Where("position_id IS NULL").
+			Group("position").
+			Scan(&variants).Error
+		if err != nil {
+			return err
+		}
+		var catalog []Position
+		if err := tx.Order("id").Find(&catalog).Error; err != nil {
+			return err
+		}
+
+		result = &PositionClustering{Clusters: ClusterPositions(variants, catalog), Applied: apply}
+		if !apply {
+			return nil
+		}
+
+		for i := range result.Clusters {
+			cluster := &result.Clusters[i]
+			if cluster.PositionID == 0 {
+				position := &Position{
+					Title:     cluster.Title,
+					TitleKey:  PositionKey(cluster.Title),
+					CreatedAt: time.Now().UTC(),
+					Version:   1,
+				}
+				if err := tx.Create(position).Error; err != nil {
+					return err
+				}
+				cluster.PositionID = position.ID
+				result.Created++
+			}
+
+			linked := tx.Model(&Employee{}).
+				Where("position_id IS NULL AND position IN ?", cluster.Titles()).
+				Updates(map[string]interface{}{
+					"position_id": cluster.PositionID,
+					"version":     gorm.Expr("version + 1"),
+				})
+			if linked.Error != nil {
+				return linked.Error
+			}
+			result.Linked += int(linked.RowsAffected)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// RekeyPositions пересчитывает title_key каталога. Вызывается при запуске: если
+// несколько записей - написания одной должности, возвращает ErrPositionExists
+func RekeyPositions(ctx context.Context, db *gorm.DB) error {
+	return db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var positions []Position
+		if err := tx.Order("id").Find(&positions).Error; err != nil {
+			return err
+		}
+
+		// Считаем новые ключи и ищем конфликты до записи
+		seen := make(map[string]string, len(positions))
+		var changed []Position
+		for _, position := range positions {
+			key := PositionKey(position.Title)
+			if other, ok := seen[key]; ok {
+				return fmt.Errorf("%w: %q and %q", ErrPositionExists, other, position.Title)
+			}
+			seen[key] = position.Title
+
+			if position.TitleKey != key {
+				position.TitleKey = key
+				changed = append(changed, position)
+			}
+		}
+
+		// Временные ключи, чтобы промежуточные состояния не нарушали уникальный индекс
+		for _, position := range changed {
+			if err := tx.Model(&Position{}).Where("id = ?", position.ID).
+				Update("title_key", "tmp:"+strconv.FormatUint(uint64(position.ID), 10)).Error; err != nil {
+				return err
+			}
+		}
+		for _, position := range changed {
+			if err := tx.Model(&Position{}).Where("id = ?", position.ID).
+				Update("title_key", position.TitleKey).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
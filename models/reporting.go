@@ -0,0 +1,286 @@
+package models
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"slices"
+
+	"gorm.io/gorm"
+)
+
+// Нарушения линий подчинения
+const (
+	ReportingOutsideAncestry   = "manager_outside_ancestry"
+	ReportingManagerTerminated = "manager_terminated"
+)
+
+// Смена руководителя; nil - без руководителя
+type ManagerRequest struct {
+	ManagerID *uint `json:"manager_id"`
+}
+
+// Охват управления: подчиненные без уволенных
+type SpanOfControl struct {
+	EmployeeID uint `json:"employee_id"`
+	Direct     int  `json:"direct"` // прямые подчиненные
+	Total      int  `json:"total"`  // все уровни
+	Depth      int  `json:"depth"`  // уровней подчинения
+}
+
+// Сотрудник, чей руководитель нарушает структуру
+type ReportingIssue struct {
+	Type                string `json:"type"`
+	EmployeeID          uint   `json:"employee_id"`
+	FullName            string `json:"full_name"`
+	DepartmentID        uint   `json:"department_id"`
+	ManagerID           uint   `json:"manager_id"`
+	ManagerDepartmentID uint   `json:"manager_department_id"`
+}
+
+// CheckReportingLines проверка руководителей сотрудников employees: руководитель
+// работает и находится в подразделении сотрудника или одном из его предков.
+// managers - руководители по id, parents - родители всех подразделений
+func CheckReportingLines(employees []Employee, managers map[uint]Employee, parents map[uint]*uint) []ReportingIssue {
+	issues := []ReportingIssue{}
+	for _, emp := range employees {
+		if emp.ManagerID == nil || emp.Status == EmployeeTerminated {
+			continue
+		}
+		manager, ok := managers[*emp.ManagerID]
+		if !ok {
+			continue
+		}
+		issue := ReportingIssue{
+			EmployeeID: emp.ID, FullName: emp.FullName, DepartmentID: emp.DepartmentId,
+			ManagerID: manager.ID, ManagerDepartmentID: manager.DepartmentId,
+		}
+
+		if manager.Status == EmployeeTerminated {
+			issue.Type = ReportingManagerTerminated
+			issues = append(issues, issue)
+			continue
+		}
+
+		// Подразделение сотрудника и его предки
+		inAncestry := false
+		seen := map[uint]bool{}
+		for id := &emp.DepartmentId; id != nil && !seen[*id]; id = parents[*id] {
+			if *id == manager.DepartmentId {
+				inAncestry = true
+				break
+			}
+			seen[*id] = true
+		}
+		if !inAncestry {
+			issue.Type = ReportingOutsideAncestry
+			issues = append(issues, issue)
+		}
+	}
+
+	slices.SortFunc(issues, func(a, b ReportingIssue) int { return cmp.Compare(a.EmployeeID, b.EmployeeID) })
+	return issues
+}
+
+// SetManager назначает или снимает руководителя сотрудника
+func SetManager(ctx context.Context, db *gorm.DB, id uint, version int, req *ManagerRequest) (*Employee, error) {
+	var employee *Employee
+	err := withTreeLock(ctx, db, func(tx *gorm.DB) error {
+		var err error
+		if employee, err = employeeForUpdate(tx, id, version); err != nil {
+			return err
+		}
+		if employee.Status == EmployeeTerminated {
+			return ErrEmployeeTerminated
+		}
+		if req.ManagerID != nil {
+			if err := checkManager(tx, id, *req.ManagerID); err != nil {
+				return err
+			}
+		}
+
+		result := tx.Model(&Employee{}).
+			Where("id = ? AND version = ?", id, employee.Version).
+			Updates(map[string]interface{}{
+				"manager_id": req.ManagerID,
+				"version":    gorm.Expr("version + 1"),
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrVersionMismatch
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	employee.ManagerID = req.ManagerID
+	employee.Version++
+	return employee, nil
+}
+
+// DirectReports прямые подчиненные, отобранные фильтром по статусу
+func DirectReports(ctx context.Context, db *gorm.DB, id uint, filter *EmployeeFilter) ([]Employee, error) {
+	db = db.WithContext(ctx)
+
+	if _, err := GetEmployee(ctx, db, id); err != nil {
+		return nil, err
+	}
+	var reports []Employee
+	if err := filter.whereStatus(db.Where("manager_id = ?", id)).Order("full_name, id").Find(&reports).Error; err != nil {
+		return nil, err
+	}
+	return reports, nil
+}
+
+// ReportingChain руководители от непосредственного до верхнего
+func ReportingChain(ctx context.Context, db *gorm.DB, id uint) ([]Employee, error) {
+	db = db.WithContext(ctx)
+
+	current, err := GetEmployee(ctx, db, id)
+	if err != nil {
+		return nil, err
+	}
+	chain := []Employee{}
+	seen := map[uint]bool{id: true}
+	for current.ManagerID != nil && !seen[*current.ManagerID] {
+		if current, err = GetEmployee(ctx, db, *current.ManagerID); err != nil {
+			return nil, err
+		}
+		seen[current.ID] = true
+		chain = append(chain, *current)
+	}
+	return chain, nil
+}
+
+// GetSpanOfControl число подчиненных по уровням
+func GetSpanOfControl(ctx context.Context, db *gorm.DB, id uint) (*SpanOfControl, error) {
+	db = db.WithContext(ctx)
+
+	if _, err := GetEmployee(ctx, db, id); err != nil {
+		return nil, err
+	}
+
+	span := &SpanOfControl{EmployeeID: id}
+	seen := map[uint]bool{id: true}
+	for level := []uint{id}; len(level) > 0; {
+		var found []uint
+		if err := db.Model(&Employee{}).
+			Where("manager_id IN ? AND status <> ?", level, EmployeeTerminated).
+			Pluck("id", &found).Error; err != nil {
+			return nil, err
+		}
+
+		level = level[:0]
+		for _, reportID := range found {
+			if !seen[reportID] {
+				seen[reportID] = true
+				level = append(level, reportID)
+			}
+		}
+		if len(level) == 0 {
+			break
+		}
+		if span.Depth == 0 {
+			span.Direct = len(level)
+		}
+		span.Depth++
+		span.Total += len(level)
+	}
+	return span, nil
+}
+
+// GetReportingIssues отчет о руководителях вне ветки подразделения сотрудника
+func GetReportingIssues(ctx context.Context, db *gorm.DB) ([]ReportingIssue, error) {
+	db = db.WithContext(ctx)
+
+	var employees []Employee
+	if err := db.Where("manager_id IS NOT NULL AND status <> ?", EmployeeTerminated).Find(&employees).Error; err != nil {
+		return nil, err
+	}
+	if len(employees) == 0 {
+		return []ReportingIssue{}, nil
+	}
+
+	managerIDs := make([]uint, len(employees))
+	for i, emp := range employees {
+		managerIDs[i] = *emp.ManagerID
+	}
+	var managerList []Employee
+	if err := db.Select("id", "department_id", "status").Where("id IN ?", uniqueIDs(managerIDs)).Find(&managerList).Error; err != nil {
+		return nil, err
+	}
+	managers := make(map[uint]Employee, len(managerList))
+	for _, m := range managerList {
+		managers[m.ID] = m
+	}
+
+	var departments []Department
+	if err := db.Select("id", "parent_id").Find(&departments).Error; err != nil {
+		return nil, err
+	}
+	parents := make(map[uint]*uint, len(departments))
+	for _, dept := range departments {
+		parents[dept.Id] = dept.ParentId
+	}
+
+	return CheckReportingLines(employees, managers, parents), nil
+}
+
+// Проверки руководителя: не сам сотрудник, существует, работает и не
+// подчиняется сотруднику ни на каком уровне
+func checkManager(tx *gorm.DB, employeeID, managerID uint) error {
+	if employeeID == managerID {
+		return ErrSelfManager
+	}
+
+	manager, err := GetEmployee(tx.Statement.Context, tx, managerID)
+	if err != nil {
+		if errors.Is(err, ErrEmployeeNotFound) {
+			return ErrManagerNotFound
+		}
+		return err
+	}
+	if manager.Status == EmployeeTerminated {
+		return ErrManagerTerminated
+	}
+
+	// Все подчиненные сотрудника
+	var reportIDs []uint
+	if err := getReportIDs(tx, employeeID, &reportIDs); err != nil {
+		return err
+	}
+	if slices.Contains(reportIDs, managerID) {
+		return ErrManagerCycle
+	}
+	return nil
+}
+
+// Собирает ID подчиненных всех уровней
+func getReportIDs(db *gorm.DB, managerID uint, ids *[]uint) error {
+	var reports []uint
+	if err := db.Model(&Employee{}).Where("manager_id = ?", managerID).Pluck("id", &reports).Error; err != nil {
+		return err
+	}
+
+	for _, reportID := range reports {
+		*ids = append(*ids, reportID)
+		if err := getReportIDs(db, reportID, ids); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Прямые подчиненные уволенного остаются без руководителя
+func releaseReports(tx *gorm.DB, managerID uint) error {
+	return tx.Model(&Employee{}).
+		Where("manager_id = ?", managerID).
+		Updates(map[string]interface{}{
+			"manager_id": nil,
+			"version":    gorm.Expr("version + 1"),
+		}).Error
+}
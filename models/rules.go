@@ -0,0 +1,403 @@
+package models
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Нарушаемые правила иерархии
+const (
+	RuleUnknownType    = "unknown_type"
+	RuleTypeNotAllowed = "type_not_allowed"
+	RuleMaxDepth       = "max_depth"
+	RuleMaxChildren    = "max_children"
+	RuleNamePattern    = "name_pattern"
+)
+
+// Тип подразделения: латиница в нижнем регистре, цифры и _
+var departmentTypePattern = regexp.MustCompile(`^[a-z][a-z0-9_]{0,49}$`)
+
+// Правила иерархии подразделений; нулевые ограничения не действуют
+type HierarchyRules struct {
+	MaxDepth    int                           `json:"max_depth"`    // уровней дерева, корень - первый
+	MaxChildren int                           `json:"max_children"` // прямых потомков у любого подразделения
+	Types       map[string]DepartmentTypeRule `json:"types"`        // допустимые типы
+}
+
+// Правила типа подразделения
+type DepartmentTypeRule struct {
+	AllowedChildren []string `json:"allowed_children"`       // типы дочерних; null - любые, [] - без дочерних
+	MaxChildren     int      `json:"max_children,omitempty"` // вместо общего ограничения, 0 - общее
+	NamePattern     string   `json:"name_pattern,omitempty"` // регулярное выражение для всего имени
+}
+
+// Сохраненные правила, одна запись
+type HierarchyRuleSet struct {
+	ID        uint           `json:"-" gorm:"primaryKey"`
+	Rules     HierarchyRules `json:"rules" gorm:"column:rules;serializer:json;not null"`
+	UpdatedAt time.Time      `json:"updated_at" gorm:"column:updated_at"`
+	Version   int            `json:"version" gorm:"column:version;not null;default:1"`
+}
+
+// Имя для таблицы
+func (HierarchyRuleSet) TableName() string {
+	return "hierarchy_rules"
+}
+
+// Подразделение для проверки правил
+type HierarchyNode struct {
+	ID       uint
+	ParentID *uint
+	Name     string
+	Type     string
+}
+
+// Нарушение правила подразделением
+type RuleViolation struct {
+	DepartmentID uint   `json:"department_id"`
+	Rule         string `json:"rule"`
+	Message      string `json:"message"`
+	err          error
+}
+
+func (v *RuleViolation) Error() string {
+	return v.Message
+}
+
+func (v *RuleViolation) Unwrap() error {
+	return v.err
+}
+
+// Empty нет ни одного правила
+func (r *HierarchyRules) Empty() bool {
+	return r.MaxDepth == 0 && r.MaxChildren == 0 && len(r.Types) == 0
+}
+
+// Clone глубокая копия
+func (r HierarchyRules) Clone() HierarchyRules {
+	if r.Types != nil {
+		types := make(map[string]DepartmentTypeRule, len(r.Types))
+		for name, rule := range r.Types {
+			rule.AllowedChildren = slices.Clone(rule.AllowedChildren)
+			types[name] = rule
+		}
+		r.Types = types
+	}
+	return r
+}
+
+// Validate проверка правил, возвращает все ошибки сразу
+func (r *HierarchyRules) Validate() error {
+	var errs []error
+	invalid := func(format string, args ...any) {
+		errs = append(errs, fmt.Errorf("%w: "+format, append([]any{ErrInvalidHierarchyRules}, args...)...))
+	}
+
+	if r.MaxDepth < 0 {
+		invalid("max_depth cannot be negative")
+	}
+	if r.MaxChildren < 0 {
+		invalid("max_children cannot be negative")
+	}
+
+	names := make([]string, 0, len(r.Types))
+	for name := range r.Types {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	for _, name := range names {
+		rule := r.Types[name]
+		if !departmentTypePattern.MatchString(name) {
+			invalid("types.%s: %v", name, ErrInvalidDepartmentType)
+		}
+		if rule.MaxChildren < 0 {
+			invalid("types.%s.max_children cannot be negative", name)
+		}
+		for _, child := range rule.AllowedChildren {
+			if _, ok := r.Types[child]; !ok {
+				invalid("types.%s.allowed_children: type %q is not defined", name, child)
+			}
+		}
+		if len(rule.NamePattern) > 500 {
+			invalid("types.%s.name_pattern too long (max 500)", name)
+		} else if _, err := regexp.Compile(rule.NamePattern); err != nil {
+			invalid("types.%s.name_pattern: %v", name, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// CheckType определен ли тип; пустой - подразделение без типа
+func (r *HierarchyRules) CheckType(typ string) error {
+	if typ == "" {
+		return nil
+	}
+	if !departmentTypePattern.MatchString(typ) {
+		return ErrInvalidDepartmentType
+	}
+	if _, ok := r.Types[typ]; !ok {
+		return &RuleViolation{Rule: RuleUnknownType, Message: fmt.Sprintf("%v: %q", ErrUnknownDepartmentType, typ), err: ErrUnknownDepartmentType}
+	}
+	return nil
+}
+
+// Check все нарушения в дереве, по возрастанию id подразделения
+func (r *HierarchyRules) Check(nodes []HierarchyNode) []RuleViolation {
+	byID := make(map[uint]HierarchyNode, len(nodes))
+	children := make(map[uint]int, len(nodes))
+	for _, node := range nodes {
+		byID[node.ID] = node
+		if node.ParentID != nil {
+			children[*node.ParentID]++
+		}
+	}
+
+	patterns := make(map[string]*regexp.Regexp, len(r.Types))
+	for name, rule := range r.Types {
+		if rule.NamePattern != "" {
+			if re, err := regexp.Compile(`^(?:` + rule.NamePattern + `)$`); err == nil {
+				patterns[name] = re
+			}
+		}
+	}
+
+	// Глубина с запоминанием; защита от цикла - не глубже числа узлов
+	depths := make(map[uint]int, len(nodes))
+	var depthOf func(id uint, limit int) int
+	depthOf = func(id uint, limit int) int {
+		if d, ok := depths[id]; ok {
+			return d
+		}
+		node, ok := byID[id]
+		d := 1
+		if ok && node.ParentID != nil && limit > 0 {
+			d = depthOf(*node.ParentID, limit-1) + 1
+		}
+		depths[id] = d
+		return d
+	}
+
+	sorted := slices.Clone(nodes)
+	slices.SortFunc(sorted, func(a, b HierarchyNode) int { return cmp.Compare(a.ID, b.ID) })
+
+	var violations []RuleViolation
+	add := func(id uint, rule string, err error, format string, args ...any) {
+		violations = append(violations, RuleViolation{
+			DepartmentID: id,
+			Rule:         rule,
+			Message:      fmt.Sprintf("%v: "+format, append([]any{err}, args...)...),
+			err:          err,
+		})
+	}
+
+	for _, node := range sorted {
+		rule, known := r.Types[node.Type]
+		if node.Type != "" && !known {
+			add(node.ID, RuleUnknownType, ErrUnknownDepartmentType, "%q", node.Type)
+		}
+
+		if re, ok := patterns[node.Type]; ok && !re.MatchString(node.Name) {
+			add(node.ID, RuleNamePattern, ErrNamePatternMismatch, "%q does not match %q", node.Name, rule.NamePattern)
+		}
+
+		if node.ParentID != nil {
+			parent := byID[*node.ParentID]
+			if parentRule, ok := r.Types[parent.Type]; ok && parentRule.AllowedChildren != nil &&
+				!slices.Contains(parentRule.AllowedChildren, node.Type) {
+				add(node.ID, RuleTypeNotAllowed, ErrTypeNotAllowed, "%s under %s", typeLabel(node.Type), typeLabel(parent.Type))
+			}
+		}
+
+		if r.MaxDepth > 0 {
+			if depth := depthOf(node.ID, len(nodes)); depth > r.MaxDepth {
+				add(node.ID, RuleMaxDepth, ErrMaxDepthExceeded, "depth %d, max %d", depth, r.MaxDepth)
+			}
+		}
+
+		limit := r.MaxChildren
+		if known && rule.MaxChildren > 0 {
+			limit = rule.MaxChildren
+		}
+		if limit > 0 && children[node.ID] > limit {
+			add(node.ID, RuleMaxChildren, ErrMaxChildrenExceeded, "%d children, max %d", children[node.ID], limit)
+		}
+	}
+	return violations
+}
+
+// CheckChange новые нарушения после замены или добавления node (ID 0 - новое подразделение).
+// Нарушения, которые были в дереве до изменения, не мешают, если изменение их не усилило
+func (r *HierarchyRules) CheckChange(before []HierarchyNode, node HierarchyNode) error {
+	if err := r.CheckType(node.Type); err != nil {
+		return err
+	}
+	if r.Empty() {
+		return nil
+	}
+
+	after := make([]HierarchyNode, 0, len(before)+1)
+	var nextID uint
+	replaced := false
+	for _, n := range before {
+		nextID = max(nextID, n.ID)
+		if node.ID != 0 && n.ID == node.ID {
+			n, replaced = node, true
+		}
+		after = append(after, n)
+	}
+	if !replaced {
+		if node.ID == 0 {
+			node.ID = nextID + 1
+		}
+		after = append(after, node)
+	}
+
+	// Сообщение содержит значения (глубину, число детей, имя), поэтому усиление - тоже новое нарушение
+	type key struct {
+		id      uint
+		message string
+	}
+	known := make(map[key]bool)
+	for _, v := range r.Check(before) {
+		known[key{v.DepartmentID, v.Message}] = true
+	}
+	var errs []error
+	for _, v := range r.Check(after) {
+		if !known[key{v.DepartmentID, v.Message}] {
+			errs = append(errs, &v)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Тип для сообщений
+func typeLabel(typ string) string {
+	if typ == "" {
+		return "untyped"
+	}
+	return fmt.Sprintf("%q", typ)
+}
+
+// Правила из базы; без записи - пустые
+func loadHierarchyRules(db *gorm.DB) (*HierarchyRuleSet, error) {
+	var set HierarchyRuleSet
+	err := db.First(&set, 1).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return &HierarchyRuleSet{ID: 1, Version: 1}, nil
+	}
+	return &set, err
+}
+
+// Все подразделения для проверки правил
+func loadHierarchyNodes(db *gorm.DB) ([]HierarchyNode, error) {
+	var departments []Department
+	if err := db.Select("id", "parent_id", "name", "type").Find(&departments).Error; err != nil {
+		return nil, err
+	}
+	nodes := make([]HierarchyNode, len(departments))
+	for i, dept := range departments {
+		nodes[i] = dept.HierarchyNode()
+	}
+	return nodes, nil
+}
+
+// Подразделения, нарушения которых может изменить правка @id под родителем @parent:
+// цепочки предков нового и прежнего родителя (для глубины), дети нового родителя
+// (для числа детей) и поддерево самого подразделения
+const hierarchyScopeSQL = `WITH RECURSIVE ancestors(id) AS (
+	SELECT id FROM departments
+	WHERE id = @parent OR id = (SELECT parent_id FROM departments WHERE id = @id)
+	UNION
+	SELECT d.parent_id FROM departments d JOIN ancestors a ON d.id = a.id WHERE d.parent_id IS NOT NULL
+), subtree(id) AS (
+	SELECT id FROM departments WHERE id = @id
+	UNION
+	SELECT d.id FROM departments d JOIN subtree s ON d.parent_id = s.id
+)
+SELECT id, parent_id, name, type FROM departments
+WHERE id IN (SELECT id FROM ancestors) OR id IN (SELECT id FROM subtree) OR parent_id = @parent`
+
+// Узлы для проверки изменения node без загрузки всего дерева
+func loadHierarchyScope(db *gorm.DB, node HierarchyNode) ([]HierarchyNode, error) {
+	var departments []Department
+	args := map[string]interface{}{"id": node.ID, "parent": node.ParentID}
+	if err := db.Raw(hierarchyScopeSQL, args).Scan(&departments).Error; err != nil {
+		return nil, err
+	}
+	nodes := make([]HierarchyNode, len(departments))
+	for i, dept := range departments {
+		nodes[i] = dept.HierarchyNode()
+	}
+	return nodes, nil
+}
+
+// Проверка правил для подразделения после изменения
+func checkHierarchy(db *gorm.DB, node HierarchyNode) error {
+	set, err := loadHierarchyRules(db)
+	if err != nil {
+		return err
+	}
+	if set.Rules.Empty() {
+		return set.Rules.CheckType(node.Type)
+	}
+	nodes, err := loadHierarchyScope(db, node)
+	if err != nil {
+		return err
+	}
+	return set.Rules.CheckChange(nodes, node)
+}
+
+// GetHierarchyRules текущие правила
+func GetHierarchyRules(ctx context.Context, db *gorm.DB) (*HierarchyRuleSet, error) {
+	return loadHierarchyRules(db.WithContext(ctx))
+}
+
+// UpdateHierarchyRules заменяет правила; уже нарушающие их подразделения не меняются,
+// их показывает CheckHierarchy
+func UpdateHierarchyRules(ctx context.Context, db *gorm.DB, version int, rules *HierarchyRules) (*HierarchyRuleSet, error) {
+	if err := rules.Validate(); err != nil {
+		return nil, err
+	}
+
+	var set *HierarchyRuleSet
+	err := withTreeLock(ctx, db, func(tx *gorm.DB) error {
+		var err error
+		if set, err = loadHierarchyRules(tx); err != nil {
+			return err
+		}
+		if version != 0 && set.Version != version {
+			return ErrVersionMismatch
+		}
+
+		set.Rules = *rules
+		set.UpdatedAt = time.Now()
+		set.Version++
+		return tx.Save(set).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return set, nil
+}
+
+// CheckHierarchy все нарушения правил в текущем дереве
+func CheckHierarchy(ctx context.Context, db *gorm.DB) ([]RuleViolation, error) {
+	db = db.WithContext(ctx)
+
+	set, err := loadHierarchyRules(db)
+	if err != nil {
+		return nil, err
+	}
+	nodes, err := loadHierarchyNodes(db)
+	if err != nil {
+		return nil, err
+	}
+	return set.Rules.Check(nodes), nil
+}
@@ -0,0 +1,305 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Виды отложенных изменений
+const (
+	ChangeDepartment = "department" // перенос и/или переименование подразделения
+	ChangeTransfer   = "transfer"   // перевод сотрудника
+)
+
+// Статусы отложенных изменений
+const (
+	ChangePending  = "pending"
+	ChangeApplied  = "applied"
+	ChangeCanceled = "canceled"
+	ChangeFailed   = "failed"
+)
+
+// Сколько изменений применяется за один проход планировщика
+const dueChangesBatch = 100
+
+// Изменение, которое вступит в силу в EffectiveAt
+type ScheduledChange struct {
+	ID             uint       `json:"id" gorm:"primaryKey"`
+	Kind           string     `json:"kind" gorm:"column:kind;not null;size:20"`
+	DepartmentID   *uint      `json:"department_id,omitempty" gorm:"column:department_id"`
+	EmployeeID     *uint      `json:"employee_id,omitempty" gorm:"column:employee_id"`
+	Name           string     `json:"name,omitempty" gorm:"column:name;not null;size:200"`
+	ParentID       *uint      `json:"parent_id,omitempty" gorm:"column:parent_id"`
+	Position       *int       `json:"position,omitempty" gorm:"column:position"`
+	ToDepartmentID *uint      `json:"to_department_id,omitempty" gorm:"column:to_department_id"`
+	EffectiveAt    time.Time  `json:"effective_at" gorm:"column:effective_at;not null"`
+	Status         string     `json:"status" gorm:"column:status;not null;size:20"`
+	Error          string     `json:"error,omitempty" gorm:"column:error;not null"`
+	CreatedAt      time.Time  `json:"created_at" gorm:"column:created_at"`
+	AppliedAt      *time.Time `json:"applied_at" gorm:"column:applied_at"`
+	Version        int        `json:"version" gorm:"column:version;not null;default:1"`
+}
+
+// Имя для таблицы
+func (ScheduledChange) TableName() string {
+	return "scheduled_changes"
+}
+
+// Проверка фильтра по статусу; пустой - все статусы
+func ValidChangeStatus(status string) bool {
+	switch status {
+	case "", ChangePending, ChangeApplied, ChangeCanceled, ChangeFailed:
+		return true
+	}
+	return false
+}
+
+// Проверка запроса на отложенное изменение подразделения id.
+// Иерархия и имена проверяются при применении: к тому времени дерево изменится
+func ValidateScheduledDepartmentChange(id uint, req *DepartmentRequest) error {
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Code != nil || req.ExternalIDs != nil || req.Attributes != nil || req.Type != nil {
+		return ErrNotSchedulable
+	}
+	if req.Name == "" && req.ParentID == nil && req.Position == nil {
+		return ErrEmptyChange
+	}
+	if len(req.Name) > 200 {
+		return ErrNameTooLong
+	}
+	if req.Position != nil && *req.Position < 0 {
+		return ErrInvalidPosition
+	}
+	if req.ParentID != nil && *req.ParentID == id {
+		return ErrSelfParent
+	}
+	return nil
+}
+
+// ScheduleDepartmentChange откладывает перенос/переименование до effectiveAt
+func ScheduleDepartmentChange(ctx context.Context, db *gorm.DB, id uint, version int, req *DepartmentRequest, effectiveAt time.Time) (*ScheduledChange, error) {
+	if err := ValidateScheduledDepartmentChange(id, req); err != nil {
+		return nil, err
+	}
+	db = db.WithContext(ctx)
+
+	// Проверка существования и версии
+	var department Department
+	if err := db.First(&department, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrDepartmentNotFound
+		}
+		return nil, err
+	}
+	if version != 0 && department.Version != version {
+		return nil, ErrVersionMismatch
+	}
+	if req.ParentID != nil {
+		if err := db.Select("id").First(&Department{}, *req.ParentID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil, ErrParentNotFound
+			}
+			return nil, err
+		}
+	}
+
+	change := &ScheduledChange{
+		Kind:         ChangeDepartment,
+		DepartmentID: &id,
+		Name:         req.Name,
+		ParentID:     req.ParentID,
+		Position:     req.Position,
+		EffectiveAt:  effectiveAt.UTC(),
+		Status:       ChangePending,
+		CreatedAt:    time.Now().UTC(),
+		Version:      1,
+	}
+	if err := db.Create(change).Error; err != nil {
+		return nil, err
+	}
+	return change, nil
+}
+
+// ScheduleTransfer откладывает перевод сотрудника до effectiveAt
+func ScheduleTransfer(ctx context.Context, db *gorm.DB, employeeID uint, version int, req *TransferRequest, effectiveAt time.Time) (*ScheduledChange, error) {
+	db = db.WithContext(ctx)
+
+	var employee Employee
+	if err := db.First(&employee, employeeID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrEmployeeNotFound
+		}
+		return nil, err
+	}
+	if version != 0 && employee.Version != version {
+		return nil, ErrVersionMismatch
+	}
+	if err := db.Select("id").First(&Department{}, req.DepartmentID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrUnknownDepartment
+		}
+		return nil, err
+	}
+
+	change := &ScheduledChange{
+		Kind:           ChangeTransfer,
+		EmployeeID:     &employeeID,
+		ToDepartmentID: &req.DepartmentID,
+		EffectiveAt:    effectiveAt.UTC(),
+		Status:         ChangePending,
+		CreatedAt:      time.Now().UTC(),
+		Version:        1,
+	}
+	if err := db.Create(change).Error; err != nil {
+		return nil, err
+	}
+	return change, nil
+}
+
+// GetScheduledChange изменение по id
+func GetScheduledChange(ctx context.Context, db *gorm.DB, id uint) (*ScheduledChange, error) {
+	var change ScheduledChange
+	if err := db.WithContext(ctx).First(&change, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrScheduledChangeNotFound
+		}
+		return nil, err
+	}
+	return &change, nil
+}
+
+// ListScheduledChanges изменения в порядке вступления в силу
+func ListScheduledChanges(ctx context.Context, db *gorm.DB, status string) ([]ScheduledChange, error) {
+	if !ValidChangeStatus(status) {
+		return nil, ErrInvalidChangeStatus
+	}
+
+	query := db.WithContext(ctx).Order("effective_at, id")
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	var changes []ScheduledChange
+	err := query.Find(&changes).Error
+	return changes, err
+}
+
+// CancelScheduledChange отменяет еще не примененное изменение
+func CancelScheduledChange(ctx context.Context, db *gorm.DB, id uint, version int) (*ScheduledChange, error) {
+	var change *ScheduledChange
+	err := db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var err error
+		if change, err = GetScheduledChange(ctx, tx, id); err != nil {
+			return err
+		}
+		if version != 0 && change.Version != version {
+			return ErrVersionMismatch
+		}
+		if change.Status != ChangePending {
+			return ErrChangeNotPending
+		}
+
+		// Планировщик мог успеть применить изменение
+		result := tx.Model(&ScheduledChange{}).
+			Where("id = ? AND version = ? AND status = ?", id, change.Version, ChangePending).
+			Updates(map[string]interface{}{
+				"status":  ChangeCanceled,
+				"version": gorm.Expr("version + 1"),
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrChangeNotPending
+		}
+		change.Status = ChangeCanceled
+		change.Version++
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return change, nil
+}
+
+// Изменение отменили или применили, пока до него дошла очередь
+var errChangeSkipped = errors.New("scheduled change is no longer pending")
+
+// ApplyDueChanges применяет изменения с effective_at <= now по порядку, каждое
+// в своей транзакции. Ошибка одного изменения помечает его failed и не останавливает
+// остальные. Работает только на экземпляре-лидере, остальные возвращают 0
+func ApplyDueChanges(ctx context.Context, db *gorm.DB, policy UniquenessPolicy, now time.Time) (int, error) {
+	applied := 0
+	err := withLeaderLock(ctx, db, func(conn *gorm.DB) error {
+		var due []ScheduledChange
+		err := conn.Where("status = ? AND effective_at <= ?", ChangePending, now.UTC()).
+			Order("effective_at, id").
+			Limit(dueChangesBatch).
+			Find(&due).Error
+		if err != nil {
+			return err
+		}
+
+		for i := range due {
+			err := applyScheduledChange(ctx, conn, policy, &due[i], now)
+			switch {
+			case err == nil:
+				applied++
+			case ctx.Err() != nil:
+				return ctx.Err()
+			case errors.Is(err, errChangeSkipped):
+			default:
+				if err := failScheduledChange(conn, &due[i], err); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+	return applied, err
+}
+
+func applyScheduledChange(ctx context.Context, db *gorm.DB, policy UniquenessPolicy, change *ScheduledChange, now time.Time) error {
+	return withTreeLock(ctx, db, func(tx *gorm.DB) error {
+		// Статус меняется первым: отмена после этого уже не пройдет
+		result := tx.Model(&ScheduledChange{}).
+			Where("id = ? AND version = ? AND status = ?", change.ID, change.Version, ChangePending).
+			Updates(map[string]interface{}{
+				"status":     ChangeApplied,
+				"applied_at": now.UTC(),
+				"version":    gorm.Expr("version + 1"),
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errChangeSkipped
+		}
+
+		switch change.Kind {
+		case ChangeDepartment:
+			_, err := updateDepartment(tx, policy, *change.DepartmentID, 0, &DepartmentRequest{Name: change.Name, ParentID: change.ParentID, Position: change.Position})
+			return err
+		case ChangeTransfer:
+			_, err := transferEmployee(tx, *change.EmployeeID, 0, *change.ToDepartmentID)
+			return err
+		default:
+			return errors.New("unknown scheduled change kind " + change.Kind)
+		}
+	})
+}
+
+// Пометка изменения как неудавшегося с текстом ошибки
+func failScheduledChange(db *gorm.DB, change *ScheduledChange, cause error) error {
+	return db.Model(&ScheduledChange{}).
+		Where("id = ? AND version = ? AND status = ?", change.ID, change.Version, ChangePending).
+		Updates(map[string]interface{}{
+			"status":  ChangeFailed,
+			"error":   cause.Error(),
+			"version": gorm.Expr("version + 1"),
+		}).Error
+}
@@ -0,0 +1,454 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Совмещение: работа сотрудника в другом подразделении на часть ставки.
+// Основное место работы остается в employees.department_id
+type SecondaryAssignment struct {
+	ID           uint      `json:"id" gorm:"primaryKey"`
+	EmployeeID   uint      `json:"employee_id" gorm:"column:employee_id;not null"`
+	DepartmentID uint      `json:"department_id" gorm:"column:department_id;not null"`
+	Role         string    `json:"role" gorm:"column:role;not null;size:100"`
+	Allocation   int       `json:"allocation" gorm:"column:allocation;not null"` // процент ставки
+	CreatedAt    time.Time `json:"created_at"`
+	Version      int       `json:"version" gorm:"not null;default:1"`
+}
+
+// Имя для таблицы
+func (SecondaryAssignment) TableName() string {
+	return "secondary_assignments"
+}
+
+// Запрос на совмещение
+type SecondaryAssignmentRequest struct {
+	DepartmentID uint   `json:"department_id"`
+	Role         string `json:"role"`
+	Allocation   int    `json:"allocation"`
+}
+
+// Частичное изменение совмещения; nil - не менять
+type SecondaryAssignmentUpdate struct {
+	Role       *string `json:"role"`
+	Allocation *int    `json:"allocation"`
+}
+
+// Совместитель в ответе подразделения
+type SecondaryMember struct {
+	Employee
+	AssignmentID uint   `json:"assignment_id"`
+	Role         string `json:"role"`
+	Allocation   int    `json:"allocation"`
+}
+
+// Численность: люди и ставки
+type HeadcountFigures struct {
+	Heads          int     `json:"heads"`           // основное место работы
+	SecondaryHeads int     `json:"secondary_heads"` // только по совмещению
+	FTE            float64 `json:"fte"`             // сумма долей ставок
+}
+
+// Численность подразделения: само и вместе с поддеревом
+type Headcount struct {
+	DepartmentID uint             `json:"department_id"`
+	Direct       HeadcountFigures `json:"direct"`
+	Total        HeadcountFigures `json:"total"`
+}
+
+// Доля сотрудника в подразделении; Primary - основное место работы
+type StaffingRow struct {
+	EmployeeID   uint
+	DepartmentID uint
+	Allocation   int
+	Primary      bool
+}
+
+// Валидация совмещения
+func (r *SecondaryAssignmentRequest) Validate() error {
+	r.Role = strings.TrimSpace(r.Role)
+
+	var errs []error
+	if r.Role == "" {
+		errs = append(errs, ErrRoleEmpty)
+	} else if len(r.Role) > 100 {
+		errs = append(errs, ErrRoleTooLong)
+	}
+	if r.Allocation < 1 || r.Allocation > 100 {
+		errs = append(errs, ErrInvalidAllocation)
+	}
+	return errors.Join(errs...)
+}
+
+// Совмещение a с изменениями u
+func (u *SecondaryAssignmentUpdate) Apply(a *SecondaryAssignment) SecondaryAssignmentRequest {
+	req := SecondaryAssignmentRequest{DepartmentID: a.DepartmentID, Role: a.Role, Allocation: a.Allocation}
+	if u.Role != nil {
+		req.Role = *u.Role
+	}
+	if u.Allocation != nil {
+		req.Allocation = *u.Allocation
+	}
+	return req
+}
+
+// Проверка суммы совмещений: other - доли остальных совмещений сотрудника
+func CheckAllocation(other []int, allocation int) error {
+	total := allocation
+	for _, a := range other {
+		total += a
+	}
+	if total > 100 {
+		return ErrAllocationExceeded
+	}
+	return nil
+}
+
+// ComputeHeadcount численность подразделения departmentID по строкам rows;
+// subtree - подразделение и все его потомки. Основному месту работы
+// достается ставка за вычетом совмещений, человек с основным местом работы
+// в пределах выборки в совместителях не учитывается
+func ComputeHeadcount(departmentID uint, subtree []uint, rows []StaffingRow) *Headcount {
+	figures := func(in func(uint) bool) HeadcountFigures {
+		primary := map[uint]bool{}
+		secondary := map[uint]bool{}
+		allocation := 0
+		for _, row := range rows {
+			if !in(row.DepartmentID) {
+				continue
+			}
+			allocation += row.Allocation
+			if row.Primary {
+				primary[row.EmployeeID] = true
+			} else {
+				secondary[row.EmployeeID] = true
+			}
+		}
+		for id := range primary {
+			delete(secondary, id)
+		}
+		return HeadcountFigures{
+			Heads:          len(primary),
+			SecondaryHeads: len(secondary),
+			FTE:            float64(allocation) / 100,
+		}
+	}
+
+	inSubtree := make(map[uint]bool, len(subtree))
+	for _, id := range subtree {
+		inSubtree[id] = true
+	}
+	return &Headcount{
+		DepartmentID: departmentID,
+		Direct:       figures(func(id uint) bool { return id == departmentID }),
+		Total:        figures(func(id uint) bool { return inSubtree[id] }),
+	}
+}
+
+// ListSecondaryAssignments совмещения сотрудника
+func ListSecondaryAssignments(ctx context.Context, db *gorm.DB, employeeID uint) ([]SecondaryAssignment, error) {
+	db = db.WithContext(ctx)
+
+	if _, err := GetEmployee(ctx, db, employeeID); err != nil {
+		return nil, err
+	}
+	var assignments []SecondaryAssignment
+	if err := db.Where("employee_id = ?", employeeID).Order("id").Find(&assignments).Error; err != nil {
+		return nil, err
+	}
+	return assignments, nil
+}
+
+// AddSecondaryAssignment назначает сотрудника по совмещению в другое подразделение
+func AddSecondaryAssignment(ctx context.Context, db *gorm.DB, employeeID uint, req *SecondaryAssignmentRequest) (*SecondaryAssignment, error) {
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+	db = db.WithContext(ctx)
+
+	assignment := &SecondaryAssignment{
+		EmployeeID:   employeeID,
+		DepartmentID: req.DepartmentID,
+		Role:         req.Role,
+		Allocation:   req.Allocation,
+		Version:      1,
+	}
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := checkSecondary(tx, employeeID, req, 0); err != nil {
+			return err
+		}
+		return tx.Create(assignment).Error
+	})
+	if err != nil {
+		if isUniqueViolation(err) {
+			return nil, ErrSecondaryExists
+		}
+		return nil, err
+	}
+	return assignment, nil
+}
+
+// UpdateSecondaryAssignment меняет роль или долю ставки совмещения
+func UpdateSecondaryAssignment(ctx context.Context, db *gorm.DB, employeeID, id uint, version int, update *SecondaryAssignmentUpdate) (*SecondaryAssignment, error) {
+	db = db.WithContext(ctx)
+
+	assignment, err := secondaryForUpdate(db, employeeID, id, version)
+	if err != nil {
+		return nil, err
+	}
+	req := update.Apply(assignment)
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if err := checkSecondary(tx, employeeID, &req, id); err != nil {
+			return err
+		}
+		result := tx.Model(&SecondaryAssignment{}).
+			Where("id = ? AND version = ?", id, assignment.Version).
+			Updates(map[string]interface{}{
+				"role":       req.Role,
+				"allocation": req.Allocation,
+				"version":    gorm.Expr("version + 1"),
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrVersionMismatch
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	assignment.Role = req.Role
+	assignment.Allocation = req.Allocation
+	assignment.Version++
+	return assignment, nil
+}
+
+// RemoveSecondaryAssignment снимает совмещение
+func RemoveSecondaryAssignment(ctx context.Context, db *gorm.DB, employeeID, id uint, version int) error {
+	db = db.WithContext(ctx)
+
+	assignment, err := secondaryForUpdate(db, employeeID, id, version)
+	if err != nil {
+		return err
+	}
+	result := db.Where("id = ? AND version = ?", id, assignment.Version).Delete(&SecondaryAssignment{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrVersionMismatch
+	}
+	return nil
+}
+
+// GetHeadcount численность подразделения по людям и ставкам; уволенные не учитываются
+func GetHeadcount(ctx context.Context, db *gorm.DB, departmentID uint) (*Headcount, error) {
+	db = db.WithContext(ctx)
+
+	// Проверка отдела
+	if err := db.Select("id").First(&Department{}, departmentID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrDepartmentNotFound
+		}
+		return nil, err
+	}
+	subtree := []uint{departmentID}
+	if err := getChildIDs(db, departmentID, &subtree); err != nil {
+		return nil, err
+	}
+
+	// Основные места работы
+	var employees []Employee
+	if err := db.Select("id", "department_id").
+		Where("department_id IN ? AND status <> ?", subtree, EmployeeTerminated).
+		Find(&employees).Error; err != nil {
+		return nil, err
+	}
+
+	// Совмещения в поддереве и все совмещения сотрудников поддерева
+	var secondary []SecondaryAssignment
+	if err := db.Where("department_id IN ? AND employee_id IN (?)", subtree,
+		db.Model(&Employee{}).Select("id").Where("status <> ?", EmployeeTerminated)).
+		Find(&secondary).Error; err != nil {
+		return nil, err
+	}
+	employeeIDs := make([]uint, len(employees))
+	for i, emp := range employees {
+		employeeIDs[i] = emp.ID
+	}
+	var totals []struct {
+		EmployeeID uint
+		Total      int
+	}
+	if len(employeeIDs) > 0 {
+		if err := db.Model(&SecondaryAssignment{}).
+			Select("employee_id, SUM(allocation) AS total").
+			Where("employee_id IN ?", employeeIDs).
+			Group("employee_id").
+			Scan(&totals).Error; err != nil {
+			return nil, err
+		}
+	}
+	allocated := make(map[uint]int, len(totals))
+	for _, t := range totals {
+		allocated[t.EmployeeID] = t.Total
+	}
+
+	rows := make([]StaffingRow, 0, len(employees)+len(secondary))
+	for _, emp := range employees {
+		rows = append(rows, StaffingRow{
+			EmployeeID: emp.ID, DepartmentID: emp.DepartmentId, Allocation: max(100-allocated[emp.ID], 0), Primary: true,
+		})
+	}
+	for _, a := range secondary {
+		rows = append(rows, StaffingRow{EmployeeID: a.EmployeeID, DepartmentID: a.DepartmentID, Allocation: a.Allocation})
+	}
+	return ComputeHeadcount(departmentID, subtree, rows), nil
+}
+
+// Совместители подразделения, отобранные фильтром по статусу
+func secondaryMembers(db *gorm.DB, departmentID uint, filter *EmployeeFilter) ([]SecondaryMember, error) {
+	var assignments []SecondaryAssignment
+	if err := db.Where("department_id = ?", departmentID).Order("id").Find(&assignments).Error; err != nil {
+		return nil, err
+	}
+	if len(assignments) == 0 {
+		return nil, nil
+	}
+
+	employeeIDs := make([]uint, len(assignments))
+	for i, a := range assignments {
+		employeeIDs[i] = a.EmployeeID
+	}
+	var employees []Employee
+	if err := filter.whereStatus(db.Where("id IN ?", employeeIDs)).Find(&employees).Error; err != nil {
+		return nil, err
+	}
+	byID := make(map[uint]Employee, len(employees))
+	for _, emp := range employees {
+		byID[emp.ID] = emp
+	}
+
+	var members []SecondaryMember
+	for _, a := range assignments {
+		if emp, ok := byID[a.EmployeeID]; ok {
+			members = append(members, SecondaryMember{Employee: emp, AssignmentID: a.ID, Role: a.Role, Allocation: a.Allocation})
+		}
+	}
+	return members, nil
+}
+
+// Проверки совмещения: сотрудник работает, подразделение существует и не
+// основное, сумма долей без совмещения exceptID не больше 100%
+func checkSecondary(tx *gorm.DB, employeeID uint, req *SecondaryAssignmentRequest, exceptID uint) error {
+	// Сумма долей читается под блокировкой сотрудника, иначе два параллельных
+	// совмещения могут вместе превысить 100%
+	if err := lockEmployee(tx, employeeID); err != nil {
+		return err
+	}
+	employee, err := GetEmployee(tx.Statement.Context, tx, employeeID)
+	if err != nil {
+		return err
+	}
+	if employee.Status == EmployeeTerminated {
+		return ErrEmployeeTerminated
+	}
+	if employee.DepartmentId == req.DepartmentID {
+		return ErrSecondaryInPrimary
+	}
+	if err := tx.Select("id").First(&Department{}, req.DepartmentID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrUnknownDepartment
+		}
+		return err
+	}
+
+	var other []int
+	if err := tx.Model(&SecondaryAssignment{}).
+		Where("employee_id = ? AND id <> ?", employeeID, exceptID).
+		Pluck("allocation", &other).Error; err != nil {
+		return err
+	}
+	return CheckAllocation(other, req.Allocation)
+}
+
+// Совмещение сотрудника с проверкой версии; version 0 - без проверки
+func secondaryForUpdate(db *gorm.DB, employeeID, id uint, version int) (*SecondaryAssignment, error) {
+	var assignment SecondaryAssignment
+	if err := db.Where("id = ? AND employee_id = ?", id, employeeID).First(&assignment).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrSecondaryNotFound
+		}
+		return nil, err
+	}
+	if version != 0 && assignment.Version != version {
+		return nil, ErrVersionMismatch
+	}
+	return &assignment, nil
+}
+
+// Совмещение в новом основном подразделении сотрудника снимается: основное место
+// уже дает полную ставку, иначе сотрудник посчитался бы дважды
+func dropSecondaryInPrimary(tx *gorm.DB, departmentID uint, employeeIDs ...uint) error {
+	return tx.Where("department_id = ? AND employee_id IN ?", departmentID, employeeIDs).
+		Delete(&SecondaryAssignment{}).Error
+}
+
+// moveSecondary совмещения в fromID переходят в toID при слиянии и удалении с переводом:
+// совмещение сотрудника, у которого в toID уже есть совмещение, складывается с ним,
+// а совмещение в основном подразделении сотрудника снимается
+func moveSecondary(tx *gorm.DB, fromID, toID uint) error {
+	var assignments []SecondaryAssignment
+	if err := tx.Where("department_id = ?", fromID).Order("id").Find(&assignments).Error; err != nil {
+		return err
+	}
+
+	for _, a := range assignments {
+		var employee Employee
+		if err := tx.Select("id", "department_id").First(&employee, a.EmployeeID).Error; err != nil {
+			return err
+		}
+		var same []SecondaryAssignment
+		if err := tx.Where("employee_id = ? AND department_id = ?", a.EmployeeID, toID).Limit(1).Find(&same).Error; err != nil {
+			return err
+		}
+
+		var err error
+		switch {
+		case employee.DepartmentId == toID:
+			err = tx.Delete(&a).Error
+		case len(same) > 0:
+			// Сумма не больше 100: доли одного сотрудника
+			err = tx.Model(&SecondaryAssignment{}).Where("id = ?", same[0].ID).
+				Updates(map[string]interface{}{
+					"allocation": gorm.Expr("allocation + ?", a.Allocation),
+					"version":    gorm.Expr("version + 1"),
+				}).Error
+			if err == nil {
+				err = tx.Delete(&a).Error
+			}
+		default:
+			err = tx.Model(&SecondaryAssignment{}).Where("id = ?", a.ID).
+				Updates(map[string]interface{}{
+					"department_id": toID,
+					"version":       gorm.Expr("version + 1"),
+				}).Error
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,123 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+
+	"gorm.io/gorm"
+)
+
+// Запрос на выделение нового подразделения
+type SplitRequest struct {
+	Name        string `json:"name"`
+	EmployeeIDs []uint `json:"employee_ids"`
+	ChildIDs    []uint `json:"child_ids"`
+}
+
+// Итог выделения
+type SplitResult struct {
+	Department       *Department `json:"department"`
+	EmployeesMoved   []uint      `json:"employees_moved"`
+	DepartmentsMoved []uint      `json:"departments_moved"`
+}
+
+// SplitDepartment создает соседнее подразделение и переносит в него выбранных
+// сотрудников и дочерние подразделения id. version 0 - без проверки версии id
+func SplitDepartment(ctx context.Context, db *gorm.DB, policy UniquenessPolicy, id uint, version int, req *SplitRequest) (*SplitResult, error) {
+	var result *SplitResult
+	err := withTreeLock(ctx, db, func(tx *gorm.DB) error {
+		// Проверка существования
+		var source Department
+		if err := tx.First(&source, id).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrDepartmentNotFound
+			}
+			return err
+		}
+		if version != 0 && source.Version != version {
+			return ErrVersionMismatch
+		}
+
+		// Новое подразделение рядом с исходным
+		department, err := createDepartment(tx, policy, &DepartmentRequest{Name: req.Name, ParentID: source.ParentId, Attributes: source.Attributes, Type: &source.Type})
+		if err != nil {
+			return err
+		}
+		result = &SplitResult{Department: department, EmployeesMoved: []uint{}, DepartmentsMoved: []uint{}}
+
+		// Сотрудники должны работать в исходном подразделении
+		employeeIDs := uniqueIDs(req.EmployeeIDs)
+		if len(employeeIDs) > 0 {
+			moved, err := reassignEmployees(tx, department.Id, "id IN ? AND department_id = ?", employeeIDs, id)
+			if err != nil {
+				return err
+			}
+			if moved != int64(len(employeeIDs)) {
+				return splitEmployeeError(tx, employeeIDs)
+			}
+			result.EmployeesMoved = employeeIDs
+		}
+
+		// Переносим только прямых потомков
+		childIDs := uniqueIDs(req.ChildIDs)
+		for _, childID := range childIDs {
+			var child Department
+			if err := tx.First(&child, childID).Error; err != nil {
+				if errors.Is(err, gorm.ErrRecordNotFound) {
+					return fmt.Errorf("%w: %d", ErrNotChildDepartment, childID)
+				}
+				return err
+			}
+			if child.ParentId == nil || *child.ParentId != id {
+				return fmt.Errorf("%w: %d", ErrNotChildDepartment, childID)
+			}
+		}
+
+		// В прежнем порядке
+		children, err := childrenOf(tx, &id)
+		if err != nil {
+			return err
+		}
+		for _, child := range children {
+			if !slices.Contains(childIDs, child.Id) {
+				continue
+			}
+			if _, err := updateDepartment(tx, policy, child.Id, 0, &DepartmentRequest{ParentID: &department.Id}); err != nil {
+				return err
+			}
+			result.DepartmentsMoved = append(result.DepartmentsMoved, child.Id)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// Отсортированные id без повторов
+func uniqueIDs(ids []uint) []uint {
+	ids = slices.Clone(ids)
+	slices.Sort(ids)
+	return slices.Compact(ids)
+}
+
+// Почему не все сотрудники перенесены: нет такого или он в другом подразделении
+func splitEmployeeError(tx *gorm.DB, employeeIDs []uint) error {
+	var found []uint
+	if err := tx.Model(&Employee{}).Where("id IN ?", employeeIDs).Pluck("id", &found).Error; err != nil {
+		return err
+	}
+	exists := make(map[uint]bool, len(found))
+	for _, id := range found {
+		exists[id] = true
+	}
+	for _, id := range employeeIDs {
+		if !exists[id] {
+			return fmt.Errorf("%w: %d", ErrEmployeeNotFound, id)
+		}
+	}
+	return ErrEmployeeNotInDepartment
+}
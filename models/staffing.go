@@ -0,0 +1,368 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Плановая численность подразделения на месяц
+type HeadcountPlan struct {
+	ID           uint      `json:"id" gorm:"primaryKey"`
+	DepartmentID uint      `json:"department_id" gorm:"column:department_id;not null"`
+	Period       string    `json:"period" gorm:"column:period;not null;size:7"` // YYYY-MM
+	Planned      int       `json:"planned" gorm:"column:planned;not null"`
+	CreatedAt    time.Time `json:"created_at" gorm:"column:created_at"`
+	Version      int       `json:"version" gorm:"column:version;not null;default:1"`
+}
+
+// Имя для таблицы
+func (HeadcountPlan) TableName() string {
+	return "headcount_plans"
+}
+
+// Запрос на плановую численность
+type HeadcountPlanRequest struct {
+	DepartmentID uint   `json:"department_id"`
+	Period       string `json:"period"`
+	Planned      int    `json:"planned"`
+}
+
+// Изменение плановой численности
+type HeadcountPlanUpdate struct {
+	Planned int `json:"planned"`
+}
+
+// Фильтр планов; пустые поля - без фильтра
+type HeadcountPlanFilter struct {
+	DepartmentID *uint
+	Period       string
+}
+
+// Плановая, фактическая и открытая численность; Gap - сколько еще не хватает
+// с учетом открытых вакансий, отрицательный - сверх плана
+type StaffingFigures struct {
+	Planned int `json:"planned"`
+	Actual  int `json:"actual"`
+	Open    int `json:"open"`
+	Gap     int `json:"gap"`
+}
+
+// Численность подразделения: своя и вместе с поддеревом
+type DepartmentStaffing struct {
+	DepartmentID uint            `json:"department_id"`
+	Name         string          `json:"name"`
+	ParentID     *uint           `json:"parent_id"`
+	Own          StaffingFigures `json:"own"`
+	Total        StaffingFigures `json:"total"`
+}
+
+// Сводка численности по поддереву за период
+type StaffingRollup struct {
+	DepartmentID uint                 `json:"department_id"`
+	Period       string               `json:"period"`
+	Total        StaffingFigures      `json:"total"`
+	Departments  []DepartmentStaffing `json:"departments"`
+}
+
+// ValidPeriod месяц в формате YYYY-MM
+func ValidPeriod(period string) bool {
+	_, err := time.Parse("2006-01", period)
+	return err == nil
+}
+
+// PeriodBounds начало месяца и начало следующего, UTC
+func PeriodBounds(period string) (time.Time, time.Time) {
+	start, _ := time.Parse("2006-01", period)
+	return start, start.AddDate(0, 1, 0)
+}
+
+// ActiveInPeriod сотрудник работал в какой-то момент месяца [start, end):
+// принят до его конца и не уволен до начала
+func (e *Employee) ActiveInPeriod(start, end time.Time) bool {
+	if e.HiredAt != nil && !e.HiredAt.Before(end) {
+		return false
+	}
+	if e.TerminatedAt != nil {
+		return !e.TerminatedAt.Before(start)
+	}
+	return e.Status != EmployeeTerminated
+}
+
+// OpenAt вакансия была открыта в момент at: создана раньше и не закрыта к нему.
+// У отмененной нет даты отмены, она не учитывается
+func (v *Vacancy) OpenAt(at time.Time) bool {
+	if !v.CreatedAt.Before(at) {
+		return false
+	}
+	switch v.Status {
+	case VacancyOpen:
+		return true
+	case VacancyFilled:
+		return v.FilledAt != nil && !v.FilledAt.Before(at)
+	}
+	return false
+}
+
+// Валидация плановой численности
+func (r *HeadcountPlanRequest) Validate() error {
+	var errs []error
+	if !ValidPeriod(r.Period) {
+		errs = append(errs, ErrInvalidPeriod)
+	}
+	if r.Planned < 0 {
+		errs = append(errs, ErrInvalidPlanned)
+	}
+	return errors.Join(errs...)
+}
+
+// ComputeStaffing сводка по поддереву departmentID: departments - подразделения
+// поддерева, родители раньше детей; planned, actual и open - собственные значения
+func ComputeStaffing(departmentID uint, period string, departments []Department, planned, actual, open map[uint]int) *StaffingRollup {
+	rollup := &StaffingRollup{DepartmentID: departmentID, Period: period, Departments: []DepartmentStaffing{}}
+	index := make(map[uint]int, len(departments))
+	for _, dept := range departments {
+		own := StaffingFigures{Planned: planned[dept.Id], Actual: actual[dept.Id], Open: open[dept.Id]}
+		own.Gap = own.Planned - own.Actual - own.Open
+		index[dept.Id] = len(rollup.Departments)
+		rollup.Departments = append(rollup.Departments, DepartmentStaffing{
+			DepartmentID: dept.Id, Name: dept.Name, ParentID: dept.ParentId, Own: own, Total: own,
+		})
+	}
+
+	// Дети после родителей: суммируем с конца
+	for i := len(rollup.Departments) - 1; i >= 0; i-- {
+		line := rollup.Departments[i]
+		if line.DepartmentID == departmentID || line.ParentID == nil {
+			continue
+		}
+		if p, ok := index[*line.ParentID]; ok {
+			total := &rollup.Departments[p].Total
+			total.Planned += line.Total.Planned
+			total.Actual += line.Total.Actual
+			total.Open += line.Total.Open
+			total.Gap += line.Total.Gap
+		}
+	}
+	if i, ok := index[departmentID]; ok {
+		rollup.Total = rollup.Departments[i].Total
+	}
+	return rollup
+}
+
+// CreateHeadcountPlan плановая численность подразделения на месяц
+func CreateHeadcountPlan(ctx context.Context, db *gorm.DB, req *HeadcountPlanRequest) (*HeadcountPlan, error) {
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+	db = db.WithContext(ctx)
+
+	// Проверка отдела
+	if err := db.Select("id").First(&Department{}, req.DepartmentID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrUnknownDepartment
+		}
+		return nil, err
+	}
+
+	plan := &HeadcountPlan{
+		DepartmentID: req.DepartmentID,
+		Period:       req.Period,
+		Planned:      req.Planned,
+		CreatedAt:    time.Now(),
+		Version:      1,
+	}
+	if err := db.Create(plan).Error; err != nil {
+		if isUniqueViolation(err) {
+			return nil, ErrHeadcountPlanExists
+		}
+		return nil, err
+	}
+	return plan, nil
+}
+
+// ListHeadcountPlans планы по подразделению и периоду
+func ListHeadcountPlans(ctx context.Context, db *gorm.DB, filter *HeadcountPlanFilter) ([]HeadcountPlan, error) {
+	if filter.Period != "" && !ValidPeriod(filter.Period) {
+		return nil, ErrInvalidPeriod
+	}
+	query := db.WithContext(ctx)
+	if filter.DepartmentID != nil {
+		query = query.Where("department_id = ?", *filter.DepartmentID)
+	}
+	if filter.Period != "" {
+		query = query.Where("period = ?", filter.Period)
+	}
+
+	var plans []HeadcountPlan
+	if err := query.Order("period, department_id").Find(&plans).Error; err != nil {
+		return nil, err
+	}
+	return plans, nil
+}
+
+// UpdateHeadcountPlan меняет плановую численность
+func UpdateHeadcountPlan(ctx context.Context, db *gorm.DB, id uint, version int, update *HeadcountPlanUpdate) (*HeadcountPlan, error) {
+	if update.Planned < 0 {
+		return nil, ErrInvalidPlanned
+	}
+	db = db.WithContext(ctx)
+
+	plan, err := headcountPlanForUpdate(db, id, version)
+	if err != nil {
+		return nil, err
+	}
+	result := db.Model(&HeadcountPlan{}).
+		Where("id = ? AND version = ?", id, plan.Version).
+		Updates(map[string]interface{}{
+			"planned": update.Planned,
+			"version": gorm.Expr("version + 1"),
+		})
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, ErrVersionMismatch
+	}
+
+	plan.Planned = update.Planned
+	plan.Version++
+	return plan, nil
+}
+
+// DeleteHeadcountPlan удаляет план
+func DeleteHeadcountPlan(ctx context.Context, db *gorm.DB, id uint, version int) error {
+	db = db.WithContext(ctx)
+
+	plan, err := headcountPlanForUpdate(db, id, version)
+	if err != nil {
+		return err
+	}
+	result := db.Where("id = ? AND version = ?", id, plan.Version).Delete(&HeadcountPlan{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrVersionMismatch
+	}
+	return nil
+}
+
+// GetStaffing план, факт и открытые вакансии по поддереву за месяц; пустой период - текущий.
+// Факт - сотрудники, работавшие в течение месяца, вакансии - открытые на его конец
+func GetStaffing(ctx context.Context, db *gorm.DB, departmentID uint, period string) (*StaffingRollup, error) {
+	if period == "" {
+		period = time.Now().UTC().Format("2006-01")
+	}
+	if !ValidPeriod(period) {
+		return nil, ErrInvalidPeriod
+	}
+	start, end := PeriodBounds(period)
+	db = db.WithContext(ctx)
+
+	departments, err := loadSubtree(db, departmentID)
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]uint, len(departments))
+	for i, dept := range departments {
+		ids[i] = dept.Id
+	}
+
+	type count struct {
+		DepartmentID uint
+		N            int
+	}
+	counts := func(query *gorm.DB) (map[uint]int, error) {
+		var rows []count
+		if err := query.Group("department_id").Scan(&rows).Error; err != nil {
+			return nil, err
+		}
+		result := make(map[uint]int, len(rows))
+		for _, row := range rows {
+			result[row.DepartmentID] = row.N
+		}
+		return result, nil
+	}
+
+	planned, err := counts(db.Model(&HeadcountPlan{}).
+		Select("department_id, SUM(planned) AS n").
+		Where("department_id IN ? AND period = ?", ids, period))
+	if err != nil {
+		return nil, err
+	}
+	actual, err := counts(db.Model(&Employee{}).
+		Select("department_id, COUNT(*) AS n").
+		Where("department_id IN ?", ids).
+		Where("hired_at IS NULL OR hired_at < ?", end).
+		Where("terminated_at >= ? OR (terminated_at IS NULL AND status <> ?)", start, EmployeeTerminated))
+	if err != nil {
+		return nil, err
+	}
+	open, err := counts(db.Model(&Vacancy{}).
+		Select("department_id, COUNT(*) AS n").
+		Where("department_id IN ? AND created_at < ?", ids, end).
+		Where("status = ? OR (status = ? AND filled_at >= ?)", VacancyOpen, VacancyFilled, end))
+	if err != nil {
+		return nil, err
+	}
+
+	return ComputeStaffing(departmentID, period, departments, planned, actual, open), nil
+}
+
+// moveStaffing переносит планы и открытые вакансии fromID в toID. План на месяц,
+// который у toID уже есть, складывается с ним: (department_id, period) уникальны
+func moveStaffing(tx *gorm.DB, fromID, toID uint) error {
+	var plans []HeadcountPlan
+	if err := tx.Where("department_id = ?", fromID).Find(&plans).Error; err != nil {
+		return err
+	}
+	for _, plan := range plans {
+		result := tx.Model(&HeadcountPlan{}).
+			Where("department_id = ? AND period = ?", toID, plan.Period).
+			Updates(map[string]interface{}{
+				"planned": gorm.Expr("planned + ?", plan.Planned),
+				"version": gorm.Expr("version + 1"),
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected > 0 {
+			if err := tx.Delete(&HeadcountPlan{}, plan.ID).Error; err != nil {
+				return err
+			}
+			continue
+		}
+		if err := tx.Model(&HeadcountPlan{}).Where("id = ?", plan.ID).
+			Updates(map[string]interface{}{
+				"department_id": toID,
+				"version":       gorm.Expr("version + 1"),
+			}).Error; err != nil {
+			return err
+		}
+	}
+
+	return tx.Model(&Vacancy{}).
+		Where("department_id = ? AND status = ?", fromID, VacancyOpen).
+		Updates(map[string]interface{}{
+			"department_id": toID,
+			"version":       gorm.Expr("version + 1"),
+		}).Error
+}
+
+// План с проверкой версии; version 0 - без проверки
+func headcountPlanForUpdate(db *gorm.DB, id uint, version int) (*HeadcountPlan, error) {
+	var plan HeadcountPlan
+	if err := db.First(&plan, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrHeadcountPlanNotFound
+		}
+		return nil, err
+	}
+	if version != 0 && plan.Version != version {
+		return nil, ErrVersionMismatch
+	}
+	return &plan, nil
+}
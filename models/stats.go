@@ -0,0 +1,154 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"math"
+	"slices"
+
+	"gorm.io/gorm"
+)
+
+// Показатели подразделения вместе с поддеревом; уволенные не учитываются,
+// кроме найма по месяцам
+type DepartmentStats struct {
+	DepartmentID uint              `json:"department_id"`
+	Headcount    StatsHeadcount    `json:"headcount"`
+	HiresByMonth []MonthlyHires    `json:"hires_by_month"`
+	Tenure       TenureStats       `json:"tenure"`
+	Positions    []PositionVariant `json:"positions"`
+	Tree         TreeShape         `json:"tree"`
+}
+
+// Численность: в самом подразделении и во всем поддереве
+type StatsHeadcount struct {
+	Direct int `json:"direct" gorm:"column:direct"`
+	Total  int `json:"total" gorm:"column:total"`
+}
+
+// Принятые в месяце по hired_at
+type MonthlyHires struct {
+	Month string `json:"month" gorm:"column:month"` // YYYY-MM
+	Hires int    `json:"hires" gorm:"column:hires"`
+}
+
+// Стаж от hired_at в днях; сотрудники без даты приема не учитываются
+type TenureStats struct {
+	Employees   int     `json:"employees" gorm:"column:employees"`
+	AverageDays float64 `json:"average_days" gorm:"column:average_days"`
+	MedianDays  float64 `json:"median_days" gorm:"column:median_days"`
+}
+
+// Форма поддерева
+type TreeShape struct {
+	Departments int `json:"departments" gorm:"column:departments"`
+	Depth       int `json:"depth" gorm:"column:depth"`     // уровней, считая само подразделение
+	Breadth     int `json:"breadth" gorm:"column:breadth"` // наибольшее число подразделений на уровне
+}
+
+// Поддерево подразделения @id с уровнями, само подразделение - уровень 0
+const subtreeCTE = `WITH RECURSIVE subtree(id, level) AS (
+	SELECT id, 0 FROM departments WHERE id = @id
+	UNION ALL
+	SELECT d.id, s.level + 1 FROM departments d JOIN subtree s ON d.parent_id = s.id
+)`
+
+// ComputeTenure среднее и медиана стажа в днях
+func ComputeTenure(days []float64) TenureStats {
+	stats := TenureStats{Employees: len(days)}
+	if len(days) == 0 {
+		return stats
+	}
+
+	days = slices.Clone(days)
+	slices.Sort(days)
+	sum := 0.0
+	for _, d := range days {
+		sum += d
+	}
+	middle := len(days) / 2
+	median := days[middle]
+	if len(days)%2 == 0 {
+		median = (days[middle-1] + days[middle]) / 2
+	}
+
+	stats.AverageDays = roundTenth(sum / float64(len(days)))
+	stats.MedianDays = roundTenth(median)
+	return stats
+}
+
+// GetDepartmentStats показатели подразделения агрегатными запросами по поддереву
+func GetDepartmentStats(ctx context.Context, db *gorm.DB, id uint) (*DepartmentStats, error) {
+	db = db.WithContext(ctx)
+
+	// Проверка отдела
+	if err := db.Select("id").First(&Department{}, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrDepartmentNotFound
+		}
+		return nil, err
+	}
+
+	// Выражения, которые различаются в Postgres и SQLite
+	month := "strftime('%Y-%m', hired_at)"
+	tenure := "julianday('now') - julianday(hired_at)"
+	if db.Dialector.Name() == "postgres" {
+		month = "to_char(hired_at, 'YYYY-MM')"
+		tenure = "CAST(EXTRACT(EPOCH FROM ((NOW() AT TIME ZONE 'UTC') - hired_at)) AS DOUBLE PRECISION) / 86400"
+	}
+	args := map[string]interface{}{"id": id, "terminated": EmployeeTerminated}
+	inSubtree := "department_id IN (SELECT id FROM subtree)"
+
+	stats := &DepartmentStats{DepartmentID: id}
+	queries := []struct {
+		sql  string
+		dest interface{}
+	}{
+		{`SELECT COUNT(*) AS departments, MAX(level) + 1 AS depth,
+			(SELECT MAX(n) FROM (SELECT COUNT(*) AS n FROM subtree GROUP BY level) levels) AS breadth
+		FROM subtree`, &stats.Tree},
+
+		{`SELECT COALESCE(SUM(CASE WHEN department_id = @id THEN 1 ELSE 0 END), 0) AS direct, COUNT(*) AS total
+		FROM employees WHERE ` + inSubtree + ` AND status <> @terminated`, &stats.Headcount},
+
+		{`SELECT ` + month + ` AS month, COUNT(*) AS hires
+		FROM employees WHERE ` + inSubtree + ` AND hired_at IS NOT NULL
+		GROUP BY month ORDER BY month`, &stats.HiresByMonth},
+
+		{`SELECT position AS title, COUNT(*) AS employees
+		FROM employees WHERE ` + inSubtree + ` AND status <> @terminated
+		GROUP BY position ORDER BY employees DESC, title`, &stats.Positions},
+
+		// Медиана без percentile_cont: одно или два средних значения по порядку
+		{`, tenure AS (
+			SELECT ` + tenure + ` AS days
+			FROM employees WHERE ` + inSubtree + ` AND status <> @terminated AND hired_at IS NOT NULL
+		)
+		SELECT COUNT(*) AS employees, COALESCE(AVG(days), 0) AS average_days,
+			COALESCE((SELECT AVG(days) FROM (
+				SELECT days FROM tenure ORDER BY days
+				LIMIT 2 - (SELECT COUNT(*) FROM tenure) % 2
+				OFFSET (SELECT (COUNT(*) - 1) / 2 FROM tenure)
+			) middle), 0) AS median_days
+		FROM tenure`, &stats.Tenure},
+	}
+	for _, q := range queries {
+		if err := db.Raw(subtreeCTE+"\n"+q.sql, args).Scan(q.dest).Error; err != nil {
+			return nil, err
+		}
+	}
+
+	stats.Tenure.AverageDays = roundTenth(stats.Tenure.AverageDays)
+	stats.Tenure.MedianDays = roundTenth(stats.Tenure.MedianDays)
+	if stats.HiresByMonth == nil {
+		stats.HiresByMonth = []MonthlyHires{}
+	}
+	if stats.Positions == nil {
+		stats.Positions = []PositionVariant{}
+	}
+	return stats, nil
+}
+
+func roundTenth(v float64) float64 {
+	return math.Round(v*10) / 10
+}
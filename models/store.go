@@ -0,0 +1,138 @@
+package models
+
+import (
+	"context"
+	"time"
+)
+
+// Хранилище подразделений
+type DepartmentStore interface {
+	CreateDepartment(ctx context.Context, req *DepartmentRequest) (*Department, error)
+	UpdateDepartment(ctx context.Context, id uint, version int, req *DepartmentRequest) (*Department, error)
+	DeleteDepartment(ctx context.Context, id uint, version int, mode string, reassignToID *uint) error
+	GetWithTree(ctx context.Context, id uint, depth int, employees *EmployeeFilter) (*DepartmentResponse, error)
+	ListDepartments(ctx context.Context, filter *DepartmentFilter) ([]Department, error)
+	CheckNameUnique(ctx context.Context, name string, parentID *uint) (bool, error)
+	MergeDepartment(ctx context.Context, sourceID, targetID uint, version int, strategy string) (*MergeSummary, error)
+	SplitDepartment(ctx context.Context, id uint, version int, req *SplitRequest) (*SplitResult, error)
+	CopyDepartment(ctx context.Context, id uint, req *CopyRequest) (*CopyResult, error)
+	ReorderChildren(ctx context.Context, id uint, version int, req *ReorderRequest) (*DepartmentResponse, error)
+	ResolveDepartment(ctx context.Context, ref DepartmentRef) (uint, error)
+}
+
+// Хранилище сотрудников
+type EmployeeStore interface {
+	CreateEmployee(ctx context.Context, departmentID uint, req *EmployeeRequest) (*Employee, error)
+	GetEmployee(ctx context.Context, id uint) (*Employee, error)
+	ResolveEmployee(ctx context.Context, ref EmployeeRef) (uint, error)
+	GetEmployeesByDepartment(ctx context.Context, departmentID uint, filter *EmployeeFilter) ([]Employee, error)
+	UpdateEmployeeAttributes(ctx context.Context, id uint, version int, req *EmployeeAttributesRequest) (*Employee, error)
+	MoveEmployees(ctx context.Context, fromDeptID, toDeptID uint) error
+	TransferEmployee(ctx context.Context, id uint, version int, req *TransferRequest) (*Employee, error)
+	TerminateEmployee(ctx context.Context, id uint, version int, req *TerminationRequest) (*Employee, error)
+	RehireEmployee(ctx context.Context, id uint, version int, req *RehireRequest) (*Employee, error)
+	SetEmployeeStatus(ctx context.Context, id uint, version int, req *EmployeeStatusRequest) (*Employee, error)
+}
+
+// Хранилище описаний пользовательских атрибутов
+type AttributeStore interface {
+	CreateAttributeDefinition(ctx context.Context, req *AttributeDefinitionRequest) (*AttributeDefinition, error)
+	ListAttributeDefinitions(ctx context.Context, entity string) ([]AttributeDefinition, error)
+	GetAttributeDefinition(ctx context.Context, id uint) (*AttributeDefinition, error)
+	UpdateAttributeDefinition(ctx context.Context, id uint, version int, req *AttributeDefinitionUpdate) (*AttributeDefinition, error)
+	DeleteAttributeDefinition(ctx context.Context, id uint, version int) error
+}
+
+// Хранилище каталога должностей
+type PositionStore interface {
+	CreatePosition(ctx context.Context, req *PositionRequest) (*Position, error)
+	ListPositions(ctx context.Context, jobFamily string) ([]Position, error)
+	GetPosition(ctx context.Context, id uint) (*Position, error)
+	UpdatePosition(ctx context.Context, id uint, version int, update *PositionUpdate) (*Position, error)
+	DeletePosition(ctx context.Context, id uint, version int) error
+	ClusterPositionTitles(ctx context.Context, apply bool) (*PositionClustering, error)
+}
+
+// История назначений сотрудников
+type AssignmentStore interface {
+	EmployeeHistory(ctx context.Context, id uint) ([]EmployeeAssignment, error)
+	GetDepartmentMovements(ctx context.Context, departmentID uint, filter *MovementFilter) ([]Movement, error)
+}
+
+// Совмещения и численность подразделений
+type SecondaryStore interface {
+	ListSecondaryAssignments(ctx context.Context, employeeID uint) ([]SecondaryAssignment, error)
+	AddSecondaryAssignment(ctx context.Context, employeeID uint, req *SecondaryAssignmentRequest) (*SecondaryAssignment, error)
+	UpdateSecondaryAssignment(ctx context.Context, employeeID, id uint, version int, update *SecondaryAssignmentUpdate) (*SecondaryAssignment, error)
+	RemoveSecondaryAssignment(ctx context.Context, employeeID, id uint, version int) error
+	GetHeadcount(ctx context.Context, departmentID uint) (*Headcount, error)
+}
+
+// Показатели подразделений
+type StatsStore interface {
+	GetDepartmentStats(ctx context.Context, id uint) (*DepartmentStats, error)
+}
+
+// Плановая численность, вакансии и сводка штата
+type StaffingStore interface {
+	CreateHeadcountPlan(ctx context.Context, req *HeadcountPlanRequest) (*HeadcountPlan, error)
+	ListHeadcountPlans(ctx context.Context, filter *HeadcountPlanFilter) ([]HeadcountPlan, error)
+	UpdateHeadcountPlan(ctx context.Context, id uint, version int, update *HeadcountPlanUpdate) (*HeadcountPlan, error)
+	DeleteHeadcountPlan(ctx context.Context, id uint, version int) error
+	CreateVacancy(ctx context.Context, req *VacancyRequest) (*Vacancy, error)
+	ListVacancies(ctx context.Context, filter *VacancyFilter) ([]Vacancy, error)
+	GetVacancy(ctx context.Context, id uint) (*Vacancy, error)
+	UpdateVacancy(ctx context.Context, id uint, version int, update *VacancyUpdate) (*Vacancy, error)
+	FillVacancy(ctx context.Context, id uint, version int, req *EmployeeRequest) (*Vacancy, *Employee, error)
+	GetStaffing(ctx context.Context, departmentID uint, period string) (*StaffingRollup, error)
+}
+
+// Линии подчинения сотрудников
+type ReportingStore interface {
+	SetManager(ctx context.Context, id uint, version int, req *ManagerRequest) (*Employee, error)
+	DirectReports(ctx context.Context, id uint, filter *EmployeeFilter) ([]Employee, error)
+	ReportingChain(ctx context.Context, id uint) ([]Employee, error)
+	GetSpanOfControl(ctx context.Context, id uint) (*SpanOfControl, error)
+	GetReportingIssues(ctx context.Context) ([]ReportingIssue, error)
+}
+
+// Хранилище правил иерархии подразделений
+type RulesStore interface {
+	GetHierarchyRules(ctx context.Context) (*HierarchyRuleSet, error)
+	UpdateHierarchyRules(ctx context.Context, version int, rules *HierarchyRules) (*HierarchyRuleSet, error)
+	CheckHierarchy(ctx context.Context) ([]RuleViolation, error)
+}
+
+// Проверка целостности данных
+type IntegrityStore interface {
+	CheckIntegrity(ctx context.Context, repair bool) (*IntegrityReport, error)
+}
+
+// Хранилище планов реорганизации
+type PlanStore interface {
+	CreatePlan(ctx context.Context, req *PlanRequest) (*Plan, error)
+	GetPlan(ctx context.Context, id uint) (*Plan, error)
+	ListPlans(ctx context.Context) ([]Plan, error)
+	AddPlanOperations(ctx context.Context, id uint, version int, ops []PlanOperation) (*Plan, error)
+	DeletePlan(ctx context.Context, id uint, version int) error
+	DiffPlan(ctx context.Context, id uint) (*PlanDiff, error)
+	ApplyPlan(ctx context.Context, id uint, version int) (*Plan, error)
+}
+
+// Хранилище отложенных изменений
+type ScheduleStore interface {
+	ScheduleDepartmentChange(ctx context.Context, id uint, version int, req *DepartmentRequest, effectiveAt time.Time) (*ScheduledChange, error)
+	ScheduleTransfer(ctx context.Context, employeeID uint, version int, req *TransferRequest, effectiveAt time.Time) (*ScheduledChange, error)
+	GetScheduledChange(ctx context.Context, id uint) (*ScheduledChange, error)
+	ListScheduledChanges(ctx context.Context, status string) ([]ScheduledChange, error)
+	CancelScheduledChange(ctx context.Context, id uint, version int) (*ScheduledChange, error)
+	ApplyDueChanges(ctx context.Context, now time.Time) (int, error)
+}
+
+// Хранилище ключей идемпотентности
+type IdempotencyStore interface {
+	ReserveIdempotencyKey(ctx context.Context, key *IdempotencyKey, staleAfter time.Duration) (*IdempotencyKey, error)
+	CompleteIdempotencyKey(ctx context.Context, key *IdempotencyKey) error
+	ReleaseIdempotencyKey(ctx context.Context, key string) error
+	PurgeExpiredIdempotencyKeys(ctx context.Context, now time.Time) (int64, error)
+}
@@ -0,0 +1,200 @@
+package models
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"golang.org/x/text/cases"
+	"golang.org/x/text/unicode/norm"
+	"gorm.io/gorm"
+)
+
+// Политика уникальности имен подразделений
+type UniquenessPolicy string
+
+const (
+	// Имя уникально среди соседей (один родитель)
+	UniqueAmongSiblings UniquenessPolicy = "siblings"
+	// Имя уникально во всем дереве корневого подразделения
+	UniqueInBranch UniquenessPolicy = "branch"
+	// Имя уникально среди всех подразделений
+	UniqueGlobal UniquenessPolicy = "global"
+)
+
+// По умолчанию - уникальность в ветке
+const DefaultUniquenessPolicy = UniqueInBranch
+
+// ParseUniquenessPolicy разбирает значение из конфигурации; пустое - политика по умолчанию
+func ParseUniquenessPolicy(s string) (UniquenessPolicy, error) {
+	switch policy := UniquenessPolicy(s); policy {
+	case "":
+		return DefaultUniquenessPolicy, nil
+	case UniqueAmongSiblings, UniqueInBranch, UniqueGlobal:
+		return policy, nil
+	default:
+		return "", fmt.Errorf("unknown uniqueness policy %q, use siblings, branch or global", s)
+	}
+}
+
+// NameKey приводит имя к виду для сравнения: NFKC и case folding,
+// поэтому "Sales", "SALES" и полноширинное "Ｓａｌｅｓ" совпадают
+func NameKey(name string) string {
+	return norm.NFKC.String(cases.Fold().String(norm.NFKC.String(name)))
+}
+
+// NameScope область, в которой имя должно быть уникально.
+// rootKey - NameKey корневого подразделения ветки (для корня - его собственный)
+func (p UniquenessPolicy) NameScope(parentID *uint, rootKey string) string {
+	switch p {
+	case UniqueGlobal:
+		return "g"
+	case UniqueInBranch:
+		return "b:" + rootKey
+	default:
+		if parentID == nil {
+			return "s:0"
+		}
+		return "s:" + strconv.FormatUint(uint64(*parentID), 10)
+	}
+}
+
+// CheckNameUnique свободно ли имя для нового подразделения под parentID
+func CheckNameUnique(ctx context.Context, db *gorm.DB, policy UniquenessPolicy, name string, parentID *uint) (bool, error) {
+	db = db.WithContext(ctx)
+
+	key, scope, err := nameScope(db, policy, name, parentID)
+	if err != nil {
+		return false, err
+	}
+	taken, err := nameTaken(db, key, scope)
+	return !taken, err
+}
+
+// nameScope ключ имени и область уникальности для подразделения под parentID
+func nameScope(db *gorm.DB, policy UniquenessPolicy, name string, parentID *uint) (key, scope string, err error) {
+	key = NameKey(name)
+	rootKey, err := branchRootKey(db, parentID, key)
+	if err != nil {
+		return "", "", err
+	}
+	return key, policy.NameScope(parentID, rootKey), nil
+}
+
+// Занята ли пара (name_scope, name_key)
+func nameTaken(db *gorm.DB, key, scope string) (bool, error) {
+	var count int64
+	err := db.Model(&Department{}).
+		Where("name_scope = ? AND name_key = ?", scope, key).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// ApplyUniquenessPolicy пересчитывает name_key и name_scope всех подразделений.
+// Вызывается при запуске: если данные нарушают политику, возвращает ErrNameExists
+func ApplyUniquenessPolicy(ctx context.Context, db *gorm.DB, policy UniquenessPolicy) error {
+	return withTreeLock(ctx, db, func(tx *gorm.DB) error {
+		var departments []Department
+		if err := tx.Find(&departments).Error; err != nil {
+			return err
+		}
+
+		byID := make(map[uint]Department, len(departments))
+		for _, dept := range departments {
+			byID[dept.Id] = dept
+		}
+
+		// Считаем новые значения и ищем конфликты до записи
+		seen := make(map[[2]string]string, len(departments))
+		var changed []Department
+		for _, dept := range departments {
+			key := NameKey(dept.Name)
+			scope := policy.NameScope(dept.ParentId, rootKeyOf(byID, dept))
+			if other, ok := seen[[2]string{scope, key}]; ok {
+				return fmt.Errorf("%w: %q and %q violate %s policy", ErrNameExists, other, dept.Name, policy)
+			}
+			seen[[2]string{scope, key}] = dept.Name
+
+			if dept.NameKey != key || dept.NameScope != scope {
+				dept.NameKey, dept.NameScope = key, scope
+				changed = append(changed, dept)
+			}
+		}
+
+		// Сначала уводим измененные строки во временную область, чтобы
+		// промежуточные состояния не нарушали уникальный индекс
+		for _, dept := range changed {
+			if err := tx.Model(&Department{}).Where("id = ?", dept.Id).
+				Update("name_scope", "tmp:"+strconv.FormatUint(uint64(dept.Id), 10)).Error; err != nil {
+				return err
+			}
+		}
+		for _, dept := range changed {
+			if err := tx.Model(&Department{}).Where("id = ?", dept.Id).
+				Updates(map[string]interface{}{"name_key": dept.NameKey, "name_scope": dept.NameScope}).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// branchRootKey NameKey корня ветки для подразделения с родителем parentID;
+// у корневого подразделения ветка своя, ключ - его собственный
+func branchRootKey(db *gorm.DB, parentID *uint, ownKey string) (string, error) {
+	if parentID == nil {
+		return ownKey, nil
+	}
+
+	id := *parentID
+	for {
+		var dept Department
+		if err := db.Select("id", "parent_id", "name_key").First(&dept, id).Error; err != nil {
+			return "", err
+		}
+		if dept.ParentId == nil {
+			return dept.NameKey, nil
+		}
+		id = *dept.ParentId
+	}
+}
+
+// Корень ветки по загруженным подразделениям; при цикле - не дальше числа подразделений
+func rootKeyOf(byID map[uint]Department, dept Department) string {
+	for steps := 0; dept.ParentId != nil && steps <= len(byID); steps++ {
+		parent, ok := byID[*dept.ParentId]
+		if !ok {
+			break
+		}
+		dept = parent
+	}
+	return NameKey(dept.Name)
+}
+
+// syncNameScope обновляет name_key и name_scope подразделения после создания,
+// переименования или переноса. В политике branch область зависит от корня,
+// поэтому пересчитывается все поддерево
+func syncNameScope(db *gorm.DB, policy UniquenessPolicy, department *Department) error {
+	key, scope, err := nameScope(db, policy, department.Name, department.ParentId)
+	if err != nil {
+		return err
+	}
+	department.NameKey, department.NameScope = key, scope
+
+	ids := []uint{department.Id}
+	if policy == UniqueInBranch {
+		if err := getChildIDs(db, department.Id, &ids); err != nil {
+			return err
+		}
+	}
+
+	err = db.Model(&Department{}).Where("id = ?", department.Id).
+		Updates(map[string]interface{}{"name_key": department.NameKey, "name_scope": department.NameScope}).Error
+	if err == nil && len(ids) > 1 {
+		err = db.Model(&Department{}).Where("id IN ?", ids[1:]).Update("name_scope", department.NameScope).Error
+	}
+	if err != nil && isUniqueViolation(err) {
+		return ErrNameExists
+	}
+	return err
+}
@@ -0,0 +1,275 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Статусы вакансии
+const (
+	VacancyOpen     = "open"
+	VacancyFilled   = "filled"
+	VacancyCanceled = "canceled"
+)
+
+// Вакансия подразделения; закрытая ссылается на принятого сотрудника
+type Vacancy struct {
+	ID           uint       `json:"id" gorm:"primaryKey"`
+	DepartmentID uint       `json:"department_id" gorm:"column:department_id;not null"`
+	Position     string     `json:"position" gorm:"column:position;not null;size:200"`
+	PositionID   *uint      `json:"position_id,omitempty" gorm:"column:position_id"`
+	Status       string     `json:"status" gorm:"column:status;not null;size:20"`
+	TargetDate   *time.Time `json:"target_date" gorm:"column:target_date"`
+	EmployeeID   *uint      `json:"employee_id,omitempty" gorm:"column:employee_id"`
+	FilledAt     *time.Time `json:"filled_at,omitempty" gorm:"column:filled_at"`
+	CreatedAt    time.Time  `json:"created_at" gorm:"column:created_at"`
+	Version      int        `json:"version" gorm:"column:version;not null;default:1"`
+}
+
+// Имя для таблицы
+func (Vacancy) TableName() string {
+	return "vacancies"
+}
+
+// Запрос на вакансию
+type VacancyRequest struct {
+	DepartmentID uint       `json:"department_id"`
+	Position     string     `json:"position"`              // с position_id можно не указывать
+	PositionID   *uint      `json:"position_id,omitempty"` // должность из каталога
+	TargetDate   *time.Time `json:"target_date"`
+}
+
+// Частичное изменение вакансии; status - open или canceled
+type VacancyUpdate struct {
+	Position   *string    `json:"position"`
+	TargetDate *time.Time `json:"target_date"`
+	Status     *string    `json:"status"`
+}
+
+// Фильтр вакансий; пустые поля - без фильтра
+type VacancyFilter struct {
+	DepartmentID *uint
+	Status       string
+}
+
+// UsePosition пустой текст должности заполняется названием из каталога,
+// заданный должен быть написанием той же должности
+func (r *VacancyRequest) UsePosition(p *Position) error {
+	return usePosition(&r.Position, p)
+}
+
+// Валидация вакансии
+func (r *VacancyRequest) Validate() error {
+	r.Position = strings.TrimSpace(r.Position)
+	if r.Position == "" {
+		return ErrPositionEmpty
+	}
+	if len(r.Position) > 200 {
+		return ErrPositionTooLong
+	}
+	return nil
+}
+
+// Apply новые значения поверх вакансии; статус проверяется отдельно
+func (u *VacancyUpdate) Apply(v *Vacancy) (VacancyRequest, string, error) {
+	req := VacancyRequest{DepartmentID: v.DepartmentID, Position: v.Position, PositionID: v.PositionID, TargetDate: v.TargetDate}
+	status := v.Status
+	if u.Position != nil {
+		req.Position = *u.Position
+	}
+	if u.TargetDate != nil {
+		req.TargetDate = u.TargetDate
+	}
+	if u.Status != nil {
+		if *u.Status != VacancyOpen && *u.Status != VacancyCanceled {
+			return req, status, ErrInvalidVacancyStatus
+		}
+		status = *u.Status
+	}
+	return req, status, req.Validate()
+}
+
+// ValidVacancyStatus статус для фильтра
+func ValidVacancyStatus(status string) bool {
+	return status == VacancyOpen || status == VacancyFilled || status == VacancyCanceled
+}
+
+// FillFrom должность закрытия вакансии: если в запросе не указана, берется из вакансии;
+// для должности из каталога текст берется из текущего названия
+func (e *EmployeeRequest) FillFrom(v *Vacancy) {
+	if e.PositionID == nil && strings.TrimSpace(e.Position) == "" {
+		if v.PositionID != nil {
+			id := *v.PositionID
+			e.PositionID = &id
+			return
+		}
+		e.Position = v.Position
+	}
+}
+
+// CreateVacancy открывает вакансию в подразделении
+func CreateVacancy(ctx context.Context, db *gorm.DB, req *VacancyRequest) (*Vacancy, error) {
+	db = db.WithContext(ctx)
+
+	// Проверка отдела
+	if err := db.Select("id").First(&Department{}, req.DepartmentID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrUnknownDepartment
+		}
+		return nil, err
+	}
+
+	// Должность из каталога
+	if req.PositionID != nil {
+		position, err := findPosition(db, *req.PositionID, ErrUnknownPosition)
+		if err != nil {
+			return nil, err
+		}
+		if err := req.UsePosition(position); err != nil {
+			return nil, err
+		}
+	}
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+
+	vacancy := &Vacancy{
+		DepartmentID: req.DepartmentID,
+		Position:     req.Position,
+		PositionID:   req.PositionID,
+		Status:       VacancyOpen,
+		TargetDate:   req.TargetDate,
+		CreatedAt:    time.Now(),
+		Version:      1,
+	}
+	if err := db.Create(vacancy).Error; err != nil {
+		return nil, err
+	}
+	return vacancy, nil
+}
+
+// ListVacancies вакансии по подразделению и статусу
+func ListVacancies(ctx context.Context, db *gorm.DB, filter *VacancyFilter) ([]Vacancy, error) {
+	query := db.WithContext(ctx)
+	if filter.DepartmentID != nil {
+		query = query.Where("department_id = ?", *filter.DepartmentID)
+	}
+	if filter.Status != "" {
+		query = query.Where("status = ?", filter.Status)
+	}
+
+	var vacancies []Vacancy
+	if err := query.Order("id").Find(&vacancies).Error; err != nil {
+		return nil, err
+	}
+	return vacancies, nil
+}
+
+// GetVacancy вакансия по id
+func GetVacancy(ctx context.Context, db *gorm.DB, id uint) (*Vacancy, error) {
+	return vacancyForUpdate(db.WithContext(ctx), id, 0)
+}
+
+// UpdateVacancy меняет должность, срок или статус открытой или отмененной вакансии
+func UpdateVacancy(ctx context.Context, db *gorm.DB, id uint, version int, update *VacancyUpdate) (*Vacancy, error) {
+	db = db.WithContext(ctx)
+
+	vacancy, err := vacancyForUpdate(db, id, version)
+	if err != nil {
+		return nil, err
+	}
+	if vacancy.Status == VacancyFilled {
+		return nil, ErrVacancyFilled
+	}
+	req, status, err := update.Apply(vacancy)
+	if err != nil {
+		return nil, err
+	}
+
+	result := db.Model(&Vacancy{}).
+		Where("id = ? AND version = ?", id, vacancy.Version).
+		Updates(map[string]interface{}{
+			"position":    req.Position,
+			"target_date": req.TargetDate,
+			"status":      status,
+			"version":     gorm.Expr("version + 1"),
+		})
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, ErrVersionMismatch
+	}
+
+	vacancy.Position = req.Position
+	vacancy.TargetDate = req.TargetDate
+	vacancy.Status = status
+	vacancy.Version++
+	return vacancy, nil
+}
+
+// FillVacancy закрывает открытую вакансию: сотрудник создается через CreateEmployee
+// в подразделении вакансии, в одной транзакции со сменой статуса
+func FillVacancy(ctx context.Context, db *gorm.DB, id uint, version int, req *EmployeeRequest) (*Vacancy, *Employee, error) {
+	var vacancy *Vacancy
+	var employee *Employee
+	err := db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var err error
+		if vacancy, err = vacancyForUpdate(tx, id, version); err != nil {
+			return err
+		}
+		if vacancy.Status != VacancyOpen {
+			return ErrVacancyNotOpen
+		}
+
+		req.FillFrom(vacancy)
+		if employee, err = CreateEmployee(ctx, tx, vacancy.DepartmentID, req); err != nil {
+			return err
+		}
+
+		now := time.Now()
+		result := tx.Model(&Vacancy{}).
+			Where("id = ? AND version = ?", id, vacancy.Version).
+			Updates(map[string]interface{}{
+				"status":      VacancyFilled,
+				"employee_id": employee.ID,
+				"filled_at":   now,
+				"version":     gorm.Expr("version + 1"),
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrVersionMismatch
+		}
+
+		vacancy.Status = VacancyFilled
+		vacancy.EmployeeID = &employee.ID
+		vacancy.FilledAt = &now
+		vacancy.Version++
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return vacancy, employee, nil
+}
+
+// Вакансия с проверкой версии; version 0 - без проверки
+func vacancyForUpdate(db *gorm.DB, id uint, version int) (*Vacancy, error) {
+	var vacancy Vacancy
+	if err := db.First(&vacancy, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrVacancyNotFound
+		}
+		return nil, err
+	}
+	if version != 0 && vacancy.Version != version {
+		return nil, ErrVersionMismatch
+	}
+	return &vacancy, nil
+}
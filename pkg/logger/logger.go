@@ -2,6 +2,7 @@ package logger
 
 import (
 	"fmt"
+	"io"
 	"log"
 	"net/http"
 	"os"
@@ -14,9 +15,14 @@ type Logger struct {
 }
 
 func New() *Logger {
+	return NewTo(os.Stdout, os.Stderr)
+}
+
+// NewTo логгер с заданными потоками для info и error
+func NewTo(out, errOut io.Writer) *Logger {
 	return &Logger{
-		infoLog:  log.New(os.Stdout, "[INFO] ", log.Ldate|log.Ltime),
-		errorLog: log.New(os.Stderr, "[ERROR] ", log.Ldate|log.Ltime|log.Lshortfile),
+		infoLog:  log.New(out, "[INFO] ", log.Ldate|log.Ltime),
+		errorLog: log.New(errOut, "[ERROR] ", log.Ldate|log.Ltime|log.Lshortfile),
 	}
 }
 
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/kroulersama/goProject/models"
+	"github.com/kroulersama/goProject/storage"
+)
+
+const positionsUsage = "usage: positions cluster [--apply] [--json]"
+
+// positions cluster: группировка текста должностей сотрудников в записи каталога
+func runPositions(config *storage.Config, args []string) error {
+	if len(args) == 0 || args[0] != "cluster" {
+		return errors.New(positionsUsage)
+	}
+
+	flags := flag.NewFlagSet("positions cluster", flag.ExitOnError)
+	apply := flags.Bool("apply", false, "create catalog entries and link employees")
+	asJSON := flags.Bool("json", false, "print the result as JSON")
+	flags.Parse(args[1:])
+
+	db, err := storage.NewConnection(config)
+	if err != nil {
+		return err
+	}
+
+	// Политика имен подразделений здесь не нужна
+	store := storage.NewGormStore(db, models.UniqueInBranch)
+	result, err := store.ClusterPositionTitles(context.Background(), *apply)
+	if err != nil {
+		return err
+	}
+
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(result)
+	}
+	return printClusters(result)
+}
+
+// Таблица групп: запись каталога и объединяемые написания
+func printClusters(result *models.PositionClustering) error {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "POSITION\tTITLE\tEMPLOYEES\tVARIANTS")
+	for _, cluster := range result.Clusters {
+		id := "new"
+		if cluster.PositionID != 0 {
+			id = fmt.Sprint(cluster.PositionID)
+		}
+		variants := make([]string, len(cluster.Variants))
+		for i, v := range cluster.Variants {
+			variants[i] = fmt.Sprintf("%q (%d)", v.Title, v.Employees)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%d\t%s\n", id, cluster.Title, cluster.Employees, strings.Join(variants, ", "))
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	if result.Applied {
+		fmt.Printf("Created %d positions, linked %d employees\n", result.Created, result.Linked)
+	} else {
+		fmt.Println("Dry run, use --apply to create positions and link employees")
+	}
+	return nil
+}
@@ -0,0 +1,355 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/kroulersama/goProject/models"
+	"gorm.io/gorm"
+)
+
+// Реализация хранилищ поверх GORM
+type GormStore struct {
+	db     *gorm.DB
+	policy models.UniquenessPolicy
+}
+
+var (
+	_ models.DepartmentStore  = (*GormStore)(nil)
+	_ models.EmployeeStore    = (*GormStore)(nil)
+	_ models.IdempotencyStore = (*GormStore)(nil)
+	_ models.AttributeStore   = (*GormStore)(nil)
+	_ models.PositionStore    = (*GormStore)(nil)
+	_ models.AssignmentStore  = (*GormStore)(nil)
+	_ models.SecondaryStore   = (*GormStore)(nil)
+	_ models.ReportingStore   = (*GormStore)(nil)
+	_ models.StatsStore       = (*GormStore)(nil)
+	_ models.StaffingStore    = (*GormStore)(nil)
+	_ models.RulesStore       = (*GormStore)(nil)
+	_ models.IntegrityStore   = (*GormStore)(nil)
+	_ models.PlanStore        = (*GormStore)(nil)
+	_ models.ScheduleStore    = (*GormStore)(nil)
+)
+
+func NewGormStore(db *gorm.DB, policy models.UniquenessPolicy) *GormStore {
+	return &GormStore{db: db, policy: policy}
+}
+
+// ApplyUniquenessPolicy приводит служебные колонки имен к политике хранилища
+// и ключи названий должностей к PositionKey
+func (s *GormStore) ApplyUniquenessPolicy(ctx context.Context) error {
+	if err := models.ApplyUniquenessPolicy(ctx, s.db, s.policy); err != nil {
+		return err
+	}
+	return models.RekeyPositions(ctx, s.db)
+}
+
+func (s *GormStore) CreateDepartment(ctx context.Context, req *models.DepartmentRequest) (*models.Department, error) {
+	return models.CreateDepartment(ctx, s.db, s.policy, req)
+}
+
+func (s *GormStore) UpdateDepartment(ctx context.Context, id uint, version int, req *models.DepartmentRequest) (*models.Department, error) {
+	return models.UpdateDepartment(ctx, s.db, s.policy, id, version, req)
+}
+
+func (s *GormStore) DeleteDepartment(ctx context.Context, id uint, version int, mode string, reassignToID *uint) error {
+	return models.DeleteDepartment(ctx, s.db, id, version, mode, reassignToID)
+}
+
+func (s *GormStore) GetWithTree(ctx context.Context, id uint, depth int, employees *models.EmployeeFilter) (*models.DepartmentResponse, error) {
+	var dept models.Department
+	response, err := dept.GetWithTree(ctx, s.db, id, depth, employees)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, models.ErrDepartmentNotFound
+	}
+	return response, err
+}
+
+func (s *GormStore) ListDepartments(ctx context.Context, filter *models.DepartmentFilter) ([]models.Department, error) {
+	return models.ListDepartments(ctx, s.db, filter)
+}
+
+func (s *GormStore) CheckNameUnique(ctx context.Context, name string, parentID *uint) (bool, error) {
+	return models.CheckNameUnique(ctx, s.db, s.policy, name, parentID)
+}
+
+func (s *GormStore) MergeDepartment(ctx context.Context, sourceID, targetID uint, version int, strategy string) (*models.MergeSummary, error) {
+	return models.MergeDepartment(ctx, s.db, s.policy, sourceID, targetID, version, strategy)
+}
+
+func (s *GormStore) SplitDepartment(ctx context.Context, id uint, version int, req *models.SplitRequest) (*models.SplitResult, error) {
+	return models.SplitDepartment(ctx, s.db, s.policy, id, version, req)
+}
+
+func (s *GormStore) CopyDepartment(ctx context.Context, id uint, req *models.CopyRequest) (*models.CopyResult, error) {
+	return models.CopyDepartment(ctx, s.db, s.policy, id, req)
+}
+
+func (s *GormStore) ReorderChildren(ctx context.Context, id uint, version int, req *models.ReorderRequest) (*models.DepartmentResponse, error) {
+	return models.ReorderChildren(ctx, s.db, id, version, req)
+}
+
+func (s *GormStore) ResolveDepartment(ctx context.Context, ref models.DepartmentRef) (uint, error) {
+	return models.ResolveDepartment(ctx, s.db, ref)
+}
+
+func (s *GormStore) CreateEmployee(ctx context.Context, departmentID uint, req *models.EmployeeRequest) (*models.Employee, error) {
+	return models.CreateEmployee(ctx, s.db, departmentID, req)
+}
+
+func (s *GormStore) GetEmployee(ctx context.Context, id uint) (*models.Employee, error) {
+	return models.GetEmployee(ctx, s.db, id)
+}
+
+func (s *GormStore) ResolveEmployee(ctx context.Context, ref models.EmployeeRef) (uint, error) {
+	return models.ResolveEmployee(ctx, s.db, ref)
+}
+
+func (s *GormStore) GetEmployeesByDepartment(ctx context.Context, departmentID uint, filter *models.EmployeeFilter) ([]models.Employee, error) {
+	return models.GetEmployeesByDepartment(ctx, s.db, departmentID, filter)
+}
+
+func (s *GormStore) UpdateEmployeeAttributes(ctx context.Context, id uint, version int, req *models.EmployeeAttributesRequest) (*models.Employee, error) {
+	return models.UpdateEmployeeAttributes(ctx, s.db, id, version, req)
+}
+
+func (s *GormStore) MoveEmployees(ctx context.Context, fromDeptID, toDeptID uint) error {
+	return models.MoveEmployees(ctx, s.db, fromDeptID, toDeptID)
+}
+
+func (s *GormStore) TransferEmployee(ctx context.Context, id uint, version int, req *models.TransferRequest) (*models.Employee, error) {
+	return models.TransferEmployee(ctx, s.db, id, version, req)
+}
+
+func (s *GormStore) TerminateEmployee(ctx context.Context, id uint, version int, req *models.TerminationRequest) (*models.Employee, error) {
+	return models.TerminateEmployee(ctx, s.db, id, version, req)
+}
+
+func (s *GormStore) RehireEmployee(ctx context.Context, id uint, version int, req *models.RehireRequest) (*models.Employee, error) {
+	return models.RehireEmployee(ctx, s.db, id, version, req)
+}
+
+func (s *GormStore) SetEmployeeStatus(ctx context.Context, id uint, version int, req *models.EmployeeStatusRequest) (*models.Employee, error) {
+	return models.SetEmployeeStatus(ctx, s.db, id, version, req)
+}
+
+func (s *GormStore) CreateAttributeDefinition(ctx context.Context, req *models.AttributeDefinitionRequest) (*models.AttributeDefinition, error) {
+	return models.CreateAttributeDefinition(ctx, s.db, req)
+}
+
+func (s *GormStore) ListAttributeDefinitions(ctx context.Context, entity string) ([]models.AttributeDefinition, error) {
+	return models.ListAttributeDefinitions(ctx, s.db, entity)
+}
+
+func (s *GormStore) GetAttributeDefinition(ctx context.Context, id uint) (*models.AttributeDefinition, error) {
+	return models.GetAttributeDefinition(ctx, s.db, id)
+}
+
+func (s *GormStore) UpdateAttributeDefinition(ctx context.Context, id uint, version int, req *models.AttributeDefinitionUpdate) (*models.AttributeDefinition, error) {
+	return models.UpdateAttributeDefinition(ctx, s.db, id, version, req)
+}
+
+func (s *GormStore) DeleteAttributeDefinition(ctx context.Context, id uint, version int) error {
+	return models.DeleteAttributeDefinition(ctx, s.db, id, version)
+}
+
+func (s *GormStore) CreatePlan(ctx context.Context, req *models.PlanRequest) (*models.Plan, error) {
+	return models.CreatePlan(ctx, s.db, s.policy, req)
+}
+
+func (s *GormStore) GetPlan(ctx context.Context, id uint) (*models.Plan, error) {
+	return models.GetPlan(ctx, s.db, id)
+}
+
+func (s *GormStore) ListPlans(ctx context.Context) ([]models.Plan, error) {
+	return models.ListPlans(ctx, s.db)
+}
+
+func (s *GormStore) AddPlanOperations(ctx context.Context, id uint, version int, ops []models.PlanOperation) (*models.Plan, error) {
+	return models.AddPlanOperations(ctx, s.db, s.policy, id, version, ops)
+}
+
+func (s *GormStore) DeletePlan(ctx context.Context, id uint, version int) error {
+	return models.DeletePlan(ctx, s.db, id, version)
+}
+
+func (s *GormStore) DiffPlan(ctx context.Context, id uint) (*models.PlanDiff, error) {
+	return models.DiffPlan(ctx, s.db, s.policy, id)
+}
+
+func (s *GormStore) ApplyPlan(ctx context.Context, id uint, version int) (*models.Plan, error) {
+	return models.ApplyPlan(ctx, s.db, s.policy, id, version)
+}
+
+func (s *GormStore) ScheduleDepartmentChange(ctx context.Context, id uint, version int, req *models.DepartmentRequest, effectiveAt time.Time) (*models.ScheduledChange, error) {
+	return models.ScheduleDepartmentChange(ctx, s.db, id, version, req, effectiveAt)
+}
+
+func (s *GormStore) ScheduleTransfer(ctx context.Context, employeeID uint, version int, req *models.TransferRequest, effectiveAt time.Time) (*models.ScheduledChange, error) {
+	return models.ScheduleTransfer(ctx, s.db, employeeID, version, req, effectiveAt)
+}
+
+func (s *GormStore) GetScheduledChange(ctx context.Context, id uint) (*models.ScheduledChange, error) {
+	return models.GetScheduledChange(ctx, s.db, id)
+}
+
+func (s *GormStore) ListScheduledChanges(ctx context.Context, status string) ([]models.ScheduledChange, error) {
+	return models.ListScheduledChanges(ctx, s.db, status)
+}
+
+func (s *GormStore) CancelScheduledChange(ctx context.Context, id uint, version int) (*models.ScheduledChange, error) {
+	return models.CancelScheduledChange(ctx, s.db, id, version)
+}
+
+func (s *GormStore) ApplyDueChanges(ctx context.Context, now time.Time) (int, error) {
+	return models.ApplyDueChanges(ctx, s.db, s.policy, now)
+}
+
+func (s *GormStore) ReserveIdempotencyKey(ctx context.Context, key *models.IdempotencyKey, staleAfter time.Duration) (*models.IdempotencyKey, error) {
+	return models.ReserveIdempotencyKey(ctx, s.db, key, staleAfter)
+}
+
+func (s *GormStore) CompleteIdempotencyKey(ctx context.Context, key *models.IdempotencyKey) error {
+	return models.CompleteIdempotencyKey(ctx, s.db, key)
+}
+
+func (s *GormStore) ReleaseIdempotencyKey(ctx context.Context, key string) error {
+	return models.ReleaseIdempotencyKey(ctx, s.db, key)
+}
+
+func (s *GormStore) PurgeExpiredIdempotencyKeys(ctx context.Context, now time.Time) (int64, error) {
+	return models.PurgeExpiredIdempotencyKeys(ctx, s.db, now)
+}
+
+func (s *GormStore) CreatePosition(ctx context.Context, req *models.PositionRequest) (*models.Position, error) {
+	return models.CreatePosition(ctx, s.db, req)
+}
+
+func (s *GormStore) ListPositions(ctx context.Context, jobFamily string) ([]models.Position, error) {
+	return models.ListPositions(ctx, s.db, jobFamily)
+}
+
+func (s *GormStore) GetPosition(ctx context.Context, id uint) (*models.Position, error) {
+	return models.GetPosition(ctx, s.db, id)
+}
+
+func (s *GormStore) UpdatePosition(ctx context.Context, id uint, version int, update *models.PositionUpdate) (*models.Position, error) {
+	return models.UpdatePosition(ctx, s.db, id, version, update)
+}
+
+func (s *GormStore) DeletePosition(ctx context.Context, id uint, version int) error {
+	return models.DeletePosition(ctx, s.db, id, version)
+}
+
+func (s *GormStore) ClusterPositionTitles(ctx context.Context, apply bool) (*models.PositionClustering, error) {
+	return models.ClusterPositionTitles(ctx, s.db, apply)
+}
+
+func (s *GormStore) EmployeeHistory(ctx context.Context, id uint) ([]models.EmployeeAssignment, error) {
+	return models.EmployeeHistory(ctx, s.db, id)
+}
+
+func (s *GormStore) GetDepartmentMovements(ctx context.Context, departmentID uint, filter *models.MovementFilter) ([]models.Movement, error) {
+	return models.GetDepartmentMovements(ctx, s.db, departmentID, filter)
+}
+
+func (s *GormStore) ListSecondaryAssignments(ctx context.Context, employeeID uint) ([]models.SecondaryAssignment, error) {
+	return models.ListSecondaryAssignments(ctx, s.db, employeeID)
+}
+
+func (s *GormStore) AddSecondaryAssignment(ctx context.Context, employeeID uint, req *models.SecondaryAssignmentRequest) (*models.SecondaryAssignment, error) {
+	return models.AddSecondaryAssignment(ctx, s.db, employeeID, req)
+}
+
+func (s *GormStore) UpdateSecondaryAssignment(ctx context.Context, employeeID, id uint, version int, update *models.SecondaryAssignmentUpdate) (*models.SecondaryAssignment, error) {
+	return models.UpdateSecondaryAssignment(ctx, s.db, employeeID, id, version, update)
+}
+
+func (s *GormStore) RemoveSecondaryAssignment(ctx context.Context, employeeID, id uint, version int) error {
+	return models.RemoveSecondaryAssignment(ctx, s.db, employeeID, id, version)
+}
+
+func (s *GormStore) GetHeadcount(ctx context.Context, departmentID uint) (*models.Headcount, error) {
+	return models.GetHeadcount(ctx, s.db, departmentID)
+}
+
+func (s *GormStore) GetDepartmentStats(ctx context.Context, id uint) (*models.DepartmentStats, error) {
+	return models.GetDepartmentStats(ctx, s.db, id)
+}
+
+func (s *GormStore) CreateHeadcountPlan(ctx context.Context, req *models.HeadcountPlanRequest) (*models.HeadcountPlan, error) {
+	return models.CreateHeadcountPlan(ctx, s.db, req)
+}
+
+func (s *GormStore) ListHeadcountPlans(ctx context.Context, filter *models.HeadcountPlanFilter) ([]models.HeadcountPlan, error) {
+	return models.ListHeadcountPlans(ctx, s.db, filter)
+}
+
+func (s *GormStore) UpdateHeadcountPlan(ctx context.Context, id uint, version int, update *models.HeadcountPlanUpdate) (*models.HeadcountPlan, error) {
+	return models.UpdateHeadcountPlan(ctx, s.db, id, version, update)
+}
+
+func (s *GormStore) DeleteHeadcountPlan(ctx context.Context, id uint, version int) error {
+	return models.DeleteHeadcountPlan(ctx, s.db, id, version)
+}
+
+func (s *GormStore) CreateVacancy(ctx context.Context, req *models.VacancyRequest) (*models.Vacancy, error) {
+	return models.CreateVacancy(ctx, s.db, req)
+}
+
+func (s *GormStore) ListVacancies(ctx context.Context, filter *models.VacancyFilter) ([]models.Vacancy, error) {
+	return models.ListVacancies(ctx, s.db, filter)
+}
+
+func (s *GormStore) GetVacancy(ctx context.Context, id uint) (*models.Vacancy, error) {
+	return models.GetVacancy(ctx, s.db, id)
+}
+
+func (s *GormStore) UpdateVacancy(ctx context.Context, id uint, version int, update *models.VacancyUpdate) (*models.Vacancy, error) {
+	return models.UpdateVacancy(ctx, s.db, id, version, update)
+}
+
+func (s *GormStore) FillVacancy(ctx context.Context, id uint, version int, req *models.EmployeeRequest) (*models.Vacancy, *models.Employee, error) {
+	return models.FillVacancy(ctx, s.db, id, version, req)
+}
+
+func (s *GormStore) GetStaffing(ctx context.Context, departmentID uint, period string) (*models.StaffingRollup, error) {
+	return models.GetStaffing(ctx, s.db, departmentID, period)
+}
+
+func (s *GormStore) SetManager(ctx context.Context, id uint, version int, req *models.ManagerRequest) (*models.Employee, error) {
+	return models.SetManager(ctx, s.db, id, version, req)
+}
+
+func (s *GormStore) DirectReports(ctx context.Context, id uint, filter *models.EmployeeFilter) ([]models.Employee, error) {
+	return models.DirectReports(ctx, s.db, id, filter)
+}
+
+func (s *GormStore) ReportingChain(ctx context.Context, id uint) ([]models.Employee, error) {
+	return models.ReportingChain(ctx, s.db, id)
+}
+
+func (s *GormStore) GetSpanOfControl(ctx context.Context, id uint) (*models.SpanOfControl, error) {
+	return models.GetSpanOfControl(ctx, s.db, id)
+}
+
+func (s *GormStore) GetReportingIssues(ctx context.Context) ([]models.ReportingIssue, error) {
+	return models.GetReportingIssues(ctx, s.db)
+}
+
+func (s *GormStore) GetHierarchyRules(ctx context.Context) (*models.HierarchyRuleSet, error) {
+	return models.GetHierarchyRules(ctx, s.db)
+}
+
+func (s *GormStore) UpdateHierarchyRules(ctx context.Context, version int, rules *models.HierarchyRules) (*models.HierarchyRuleSet, error) {
+	return models.UpdateHierarchyRules(ctx, s.db, version, rules)
+}
+
+func (s *GormStore) CheckHierarchy(ctx context.Context) ([]models.RuleViolation, error) {
+	return models.CheckHierarchy(ctx, s.db)
+}
+
+func (s *GormStore) CheckIntegrity(ctx context.Context, repair bool) (*models.IntegrityReport, error) {
+	return models.CheckIntegrity(ctx, s.db, s.policy, repair, time.Now())
+}
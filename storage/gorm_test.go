@@ -0,0 +1,71 @@
+package storage_test
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/kroulersama/goProject/internal/migrate"
+	"github.com/kroulersama/goProject/models"
+	"github.com/kroulersama/goProject/storage"
+	"github.com/kroulersama/goProject/storage/storetest"
+)
+
+func TestConformanceSQLite(t *testing.T) {
+	storetest.Run(t, func(t *testing.T, policy models.UniquenessPolicy) storetest.Store {
+		return newGormStore(t, &storage.Config{Driver: storage.DriverSQLite, Path: t.TempDir() + "/test.db"}, policy)
+	})
+}
+
+// TEST_POSTGRES_DSN - пустая база для проверок; схема public пересоздается перед каждой
+func TestConformancePostgres(t *testing.T) {
+	dsn := os.Getenv("TEST_POSTGRES_DSN")
+	if dsn == "" {
+		t.Skip("TEST_POSTGRES_DSN is not set")
+	}
+	cfg := &storage.Config{Driver: storage.DriverPostgres, DSN: dsn}
+
+	storetest.Run(t, func(t *testing.T, policy models.UniquenessPolicy) storetest.Store {
+		db, err := storage.NewConnection(cfg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = db.Exec("DROP SCHEMA public CASCADE; CREATE SCHEMA public").Error
+		if sqlDB, dbErr := db.DB(); dbErr == nil {
+			sqlDB.Close()
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		return newGormStore(t, cfg, policy)
+	})
+}
+
+// Хранилище на базе с примененными миграциями
+func newGormStore(t *testing.T, cfg *storage.Config, policy models.UniquenessPolicy) *storage.GormStore {
+	t.Helper()
+	m, err := migrate.New(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer m.Close()
+	if _, err := m.Up(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	db, err := storage.NewConnection(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+
+	s := storage.NewGormStore(db, policy)
+	if err := s.ApplyUniquenessPolicy(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
@@ -0,0 +1,116 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/kroulersama/goProject/models"
+)
+
+// EmployeeHistory периоды сотрудника от первого к последнему
+func (s *Store) EmployeeHistory(ctx context.Context, id uint) ([]models.EmployeeAssignment, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if _, ok := s.employees[id]; !ok {
+		return nil, models.ErrEmployeeNotFound
+	}
+	history := s.assignmentsOf(func(a models.EmployeeAssignment) bool { return a.EmployeeID == id })
+	return history, nil
+}
+
+// GetDepartmentMovements кто пришел в подразделение и кто из него ушел
+func (s *Store) GetDepartmentMovements(ctx context.Context, departmentID uint, filter *models.MovementFilter) ([]models.Movement, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if _, ok := s.departments[departmentID]; !ok {
+		return nil, models.ErrDepartmentNotFound
+	}
+
+	own := s.assignmentsOf(func(a models.EmployeeAssignment) bool { return a.DepartmentID == departmentID })
+	employees := make(map[uint]bool)
+	names := make(map[uint]string)
+	for _, a := range own {
+		employees[a.EmployeeID] = true
+		names[a.EmployeeID] = s.employees[a.EmployeeID].FullName
+	}
+	related := s.assignmentsOf(func(a models.EmployeeAssignment) bool { return employees[a.EmployeeID] })
+
+	return models.DepartmentMovements(departmentID, own, related, names, filter), nil
+}
+
+// Периоды по условию в порядке начала
+func (s *Store) assignmentsOf(match func(models.EmployeeAssignment) bool) []models.EmployeeAssignment {
+	var assignments []models.EmployeeAssignment
+	for _, a := range s.assignments {
+		if match(a) {
+			assignments = append(assignments, a)
+		}
+	}
+	sort.Slice(assignments, func(i, j int) bool {
+		a, b := assignments[i], assignments[j]
+		if !a.From.Equal(b.From) {
+			return a.From.Before(b.From)
+		}
+		return a.ID < b.ID
+	})
+	return assignments
+}
+
+// Открывает период с текущими отделом и должностью сотрудника, закрывая прежний;
+// если они не изменились или сотрудник уволен, ничего не делает
+func (s *Store) recordAssignment(emp models.Employee, at time.Time, actor string) {
+	if emp.Status == models.EmployeeTerminated {
+		return
+	}
+	for _, a := range s.assignments {
+		if a.EmployeeID == emp.ID && a.To == nil && a.DepartmentID == emp.DepartmentId && a.Position == emp.Position {
+			return
+		}
+	}
+	s.closeAssignment(emp.ID, at, actor)
+
+	s.nextAssignID++
+	s.assignments[s.nextAssignID] = models.EmployeeAssignment{
+		ID:           s.nextAssignID,
+		EmployeeID:   emp.ID,
+		DepartmentID: emp.DepartmentId,
+		Position:     emp.Position,
+		From:         at.UTC(),
+		Actor:        actor,
+	}
+}
+
+// Закрывает текущий период сотрудника моментом at, но не раньше его начала
+func (s *Store) closeAssignment(employeeID uint, at time.Time, actor string) {
+	for id, a := range s.assignments {
+		if a.EmployeeID == employeeID && a.To == nil {
+			end := at.UTC()
+			if end.Before(a.From) {
+				end = a.From
+			}
+			a.To = &end
+			a.ClosedBy = actor
+			s.assignments[id] = a
+		}
+	}
+}
+
+// Аналог ON DELETE CASCADE для истории
+func (s *Store) deleteAssignments(employeeID uint) {
+	for id, a := range s.assignments {
+		if a.EmployeeID == employeeID {
+			delete(s.assignments, id)
+		}
+	}
+}
@@ -0,0 +1,240 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/kroulersama/goProject/models"
+)
+
+// CreateAttributeDefinition добавляет описание атрибута
+func (s *Store) CreateAttributeDefinition(ctx context.Context, req *models.AttributeDefinitionRequest) (*models.AttributeDefinition, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, def := range s.attributes {
+		if def.Entity == req.Entity && def.Name == req.Name {
+			return nil, models.ErrAttributeExists
+		}
+	}
+
+	s.nextAttrID++
+	def := models.AttributeDefinition{
+		ID:          s.nextAttrID,
+		Entity:      req.Entity,
+		Name:        req.Name,
+		Type:        req.Type,
+		Required:    req.Required,
+		EnumValues:  append([]string{}, req.EnumValues...),
+		Pattern:     req.Pattern,
+		Description: req.Description,
+		CreatedAt:   time.Now().UTC(),
+		Version:     1,
+	}
+	s.attributes[def.ID] = def
+	return &def, nil
+}
+
+// ListAttributeDefinitions описания по сущности; пустая - все
+func (s *Store) ListAttributeDefinitions(ctx context.Context, entity string) ([]models.AttributeDefinition, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if !models.ValidAttributeEntity(entity) {
+		return nil, models.ErrInvalidAttributeEntity
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var defs []models.AttributeDefinition
+	for _, def := range s.attributes {
+		if entity == "" || def.Entity == entity {
+			defs = append(defs, def)
+		}
+	}
+	sort.Slice(defs, func(i, j int) bool {
+		if defs[i].Entity != defs[j].Entity {
+			return defs[i].Entity < defs[j].Entity
+		}
+		return defs[i].Name < defs[j].Name
+	})
+	return defs, nil
+}
+
+// GetAttributeDefinition описание по id
+func (s *Store) GetAttributeDefinition(ctx context.Context, id uint) (*models.AttributeDefinition, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	def, ok := s.attributes[id]
+	if !ok {
+		return nil, models.ErrAttributeDefinitionNotFound
+	}
+	return &def, nil
+}
+
+// UpdateAttributeDefinition меняет обязательность, перечень, шаблон и описание
+func (s *Store) UpdateAttributeDefinition(ctx context.Context, id uint, version int, update *models.AttributeDefinitionUpdate) (*models.AttributeDefinition, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	def, ok := s.attributes[id]
+	if !ok {
+		return nil, models.ErrAttributeDefinitionNotFound
+	}
+	if version != 0 && def.Version != version {
+		return nil, models.ErrVersionMismatch
+	}
+
+	req := def.WithUpdate(update)
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+
+	def.Required = req.Required
+	def.EnumValues = append([]string{}, req.EnumValues...)
+	def.Pattern = req.Pattern
+	def.Description = req.Description
+	def.Version++
+	s.attributes[id] = def
+	return &def, nil
+}
+
+// DeleteAttributeDefinition удаляет описание вместе со значениями атрибута
+func (s *Store) DeleteAttributeDefinition(ctx context.Context, id uint, version int) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	def, ok := s.attributes[id]
+	if !ok {
+		return models.ErrAttributeDefinitionNotFound
+	}
+	if version != 0 && def.Version != version {
+		return models.ErrVersionMismatch
+	}
+	delete(s.attributes, id)
+
+	// Хранимые map не меняются на месте: отданные копии их разделяют
+	removed := map[string]any{def.Name: nil}
+	if def.Entity == models.EntityEmployee {
+		for empID, emp := range s.employees {
+			if _, ok := emp.Attributes[def.Name]; ok {
+				emp.Attributes = models.MergeAttributes(emp.Attributes, removed)
+				s.employees[empID] = emp
+			}
+		}
+		return nil
+	}
+	for deptID, dept := range s.departments {
+		if _, ok := dept.Attributes[def.Name]; ok {
+			dept.Attributes = models.MergeAttributes(dept.Attributes, removed)
+			s.departments[deptID] = dept
+		}
+	}
+	return nil
+}
+
+// ListDepartments подразделения без дерева, отфильтрованные по атрибутам
+func (s *Store) ListDepartments(ctx context.Context, filter *models.DepartmentFilter) ([]models.Department, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	attrs, err := s.attributeFilter(models.EntityDepartment, filter.Attributes)
+	if err != nil {
+		return nil, err
+	}
+
+	var departments []models.Department
+	for _, dept := range s.departments {
+		if attrs.Match(dept.Attributes) {
+			departments = append(departments, dept)
+		}
+	}
+	// Как в ListDepartments модели: корни, затем по родителю, sort_order и id
+	sort.Slice(departments, func(i, j int) bool {
+		a, b := departments[i], departments[j]
+		if (a.ParentId == nil) != (b.ParentId == nil) {
+			return a.ParentId == nil
+		}
+		if a.ParentId != nil && *a.ParentId != *b.ParentId {
+			return *a.ParentId < *b.ParentId
+		}
+		if a.SortOrder != b.SortOrder {
+			return a.SortOrder < b.SortOrder
+		}
+		return a.Id < b.Id
+	})
+	return departments, nil
+}
+
+// UpdateEmployeeAttributes дополняет атрибуты сотрудника
+func (s *Store) UpdateEmployeeAttributes(ctx context.Context, id uint, version int, req *models.EmployeeAttributesRequest) (*models.Employee, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	emp, ok := s.employees[id]
+	if !ok {
+		return nil, models.ErrEmployeeNotFound
+	}
+	if version != 0 && emp.Version != version {
+		return nil, models.ErrVersionMismatch
+	}
+
+	attrs, err := s.attributeSchema(models.EntityEmployee).Validate(req.Attributes, false)
+	if err != nil {
+		return nil, err
+	}
+	emp.Attributes = models.MergeAttributes(emp.Attributes, attrs)
+	emp.Version++
+	s.employees[id] = emp
+	return &emp, nil
+}
+
+// Описания атрибутов сущности
+func (s *Store) attributeSchema(entity string) models.AttributeSchema {
+	var schema models.AttributeSchema
+	for _, def := range s.attributes {
+		if def.Entity == entity {
+			schema = append(schema, def)
+		}
+	}
+	sort.Slice(schema, func(i, j int) bool { return schema[i].Name < schema[j].Name })
+	return schema
+}
+
+// Фильтр из query по описаниям атрибутов сущности
+func (s *Store) attributeFilter(entity string, raw map[string]string) (models.AttributeFilter, error) {
+	if len(raw) == 0 {
+		return nil, nil
+	}
+	return s.attributeSchema(entity).ParseFilter(raw)
+}
@@ -0,0 +1,112 @@
+package memory
+
+import (
+	"context"
+
+	"github.com/kroulersama/goProject/models"
+)
+
+// TerminateEmployee увольнение с датой и причиной
+func (s *Store) TerminateEmployee(ctx context.Context, id uint, version int, req *models.TerminationRequest) (*models.Employee, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	emp, err := s.employeeForUpdate(id, version)
+	if err != nil {
+		return nil, err
+	}
+	if emp.Status == models.EmployeeTerminated {
+		return nil, models.ErrEmployeeTerminated
+	}
+	if err := req.Validate(&emp); err != nil {
+		return nil, err
+	}
+
+	emp.Status = models.EmployeeTerminated
+	emp.TerminatedAt = req.TerminatedAt
+	emp.TerminationReason = req.Reason
+	emp.Version++
+	s.employees[id] = emp
+	s.releaseReports(id)
+	s.closeAssignment(id, *emp.TerminatedAt, models.ActorFromContext(ctx))
+	return &emp, nil
+}
+
+// RehireEmployee повторный прием уволенного сотрудника
+func (s *Store) RehireEmployee(ctx context.Context, id uint, version int, req *models.RehireRequest) (*models.Employee, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	emp, err := s.employeeForUpdate(id, version)
+	if err != nil {
+		return nil, err
+	}
+	if emp.Status != models.EmployeeTerminated {
+		return nil, models.ErrEmployeeNotTerminated
+	}
+	if err := req.Validate(&emp); err != nil {
+		return nil, err
+	}
+	if req.DepartmentID != nil {
+		if _, ok := s.departments[*req.DepartmentID]; !ok {
+			return nil, models.ErrUnknownDepartment
+		}
+		emp.DepartmentId = *req.DepartmentID
+	}
+
+	emp.Status = models.EmployeeActive
+	emp.HiredAt = req.HiredAt
+	emp.TerminatedAt = nil
+	emp.TerminationReason = ""
+	emp.Version++
+	s.employees[id] = emp
+	s.dropSecondaryInPrimary(emp)
+	s.recordAssignment(emp, *emp.HiredAt, models.ActorFromContext(ctx))
+	return &emp, nil
+}
+
+// SetEmployeeStatus отпуск и возврат из него
+func (s *Store) SetEmployeeStatus(ctx context.Context, id uint, version int, req *models.EmployeeStatusRequest) (*models.Employee, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	emp, err := s.employeeForUpdate(id, version)
+	if err != nil {
+		return nil, err
+	}
+	if emp.Status == models.EmployeeTerminated {
+		return nil, models.ErrEmployeeTerminated
+	}
+
+	emp.Status = req.Status
+	emp.Version++
+	s.employees[id] = emp
+	return &emp, nil
+}
+
+// Сотрудник с проверкой версии; version 0 - без проверки
+func (s *Store) employeeForUpdate(id uint, version int) (models.Employee, error) {
+	emp, ok := s.employees[id]
+	if !ok {
+		return models.Employee{}, models.ErrEmployeeNotFound
+	}
+	if version != 0 && emp.Version != version {
+		return models.Employee{}, models.ErrVersionMismatch
+	}
+	return emp, nil
+}
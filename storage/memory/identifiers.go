@@ -0,0 +1,115 @@
+package memory
+
+import (
+	"context"
+	"maps"
+
+	"github.com/kroulersama/goProject/models"
+)
+
+// ResolveDepartment id подразделения по коду или внешнему id
+func (s *Store) ResolveDepartment(ctx context.Context, ref models.DepartmentRef) (uint, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	if ref.ID != 0 {
+		return ref.ID, nil
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for id, dept := range s.departments {
+		if ref.Code != "" && dept.Code != nil && *dept.Code == ref.Code {
+			return id, nil
+		}
+		if ref.System != "" && dept.ExternalIDs[ref.System] == ref.ExternalID {
+			return id, nil
+		}
+	}
+	return 0, models.ErrDepartmentNotFound
+}
+
+// ResolveEmployee id сотрудника по табельному номеру
+func (s *Store) ResolveEmployee(ctx context.Context, ref models.EmployeeRef) (uint, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	if ref.ID != 0 {
+		return ref.ID, nil
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for id, emp := range s.employees {
+		if emp.PersonnelNumber != nil && *emp.PersonnelNumber == ref.PersonnelNumber {
+			return id, nil
+		}
+	}
+	return 0, models.ErrEmployeeNotFound
+}
+
+// GetEmployee сотрудник по id
+func (s *Store) GetEmployee(ctx context.Context, id uint) (*models.Employee, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	emp, ok := s.employees[id]
+	if !ok {
+		return nil, models.ErrEmployeeNotFound
+	}
+	return &emp, nil
+}
+
+// Аналог уникальных индексов по коду и внешним id
+func (s *Store) identifiersFree(changed models.Department) error {
+	for id, dept := range s.departments {
+		if id == changed.Id {
+			continue
+		}
+		if changed.Code != nil && dept.Code != nil && *dept.Code == *changed.Code {
+			return models.ErrCodeExists
+		}
+		for system, externalID := range changed.ExternalIDs {
+			if other, ok := dept.ExternalIDs[system]; ok && other == externalID {
+				return models.ErrExternalIDExists
+			}
+		}
+	}
+	return nil
+}
+
+// Аналог уникального индекса по табельному номеру
+func (s *Store) personnelNumberFree(number *string) error {
+	if number == nil {
+		return nil
+	}
+	for _, emp := range s.employees {
+		if emp.PersonnelNumber != nil && *emp.PersonnelNumber == *number {
+			return models.ErrPersonnelNumberExists
+		}
+	}
+	return nil
+}
+
+// Применение кода и внешних id запроса; хранимые map не меняются на месте
+func applyIdentifiers(department *models.Department, req *models.DepartmentRequest) {
+	if req.Code != nil {
+		department.Code = nil
+		if *req.Code != "" {
+			code := *req.Code
+			department.Code = &code
+		}
+	}
+	if req.ExternalIDs != nil {
+		department.ExternalIDs = maps.Clone(req.ExternalIDs)
+	}
+	if department.ExternalIDs == nil {
+		department.ExternalIDs = map[string]string{}
+	}
+}
@@ -0,0 +1,45 @@
+package memory
+
+import (
+	"context"
+	"time"
+
+	"github.com/kroulersama/goProject/models"
+)
+
+// CheckIntegrity проверка подразделений и сотрудников; с repair - безопасные исправления
+func (s *Store) CheckIntegrity(ctx context.Context, repair bool) (*models.IntegrityReport, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Служебные колонки имен здесь не хранятся, считаем их по текущему дереву
+	departments := make([]models.Department, 0, len(s.departments))
+	for _, dept := range s.departments {
+		dept.NameKey = models.NameKey(dept.Name)
+		dept.NameScope = s.policy.NameScope(dept.ParentId, rootKey(s.departments, dept))
+		departments = append(departments, dept)
+	}
+	employees := make([]models.Employee, 0, len(s.employees))
+	for _, emp := range s.employees {
+		employees = append(employees, emp)
+	}
+
+	report, repairs := models.InspectIntegrity(s.policy, departments, employees, time.Now())
+	if !repair {
+		return report, nil
+	}
+	for _, fixed := range repairs {
+		dept := s.departments[fixed.Id]
+		dept.ParentId = fixed.ParentId
+		dept.SortOrder = fixed.SortOrder
+		dept.Name = fixed.Name
+		dept.Version++
+		s.departments[fixed.Id] = dept
+	}
+	report.MarkRepaired()
+	return report, nil
+}
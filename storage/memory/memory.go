@@ -0,0 +1,672 @@
+package memory
+
+import (
+	"context"
+	"maps"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kroulersama/goProject/models"
+)
+
+// Хранилище в памяти с той же семантикой, что и GORM/Postgres
+type Store struct {
+	policy          models.UniquenessPolicy
+	mu              sync.RWMutex
+	departments     map[uint]models.Department
+	employees       map[uint]models.Employee
+	idempotency     map[string]models.IdempotencyKey
+	plans           map[uint]models.Plan
+	changes         map[uint]models.ScheduledChange
+	attributes      map[uint]models.AttributeDefinition
+	positions       map[uint]models.Position
+	assignments     map[uint]models.EmployeeAssignment
+	secondary       map[uint]models.SecondaryAssignment
+	headcountPlans  map[uint]models.HeadcountPlan
+	vacancies       map[uint]models.Vacancy
+	rules           models.HierarchyRuleSet
+	nextDeptID      uint
+	nextEmpID       uint
+	nextPlanID      uint
+	nextChangeID    uint
+	nextAttrID      uint
+	nextPosID       uint
+	nextAssignID    uint
+	nextSecondaryID uint
+	nextHCPlanID    uint
+	nextVacancyID   uint
+}
+
+var (
+	_ models.DepartmentStore  = (*Store)(nil)
+	_ models.EmployeeStore    = (*Store)(nil)
+	_ models.IdempotencyStore = (*Store)(nil)
+	_ models.AttributeStore   = (*Store)(nil)
+	_ models.PositionStore    = (*Store)(nil)
+	_ models.AssignmentStore  = (*Store)(nil)
+	_ models.SecondaryStore   = (*Store)(nil)
+	_ models.ReportingStore   = (*Store)(nil)
+	_ models.StatsStore       = (*Store)(nil)
+	_ models.StaffingStore    = (*Store)(nil)
+	_ models.RulesStore       = (*Store)(nil)
+	_ models.IntegrityStore   = (*Store)(nil)
+	_ models.PlanStore        = (*Store)(nil)
+	_ models.ScheduleStore    = (*Store)(nil)
+)
+
+func New(policy models.UniquenessPolicy) *Store {
+	return &Store{
+		policy:         policy,
+		departments:    make(map[uint]models.Department),
+		employees:      make(map[uint]models.Employee),
+		idempotency:    make(map[string]models.IdempotencyKey),
+		plans:          make(map[uint]models.Plan),
+		changes:        make(map[uint]models.ScheduledChange),
+		attributes:     make(map[uint]models.AttributeDefinition),
+		positions:      make(map[uint]models.Position),
+		assignments:    make(map[uint]models.EmployeeAssignment),
+		secondary:      make(map[uint]models.SecondaryAssignment),
+		headcountPlans: make(map[uint]models.HeadcountPlan),
+		vacancies:      make(map[uint]models.Vacancy),
+		rules:          models.HierarchyRuleSet{ID: 1, Version: 1},
+	}
+}
+
+// CreateDepartment создание подразделения
+func (s *Store) CreateDepartment(ctx context.Context, req *models.DepartmentRequest) (*models.Department, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.createDepartment(req)
+}
+
+func (s *Store) createDepartment(req *models.DepartmentRequest) (*models.Department, error) {
+	if err := req.Validate(s.attributeSchema(models.EntityDepartment)); err != nil {
+		return nil, err
+	}
+
+	// Проверка родителя
+	if req.ParentID != nil {
+		if _, ok := s.departments[*req.ParentID]; !ok {
+			return nil, models.ErrParentNotFound
+		}
+	}
+
+	// Правила иерархии
+	node := models.HierarchyNode{ParentID: req.ParentID, Name: req.Name, Type: req.TypeName()}
+	if err := s.rules.Rules.CheckChange(s.hierarchyNodes(), node); err != nil {
+		return nil, err
+	}
+
+	department := models.Department{
+		Id:        s.nextDeptID + 1,
+		Name:      req.Name,
+		ParentId:  copyID(req.ParentID),
+		CreatedAt: time.Now(),
+		Version:   1,
+		SortOrder: s.nextSortOrder(req.ParentID),
+		Type:      node.Type,
+
+		Attributes: models.MergeAttributes(nil, req.Attributes),
+	}
+	applyIdentifiers(&department, req)
+
+	// Уникальность имени по политике
+	if !s.namesUniqueWith(department) {
+		return nil, models.ErrNameExists
+	}
+	if err := s.identifiersFree(department); err != nil {
+		return nil, err
+	}
+
+	s.nextDeptID++
+	s.departments[department.Id] = department
+
+	if req.Position != nil {
+		department = s.placeAt(department.Id, *req.Position)
+	}
+	return &department, nil
+}
+
+// UpdateDepartment переименование и перемещение
+func (s *Store) UpdateDepartment(ctx context.Context, id uint, version int, req *models.DepartmentRequest) (*models.Department, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.updateDepartment(id, version, req)
+}
+
+func (s *Store) updateDepartment(id uint, version int, req *models.DepartmentRequest) (*models.Department, error) {
+	department, ok := s.departments[id]
+	if !ok {
+		return nil, models.ErrDepartmentNotFound
+	}
+	if version != 0 && department.Version != version {
+		return nil, models.ErrVersionMismatch
+	}
+
+	// Валидация имени
+	if req.Name != "" {
+		req.Name = strings.TrimSpace(req.Name)
+		if len(req.Name) > 200 {
+			return nil, models.ErrNameTooLong
+		}
+		department.Name = req.Name
+	}
+	if req.Position != nil && *req.Position < 0 {
+		return nil, models.ErrInvalidPosition
+	}
+
+	// Код и внешние id
+	if err := req.ValidateIdentifiers(); err != nil {
+		return nil, err
+	}
+	applyIdentifiers(&department, req)
+
+	// Атрибуты дополняют текущие
+	if req.Attributes != nil {
+		attrs, err := s.attributeSchema(models.EntityDepartment).Validate(req.Attributes, false)
+		if err != nil {
+			return nil, err
+		}
+		department.Attributes = models.MergeAttributes(department.Attributes, attrs)
+	}
+
+	// Обновление parent_id
+	if req.ParentID != nil {
+		if _, ok := s.departments[*req.ParentID]; !ok {
+			return nil, models.ErrParentNotFound
+		}
+		if id == *req.ParentID {
+			return nil, models.ErrSelfParent
+		}
+		for _, childID := range s.descendantIDs(id) {
+			if childID == *req.ParentID {
+				return nil, models.ErrCycleDetected
+			}
+		}
+		// У нового родителя - в конец списка
+		if department.ParentId == nil || *department.ParentId != *req.ParentID {
+			department.SortOrder = s.nextSortOrder(req.ParentID)
+		}
+		department.ParentId = copyID(req.ParentID)
+	}
+
+	// Правила иерархии для нового имени, типа и места
+	if req.Type != nil {
+		department.Type = req.TypeName()
+	}
+	if req.Name != "" || req.ParentID != nil || req.Type != nil {
+		if err := s.rules.Rules.CheckChange(s.hierarchyNodes(), department.HierarchyNode()); err != nil {
+			return nil, err
+		}
+	}
+
+	// Аналог уникального индекса (name_scope, name_key)
+	if !s.namesUniqueWith(department) {
+		return nil, models.ErrNameExists
+	}
+	if err := s.identifiersFree(department); err != nil {
+		return nil, err
+	}
+
+	department.Version++
+	s.departments[id] = department
+
+	if req.Position != nil {
+		department = s.placeAt(id, *req.Position)
+	}
+	return &department, nil
+}
+
+// DeleteDepartment удаление в режиме cascade или reassign
+func (s *Store) DeleteDepartment(ctx context.Context, id uint, version int, mode string, reassignToID *uint) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.deleteDepartment(id, version, mode, reassignToID, models.ActorFromContext(ctx))
+}
+
+func (s *Store) deleteDepartment(id uint, version int, mode string, reassignToID *uint, actor string) error {
+	department, ok := s.departments[id]
+	if !ok {
+		return models.ErrDepartmentNotFound
+	}
+	if version != 0 && department.Version != version {
+		return models.ErrVersionMismatch
+	}
+
+	switch mode {
+	case "cascade":
+		s.deleteSubtree(id)
+		return nil
+
+	case "reassign":
+		if reassignToID == nil {
+			return models.ErrReassignRequired
+		}
+		if *reassignToID == id {
+			return models.ErrReassignToSame
+		}
+		if _, ok := s.departments[*reassignToID]; !ok {
+			return models.ErrTargetNotFound
+		}
+
+		// Переводим сотрудников, дочерние удаляются каскадно
+		s.moveEmployees(id, *reassignToID, actor)
+		s.moveSecondary(id, *reassignToID)
+		s.moveStaffing(id, *reassignToID)
+		s.deleteSubtree(id)
+		return nil
+
+	default:
+		return models.ErrInvalidMode
+	}
+}
+
+// GetWithTree подразделение с поддеревом
+func (s *Store) GetWithTree(ctx context.Context, id uint, depth int, employees *models.EmployeeFilter) (*models.DepartmentResponse, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if _, ok := s.departments[id]; !ok {
+		return nil, models.ErrDepartmentNotFound
+	}
+	return s.tree(id, depth, employees), nil
+}
+
+// CheckNameUnique свободно ли имя для нового подразделения
+func (s *Store) CheckNameUnique(ctx context.Context, name string, parentID *uint) (bool, error) {
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.namesUniqueWith(models.Department{Id: s.nextDeptID + 1, Name: name, ParentId: parentID}), nil
+}
+
+// CreateEmployee создание сотрудника
+func (s *Store) CreateEmployee(ctx context.Context, departmentID uint, req *models.EmployeeRequest) (*models.Employee, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.createEmployee(departmentID, req, models.ActorFromContext(ctx))
+}
+
+func (s *Store) createEmployee(departmentID uint, req *models.EmployeeRequest, actor string) (*models.Employee, error) {
+	if _, ok := s.departments[departmentID]; !ok {
+		return nil, models.ErrDepartmentNotFound
+	}
+
+	// Должность из каталога
+	if req.PositionID != nil {
+		position, ok := s.positions[*req.PositionID]
+		if !ok {
+			return nil, models.ErrUnknownPosition
+		}
+		if err := req.UsePosition(&position); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := req.Validate(s.attributeSchema(models.EntityEmployee)); err != nil {
+		return nil, err
+	}
+	if err := s.personnelNumberFree(req.PersonnelNumber); err != nil {
+		return nil, err
+	}
+
+	s.nextEmpID++
+	employee := models.Employee{
+		ID:              s.nextEmpID,
+		DepartmentId:    departmentID,
+		FullName:        req.FullName,
+		Position:        req.Position,
+		PositionID:      req.PositionID,
+		HiredAt:         req.HiredAt,
+		PersonnelNumber: req.PersonnelNumber,
+		Attributes:      models.MergeAttributes(nil, req.Attributes),
+		Status:          models.EmployeeActive,
+		CreatedAt:       time.Now(),
+		Version:         1,
+	}
+	s.employees[employee.ID] = employee
+
+	// Первый период истории начинается с даты найма
+	from := employee.CreatedAt
+	if employee.HiredAt != nil {
+		from = *employee.HiredAt
+	}
+	s.recordAssignment(employee, from, actor)
+
+	return &employee, nil
+}
+
+// GetEmployeesByDepartment сотрудники отдела
+func (s *Store) GetEmployeesByDepartment(ctx context.Context, departmentID uint, filter *models.EmployeeFilter) ([]models.Employee, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	// Фильтр по атрибутам
+	attrs, err := s.attributeFilter(models.EntityEmployee, filter.Attributes)
+	if err != nil {
+		return nil, err
+	}
+	var employees []models.Employee
+	for _, emp := range s.employeesOf(departmentID) {
+		if attrs.Match(emp.Attributes) && filter.Includes(emp.Status) {
+			employees = append(employees, emp)
+		}
+	}
+
+	// Сортировка
+	switch filter.SortBy {
+	case "name":
+		sort.SliceStable(employees, func(i, j int) bool {
+			return employees[i].FullName < employees[j].FullName
+		})
+	default:
+		sort.SliceStable(employees, func(i, j int) bool {
+			return employees[i].CreatedAt.After(employees[j].CreatedAt)
+		})
+	}
+
+	return employees, nil
+}
+
+// MoveEmployees перевод всех сотрудников отдела
+func (s *Store) MoveEmployees(ctx context.Context, fromDeptID, toDeptID uint) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.moveEmployees(fromDeptID, toDeptID, models.ActorFromContext(ctx))
+	return nil
+}
+
+// TransferEmployee перевод сотрудника в другой отдел
+func (s *Store) TransferEmployee(ctx context.Context, id uint, version int, req *models.TransferRequest) (*models.Employee, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.transferEmployee(id, version, req.DepartmentID, models.ActorFromContext(ctx))
+}
+
+func (s *Store) transferEmployee(id uint, version int, toDeptID uint, actor string) (*models.Employee, error) {
+	emp, ok := s.employees[id]
+	if !ok {
+		return nil, models.ErrEmployeeNotFound
+	}
+	if version != 0 && emp.Version != version {
+		return nil, models.ErrVersionMismatch
+	}
+	if emp.Status == models.EmployeeTerminated {
+		return nil, models.ErrEmployeeTerminated
+	}
+	if _, ok := s.departments[toDeptID]; !ok {
+		return nil, models.ErrUnknownDepartment
+	}
+
+	emp.DepartmentId = toDeptID
+	emp.Version++
+	s.employees[id] = emp
+	s.dropSecondaryInPrimary(emp)
+	s.recordAssignment(emp, time.Now(), actor)
+	return &emp, nil
+}
+
+// ReserveIdempotencyKey занимает ключ или возвращает сохраненный
+func (s *Store) ReserveIdempotencyKey(ctx context.Context, key *models.IdempotencyKey, staleAfter time.Duration) (*models.IdempotencyKey, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now().UTC()
+	if existing, ok := s.idempotency[key.Key]; ok {
+		expired := now.After(existing.ExpiresAt)
+		abandoned := existing.Pending() && now.Sub(existing.CreatedAt) > staleAfter
+		if !expired && !abandoned {
+			return &existing, nil
+		}
+	}
+
+	stored := *key
+	if stored.CreatedAt.IsZero() {
+		stored.CreatedAt = now
+	}
+	s.idempotency[key.Key] = stored
+	return nil, nil
+}
+
+// CompleteIdempotencyKey сохраняет ответ
+func (s *Store) CompleteIdempotencyKey(ctx context.Context, key *models.IdempotencyKey) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if stored, ok := s.idempotency[key.Key]; ok {
+		stored.StatusCode = key.StatusCode
+		stored.ContentType = key.ContentType
+		stored.ETag = key.ETag
+		stored.Body = append([]byte(nil), key.Body...)
+		s.idempotency[key.Key] = stored
+	}
+	return nil
+}
+
+// ReleaseIdempotencyKey освобождает ключ
+func (s *Store) ReleaseIdempotencyKey(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.idempotency, key)
+	return nil
+}
+
+// PurgeExpiredIdempotencyKeys удаляет просроченные ключи
+func (s *Store) PurgeExpiredIdempotencyKeys(ctx context.Context, now time.Time) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var purged int64
+	for k, v := range s.idempotency {
+		if v.ExpiresAt.Before(now) {
+			delete(s.idempotency, k)
+			purged++
+		}
+	}
+	return purged, nil
+}
+
+func (s *Store) tree(id uint, depth int, filter *models.EmployeeFilter) *models.DepartmentResponse {
+	response := &models.DepartmentResponse{
+		Department: s.departments[id],
+	}
+
+	if filter != nil {
+		var employees []models.Employee
+		for _, emp := range s.employeesOf(id) {
+			if filter.Includes(emp.Status) {
+				employees = append(employees, emp)
+			}
+		}
+		sort.SliceStable(employees, func(i, j int) bool {
+			a, b := employees[i], employees[j]
+			if !a.CreatedAt.Equal(b.CreatedAt) {
+				return a.CreatedAt.After(b.CreatedAt)
+			}
+			return a.FullName < b.FullName
+		})
+		response.Employees = employees
+		if filter.Secondary {
+			response.Secondary = s.secondaryMembers(id, filter)
+		}
+	}
+
+	if depth > 0 {
+		for _, childID := range s.childIDs(id) {
+			response.Children = append(response.Children, *s.tree(childID, depth-1, filter))
+		}
+	}
+
+	return response
+}
+
+// namesUniqueWith не нарушит ли changed политику уникальности имен:
+// пары (область, ключ) всех подразделений после изменения должны быть различны
+func (s *Store) namesUniqueWith(changed models.Department) bool {
+	view := maps.Clone(s.departments)
+	view[changed.Id] = changed
+
+	seen := make(map[[2]string]bool, len(view))
+	for _, dept := range view {
+		name := [2]string{s.policy.NameScope(dept.ParentId, rootKey(view, dept)), models.NameKey(dept.Name)}
+		if seen[name] {
+			return false
+		}
+		seen[name] = true
+	}
+	return true
+}
+
+// Ключ имени корня ветки
+func rootKey(view map[uint]models.Department, dept models.Department) string {
+	for dept.ParentId != nil {
+		dept = view[*dept.ParentId]
+	}
+	return models.NameKey(dept.Name)
+}
+
+func (s *Store) childIDs(parentID uint) []uint {
+	return s.orderedChildIDs(&parentID)
+}
+
+// Дочерние parentID (nil - корни) по sort_order, id
+func (s *Store) orderedChildIDs(parentID *uint) []uint {
+	var ids []uint
+	for id, dept := range s.departments {
+		if sameParent(dept.ParentId, parentID) {
+			ids = append(ids, id)
+		}
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		a, b := s.departments[ids[i]], s.departments[ids[j]]
+		if a.SortOrder != b.SortOrder {
+			return a.SortOrder < b.SortOrder
+		}
+		return a.Id < b.Id
+	})
+	return ids
+}
+
+func (s *Store) descendantIDs(parentID uint) []uint {
+	var ids []uint
+	for _, childID := range s.childIDs(parentID) {
+		ids = append(ids, childID)
+		ids = append(ids, s.descendantIDs(childID)...)
+	}
+	return ids
+}
+
+func (s *Store) employeesOf(departmentID uint) []models.Employee {
+	var employees []models.Employee
+	for _, emp := range s.employees {
+		if emp.DepartmentId == departmentID {
+			employees = append(employees, emp)
+		}
+	}
+	sort.Slice(employees, func(i, j int) bool { return employees[i].ID < employees[j].ID })
+	return employees
+}
+
+func (s *Store) moveEmployees(fromDeptID, toDeptID uint, actor string) {
+	at := time.Now()
+	for id, emp := range s.employees {
+		if emp.DepartmentId == fromDeptID {
+			emp.DepartmentId = toDeptID
+			emp.Version++
+			s.employees[id] = emp
+			s.dropSecondaryInPrimary(emp)
+			s.recordAssignment(emp, at, actor)
+		}
+	}
+}
+
+// Аналог ON DELETE CASCADE
+func (s *Store) deleteSubtree(id uint) {
+	ids := append(s.descendantIDs(id), id)
+	for _, deptID := range ids {
+		for empID, emp := range s.employees {
+			if emp.DepartmentId == deptID {
+				delete(s.employees, empID)
+				s.deleteAssignments(empID)
+				s.clearManager(empID)
+				s.deleteSecondary(func(a models.SecondaryAssignment) bool { return a.EmployeeID == empID })
+				s.clearVacancyEmployee(empID)
+			}
+		}
+		s.deleteSecondary(func(a models.SecondaryAssignment) bool { return a.DepartmentID == deptID })
+		s.deleteStaffing(deptID)
+		delete(s.departments, deptID)
+	}
+}
+
+func sameParent(a, b *uint) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return *a == *b
+}
+
+func copyInt(v *int) *int {
+	if v == nil {
+		return nil
+	}
+	c := *v
+	return &c
+}
+
+func copyID(id *uint) *uint {
+	if id == nil {
+		return nil
+	}
+	v := *id
+	return &v
+}
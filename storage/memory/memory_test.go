@@ -0,0 +1,15 @@
+package memory_test
+
+import (
+	"testing"
+
+	"github.com/kroulersama/goProject/models"
+	"github.com/kroulersama/goProject/storage/memory"
+	"github.com/kroulersama/goProject/storage/storetest"
+)
+
+func TestConformance(t *testing.T) {
+	storetest.Run(t, func(t *testing.T, policy models.UniquenessPolicy) storetest.Store {
+		return memory.New(policy)
+	})
+}
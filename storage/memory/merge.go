@@ -0,0 +1,227 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"time"
+
+	"github.com/kroulersama/goProject/models"
+)
+
+// Сколько суффиксов перебирать до отказа, как в models
+const maxMergeSuffix = 100
+
+// MergeDepartment слияние source в target; при ошибке состояние восстанавливается
+func (s *Store) MergeDepartment(ctx context.Context, sourceID, targetID uint, version int, strategy string) (*models.MergeSummary, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	if strategy == "" {
+		strategy = models.MergeFail
+	}
+	if strategy != models.MergeFail && strategy != models.MergeSuffix && strategy != models.MergeRecursive {
+		return nil, models.ErrInvalidMergeStrategy
+	}
+	if sourceID == targetID {
+		return nil, models.ErrMergeIntoSelf
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	source, ok := s.departments[sourceID]
+	if !ok {
+		return nil, models.ErrDepartmentNotFound
+	}
+	if version != 0 && source.Version != version {
+		return nil, models.ErrVersionMismatch
+	}
+	if _, ok := s.departments[targetID]; !ok {
+		return nil, models.ErrTargetNotFound
+	}
+	for _, id := range s.descendantIDs(sourceID) {
+		if id == targetID {
+			return nil, models.ErrMergeIntoDescendant
+		}
+	}
+
+	// Аналог отката транзакции
+	departments, employees, assignments := maps.Clone(s.departments), maps.Clone(s.employees), maps.Clone(s.assignments)
+	secondary, headcountPlans, vacancies := maps.Clone(s.secondary), maps.Clone(s.headcountPlans), maps.Clone(s.vacancies)
+
+	summary := models.NewMergeSummary(sourceID, targetID, strategy)
+	if err := s.mergeInto(sourceID, targetID, summary, models.ActorFromContext(ctx)); err != nil {
+		s.departments, s.employees, s.assignments = departments, employees, assignments
+		s.secondary, s.headcountPlans, s.vacancies = secondary, headcountPlans, vacancies
+		return nil, err
+	}
+	return summary, nil
+}
+
+func (s *Store) mergeInto(sourceID, targetID uint, summary *models.MergeSummary, actor string) error {
+	// Сотрудники
+	at := time.Now()
+	for id, emp := range s.employees {
+		if emp.DepartmentId == sourceID {
+			emp.DepartmentId = targetID
+			emp.Version++
+			s.employees[id] = emp
+			s.dropSecondaryInPrimary(emp)
+			s.recordAssignment(emp, at, actor)
+			summary.EmployeesMoved++
+		}
+	}
+
+	// Совмещения, планы численности и открытые вакансии
+	s.moveSecondary(sourceID, targetID)
+	s.moveStaffing(sourceID, targetID)
+
+	// Дочерние подразделения; совпадение ищется в области уникальности у target
+	scope := s.scopeUnder(targetID)
+	for _, childID := range s.childIDs(sourceID) {
+		child := s.departments[childID]
+
+		subtree := append([]uint{childID}, s.descendantIDs(childID)...)
+		sameID, found := s.nameOwner(models.NameKey(child.Name), scope, subtree)
+		switch {
+		case !found:
+			if err := s.resolveSubtreeNames(childID, targetID, summary, actor); err != nil {
+				return err
+			}
+			if err := s.moveChild(s.departments[childID], child.Name, targetID); err != nil {
+				return err
+			}
+			summary.DepartmentsMoved = append(summary.DepartmentsMoved, childID)
+
+		case summary.Strategy == models.MergeSuffix:
+			name, err := s.freeSuffixName(child.Name, &targetID)
+			if err != nil {
+				return err
+			}
+			if err := s.resolveSubtreeNames(childID, targetID, summary, actor); err != nil {
+				return err
+			}
+			if err := s.moveChild(s.departments[childID], name, targetID); err != nil {
+				return err
+			}
+			summary.DepartmentsMoved = append(summary.DepartmentsMoved, childID)
+			summary.Renamed = append(summary.Renamed, models.RenamedDepartment{ID: childID, From: child.Name, To: name})
+
+		case summary.Strategy == models.MergeRecursive:
+			if err := s.mergeInto(childID, sameID, summary, actor); err != nil {
+				return err
+			}
+			summary.Merged = append(summary.Merged, models.MergedDepartment{SourceID: childID, TargetID: sameID})
+
+		default:
+			return fmt.Errorf("%w: %q", models.ErrMergeConflict, child.Name)
+		}
+	}
+
+	// Источник пуст, удаляем вместе с оставшимися в нем закрытыми вакансиями
+	s.deleteStaffing(sourceID)
+	delete(s.departments, sourceID)
+	return nil
+}
+
+// Перенос подразделения в конец детей parentID с новым именем
+func (s *Store) moveChild(child models.Department, name string, parentID uint) error {
+	child.Name = name
+	child.ParentId = copyID(&parentID)
+	child.SortOrder = s.nextSortOrder(&parentID)
+	if !s.namesUniqueWith(child) {
+		return models.ErrNameExists
+	}
+	child.Version++
+	s.departments[child.Id] = child
+	return nil
+}
+
+// resolveSubtreeNames как в models: при политике branch совпадения потомков childID
+// с веткой targetID решаются по стратегии до переноса
+func (s *Store) resolveSubtreeNames(childID, targetID uint, summary *models.MergeSummary, actor string) error {
+	if s.policy != models.UniqueInBranch {
+		return nil
+	}
+
+	scope := s.scopeUnder(targetID)
+	for {
+		// Родители раньше детей; после слияния поддерево меняется, проход начинается заново
+		subtree := append([]uint{childID}, s.descendantIDs(childID)...)
+		merged := false
+		for _, id := range subtree[1:] {
+			dept := s.departments[id]
+			sameID, found := s.nameOwner(models.NameKey(dept.Name), scope, subtree)
+			if !found {
+				continue
+			}
+
+			switch summary.Strategy {
+			case models.MergeSuffix:
+				name, err := s.freeSuffixName(dept.Name, dept.ParentId, &targetID)
+				if err != nil {
+					return err
+				}
+				if _, err := s.updateDepartment(id, 0, &models.DepartmentRequest{Name: name}); err != nil {
+					return err
+				}
+				summary.Renamed = append(summary.Renamed, models.RenamedDepartment{ID: id, From: dept.Name, To: name})
+
+			case models.MergeRecursive:
+				if err := s.mergeInto(id, sameID, summary, actor); err != nil {
+					return err
+				}
+				summary.Merged = append(summary.Merged, models.MergedDepartment{SourceID: id, TargetID: sameID})
+				merged = true
+
+			default:
+				return fmt.Errorf("%w: %q", models.ErrMergeConflict, dept.Name)
+			}
+			if merged {
+				break
+			}
+		}
+		if !merged {
+			return nil
+		}
+	}
+}
+
+// Область уникальности для подразделения под parentID
+func (s *Store) scopeUnder(parentID uint) string {
+	return s.policy.NameScope(&parentID, rootKey(s.departments, s.departments[parentID]))
+}
+
+// Подразделение с ключом имени key в области scope, кроме exclude
+func (s *Store) nameOwner(key, scope string, exclude []uint) (uint, bool) {
+	for id, dept := range s.departments {
+		if slices.Contains(exclude, id) || models.NameKey(dept.Name) != key {
+			continue
+		}
+		if s.policy.NameScope(dept.ParentId, rootKey(s.departments, dept)) == scope {
+			return id, true
+		}
+	}
+	return 0, false
+}
+
+// Первое свободное имя вида "Имя (N)" под каждым из parentIDs (nil - среди корней)
+func (s *Store) freeSuffixName(name string, parentIDs ...*uint) (string, error) {
+	for n := 2; n <= maxMergeSuffix; n++ {
+		candidate := fmt.Sprintf("%s (%d)", name, n)
+		if len(candidate) > 200 {
+			break
+		}
+		free := true
+		for _, parentID := range parentIDs {
+			free = free && s.namesUniqueWith(models.Department{Id: s.nextDeptID + 1, Name: candidate, ParentId: parentID})
+		}
+		if free {
+			return candidate, nil
+		}
+	}
+	return "", fmt.Errorf("%w: no free name for %q", models.ErrMergeConflict, name)
+}
@@ -0,0 +1,73 @@
+package memory
+
+import (
+	"context"
+	"slices"
+
+	"github.com/kroulersama/goProject/models"
+)
+
+// ReorderChildren новый порядок дочерних подразделений
+func (s *Store) ReorderChildren(ctx context.Context, id uint, version int, req *models.ReorderRequest) (*models.DepartmentResponse, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	parent, ok := s.departments[id]
+	if !ok {
+		return nil, models.ErrDepartmentNotFound
+	}
+	if version != 0 && parent.Version != version {
+		return nil, models.ErrVersionMismatch
+	}
+
+	children := s.childIDs(id)
+	if len(req.IDs) != len(children) {
+		return nil, models.ErrInvalidChildOrder
+	}
+	pending := make(map[uint]bool, len(children))
+	for _, childID := range children {
+		pending[childID] = true
+	}
+	for _, childID := range req.IDs {
+		if !pending[childID] {
+			return nil, models.ErrInvalidChildOrder
+		}
+		delete(pending, childID)
+	}
+
+	s.renumber(req.IDs)
+	parent.Version++
+	s.departments[id] = parent
+	return s.tree(id, 1, nil), nil
+}
+
+// Следующий sort_order в конце списка детей parentID
+func (s *Store) nextSortOrder(parentID *uint) int {
+	ids := s.orderedChildIDs(parentID)
+	if len(ids) == 0 {
+		return 0
+	}
+	return s.departments[ids[len(ids)-1]].SortOrder + 1
+}
+
+// Подразделение на позицию position среди соседей, соседи нумеруются подряд
+func (s *Store) placeAt(id uint, position int) models.Department {
+	ids := slices.DeleteFunc(s.orderedChildIDs(s.departments[id].ParentId), func(sibling uint) bool {
+		return sibling == id
+	})
+	ids = slices.Insert(ids, min(position, len(ids)), id)
+	s.renumber(ids)
+	return s.departments[id]
+}
+
+func (s *Store) renumber(ids []uint) {
+	for i, id := range ids {
+		dept := s.departments[id]
+		dept.SortOrder = i
+		s.departments[id] = dept
+	}
+}
@@ -0,0 +1,295 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"sort"
+	"time"
+
+	"github.com/kroulersama/goProject/models"
+)
+
+// CreatePlan создание черновика
+func (s *Store) CreatePlan(ctx context.Context, req *models.PlanRequest) (*models.Plan, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	plan := models.Plan{
+		Name:       req.Name,
+		Status:     models.PlanDraft,
+		Operations: []models.PlanOperation{},
+		CreatedAt:  time.Now(),
+		Version:    1,
+	}
+	if req.Operations != nil {
+		plan.Operations = slices.Clone(req.Operations)
+	}
+	if _, err := models.CheckPlan(s.policy, s.planSnapshot(), plan.Operations); err != nil {
+		return nil, err
+	}
+	plan.RecordSnapshot(plan.Operations, s.planVersions())
+
+	s.nextPlanID++
+	plan.ID = s.nextPlanID
+	s.plans[plan.ID] = plan
+	return clonePlan(plan), nil
+}
+
+// GetPlan план по id
+func (s *Store) GetPlan(ctx context.Context, id uint) (*models.Plan, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	plan, ok := s.plans[id]
+	if !ok {
+		return nil, models.ErrPlanNotFound
+	}
+	return clonePlan(plan), nil
+}
+
+// ListPlans все планы, новые первыми
+func (s *Store) ListPlans(ctx context.Context) ([]models.Plan, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	plans := make([]models.Plan, 0, len(s.plans))
+	for _, plan := range s.plans {
+		plans = append(plans, *clonePlan(plan))
+	}
+	sort.Slice(plans, func(i, j int) bool { return plans[i].ID > plans[j].ID })
+	return plans, nil
+}
+
+// AddPlanOperations добавление операций в черновик
+func (s *Store) AddPlanOperations(ctx context.Context, id uint, version int, ops []models.PlanOperation) (*models.Plan, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	plan, err := s.draftPlan(id, version)
+	if err != nil {
+		return nil, err
+	}
+
+	operations := append(slices.Clone(plan.Operations), ops...)
+	if _, err := models.CheckPlan(s.policy, s.planSnapshot(), operations); err != nil {
+		return nil, err
+	}
+	plan.Operations = operations
+	plan.RecordSnapshot(ops, s.planVersions())
+	plan.Version++
+	s.plans[id] = plan
+	return clonePlan(plan), nil
+}
+
+// DeletePlan удаление плана
+func (s *Store) DeletePlan(ctx context.Context, id uint, version int) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	plan, ok := s.plans[id]
+	if !ok {
+		return models.ErrPlanNotFound
+	}
+	if version != 0 && plan.Version != version {
+		return models.ErrVersionMismatch
+	}
+	delete(s.plans, id)
+	return nil
+}
+
+// DiffPlan изменения, которые внесет план
+func (s *Store) DiffPlan(ctx context.Context, id uint) (*models.PlanDiff, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	plan, ok := s.plans[id]
+	if !ok {
+		return nil, models.ErrPlanNotFound
+	}
+	if plan.Status != models.PlanDraft {
+		return nil, models.ErrPlanApplied
+	}
+	return models.CheckPlan(s.policy, s.planSnapshot(), plan.Operations)
+}
+
+// ApplyPlan выполнение плана; при ошибке состояние восстанавливается
+func (s *Store) ApplyPlan(ctx context.Context, id uint, version int) (*models.Plan, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	plan, err := s.draftPlan(id, version)
+	if err != nil {
+		return nil, err
+	}
+	if plan.Stale(s.planVersions()) {
+		return nil, models.ErrPlanStale
+	}
+	if _, err := models.CheckPlan(s.policy, s.planSnapshot(), plan.Operations); err != nil {
+		return nil, err
+	}
+
+	// Аналог отката транзакции
+	departments, employees, nextDeptID := maps.Clone(s.departments), maps.Clone(s.employees), s.nextDeptID
+	assignments, secondary := maps.Clone(s.assignments), maps.Clone(s.secondary)
+	headcountPlans, vacancies := maps.Clone(s.headcountPlans), maps.Clone(s.vacancies)
+	refs := make(map[string]uint)
+	actor := models.ActorFromContext(ctx)
+	for i := range plan.Operations {
+		if err := s.applyPlanOperation(&plan.Operations[i], refs, actor); err != nil {
+			s.departments, s.employees, s.nextDeptID = departments, employees, nextDeptID
+			s.assignments, s.secondary = assignments, secondary
+			s.headcountPlans, s.vacancies = headcountPlans, vacancies
+			return nil, fmt.Errorf("operation %d (%s): %w", i, plan.Operations[i].Op, err)
+		}
+	}
+
+	now := time.Now()
+	plan.Status = models.PlanApplied
+	plan.AppliedAt = &now
+	plan.Version++
+	s.plans[id] = plan
+	return clonePlan(plan), nil
+}
+
+func (s *Store) applyPlanOperation(op *models.PlanOperation, refs map[string]uint, actor string) error {
+	resolve := func(id *uint, ref string) *uint {
+		if id != nil || ref == "" {
+			return id
+		}
+		realID := refs[ref]
+		return &realID
+	}
+
+	switch op.Op {
+	case models.OpCreate:
+		department, err := s.createDepartment(&models.DepartmentRequest{Name: op.Name, ParentID: resolve(op.ParentID, op.ParentRef), Attributes: op.Attributes, Type: &op.Type})
+		if err != nil {
+			return err
+		}
+		if op.Ref != "" {
+			refs[op.Ref] = department.Id
+		}
+		return nil
+
+	case models.OpMove:
+		_, err := s.updateDepartment(*resolve(op.DepartmentID, op.DepartmentRef), 0,
+			&models.DepartmentRequest{ParentID: resolve(op.ParentID, op.ParentRef)})
+		return err
+
+	case models.OpRename:
+		_, err := s.updateDepartment(*resolve(op.DepartmentID, op.DepartmentRef), 0,
+			&models.DepartmentRequest{Name: op.Name})
+		return err
+
+	case models.OpDelete:
+		return s.deleteDepartment(*resolve(op.DepartmentID, op.DepartmentRef), 0, op.Mode,
+			resolve(op.ToDepartmentID, op.ToDepartmentRef), actor)
+
+	case models.OpTransfer:
+		to := *resolve(op.ToDepartmentID, op.ToDepartmentRef)
+		for _, empID := range uniqueIDs(op.EmployeeIDs) {
+			if _, err := s.transferEmployee(empID, 0, to, actor); err != nil {
+				return err
+			}
+		}
+		return nil
+
+	default:
+		return fmt.Errorf("%w: unknown op %q", models.ErrInvalidPlanOperation, op.Op)
+	}
+}
+
+// Черновик с проверкой версии
+func (s *Store) draftPlan(id uint, version int) (models.Plan, error) {
+	plan, ok := s.plans[id]
+	if !ok {
+		return models.Plan{}, models.ErrPlanNotFound
+	}
+	if version != 0 && plan.Version != version {
+		return models.Plan{}, models.ErrVersionMismatch
+	}
+	if plan.Status != models.PlanDraft {
+		return models.Plan{}, models.ErrPlanApplied
+	}
+	plan.Operations = slices.Clone(plan.Operations)
+	plan.Snapshot = maps.Clone(plan.Snapshot)
+	plan.EmployeeSnapshot = maps.Clone(plan.EmployeeSnapshot)
+	return plan, nil
+}
+
+// Текущее дерево для проверки плана
+func (s *Store) planSnapshot() *models.PlanSnapshot {
+	snapshot := &models.PlanSnapshot{
+		Employees:  make(map[uint]uint, len(s.employees)),
+		Terminated: make(map[uint]bool),
+		Attributes: s.attributeSchema(models.EntityDepartment),
+		Rules:      s.rules.Rules,
+	}
+	for _, dept := range s.departments {
+		snapshot.Departments = append(snapshot.Departments, dept)
+	}
+	sort.Slice(snapshot.Departments, func(i, j int) bool {
+		return snapshot.Departments[i].Id < snapshot.Departments[j].Id
+	})
+	for id, emp := range s.employees {
+		snapshot.Employees[id] = emp.DepartmentId
+		if emp.Status == models.EmployeeTerminated {
+			snapshot.Terminated[id] = true
+		}
+	}
+	return snapshot
+}
+
+func (s *Store) planVersions() models.PlanVersions {
+	return models.PlanVersions{
+		Department: func(id uint) (int, bool) {
+			dept, ok := s.departments[id]
+			return dept.Version, ok
+		},
+		Employee: func(id uint) (int, bool) {
+			emp, ok := s.employees[id]
+			return emp.Version, ok
+		},
+	}
+}
+
+// Копия, чтобы вызывающий не менял хранилище
+func clonePlan(plan models.Plan) *models.Plan {
+	plan.Operations = slices.Clone(plan.Operations)
+	plan.Snapshot = maps.Clone(plan.Snapshot)
+	plan.EmployeeSnapshot = maps.Clone(plan.EmployeeSnapshot)
+	return &plan
+}
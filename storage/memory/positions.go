@@ -0,0 +1,237 @@
+package memory
+
+import (
+	"cmp"
+	"context"
+	"slices"
+	"sort"
+	"time"
+
+	"github.com/kroulersama/goProject/models"
+)
+
+// CreatePosition добавляет должность в каталог
+func (s *Store) CreatePosition(ctx context.Context, req *models.PositionRequest) (*models.Position, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	titleKey := models.PositionKey(req.Title)
+	if !s.positionTitleFree(titleKey, 0) {
+		return nil, models.ErrPositionExists
+	}
+
+	s.nextPosID++
+	position := models.Position{
+		ID:        s.nextPosID,
+		Title:     req.Title,
+		TitleKey:  titleKey,
+		Grade:     req.Grade,
+		JobFamily: req.JobFamily,
+		CreatedAt: time.Now().UTC(),
+		Version:   1,
+	}
+	s.positions[position.ID] = position
+	return &position, nil
+}
+
+// ListPositions каталог по семейству; пустое - весь
+func (s *Store) ListPositions(ctx context.Context, jobFamily string) ([]models.Position, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var positions []models.Position
+	for _, position := range s.positions {
+		if jobFamily == "" || position.JobFamily == jobFamily {
+			positions = append(positions, position)
+		}
+	}
+	sort.Slice(positions, func(i, j int) bool {
+		a, b := positions[i], positions[j]
+		if a.JobFamily != b.JobFamily {
+			return a.JobFamily < b.JobFamily
+		}
+		if a.Title != b.Title {
+			return a.Title < b.Title
+		}
+		return a.ID < b.ID
+	})
+	return positions, nil
+}
+
+// GetPosition должность по id
+func (s *Store) GetPosition(ctx context.Context, id uint) (*models.Position, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	position, ok := s.positions[id]
+	if !ok {
+		return nil, models.ErrPositionNotFound
+	}
+	return &position, nil
+}
+
+// UpdatePosition меняет должность; новое название получают и сотрудники с этой должностью
+func (s *Store) UpdatePosition(ctx context.Context, id uint, version int, update *models.PositionUpdate) (*models.Position, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	position, ok := s.positions[id]
+	if !ok {
+		return nil, models.ErrPositionNotFound
+	}
+	if version != 0 && position.Version != version {
+		return nil, models.ErrVersionMismatch
+	}
+
+	req := position.WithUpdate(update)
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+	titleKey := models.PositionKey(req.Title)
+	if !s.positionTitleFree(titleKey, id) {
+		return nil, models.ErrPositionExists
+	}
+
+	// Новое название - новый период в истории сотрудников
+	if req.Title != position.Title {
+		at, actor := time.Now(), models.ActorFromContext(ctx)
+		for empID, emp := range s.employees {
+			if emp.PositionID != nil && *emp.PositionID == id {
+				emp.Position = req.Title
+				emp.Version++
+				s.employees[empID] = emp
+				s.recordAssignment(emp, at, actor)
+			}
+		}
+	}
+
+	position.Title = req.Title
+	position.TitleKey = titleKey
+	position.Grade = req.Grade
+	position.JobFamily = req.JobFamily
+	position.Version++
+	s.positions[id] = position
+	return &position, nil
+}
+
+// DeletePosition удаляет должность, на которую не ссылается ни один сотрудник
+func (s *Store) DeletePosition(ctx context.Context, id uint, version int) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	position, ok := s.positions[id]
+	if !ok {
+		return models.ErrPositionNotFound
+	}
+	if version != 0 && position.Version != version {
+		return models.ErrVersionMismatch
+	}
+	for _, emp := range s.employees {
+		if emp.PositionID != nil && *emp.PositionID == id {
+			return models.ErrPositionInUse
+		}
+	}
+	delete(s.positions, id)
+
+	// Аналог ON DELETE SET NULL у вакансий
+	for vacancyID, vacancy := range s.vacancies {
+		if vacancy.PositionID != nil && *vacancy.PositionID == id {
+			vacancy.PositionID = nil
+			s.vacancies[vacancyID] = vacancy
+		}
+	}
+	return nil
+}
+
+// ClusterPositionTitles группирует текст должностей сотрудников без position_id;
+// с apply создает недостающие записи каталога и привязывает к ним сотрудников
+func (s *Store) ClusterPositionTitles(ctx context.Context, apply bool) (*models.PositionClustering, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	counts := make(map[string]int)
+	for _, emp := range s.employees {
+		if emp.PositionID == nil {
+			counts[emp.Position]++
+		}
+	}
+	variants := make([]models.PositionVariant, 0, len(counts))
+	for title, n := range counts {
+		variants = append(variants, models.PositionVariant{Title: title, Employees: n})
+	}
+	catalog := make([]models.Position, 0, len(s.positions))
+	for _, position := range s.positions {
+		catalog = append(catalog, position)
+	}
+	slices.SortFunc(catalog, func(a, b models.Position) int { return cmp.Compare(a.ID, b.ID) })
+
+	result := &models.PositionClustering{Clusters: models.ClusterPositions(variants, catalog), Applied: apply}
+	if !apply {
+		return result, nil
+	}
+
+	for i := range result.Clusters {
+		cluster := &result.Clusters[i]
+		if cluster.PositionID == 0 {
+			s.nextPosID++
+			s.positions[s.nextPosID] = models.Position{
+				ID:        s.nextPosID,
+				Title:     cluster.Title,
+				TitleKey:  models.PositionKey(cluster.Title),
+				CreatedAt: time.Now().UTC(),
+				Version:   1,
+			}
+			cluster.PositionID = s.nextPosID
+			result.Created++
+		}
+
+		titles := cluster.Titles()
+		for empID, emp := range s.employees {
+			if emp.PositionID == nil && slices.Contains(titles, emp.Position) {
+				positionID := cluster.PositionID
+				emp.PositionID = &positionID
+				emp.Version++
+				s.employees[empID] = emp
+				result.Linked++
+			}
+		}
+	}
+	return result, nil
+}
+
+// Свободно ли название, кроме должности exceptID
+func (s *Store) positionTitleFree(titleKey string, exceptID uint) bool {
+	for _, position := range s.positions {
+		if position.ID != exceptID && position.TitleKey == titleKey {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,202 @@
+package memory
+
+import (
+	"context"
+	"slices"
+	"sort"
+
+	"github.com/kroulersama/goProject/models"
+)
+
+// SetManager назначает или снимает руководителя сотрудника
+func (s *Store) SetManager(ctx context.Context, id uint, version int, req *models.ManagerRequest) (*models.Employee, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	employee, err := s.employeeForUpdate(id, version)
+	if err != nil {
+		return nil, err
+	}
+	if employee.Status == models.EmployeeTerminated {
+		return nil, models.ErrEmployeeTerminated
+	}
+	if req.ManagerID != nil {
+		if err := s.checkManager(id, *req.ManagerID); err != nil {
+			return nil, err
+		}
+	}
+
+	employee.ManagerID = copyID(req.ManagerID)
+	employee.Version++
+	s.employees[id] = employee
+	return &employee, nil
+}
+
+// DirectReports прямые подчиненные, отобранные фильтром по статусу
+func (s *Store) DirectReports(ctx context.Context, id uint, filter *models.EmployeeFilter) ([]models.Employee, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if _, ok := s.employees[id]; !ok {
+		return nil, models.ErrEmployeeNotFound
+	}
+	var reports []models.Employee
+	for _, emp := range s.employees {
+		if emp.ManagerID != nil && *emp.ManagerID == id && filter.Includes(emp.Status) {
+			reports = append(reports, emp)
+		}
+	}
+	sort.Slice(reports, func(i, j int) bool {
+		a, b := reports[i], reports[j]
+		if a.FullName != b.FullName {
+			return a.FullName < b.FullName
+		}
+		return a.ID < b.ID
+	})
+	return reports, nil
+}
+
+// ReportingChain руководители от непосредственного до верхнего
+func (s *Store) ReportingChain(ctx context.Context, id uint) ([]models.Employee, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	current, ok := s.employees[id]
+	if !ok {
+		return nil, models.ErrEmployeeNotFound
+	}
+	chain := []models.Employee{}
+	seen := map[uint]bool{id: true}
+	for current.ManagerID != nil && !seen[*current.ManagerID] {
+		if current, ok = s.employees[*current.ManagerID]; !ok {
+			return nil, models.ErrEmployeeNotFound
+		}
+		seen[current.ID] = true
+		chain = append(chain, current)
+	}
+	return chain, nil
+}
+
+// GetSpanOfControl число подчиненных по уровням
+func (s *Store) GetSpanOfControl(ctx context.Context, id uint) (*models.SpanOfControl, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if _, ok := s.employees[id]; !ok {
+		return nil, models.ErrEmployeeNotFound
+	}
+
+	span := &models.SpanOfControl{EmployeeID: id}
+	seen := map[uint]bool{id: true}
+	for level := []uint{id}; len(level) > 0; {
+		var next []uint
+		for _, emp := range s.employees {
+			if emp.ManagerID != nil && slices.Contains(level, *emp.ManagerID) &&
+				emp.Status != models.EmployeeTerminated && !seen[emp.ID] {
+				seen[emp.ID] = true
+				next = append(next, emp.ID)
+			}
+		}
+		if len(next) == 0 {
+			break
+		}
+		if span.Depth == 0 {
+			span.Direct = len(next)
+		}
+		span.Depth++
+		span.Total += len(next)
+		level = next
+	}
+	return span, nil
+}
+
+// GetReportingIssues отчет о руководителях вне ветки подразделения сотрудника
+func (s *Store) GetReportingIssues(ctx context.Context) ([]models.ReportingIssue, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	employees := make([]models.Employee, 0, len(s.employees))
+	for _, emp := range s.employees {
+		employees = append(employees, emp)
+	}
+	parents := make(map[uint]*uint, len(s.departments))
+	for id, dept := range s.departments {
+		parents[id] = dept.ParentId
+	}
+	return models.CheckReportingLines(employees, s.employees, parents), nil
+}
+
+// Проверки руководителя: не сам сотрудник, существует, работает и не
+// подчиняется сотруднику ни на каком уровне
+func (s *Store) checkManager(employeeID, managerID uint) error {
+	if employeeID == managerID {
+		return models.ErrSelfManager
+	}
+
+	manager, ok := s.employees[managerID]
+	if !ok {
+		return models.ErrManagerNotFound
+	}
+	if manager.Status == models.EmployeeTerminated {
+		return models.ErrManagerTerminated
+	}
+
+	// Все подчиненные сотрудника
+	if slices.Contains(s.reportIDs(employeeID), managerID) {
+		return models.ErrManagerCycle
+	}
+	return nil
+}
+
+// ID подчиненных всех уровней
+func (s *Store) reportIDs(managerID uint) []uint {
+	var ids []uint
+	for id, emp := range s.employees {
+		if emp.ManagerID != nil && *emp.ManagerID == managerID {
+			ids = append(ids, id)
+			ids = append(ids, s.reportIDs(id)...)
+		}
+	}
+	return ids
+}
+
+// Прямые подчиненные уволенного остаются без руководителя
+func (s *Store) releaseReports(managerID uint) {
+	for id, emp := range s.employees {
+		if emp.ManagerID != nil && *emp.ManagerID == managerID {
+			emp.ManagerID = nil
+			emp.Version++
+			s.employees[id] = emp
+		}
+	}
+}
+
+// Аналог ON DELETE SET NULL для руководителя
+func (s *Store) clearManager(managerID uint) {
+	for id, emp := range s.employees {
+		if emp.ManagerID != nil && *emp.ManagerID == managerID {
+			emp.ManagerID = nil
+			s.employees[id] = emp
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package memory
+
+import (
+	"context"
+	"time"
+
+	"github.com/kroulersama/goProject/models"
+)
+
+// GetHierarchyRules текущие правила
+func (s *Store) GetHierarchyRules(ctx context.Context) (*models.HierarchyRuleSet, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	set := s.rules
+	set.Rules = set.Rules.Clone()
+	return &set, nil
+}
+
+// UpdateHierarchyRules заменяет правила
+func (s *Store) UpdateHierarchyRules(ctx context.Context, version int, rules *models.HierarchyRules) (*models.HierarchyRuleSet, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if err := rules.Validate(); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if version != 0 && s.rules.Version != version {
+		return nil, models.ErrVersionMismatch
+	}
+	s.rules.Rules = rules.Clone()
+	s.rules.UpdatedAt = time.Now()
+	s.rules.Version++
+
+	set := s.rules
+	set.Rules = set.Rules.Clone()
+	return &set, nil
+}
+
+// CheckHierarchy все нарушения правил в текущем дереве
+func (s *Store) CheckHierarchy(ctx context.Context) ([]models.RuleViolation, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.rules.Rules.Check(s.hierarchyNodes()), nil
+}
+
+// Все подразделения для проверки правил
+func (s *Store) hierarchyNodes() []models.HierarchyNode {
+	nodes := make([]models.HierarchyNode, 0, len(s.departments))
+	for _, dept := range s.departments {
+		nodes = append(nodes, dept.HierarchyNode())
+	}
+	return nodes
+}
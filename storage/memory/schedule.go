@@ -0,0 +1,207 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"maps"
+	"sort"
+	"time"
+
+	"github.com/kroulersama/goProject/models"
+)
+
+// ScheduleDepartmentChange отложенный перенос/переименование
+func (s *Store) ScheduleDepartmentChange(ctx context.Context, id uint, version int, req *models.DepartmentRequest, effectiveAt time.Time) (*models.ScheduledChange, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if err := models.ValidateScheduledDepartmentChange(id, req); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	dept, ok := s.departments[id]
+	if !ok {
+		return nil, models.ErrDepartmentNotFound
+	}
+	if version != 0 && dept.Version != version {
+		return nil, models.ErrVersionMismatch
+	}
+	if req.ParentID != nil {
+		if _, ok := s.departments[*req.ParentID]; !ok {
+			return nil, models.ErrParentNotFound
+		}
+	}
+
+	return s.addChange(models.ScheduledChange{
+		Kind:         models.ChangeDepartment,
+		DepartmentID: copyID(&id),
+		Name:         req.Name,
+		ParentID:     copyID(req.ParentID),
+		Position:     copyInt(req.Position),
+		EffectiveAt:  effectiveAt.UTC(),
+	}), nil
+}
+
+// ScheduleTransfer отложенный перевод сотрудника
+func (s *Store) ScheduleTransfer(ctx context.Context, employeeID uint, version int, req *models.TransferRequest, effectiveAt time.Time) (*models.ScheduledChange, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	emp, ok := s.employees[employeeID]
+	if !ok {
+		return nil, models.ErrEmployeeNotFound
+	}
+	if version != 0 && emp.Version != version {
+		return nil, models.ErrVersionMismatch
+	}
+	if _, ok := s.departments[req.DepartmentID]; !ok {
+		return nil, models.ErrUnknownDepartment
+	}
+
+	return s.addChange(models.ScheduledChange{
+		Kind:           models.ChangeTransfer,
+		EmployeeID:     copyID(&employeeID),
+		ToDepartmentID: copyID(&req.DepartmentID),
+		EffectiveAt:    effectiveAt.UTC(),
+	}), nil
+}
+
+func (s *Store) addChange(change models.ScheduledChange) *models.ScheduledChange {
+	s.nextChangeID++
+	change.ID = s.nextChangeID
+	change.Status = models.ChangePending
+	change.CreatedAt = time.Now().UTC()
+	change.Version = 1
+	s.changes[change.ID] = change
+	return &change
+}
+
+// GetScheduledChange изменение по id
+func (s *Store) GetScheduledChange(ctx context.Context, id uint) (*models.ScheduledChange, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	change, ok := s.changes[id]
+	if !ok {
+		return nil, models.ErrScheduledChangeNotFound
+	}
+	return &change, nil
+}
+
+// ListScheduledChanges изменения в порядке вступления в силу
+func (s *Store) ListScheduledChanges(ctx context.Context, status string) ([]models.ScheduledChange, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if !models.ValidChangeStatus(status) {
+		return nil, models.ErrInvalidChangeStatus
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.sortedChanges(func(change models.ScheduledChange) bool {
+		return status == "" || change.Status == status
+	}), nil
+}
+
+// CancelScheduledChange отмена еще не примененного изменения
+func (s *Store) CancelScheduledChange(ctx context.Context, id uint, version int) (*models.ScheduledChange, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	change, ok := s.changes[id]
+	if !ok {
+		return nil, models.ErrScheduledChangeNotFound
+	}
+	if version != 0 && change.Version != version {
+		return nil, models.ErrVersionMismatch
+	}
+	if change.Status != models.ChangePending {
+		return nil, models.ErrChangeNotPending
+	}
+
+	change.Status = models.ChangeCanceled
+	change.Version++
+	s.changes[id] = change
+	return &change, nil
+}
+
+// ApplyDueChanges применение наступивших изменений; в памяти процесс всегда лидер
+func (s *Store) ApplyDueChanges(ctx context.Context, now time.Time) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	applied := 0
+	due := s.sortedChanges(func(change models.ScheduledChange) bool {
+		return change.Status == models.ChangePending && !change.EffectiveAt.After(now)
+	})
+	for _, change := range due {
+		// Аналог отката транзакции
+		departments, employees, assignments := maps.Clone(s.departments), maps.Clone(s.employees), maps.Clone(s.assignments)
+
+		err := s.applyChange(change, models.ActorFromContext(ctx))
+		change.Version++
+		if err != nil {
+			s.departments, s.employees, s.assignments = departments, employees, assignments
+			change.Status = models.ChangeFailed
+			change.Error = err.Error()
+		} else {
+			appliedAt := now.UTC()
+			change.Status = models.ChangeApplied
+			change.AppliedAt = &appliedAt
+			applied++
+		}
+		s.changes[change.ID] = change
+	}
+	return applied, nil
+}
+
+func (s *Store) applyChange(change models.ScheduledChange, actor string) error {
+	switch change.Kind {
+	case models.ChangeDepartment:
+		_, err := s.updateDepartment(*change.DepartmentID, 0, &models.DepartmentRequest{Name: change.Name, ParentID: copyID(change.ParentID), Position: copyInt(change.Position)})
+		return err
+	case models.ChangeTransfer:
+		_, err := s.transferEmployee(*change.EmployeeID, 0, *change.ToDepartmentID, actor)
+		return err
+	default:
+		return errors.New("unknown scheduled change kind " + change.Kind)
+	}
+}
+
+// Изменения по порядку effective_at, id
+func (s *Store) sortedChanges(keep func(models.ScheduledChange) bool) []models.ScheduledChange {
+	changes := []models.ScheduledChange{}
+	for _, change := range s.changes {
+		if keep(change) {
+			changes = append(changes, change)
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool {
+		if !changes[i].EffectiveAt.Equal(changes[j].EffectiveAt) {
+			return changes[i].EffectiveAt.Before(changes[j].EffectiveAt)
+		}
+		return changes[i].ID < changes[j].ID
+	})
+	return changes
+}
@@ -0,0 +1,238 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/kroulersama/goProject/models"
+)
+
+// ListSecondaryAssignments совмещения сотрудника
+func (s *Store) ListSecondaryAssignments(ctx context.Context, employeeID uint) ([]models.SecondaryAssignment, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if _, ok := s.employees[employeeID]; !ok {
+		return nil, models.ErrEmployeeNotFound
+	}
+	assignments := s.secondaryOf(func(a models.SecondaryAssignment) bool { return a.EmployeeID == employeeID })
+	return assignments, nil
+}
+
+// AddSecondaryAssignment назначает сотрудника по совмещению в другое подразделение
+func (s *Store) AddSecondaryAssignment(ctx context.Context, employeeID uint, req *models.SecondaryAssignmentRequest) (*models.SecondaryAssignment, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.checkSecondary(employeeID, req, 0); err != nil {
+		return nil, err
+	}
+	for _, a := range s.secondary {
+		if a.EmployeeID == employeeID && a.DepartmentID == req.DepartmentID {
+			return nil, models.ErrSecondaryExists
+		}
+	}
+
+	s.nextSecondaryID++
+	assignment := models.SecondaryAssignment{
+		ID:           s.nextSecondaryID,
+		EmployeeID:   employeeID,
+		DepartmentID: req.DepartmentID,
+		Role:         req.Role,
+		Allocation:   req.Allocation,
+		CreatedAt:    time.Now(),
+		Version:      1,
+	}
+	s.secondary[assignment.ID] = assignment
+	return &assignment, nil
+}
+
+// UpdateSecondaryAssignment меняет роль или долю ставки совмещения
+func (s *Store) UpdateSecondaryAssignment(ctx context.Context, employeeID, id uint, version int, update *models.SecondaryAssignmentUpdate) (*models.SecondaryAssignment, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	assignment, err := s.secondaryForUpdate(employeeID, id, version)
+	if err != nil {
+		return nil, err
+	}
+	req := update.Apply(&assignment)
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+	if err := s.checkSecondary(employeeID, &req, id); err != nil {
+		return nil, err
+	}
+
+	assignment.Role = req.Role
+	assignment.Allocation = req.Allocation
+	assignment.Version++
+	s.secondary[id] = assignment
+	return &assignment, nil
+}
+
+// RemoveSecondaryAssignment снимает совмещение
+func (s *Store) RemoveSecondaryAssignment(ctx context.Context, employeeID, id uint, version int) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, err := s.secondaryForUpdate(employeeID, id, version); err != nil {
+		return err
+	}
+	delete(s.secondary, id)
+	return nil
+}
+
+// GetHeadcount численность подразделения по людям и ставкам; уволенные не учитываются
+func (s *Store) GetHeadcount(ctx context.Context, departmentID uint) (*models.Headcount, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if _, ok := s.departments[departmentID]; !ok {
+		return nil, models.ErrDepartmentNotFound
+	}
+	subtree := append([]uint{departmentID}, s.descendantIDs(departmentID)...)
+
+	allocated := make(map[uint]int)
+	for _, a := range s.secondary {
+		allocated[a.EmployeeID] += a.Allocation
+	}
+	var rows []models.StaffingRow
+	for _, emp := range s.employees {
+		if emp.Status != models.EmployeeTerminated {
+			rows = append(rows, models.StaffingRow{
+				EmployeeID: emp.ID, DepartmentID: emp.DepartmentId, Allocation: max(100-allocated[emp.ID], 0), Primary: true,
+			})
+		}
+	}
+	for _, a := range s.secondary {
+		if s.employees[a.EmployeeID].Status != models.EmployeeTerminated {
+			rows = append(rows, models.StaffingRow{EmployeeID: a.EmployeeID, DepartmentID: a.DepartmentID, Allocation: a.Allocation})
+		}
+	}
+	return models.ComputeHeadcount(departmentID, subtree, rows), nil
+}
+
+// Совместители подразделения, отобранные фильтром по статусу
+func (s *Store) secondaryMembers(departmentID uint, filter *models.EmployeeFilter) []models.SecondaryMember {
+	var members []models.SecondaryMember
+	for _, a := range s.secondaryOf(func(a models.SecondaryAssignment) bool { return a.DepartmentID == departmentID }) {
+		emp := s.employees[a.EmployeeID]
+		if filter.Includes(emp.Status) {
+			members = append(members, models.SecondaryMember{Employee: emp, AssignmentID: a.ID, Role: a.Role, Allocation: a.Allocation})
+		}
+	}
+	return members
+}
+
+// Проверки совмещения: сотрудник работает, подразделение существует и не
+// основное, сумма долей без совмещения exceptID не больше 100%
+func (s *Store) checkSecondary(employeeID uint, req *models.SecondaryAssignmentRequest, exceptID uint) error {
+	employee, ok := s.employees[employeeID]
+	if !ok {
+		return models.ErrEmployeeNotFound
+	}
+	if employee.Status == models.EmployeeTerminated {
+		return models.ErrEmployeeTerminated
+	}
+	if employee.DepartmentId == req.DepartmentID {
+		return models.ErrSecondaryInPrimary
+	}
+	if _, ok := s.departments[req.DepartmentID]; !ok {
+		return models.ErrUnknownDepartment
+	}
+
+	var other []int
+	for _, a := range s.secondary {
+		if a.EmployeeID == employeeID && a.ID != exceptID {
+			other = append(other, a.Allocation)
+		}
+	}
+	return models.CheckAllocation(other, req.Allocation)
+}
+
+// Совмещение сотрудника с проверкой версии; version 0 - без проверки
+func (s *Store) secondaryForUpdate(employeeID, id uint, version int) (models.SecondaryAssignment, error) {
+	assignment, ok := s.secondary[id]
+	if !ok || assignment.EmployeeID != employeeID {
+		return models.SecondaryAssignment{}, models.ErrSecondaryNotFound
+	}
+	if version != 0 && assignment.Version != version {
+		return models.SecondaryAssignment{}, models.ErrVersionMismatch
+	}
+	return assignment, nil
+}
+
+// Совмещения по условию в порядке создания
+func (s *Store) secondaryOf(match func(models.SecondaryAssignment) bool) []models.SecondaryAssignment {
+	var assignments []models.SecondaryAssignment
+	for _, a := range s.secondary {
+		if match(a) {
+			assignments = append(assignments, a)
+		}
+	}
+	sort.Slice(assignments, func(i, j int) bool { return assignments[i].ID < assignments[j].ID })
+	return assignments
+}
+
+// Аналог ON DELETE CASCADE для совмещений
+func (s *Store) deleteSecondary(match func(models.SecondaryAssignment) bool) {
+	for id, a := range s.secondary {
+		if match(a) {
+			delete(s.secondary, id)
+		}
+	}
+}
+
+// Снятие совмещения в новом основном подразделении сотрудника
+func (s *Store) dropSecondaryInPrimary(emp models.Employee) {
+	s.deleteSecondary(func(a models.SecondaryAssignment) bool {
+		return a.EmployeeID == emp.ID && a.DepartmentID == emp.DepartmentId
+	})
+}
+
+// moveSecondary как в models: совмещения в fromID переходят в toID, совпавшие
+// складываются, совмещение в основном подразделении снимается
+func (s *Store) moveSecondary(fromID, toID uint) {
+	for _, a := range s.secondaryOf(func(a models.SecondaryAssignment) bool { return a.DepartmentID == fromID }) {
+		same := s.secondaryOf(func(b models.SecondaryAssignment) bool { return b.EmployeeID == a.EmployeeID && b.DepartmentID == toID })
+		switch {
+		case s.employees[a.EmployeeID].DepartmentId == toID:
+			delete(s.secondary, a.ID)
+		case len(same) > 0:
+			merged := same[0]
+			merged.Allocation += a.Allocation
+			merged.Version++
+			s.secondary[merged.ID] = merged
+			delete(s.secondary, a.ID)
+		default:
+			a.DepartmentID = toID
+			a.Version++
+			s.secondary[a.ID] = a
+		}
+	}
+}
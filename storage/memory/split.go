@@ -0,0 +1,213 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"sort"
+	"time"
+
+	"github.com/kroulersama/goProject/models"
+)
+
+// SplitDepartment выделение нового соседнего подразделения
+func (s *Store) SplitDepartment(ctx context.Context, id uint, version int, req *models.SplitRequest) (*models.SplitResult, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	source, ok := s.departments[id]
+	if !ok {
+		return nil, models.ErrDepartmentNotFound
+	}
+	if version != 0 && source.Version != version {
+		return nil, models.ErrVersionMismatch
+	}
+
+	// Аналог отката транзакции
+	departments, employees, nextDeptID := maps.Clone(s.departments), maps.Clone(s.employees), s.nextDeptID
+	assignments := maps.Clone(s.assignments)
+	result, err := s.split(id, source.ParentId, req, models.ActorFromContext(ctx))
+	if err != nil {
+		s.departments, s.employees, s.nextDeptID = departments, employees, nextDeptID
+		s.assignments = assignments
+		return nil, err
+	}
+	return result, nil
+}
+
+func (s *Store) split(id uint, parentID *uint, req *models.SplitRequest, actor string) (*models.SplitResult, error) {
+	source := s.departments[id]
+	department, err := s.createDepartment(&models.DepartmentRequest{Name: req.Name, ParentID: parentID, Attributes: source.Attributes, Type: &source.Type})
+	if err != nil {
+		return nil, err
+	}
+	result := &models.SplitResult{Department: department, EmployeesMoved: []uint{}, DepartmentsMoved: []uint{}}
+
+	for _, empID := range uniqueIDs(req.EmployeeIDs) {
+		emp, ok := s.employees[empID]
+		if !ok {
+			return nil, fmt.Errorf("%w: %d", models.ErrEmployeeNotFound, empID)
+		}
+		if emp.DepartmentId != id {
+			return nil, models.ErrEmployeeNotInDepartment
+		}
+		emp.DepartmentId = department.Id
+		emp.Version++
+		s.employees[empID] = emp
+		s.recordAssignment(emp, time.Now(), actor)
+		result.EmployeesMoved = append(result.EmployeesMoved, empID)
+	}
+
+	childIDs := uniqueIDs(req.ChildIDs)
+	for _, childID := range childIDs {
+		child, ok := s.departments[childID]
+		if !ok || child.ParentId == nil || *child.ParentId != id {
+			return nil, fmt.Errorf("%w: %d", models.ErrNotChildDepartment, childID)
+		}
+	}
+
+	// Переносим в прежнем порядке
+	for _, childID := range s.childIDs(id) {
+		if !slices.Contains(childIDs, childID) {
+			continue
+		}
+		child := s.departments[childID]
+		if err := s.moveChild(child, child.Name, department.Id); err != nil {
+			return nil, err
+		}
+		result.DepartmentsMoved = append(result.DepartmentsMoved, childID)
+	}
+	return result, nil
+}
+
+// CopyDepartment копирование структуры поддерева без сотрудников
+func (s *Store) CopyDepartment(ctx context.Context, id uint, req *models.CopyRequest) (*models.CopyResult, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.departments[id]; !ok {
+		return nil, models.ErrDepartmentNotFound
+	}
+
+	// Снимок до создания копий, родитель раньше детей
+	subtree := append([]uint{id}, s.descendantIDs(id)...)
+
+	departments, nextDeptID := maps.Clone(s.departments), s.nextDeptID
+	vacancies, nextVacancyID := maps.Clone(s.vacancies), s.nextVacancyID
+	result, err := s.copySubtree(subtree, req)
+	if err != nil {
+		s.departments, s.nextDeptID = departments, nextDeptID
+		s.vacancies, s.nextVacancyID = vacancies, nextVacancyID
+		return nil, err
+	}
+	return result, nil
+}
+
+func (s *Store) copySubtree(subtree []uint, req *models.CopyRequest) (*models.CopyResult, error) {
+	// Места снимаются до создания копий
+	seats := map[uint][]models.VacancyRequest{}
+	if req.IncludePositions {
+		for _, sourceID := range subtree {
+			seats[sourceID] = s.seatsOf(sourceID)
+		}
+	}
+
+	result := &models.CopyResult{Renamed: []models.RenamedDepartment{}}
+	copies := make(map[uint]uint, len(subtree))
+	for i, sourceID := range subtree {
+		source := s.departments[sourceID]
+		create := &models.DepartmentRequest{Name: source.Name, Attributes: source.Attributes, Type: &source.Type}
+		if i == 0 {
+			create.ParentID = req.ParentID
+		} else {
+			parentID := copies[*source.ParentId]
+			create.ParentID = &parentID
+		}
+
+		// Явное имя корня не меняется, остальные при совпадении получают суффикс
+		suffixed := false
+		if i == 0 && req.Name != "" {
+			create.Name = req.Name
+		} else if !s.namesUniqueWith(models.Department{Id: s.nextDeptID + 1, Name: source.Name, ParentId: create.ParentID}) {
+			name, err := s.freeSuffixName(source.Name, create.ParentID)
+			if err != nil {
+				return nil, err
+			}
+			create.Name, suffixed = name, true
+		}
+
+		department, err := s.createDepartment(create)
+		if err != nil {
+			return nil, err
+		}
+		copies[sourceID] = department.Id
+		if suffixed {
+			result.Renamed = append(result.Renamed, models.RenamedDepartment{ID: department.Id, From: source.Name, To: department.Name})
+		}
+
+		copied := models.CopiedDepartment{
+			SourceID: sourceID,
+			ID:       department.Id,
+			ParentID: department.ParentId,
+			Name:     department.Name,
+		}
+		for _, seat := range seats[sourceID] {
+			s.nextVacancyID++
+			vacancy := models.Vacancy{
+				ID:           s.nextVacancyID,
+				DepartmentID: department.Id,
+				Position:     seat.Position,
+				PositionID:   copyID(seat.PositionID),
+				Status:       models.VacancyOpen,
+				TargetDate:   seat.TargetDate,
+				CreatedAt:    time.Now(),
+				Version:      1,
+			}
+			s.vacancies[vacancy.ID] = vacancy
+			copied.Vacancies = append(copied.Vacancies, vacancy)
+		}
+
+		if i == 0 {
+			result.Root = department
+		}
+		result.Departments = append(result.Departments, copied)
+	}
+	return result, nil
+}
+
+// Места подразделения для копии, как seatsByDepartment в models
+func (s *Store) seatsOf(departmentID uint) []models.VacancyRequest {
+	var seats []models.VacancyRequest
+	for _, emp := range s.employeesOf(departmentID) {
+		if emp.Status != models.EmployeeTerminated {
+			seats = append(seats, models.VacancyRequest{Position: emp.Position, PositionID: copyID(emp.PositionID)})
+		}
+	}
+	var open []models.Vacancy
+	for _, vacancy := range s.vacancies {
+		if vacancy.DepartmentID == departmentID && vacancy.Status == models.VacancyOpen {
+			open = append(open, vacancy)
+		}
+	}
+	sort.Slice(open, func(i, j int) bool { return open[i].ID < open[j].ID })
+	for _, vacancy := range open {
+		seats = append(seats, models.VacancyRequest{Position: vacancy.Position, PositionID: copyID(vacancy.PositionID), TargetDate: vacancy.TargetDate})
+	}
+	return seats
+}
+
+// Отсортированные id без повторов
+func uniqueIDs(ids []uint) []uint {
+	ids = slices.Clone(ids)
+	slices.Sort(ids)
+	return slices.Compact(ids)
+}
@@ -0,0 +1,220 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/kroulersama/goProject/models"
+)
+
+// CreateHeadcountPlan плановая численность подразделения на месяц
+func (s *Store) CreateHeadcountPlan(ctx context.Context, req *models.HeadcountPlanRequest) (*models.HeadcountPlan, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.departments[req.DepartmentID]; !ok {
+		return nil, models.ErrUnknownDepartment
+	}
+	for _, plan := range s.headcountPlans {
+		if plan.DepartmentID == req.DepartmentID && plan.Period == req.Period {
+			return nil, models.ErrHeadcountPlanExists
+		}
+	}
+
+	s.nextHCPlanID++
+	plan := models.HeadcountPlan{
+		ID:           s.nextHCPlanID,
+		DepartmentID: req.DepartmentID,
+		Period:       req.Period,
+		Planned:      req.Planned,
+		CreatedAt:    time.Now(),
+		Version:      1,
+	}
+	s.headcountPlans[plan.ID] = plan
+	return &plan, nil
+}
+
+// ListHeadcountPlans планы по подразделению и периоду
+func (s *Store) ListHeadcountPlans(ctx context.Context, filter *models.HeadcountPlanFilter) ([]models.HeadcountPlan, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if filter.Period != "" && !models.ValidPeriod(filter.Period) {
+		return nil, models.ErrInvalidPeriod
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var plans []models.HeadcountPlan
+	for _, plan := range s.headcountPlans {
+		if filter.DepartmentID != nil && plan.DepartmentID != *filter.DepartmentID {
+			continue
+		}
+		if filter.Period != "" && plan.Period != filter.Period {
+			continue
+		}
+		plans = append(plans, plan)
+	}
+	sort.Slice(plans, func(i, j int) bool {
+		if plans[i].Period != plans[j].Period {
+			return plans[i].Period < plans[j].Period
+		}
+		return plans[i].DepartmentID < plans[j].DepartmentID
+	})
+	return plans, nil
+}
+
+// UpdateHeadcountPlan меняет плановую численность
+func (s *Store) UpdateHeadcountPlan(ctx context.Context, id uint, version int, update *models.HeadcountPlanUpdate) (*models.HeadcountPlan, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if update.Planned < 0 {
+		return nil, models.ErrInvalidPlanned
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	plan, err := s.headcountPlanForUpdate(id, version)
+	if err != nil {
+		return nil, err
+	}
+	plan.Planned = update.Planned
+	plan.Version++
+	s.headcountPlans[id] = plan
+	return &plan, nil
+}
+
+// DeleteHeadcountPlan удаляет план
+func (s *Store) DeleteHeadcountPlan(ctx context.Context, id uint, version int) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, err := s.headcountPlanForUpdate(id, version); err != nil {
+		return err
+	}
+	delete(s.headcountPlans, id)
+	return nil
+}
+
+// GetStaffing план, факт и открытые вакансии по поддереву за месяц; факт - работавшие
+// в течение месяца, вакансии - открытые на его конец
+func (s *Store) GetStaffing(ctx context.Context, departmentID uint, period string) (*models.StaffingRollup, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if period == "" {
+		period = time.Now().UTC().Format("2006-01")
+	}
+	if !models.ValidPeriod(period) {
+		return nil, models.ErrInvalidPeriod
+	}
+
+	start, end := models.PeriodBounds(period)
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	root, ok := s.departments[departmentID]
+	if !ok {
+		return nil, models.ErrDepartmentNotFound
+	}
+
+	// Родители раньше детей, как в loadSubtree
+	departments := []models.Department{root}
+	for i := 0; i < len(departments); i++ {
+		for _, childID := range s.childIDs(departments[i].Id) {
+			departments = append(departments, s.departments[childID])
+		}
+	}
+
+	planned, actual, open := make(map[uint]int), make(map[uint]int), make(map[uint]int)
+	for _, plan := range s.headcountPlans {
+		if plan.Period == period {
+			planned[plan.DepartmentID] += plan.Planned
+		}
+	}
+	for _, emp := range s.employees {
+		if emp.ActiveInPeriod(start, end) {
+			actual[emp.DepartmentId]++
+		}
+	}
+	for _, vacancy := range s.vacancies {
+		if vacancy.OpenAt(end) {
+			open[vacancy.DepartmentID]++
+		}
+	}
+	return models.ComputeStaffing(departmentID, period, departments, planned, actual, open), nil
+}
+
+func (s *Store) headcountPlanForUpdate(id uint, version int) (models.HeadcountPlan, error) {
+	plan, ok := s.headcountPlans[id]
+	if !ok {
+		return plan, models.ErrHeadcountPlanNotFound
+	}
+	if version != 0 && plan.Version != version {
+		return plan, models.ErrVersionMismatch
+	}
+	return plan, nil
+}
+
+// Планы и открытые вакансии fromID переходят в toID; план на месяц,
+// который у toID уже есть, складывается с ним
+func (s *Store) moveStaffing(fromID, toID uint) {
+	for id, plan := range s.headcountPlans {
+		if plan.DepartmentID != fromID {
+			continue
+		}
+		merged := false
+		for otherID, other := range s.headcountPlans {
+			if other.DepartmentID == toID && other.Period == plan.Period {
+				other.Planned += plan.Planned
+				other.Version++
+				s.headcountPlans[otherID] = other
+				delete(s.headcountPlans, id)
+				merged = true
+				break
+			}
+		}
+		if !merged {
+			plan.DepartmentID = toID
+			plan.Version++
+			s.headcountPlans[id] = plan
+		}
+	}
+	for id, vacancy := range s.vacancies {
+		if vacancy.DepartmentID == fromID && vacancy.Status == models.VacancyOpen {
+			vacancy.DepartmentID = toID
+			vacancy.Version++
+			s.vacancies[id] = vacancy
+		}
+	}
+}
+
+// Аналог ON DELETE CASCADE для планов и вакансий подразделения
+func (s *Store) deleteStaffing(departmentID uint) {
+	for id, plan := range s.headcountPlans {
+		if plan.DepartmentID == departmentID {
+			delete(s.headcountPlans, id)
+		}
+	}
+	for id, vacancy := range s.vacancies {
+		if vacancy.DepartmentID == departmentID {
+			delete(s.vacancies, id)
+		}
+	}
+}
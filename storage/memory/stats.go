@@ -0,0 +1,84 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/kroulersama/goProject/models"
+)
+
+// GetDepartmentStats показатели подразделения вместе с поддеревом
+func (s *Store) GetDepartmentStats(ctx context.Context, id uint) (*models.DepartmentStats, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if _, ok := s.departments[id]; !ok {
+		return nil, models.ErrDepartmentNotFound
+	}
+	stats := &models.DepartmentStats{DepartmentID: id}
+
+	// Поддерево по уровням
+	inSubtree := make(map[uint]bool)
+	for level := []uint{id}; len(level) > 0; {
+		stats.Tree.Depth++
+		stats.Tree.Departments += len(level)
+		stats.Tree.Breadth = max(stats.Tree.Breadth, len(level))
+		var next []uint
+		for _, deptID := range level {
+			inSubtree[deptID] = true
+			next = append(next, s.childIDs(deptID)...)
+		}
+		level = next
+	}
+
+	now := time.Now()
+	hires := make(map[string]int)
+	positions := make(map[string]int)
+	var tenure []float64
+	for _, emp := range s.employees {
+		if !inSubtree[emp.DepartmentId] {
+			continue
+		}
+		if emp.HiredAt != nil {
+			hires[emp.HiredAt.UTC().Format("2006-01")]++
+		}
+		if emp.Status == models.EmployeeTerminated {
+			continue
+		}
+
+		stats.Headcount.Total++
+		if emp.DepartmentId == id {
+			stats.Headcount.Direct++
+		}
+		positions[emp.Position]++
+		if emp.HiredAt != nil {
+			tenure = append(tenure, now.Sub(*emp.HiredAt).Hours()/24)
+		}
+	}
+
+	stats.HiresByMonth = []models.MonthlyHires{}
+	for month, n := range hires {
+		stats.HiresByMonth = append(stats.HiresByMonth, models.MonthlyHires{Month: month, Hires: n})
+	}
+	sort.Slice(stats.HiresByMonth, func(i, j int) bool { return stats.HiresByMonth[i].Month < stats.HiresByMonth[j].Month })
+
+	stats.Positions = []models.PositionVariant{}
+	for title, n := range positions {
+		stats.Positions = append(stats.Positions, models.PositionVariant{Title: title, Employees: n})
+	}
+	sort.Slice(stats.Positions, func(i, j int) bool {
+		a, b := stats.Positions[i], stats.Positions[j]
+		if a.Employees != b.Employees {
+			return a.Employees > b.Employees
+		}
+		return a.Title < b.Title
+	})
+
+	stats.Tenure = models.ComputeTenure(tenure)
+	return stats, nil
+}
@@ -0,0 +1,172 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/kroulersama/goProject/models"
+)
+
+// CreateVacancy открывает вакансию в подразделении
+func (s *Store) CreateVacancy(ctx context.Context, req *models.VacancyRequest) (*models.Vacancy, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.departments[req.DepartmentID]; !ok {
+		return nil, models.ErrUnknownDepartment
+	}
+
+	// Должность из каталога
+	if req.PositionID != nil {
+		position, ok := s.positions[*req.PositionID]
+		if !ok {
+			return nil, models.ErrUnknownPosition
+		}
+		if err := req.UsePosition(&position); err != nil {
+			return nil, err
+		}
+	}
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+
+	s.nextVacancyID++
+	vacancy := models.Vacancy{
+		ID:           s.nextVacancyID,
+		DepartmentID: req.DepartmentID,
+		Position:     req.Position,
+		PositionID:   copyID(req.PositionID),
+		Status:       models.VacancyOpen,
+		TargetDate:   req.TargetDate,
+		CreatedAt:    time.Now(),
+		Version:      1,
+	}
+	s.vacancies[vacancy.ID] = vacancy
+	return &vacancy, nil
+}
+
+// ListVacancies вакансии по подразделению и статусу
+func (s *Store) ListVacancies(ctx context.Context, filter *models.VacancyFilter) ([]models.Vacancy, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var vacancies []models.Vacancy
+	for _, vacancy := range s.vacancies {
+		if filter.DepartmentID != nil && vacancy.DepartmentID != *filter.DepartmentID {
+			continue
+		}
+		if filter.Status != "" && vacancy.Status != filter.Status {
+			continue
+		}
+		vacancies = append(vacancies, vacancy)
+	}
+	sort.Slice(vacancies, func(i, j int) bool { return vacancies[i].ID < vacancies[j].ID })
+	return vacancies, nil
+}
+
+// GetVacancy вакансия по id
+func (s *Store) GetVacancy(ctx context.Context, id uint) (*models.Vacancy, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	vacancy, err := s.vacancyForUpdate(id, 0)
+	if err != nil {
+		return nil, err
+	}
+	return &vacancy, nil
+}
+
+// UpdateVacancy меняет должность, срок или статус незакрытой вакансии
+func (s *Store) UpdateVacancy(ctx context.Context, id uint, version int, update *models.VacancyUpdate) (*models.Vacancy, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	vacancy, err := s.vacancyForUpdate(id, version)
+	if err != nil {
+		return nil, err
+	}
+	if vacancy.Status == models.VacancyFilled {
+		return nil, models.ErrVacancyFilled
+	}
+	req, status, err := update.Apply(&vacancy)
+	if err != nil {
+		return nil, err
+	}
+
+	vacancy.Position = req.Position
+	vacancy.TargetDate = req.TargetDate
+	vacancy.Status = status
+	vacancy.Version++
+	s.vacancies[id] = vacancy
+	return &vacancy, nil
+}
+
+// FillVacancy закрывает открытую вакансию созданием сотрудника в ее подразделении
+func (s *Store) FillVacancy(ctx context.Context, id uint, version int, req *models.EmployeeRequest) (*models.Vacancy, *models.Employee, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	vacancy, err := s.vacancyForUpdate(id, version)
+	if err != nil {
+		return nil, nil, err
+	}
+	if vacancy.Status != models.VacancyOpen {
+		return nil, nil, models.ErrVacancyNotOpen
+	}
+
+	req.FillFrom(&vacancy)
+	employee, err := s.createEmployee(vacancy.DepartmentID, req, models.ActorFromContext(ctx))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	now := time.Now()
+	vacancy.Status = models.VacancyFilled
+	vacancy.EmployeeID = copyID(&employee.ID)
+	vacancy.FilledAt = &now
+	vacancy.Version++
+	s.vacancies[id] = vacancy
+	return &vacancy, employee, nil
+}
+
+func (s *Store) vacancyForUpdate(id uint, version int) (models.Vacancy, error) {
+	vacancy, ok := s.vacancies[id]
+	if !ok {
+		return vacancy, models.ErrVacancyNotFound
+	}
+	if version != 0 && vacancy.Version != version {
+		return vacancy, models.ErrVersionMismatch
+	}
+	return vacancy, nil
+}
+
+// Аналог ON DELETE SET NULL для принятого по вакансии сотрудника
+func (s *Store) clearVacancyEmployee(employeeID uint) {
+	for id, vacancy := range s.vacancies {
+		if vacancy.EmployeeID != nil && *vacancy.EmployeeID == employeeID {
+			vacancy.EmployeeID = nil
+			s.vacancies[id] = vacancy
+		}
+	}
+}
@@ -7,28 +7,50 @@ import (
 	"gorm.io/gorm"
 )
 
+// Поддерживаемые драйверы
+const (
+	DriverPostgres = "postgres"
+	DriverSQLite   = "sqlite"
+)
+
 type Config struct {
+	Driver   string
 	Host     string
 	Port     string
 	Password string
 	User     string
 	DBName   string
 	SSLMode  string
+	Path     string
+	DSN      string // готовая строка подключения Postgres вместо отдельных полей
 }
 
 func NewConnection(config *Config) (*gorm.DB, error) {
-	dsn := fmt.Sprintf(
-		"host=%s port=%s user=%s password=%s dbname=%s sslmode=%s",
-		config.Host, config.Port, config.User, config.Password, config.DBName, config.SSLMode,
-	)
-	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{})
-	if err != nil {
-		return db, err
+	switch config.GetDriver() {
+	case DriverPostgres:
+		return gorm.Open(postgres.Open(config.GetDSN()), &gorm.Config{})
+	case DriverSQLite:
+		return openSQLite(config)
+	default:
+		return nil, fmt.Errorf("unsupported database driver %q", config.Driver)
 	}
-	return db, err
+}
+
+// Драйвер, по умолчанию postgres
+func (c *Config) GetDriver() string {
+	if c.Driver == "" {
+		return DriverPostgres
+	}
+	return c.Driver
 }
 
 func (c *Config) GetDSN() string {
+	if c.GetDriver() == DriverSQLite {
+		return sqliteDSN(c.Path)
+	}
+	if c.DSN != "" {
+		return c.DSN
+	}
 	return fmt.Sprintf(
 		"host=%s port=%s user=%s password=%s dbname=%s sslmode=%s",
 		c.Host, c.Port, c.User, c.Password, c.DBName, c.SSLMode,
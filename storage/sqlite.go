@@ -0,0 +1,41 @@
+package storage
+
+import (
+	"fmt"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+
+	// Чистый Go драйвер, регистрируется как "sqlite"
+	_ "modernc.org/sqlite"
+)
+
+// Файл базы по умолчанию
+const defaultSQLitePath = "goproject.db"
+
+// Внешние ключи включаются на каждом соединении, иначе не работает ON DELETE CASCADE
+func sqliteDSN(path string) string {
+	if path == "" {
+		path = defaultSQLitePath
+	}
+	return fmt.Sprintf("file:%s?_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)&_time_format=sqlite", path)
+}
+
+func openSQLite(config *Config) (*gorm.DB, error) {
+	db, err := gorm.Open(sqlite.New(sqlite.Config{
+		DriverName: DriverSQLite,
+		DSN:        config.GetDSN(),
+	}), &gorm.Config{})
+	if err != nil {
+		return nil, err
+	}
+
+	// Один писатель: SQLite не любит конкурентные записи
+	sqlDB, err := db.DB()
+	if err != nil {
+		return nil, err
+	}
+	sqlDB.SetMaxOpenConns(1)
+
+	return db, nil
+}
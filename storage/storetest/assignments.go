@@ -0,0 +1,86 @@
+package storetest
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/kroulersama/goProject/models"
+)
+
+func testAssignmentHistory(t *testing.T, s Store) {
+	alice, bob := models.WithActor(ctx, "alice"), models.WithActor(ctx, "bob")
+	a := mustCreate(t, s, "A", nil)
+	b := mustCreate(t, s, "B", nil)
+	hiredAt := time.Now().Add(-72 * time.Hour).UTC().Truncate(time.Second)
+
+	emp, err := s.CreateEmployee(alice, a.Id, &models.EmployeeRequest{FullName: "Ivan", Position: "Dev", HiredAt: &hiredAt})
+	if err != nil {
+		t.Fatalf("CreateEmployee: %v", err)
+	}
+	history, err := s.EmployeeHistory(ctx, emp.ID)
+	if err != nil || len(history) != 1 || history[0].DepartmentID != a.Id || !history[0].From.Equal(hiredAt) ||
+		history[0].To != nil || history[0].Actor != "alice" {
+		t.Fatalf("history after create: %+v, %v", history, err)
+	}
+
+	// Перевод закрывает период и открывает новый; перевод в тот же отдел истории не меняет
+	if _, err := s.TransferEmployee(bob, emp.ID, 0, &models.TransferRequest{DepartmentID: b.Id}); err != nil {
+		t.Fatalf("TransferEmployee: %v", err)
+	}
+	if _, err := s.TransferEmployee(bob, emp.ID, 0, &models.TransferRequest{DepartmentID: b.Id}); err != nil {
+		t.Fatalf("TransferEmployee same department: %v", err)
+	}
+	history, err = s.EmployeeHistory(ctx, emp.ID)
+	if err != nil || len(history) != 2 || history[0].To == nil || history[0].ClosedBy != "bob" ||
+		!history[0].To.Equal(history[1].From) || history[1].DepartmentID != b.Id || history[1].Actor != "bob" {
+		t.Fatalf("history after transfer: %+v, %v", history, err)
+	}
+
+	movements, err := s.GetDepartmentMovements(ctx, a.Id, &models.MovementFilter{})
+	if err != nil || len(movements) != 2 {
+		t.Fatalf("movements of A: %+v, %v", movements, err)
+	}
+	left, joined := movements[0], movements[1]
+	if left.Type != models.MovementLeft || left.ToDepartmentID == nil || *left.ToDepartmentID != b.Id || left.Actor != "bob" ||
+		joined.Type != models.MovementJoined || joined.FromDepartmentID != nil || joined.FullName != "Ivan" {
+		t.Fatalf("movements of A: %+v", movements)
+	}
+
+	// Увольнение - уход без нового подразделения
+	if _, err := s.TerminateEmployee(alice, emp.ID, 0, &models.TerminationRequest{Reason: "resigned"}); err != nil {
+		t.Fatalf("TerminateEmployee: %v", err)
+	}
+	movements, err = s.GetDepartmentMovements(ctx, b.Id, &models.MovementFilter{})
+	if err != nil || len(movements) != 2 || movements[0].Type != models.MovementLeft || movements[0].ToDepartmentID != nil ||
+		movements[0].Actor != "alice" || movements[1].FromDepartmentID == nil || *movements[1].FromDepartmentID != a.Id {
+		t.Fatalf("movements of B: %+v, %v", movements, err)
+	}
+
+	// Повторный прием открывает новый период; фильтр по времени
+	since := time.Now().UTC()
+	if _, err := s.RehireEmployee(ctx, emp.ID, 0, &models.RehireRequest{DepartmentID: &a.Id}); err != nil {
+		t.Fatalf("RehireEmployee: %v", err)
+	}
+	movements, err = s.GetDepartmentMovements(ctx, a.Id, &models.MovementFilter{Since: &since})
+	if err != nil || len(movements) != 1 || movements[0].Type != models.MovementJoined || movements[0].FromDepartmentID != nil ||
+		movements[0].Actor != models.DefaultActor {
+		t.Fatalf("movements of A since rehire: %+v, %v", movements, err)
+	}
+
+	// Перевод всех сотрудников отдела тоже попадает в историю
+	if err := s.MoveEmployees(ctx, a.Id, b.Id); err != nil {
+		t.Fatalf("MoveEmployees: %v", err)
+	}
+	history, err = s.EmployeeHistory(ctx, emp.ID)
+	if err != nil || len(history) != 4 || history[2].To == nil || history[3].DepartmentID != b.Id || history[3].To != nil {
+		t.Fatalf("history after MoveEmployees: %+v, %v", history, err)
+	}
+
+	if _, err := s.EmployeeHistory(ctx, 999999); !errors.Is(err, models.ErrEmployeeNotFound) {
+		t.Fatalf("missing employee: got %v", err)
+	}
+	if _, err := s.GetDepartmentMovements(ctx, 999999, &models.MovementFilter{}); !errors.Is(err, models.ErrDepartmentNotFound) {
+		t.Fatalf("missing department: got %v", err)
+	}
+}
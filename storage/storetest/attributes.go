@@ -0,0 +1,175 @@
+package storetest
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/kroulersama/goProject/models"
+)
+
+func testAttributes(t *testing.T, s Store) {
+	// Валидация описаний
+	invalid := []struct {
+		req  models.AttributeDefinitionRequest
+		want error
+	}{
+		{models.AttributeDefinitionRequest{Entity: "plan", Name: "x", Type: models.AttrString}, models.ErrInvalidAttributeEntity},
+		{models.AttributeDefinitionRequest{Entity: models.EntityDepartment, Name: "Bad Name", Type: models.AttrString}, models.ErrInvalidAttributeName},
+		{models.AttributeDefinitionRequest{Entity: models.EntityDepartment, Name: "x", Type: "money"}, models.ErrInvalidAttributeType},
+		{models.AttributeDefinitionRequest{Entity: models.EntityDepartment, Name: "x", Type: models.AttrNumber, EnumValues: []string{"a"}}, models.ErrInvalidEnumValues},
+		{models.AttributeDefinitionRequest{Entity: models.EntityDepartment, Name: "x", Type: models.AttrString, Pattern: "("}, models.ErrInvalidAttributePattern},
+	}
+	for _, tt := range invalid {
+		if _, err := s.CreateAttributeDefinition(ctx, &tt.req); !errors.Is(err, tt.want) {
+			t.Fatalf("CreateAttributeDefinition(%+v): got %v, want %v", tt.req, err, tt.want)
+		}
+	}
+
+	region := mustDefine(t, s, models.AttributeDefinitionRequest{
+		Entity: models.EntityDepartment, Name: "region", Type: models.AttrString,
+		Required: true, EnumValues: []string{"north", "south"},
+	})
+	mustDefine(t, s, models.AttributeDefinitionRequest{Entity: models.EntityDepartment, Name: "budget", Type: models.AttrNumber})
+	mustDefine(t, s, models.AttributeDefinitionRequest{Entity: models.EntityDepartment, Name: "remote", Type: models.AttrBoolean})
+	mustDefine(t, s, models.AttributeDefinitionRequest{Entity: models.EntityEmployee, Name: "grade", Type: models.AttrString, Pattern: `[A-C][0-9]`})
+	mustDefine(t, s, models.AttributeDefinitionRequest{Entity: models.EntityEmployee, Name: "since", Type: models.AttrDate})
+
+	if _, err := s.CreateAttributeDefinition(ctx, &models.AttributeDefinitionRequest{Entity: models.EntityDepartment, Name: "region", Type: models.AttrString}); !errors.Is(err, models.ErrAttributeExists) {
+		t.Fatalf("duplicate attribute: got %v", err)
+	}
+	// То же имя у другой сущности допустимо
+	mustDefine(t, s, models.AttributeDefinitionRequest{Entity: models.EntityEmployee, Name: "region", Type: models.AttrString})
+
+	defs, err := s.ListAttributeDefinitions(ctx, models.EntityDepartment)
+	if err != nil || len(defs) != 3 {
+		t.Fatalf("ListAttributeDefinitions: %+v, %v", defs, err)
+	}
+
+	// Значения проверяются по описаниям
+	if _, err := s.CreateDepartment(ctx, &models.DepartmentRequest{Name: "NoRegion"}); !errors.Is(err, models.ErrAttributeRequired) {
+		t.Fatalf("missing required attribute: got %v", err)
+	}
+	badValues := []struct {
+		attrs map[string]any
+		want  error
+	}{
+		{map[string]any{"region": "east"}, models.ErrAttributeNotAllowed},
+		{map[string]any{"region": "north", "budget": "big"}, models.ErrAttributeType},
+		{map[string]any{"region": "north", "color": "red"}, models.ErrUnknownAttribute},
+	}
+	for _, tt := range badValues {
+		_, err := s.CreateDepartment(ctx, &models.DepartmentRequest{Name: "Bad", Attributes: tt.attrs})
+		var attrErr *models.AttributeError
+		if !errors.Is(err, tt.want) || !errors.As(err, &attrErr) {
+			t.Fatalf("attributes %v: got %v, want %v", tt.attrs, err, tt.want)
+		}
+	}
+
+	north, err := s.CreateDepartment(ctx, &models.DepartmentRequest{Name: "North", Attributes: map[string]any{"region": "north", "budget": 100, "remote": true}})
+	if err != nil {
+		t.Fatalf("CreateDepartment with attributes: %v", err)
+	}
+	south, err := s.CreateDepartment(ctx, &models.DepartmentRequest{Name: "South", Attributes: map[string]any{"region": "south", "budget": 50}})
+	if err != nil {
+		t.Fatalf("CreateDepartment with attributes: %v", err)
+	}
+
+	// Изменение дополняет значения, null удаляет
+	updated, err := s.UpdateDepartment(ctx, south.Id, 0, &models.DepartmentRequest{Attributes: map[string]any{"remote": false, "budget": nil}})
+	if err != nil {
+		t.Fatalf("UpdateDepartment attributes: %v", err)
+	}
+	if updated.Attributes["region"] != "south" || updated.Attributes["remote"] != false || updated.Attributes["budget"] != nil {
+		t.Fatalf("merged attributes: %v", updated.Attributes)
+	}
+	if _, err := s.UpdateDepartment(ctx, south.Id, 0, &models.DepartmentRequest{Attributes: map[string]any{"region": nil}}); !errors.Is(err, models.ErrAttributeRequired) {
+		t.Fatalf("removing required attribute: got %v", err)
+	}
+
+	// Фильтры по атрибутам
+	listed, err := s.ListDepartments(ctx, &models.DepartmentFilter{Attributes: map[string]string{"region": "north", "budget": "100", "remote": "true"}})
+	if err != nil || len(listed) != 1 || listed[0].Id != north.Id {
+		t.Fatalf("ListDepartments by attributes: %+v, %v", listed, err)
+	}
+	listed, err = s.ListDepartments(ctx, &models.DepartmentFilter{Attributes: map[string]string{"remote": "false"}})
+	if err != nil || len(listed) != 1 || listed[0].Id != south.Id {
+		t.Fatalf("ListDepartments by boolean: %+v, %v", listed, err)
+	}
+	if _, err := s.ListDepartments(ctx, &models.DepartmentFilter{Attributes: map[string]string{"budget": "many"}}); !errors.Is(err, models.ErrAttributeType) {
+		t.Fatalf("filter with bad value: got %v", err)
+	}
+
+	// Атрибуты сотрудников
+	if _, err := s.CreateEmployee(ctx, north.Id, &models.EmployeeRequest{FullName: "X", Position: "Dev", Attributes: map[string]any{"grade": "D1"}}); !errors.Is(err, models.ErrAttributePattern) {
+		t.Fatalf("pattern mismatch: got %v", err)
+	}
+	anna, err := s.CreateEmployee(ctx, north.Id, &models.EmployeeRequest{FullName: "Anna", Position: "Dev", Attributes: map[string]any{"grade": "B2", "since": "2024-03-01"}})
+	if err != nil {
+		t.Fatalf("CreateEmployee with attributes: %v", err)
+	}
+	mustHire(t, s, north.Id, "Petr")
+
+	employees, err := s.GetEmployeesByDepartment(ctx, north.Id, &models.EmployeeFilter{Attributes: map[string]string{"grade": "B2"}})
+	if err != nil || len(employees) != 1 || employees[0].ID != anna.ID {
+		t.Fatalf("employees by attribute: %+v, %v", employees, err)
+	}
+	if _, err := s.UpdateEmployeeAttributes(ctx, anna.ID, anna.Version+1, &models.EmployeeAttributesRequest{Attributes: map[string]any{"grade": "C1"}}); !errors.Is(err, models.ErrVersionMismatch) {
+		t.Fatalf("stale employee version: got %v", err)
+	}
+	emp, err := s.UpdateEmployeeAttributes(ctx, anna.ID, anna.Version, &models.EmployeeAttributesRequest{Attributes: map[string]any{"grade": "C1", "since": nil}})
+	if err != nil {
+		t.Fatalf("UpdateEmployeeAttributes: %v", err)
+	}
+	if emp.Attributes["grade"] != "C1" || emp.Attributes["since"] != nil || emp.Version != anna.Version+1 {
+		t.Fatalf("employee attributes: %+v", emp)
+	}
+
+	// Копия наследует атрибуты
+	copied, err := s.CopyDepartment(ctx, north.Id, &models.CopyRequest{Name: "North copy"})
+	if err != nil {
+		t.Fatalf("CopyDepartment: %v", err)
+	}
+	if copied.Root.Attributes["region"] != "north" {
+		t.Fatalf("copy attributes: %v", copied.Root.Attributes)
+	}
+
+	// Изменение описания: новые ограничения действуют для новых значений
+	enum := []string{"north", "south", "east"}
+	region, err = s.UpdateAttributeDefinition(ctx, region.ID, region.Version, &models.AttributeDefinitionUpdate{EnumValues: &enum})
+	if err != nil || len(region.EnumValues) != 3 || region.Version != 2 {
+		t.Fatalf("UpdateAttributeDefinition: %+v, %v", region, err)
+	}
+	if _, err := s.CreateDepartment(ctx, &models.DepartmentRequest{Name: "East", Attributes: map[string]any{"region": "east"}}); err != nil {
+		t.Fatalf("value allowed after update: %v", err)
+	}
+
+	// Удаление описания убирает значения
+	if err := s.DeleteAttributeDefinition(ctx, region.ID, 1); !errors.Is(err, models.ErrVersionMismatch) {
+		t.Fatalf("stale definition version: got %v", err)
+	}
+	if err := s.DeleteAttributeDefinition(ctx, region.ID, region.Version); err != nil {
+		t.Fatalf("DeleteAttributeDefinition: %v", err)
+	}
+	if _, err := s.GetAttributeDefinition(ctx, region.ID); !errors.Is(err, models.ErrAttributeDefinitionNotFound) {
+		t.Fatalf("deleted definition: got %v", err)
+	}
+	tree, err := s.GetWithTree(ctx, north.Id, 0, nil)
+	if err != nil {
+		t.Fatalf("GetWithTree: %v", err)
+	}
+	if _, ok := tree.Attributes["region"]; ok || tree.Attributes["budget"] == nil {
+		t.Fatalf("attributes after delete: %v", tree.Attributes)
+	}
+	if _, err := s.CreateDepartment(ctx, &models.DepartmentRequest{Name: "Plain"}); err != nil {
+		t.Fatalf("create without deleted required attribute: %v", err)
+	}
+}
+
+func mustDefine(t *testing.T, s Store, req models.AttributeDefinitionRequest) *models.AttributeDefinition {
+	t.Helper()
+	def, err := s.CreateAttributeDefinition(ctx, &req)
+	if err != nil {
+		t.Fatalf("CreateAttributeDefinition(%q): %v", req.Name, err)
+	}
+	return def
+}
@@ -0,0 +1,98 @@
+package storetest
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/kroulersama/goProject/models"
+)
+
+func testEmploymentStatus(t *testing.T, s Store) {
+	dept := mustCreate(t, s, "Dept", nil)
+	other := mustCreate(t, s, "Other", nil)
+	hiredAt := time.Now().Add(-48 * time.Hour).UTC().Truncate(time.Second)
+
+	emp, err := s.CreateEmployee(ctx, dept.Id, &models.EmployeeRequest{FullName: "Ivan", Position: "Dev", HiredAt: &hiredAt})
+	if err != nil || emp.Status != models.EmployeeActive {
+		t.Fatalf("CreateEmployee: %+v, %v", emp, err)
+	}
+	if _, err := s.CreateEmployee(ctx, dept.Id, &models.EmployeeRequest{FullName: "Anna", Position: "QA"}); err != nil {
+		t.Fatalf("CreateEmployee: %v", err)
+	}
+
+	// Отпуск; terminated через статус поставить нельзя
+	if _, err := s.SetEmployeeStatus(ctx, emp.ID, 0, &models.EmployeeStatusRequest{Status: models.EmployeeTerminated}); !errors.Is(err, models.ErrInvalidEmployeeStatus) {
+		t.Fatalf("status terminated: got %v", err)
+	}
+	emp, err = s.SetEmployeeStatus(ctx, emp.ID, 1, &models.EmployeeStatusRequest{Status: models.EmployeeOnLeave})
+	if err != nil || emp.Status != models.EmployeeOnLeave || emp.Version != 2 {
+		t.Fatalf("SetEmployeeStatus: %+v, %v", emp, err)
+	}
+
+	// Дата увольнения не раньше приема, причина обязательна
+	early := hiredAt.Add(-time.Hour)
+	_, err = s.TerminateEmployee(ctx, emp.ID, 0, &models.TerminationRequest{TerminatedAt: &early})
+	if !errors.Is(err, models.ErrTerminatedBeforeHired) || !errors.Is(err, models.ErrTerminationReasonEmpty) {
+		t.Fatalf("invalid termination: got %v", err)
+	}
+	if _, err := s.TerminateEmployee(ctx, emp.ID, 1, &models.TerminationRequest{Reason: "resigned"}); !errors.Is(err, models.ErrVersionMismatch) {
+		t.Fatalf("stale terminate: got %v", err)
+	}
+	terminatedAt := hiredAt.Add(24 * time.Hour)
+	emp, err = s.TerminateEmployee(ctx, emp.ID, 2, &models.TerminationRequest{TerminatedAt: &terminatedAt, Reason: " resigned "})
+	if err != nil || emp.Status != models.EmployeeTerminated || emp.TerminationReason != "resigned" ||
+		emp.TerminatedAt == nil || !emp.TerminatedAt.Equal(terminatedAt) {
+		t.Fatalf("TerminateEmployee: %+v, %v", emp, err)
+	}
+	if _, err := s.TerminateEmployee(ctx, emp.ID, 0, &models.TerminationRequest{Reason: "again"}); !errors.Is(err, models.ErrEmployeeTerminated) {
+		t.Fatalf("terminate twice: got %v", err)
+	}
+	if _, err := s.TransferEmployee(ctx, emp.ID, 0, &models.TransferRequest{DepartmentID: other.Id}); !errors.Is(err, models.ErrEmployeeTerminated) {
+		t.Fatalf("transfer terminated: got %v", err)
+	}
+
+	// Уволенные скрыты по умолчанию
+	employees, err := s.GetEmployeesByDepartment(ctx, dept.Id, &models.EmployeeFilter{})
+	if err != nil || len(employees) != 1 || employees[0].FullName != "Anna" {
+		t.Fatalf("default list: %+v, %v", employees, err)
+	}
+	employees, err = s.GetEmployeesByDepartment(ctx, dept.Id, &models.EmployeeFilter{IncludeTerminated: true})
+	if err != nil || len(employees) != 2 {
+		t.Fatalf("list with terminated: %+v, %v", employees, err)
+	}
+	employees, err = s.GetEmployeesByDepartment(ctx, dept.Id, &models.EmployeeFilter{Status: models.EmployeeTerminated})
+	if err != nil || len(employees) != 1 || employees[0].ID != emp.ID {
+		t.Fatalf("list terminated: %+v, %v", employees, err)
+	}
+	tree, err := s.GetWithTree(ctx, dept.Id, 1, &models.EmployeeFilter{})
+	if err != nil || len(tree.Employees) != 1 {
+		t.Fatalf("default tree: %+v, %v", tree, err)
+	}
+	tree, err = s.GetWithTree(ctx, dept.Id, 1, &models.EmployeeFilter{IncludeTerminated: true})
+	if err != nil || len(tree.Employees) != 2 {
+		t.Fatalf("tree with terminated: %+v, %v", tree, err)
+	}
+
+	// Повторный прием не раньше увольнения, можно в другой отдел
+	if _, err := s.RehireEmployee(ctx, emp.ID, 0, &models.RehireRequest{HiredAt: &hiredAt}); !errors.Is(err, models.ErrRehireBeforeTermination) {
+		t.Fatalf("rehire before termination: got %v", err)
+	}
+	missing := uint(999999)
+	if _, err := s.RehireEmployee(ctx, emp.ID, 0, &models.RehireRequest{DepartmentID: &missing}); !errors.Is(err, models.ErrUnknownDepartment) {
+		t.Fatalf("rehire into missing department: got %v", err)
+	}
+	emp, err = s.RehireEmployee(ctx, emp.ID, emp.Version, &models.RehireRequest{DepartmentID: &other.Id})
+	if err != nil || emp.Status != models.EmployeeActive || emp.TerminatedAt != nil || emp.TerminationReason != "" ||
+		emp.DepartmentId != other.Id || emp.HiredAt == nil || !emp.HiredAt.After(terminatedAt) {
+		t.Fatalf("RehireEmployee: %+v, %v", emp, err)
+	}
+	if _, err := s.RehireEmployee(ctx, emp.ID, 0, &models.RehireRequest{}); !errors.Is(err, models.ErrEmployeeNotTerminated) {
+		t.Fatalf("rehire active: got %v", err)
+	}
+
+	stored, err := s.GetEmployee(ctx, emp.ID)
+	if err != nil || stored.Status != models.EmployeeActive || stored.DepartmentId != other.Id || stored.Version != emp.Version {
+		t.Fatalf("employee after rehire: %+v, %v", stored, err)
+	}
+}
@@ -0,0 +1,94 @@
+package storetest
+
+import (
+	"testing"
+	"time"
+
+	"github.com/kroulersama/goProject/models"
+)
+
+func testIdempotencyKeys(t *testing.T, s Store) {
+	now := time.Now().UTC()
+	record := func(key string, createdAt, expiresAt time.Time) *models.IdempotencyKey {
+		return &models.IdempotencyKey{
+			Key:         key,
+			Method:      "POST",
+			Path:        "/departments",
+			RequestHash: "hash-" + key,
+			CreatedAt:   createdAt,
+			ExpiresAt:   expiresAt,
+		}
+	}
+	reserve := func(key *models.IdempotencyKey, staleAfter time.Duration) *models.IdempotencyKey {
+		t.Helper()
+		existing, err := s.ReserveIdempotencyKey(ctx, key, staleAfter)
+		if err != nil {
+			t.Fatalf("ReserveIdempotencyKey %s: %v", key.Key, err)
+		}
+		return existing
+	}
+
+	// Первый запрос занимает ключ, повтор видит незавершенную запись
+	key := record("a", now, now.Add(time.Hour))
+	if existing := reserve(key, time.Minute); existing != nil {
+		t.Fatalf("new key already reserved: %+v", existing)
+	}
+	existing := reserve(record("a", now, now.Add(time.Hour)), time.Minute)
+	if existing == nil || !existing.Pending() || existing.RequestHash != key.RequestHash {
+		t.Fatalf("unexpected pending record: %+v", existing)
+	}
+
+	// Сохраненный ответ возвращается повтору
+	key.StatusCode = 201
+	key.ContentType = "application/json"
+	key.ETag = `"1-abc"`
+	key.Body = []byte(`{"id":1}`)
+	if err := s.CompleteIdempotencyKey(ctx, key); err != nil {
+		t.Fatalf("CompleteIdempotencyKey: %v", err)
+	}
+	existing = reserve(record("a", now, now.Add(time.Hour)), time.Minute)
+	if existing == nil || existing.Pending() || existing.StatusCode != 201 || existing.ContentType != key.ContentType ||
+		existing.ETag != key.ETag || string(existing.Body) != string(key.Body) {
+		t.Fatalf("unexpected completed record: %+v", existing)
+	}
+
+	// Освобожденный ключ занимается заново
+	if err := s.ReleaseIdempotencyKey(ctx, "a"); err != nil {
+		t.Fatalf("ReleaseIdempotencyKey: %v", err)
+	}
+	if existing := reserve(record("a", now, now.Add(time.Hour)), time.Minute); existing != nil {
+		t.Fatalf("released key still reserved: %+v", existing)
+	}
+
+	// Незавершенный запрос считается брошенным только после staleAfter
+	reserve(record("b", now.Add(-2*time.Minute), now.Add(time.Hour)), time.Minute)
+	if existing := reserve(record("b", now, now.Add(time.Hour)), 5*time.Minute); existing == nil || !existing.Pending() {
+		t.Fatalf("running request taken over: %+v", existing)
+	}
+	if existing := reserve(record("b", now, now.Add(time.Hour)), time.Minute); existing != nil {
+		t.Fatalf("abandoned request not taken over: %+v", existing)
+	}
+
+	// Просроченный ответ заменяется новым запросом
+	expired := record("c", now.Add(-2*time.Hour), now.Add(-time.Hour))
+	reserve(expired, time.Minute)
+	expired.StatusCode = 200
+	if err := s.CompleteIdempotencyKey(ctx, expired); err != nil {
+		t.Fatalf("CompleteIdempotencyKey: %v", err)
+	}
+	if existing := reserve(record("c", now, now.Add(time.Hour)), time.Minute); existing != nil {
+		t.Fatalf("expired key not replaced: %+v", existing)
+	}
+
+	// Очистка удаляет только просроченные
+	reserve(record("d", now.Add(-2*time.Hour), now.Add(-time.Hour)), time.Minute)
+	purged, err := s.PurgeExpiredIdempotencyKeys(ctx, now)
+	if err != nil || purged != 1 {
+		t.Fatalf("PurgeExpiredIdempotencyKeys: %d, %v", purged, err)
+	}
+	for _, k := range []string{"a", "b", "c"} {
+		if existing := reserve(record(k, now, now.Add(time.Hour)), time.Hour); existing == nil {
+			t.Fatalf("live key %s purged", k)
+		}
+	}
+}
@@ -0,0 +1,113 @@
+package storetest
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/kroulersama/goProject/models"
+)
+
+func testIdentifiers(t *testing.T, s Store) {
+	code := " FIN-01 "
+	fin, err := s.CreateDepartment(ctx, &models.DepartmentRequest{
+		Name:        "Finance",
+		Code:        &code,
+		ExternalIDs: map[string]string{"sap": "1000", "payroll": "F1"},
+	})
+	if err != nil {
+		t.Fatalf("CreateDepartment with code: %v", err)
+	}
+	if fin.Code == nil || *fin.Code != "FIN-01" || fin.ExternalIDs["sap"] != "1000" {
+		t.Fatalf("unexpected identifiers: %+v", fin)
+	}
+
+	// Поиск по коду и внешнему id
+	for _, ref := range []models.DepartmentRef{{Code: "FIN-01"}, {System: "sap", ExternalID: "1000"}, {ID: fin.Id}} {
+		id, err := s.ResolveDepartment(ctx, ref)
+		if err != nil || id != fin.Id {
+			t.Fatalf("ResolveDepartment %+v: got %d, %v", ref, id, err)
+		}
+	}
+	if _, err := s.ResolveDepartment(ctx, models.DepartmentRef{Code: "NOPE"}); !errors.Is(err, models.ErrDepartmentNotFound) {
+		t.Fatalf("unknown code: got %v", err)
+	}
+
+	// Код и внешние id уникальны
+	if _, err := s.CreateDepartment(ctx, &models.DepartmentRequest{Name: "Other", Code: &code}); !errors.Is(err, models.ErrCodeExists) {
+		t.Fatalf("duplicate code: got %v", err)
+	}
+	if _, err := s.CreateDepartment(ctx, &models.DepartmentRequest{Name: "Other", ExternalIDs: map[string]string{"sap": "1000"}}); !errors.Is(err, models.ErrExternalIDExists) {
+		t.Fatalf("duplicate external id: got %v", err)
+	}
+	bad := "a:b"
+	if _, err := s.CreateDepartment(ctx, &models.DepartmentRequest{Name: "Other", Code: &bad}); !errors.Is(err, models.ErrInvalidCode) {
+		t.Fatalf("invalid code: got %v", err)
+	}
+	if _, err := s.CreateDepartment(ctx, &models.DepartmentRequest{Name: "Other", ExternalIDs: map[string]string{"sap": " "}}); !errors.Is(err, models.ErrInvalidExternalID) {
+		t.Fatalf("invalid external id: got %v", err)
+	}
+
+	// Обновление без полей не трогает идентификаторы, map заменяет набор
+	other := mustCreate(t, s, "Other", nil)
+	updated, err := s.UpdateDepartment(ctx, fin.Id, 0, &models.DepartmentRequest{ParentID: &other.Id})
+	if err != nil {
+		t.Fatalf("UpdateDepartment: %v", err)
+	}
+	if updated.Code == nil || *updated.Code != "FIN-01" || len(updated.ExternalIDs) != 2 {
+		t.Fatalf("identifiers lost on move: %+v", updated)
+	}
+	updated, err = s.UpdateDepartment(ctx, fin.Id, 0, &models.DepartmentRequest{ExternalIDs: map[string]string{"sap": "2000"}})
+	if err != nil {
+		t.Fatalf("replace external ids: %v", err)
+	}
+	if len(updated.ExternalIDs) != 1 || updated.ExternalIDs["sap"] != "2000" {
+		t.Fatalf("unexpected external ids: %v", updated.ExternalIDs)
+	}
+	if _, err := s.ResolveDepartment(ctx, models.DepartmentRef{System: "payroll", ExternalID: "F1"}); !errors.Is(err, models.ErrDepartmentNotFound) {
+		t.Fatalf("removed external id still resolves: %v", err)
+	}
+	if _, err := s.UpdateDepartment(ctx, other.Id, 0, &models.DepartmentRequest{Code: &code}); !errors.Is(err, models.ErrCodeExists) {
+		t.Fatalf("update to taken code: got %v", err)
+	}
+
+	// Пустой код снимает его
+	empty := ""
+	updated, err = s.UpdateDepartment(ctx, fin.Id, 0, &models.DepartmentRequest{Code: &empty})
+	if err != nil {
+		t.Fatalf("clear code: %v", err)
+	}
+	if updated.Code != nil {
+		t.Fatalf("code not cleared: %v", *updated.Code)
+	}
+	if _, err := s.UpdateDepartment(ctx, other.Id, 0, &models.DepartmentRequest{Code: &code}); err != nil {
+		t.Fatalf("reuse released code: %v", err)
+	}
+	tree, err := s.GetWithTree(ctx, other.Id, 1, nil)
+	if err != nil {
+		t.Fatalf("GetWithTree: %v", err)
+	}
+	if tree.Code == nil || *tree.Code != "FIN-01" || tree.Children[0].ExternalIDs["sap"] != "2000" {
+		t.Fatalf("identifiers not stored: %+v", tree)
+	}
+
+	// Табельные номера сотрудников
+	number := "00042"
+	emp, err := s.CreateEmployee(ctx, fin.Id, &models.EmployeeRequest{FullName: "Ann", Position: "Accountant", PersonnelNumber: &number})
+	if err != nil {
+		t.Fatalf("CreateEmployee with personnel number: %v", err)
+	}
+	id, err := s.ResolveEmployee(ctx, models.EmployeeRef{PersonnelNumber: number})
+	if err != nil || id != emp.ID {
+		t.Fatalf("ResolveEmployee: got %d, %v", id, err)
+	}
+	got, err := s.GetEmployee(ctx, id)
+	if err != nil || got.PersonnelNumber == nil || *got.PersonnelNumber != number {
+		t.Fatalf("GetEmployee: got %+v, %v", got, err)
+	}
+	if _, err := s.CreateEmployee(ctx, fin.Id, &models.EmployeeRequest{FullName: "Bob", Position: "Clerk", PersonnelNumber: &number}); !errors.Is(err, models.ErrPersonnelNumberExists) {
+		t.Fatalf("duplicate personnel number: got %v", err)
+	}
+	if _, err := s.ResolveEmployee(ctx, models.EmployeeRef{PersonnelNumber: "missing"}); !errors.Is(err, models.ErrEmployeeNotFound) {
+		t.Fatalf("unknown personnel number: got %v", err)
+	}
+}
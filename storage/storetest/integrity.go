@@ -0,0 +1,44 @@
+package storetest
+
+import (
+	"testing"
+
+	"github.com/kroulersama/goProject/models"
+)
+
+// Данные, созданные через хранилище, проверку проходят, а исправление их не трогает
+func testIntegrity(t *testing.T, s Store) {
+	report, err := s.CheckIntegrity(ctx, false)
+	if err != nil || report.Departments != 0 || len(report.Issues) != 0 {
+		t.Fatalf("empty store: %+v, %v", report, err)
+	}
+
+	root := mustCreate(t, s, "Root", nil)
+	a := mustCreate(t, s, "A", &root.Id)
+	b := mustCreate(t, s, "B", &a.Id)
+	mustCreate(t, s, "Other", nil)
+	mustHire(t, s, a.Id, "Ivan")
+	mustHire(t, s, b.Id, "Petr")
+
+	// Переименование и перенос пересчитывают служебные колонки
+	if _, err := s.UpdateDepartment(ctx, b.Id, 0, &models.DepartmentRequest{Name: "B renamed", ParentID: &root.Id}); err != nil {
+		t.Fatalf("UpdateDepartment: %v", err)
+	}
+
+	report, err = s.CheckIntegrity(ctx, false)
+	if err != nil {
+		t.Fatalf("CheckIntegrity: %v", err)
+	}
+	if report.Departments != 4 || report.Employees != 2 || len(report.Issues) != 0 || report.Remaining() != 0 {
+		t.Fatalf("clean tree: %+v", report)
+	}
+
+	report, err = s.CheckIntegrity(ctx, true)
+	if err != nil || len(report.Issues) != 0 || report.Repaired != 0 {
+		t.Fatalf("repair of clean tree: %+v, %v", report, err)
+	}
+	tree, err := s.GetWithTree(ctx, root.Id, 0, nil)
+	if err != nil || tree.Version != root.Version {
+		t.Fatalf("repair changed the tree: %+v, %v", tree, err)
+	}
+}
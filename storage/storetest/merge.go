@@ -0,0 +1,170 @@
+package storetest
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/kroulersama/goProject/models"
+)
+
+func testMerge(t *testing.T, s Store) {
+	root := mustCreate(t, s, "Root", nil)
+	src := mustCreate(t, s, "Source", &root.Id)
+	dst := mustCreate(t, s, "Target", &root.Id)
+	mustCreate(t, s, "Only source", &src.Id)
+	mustHire(t, s, src.Id, "Ivan")
+	mustHire(t, s, src.Id, "Anna")
+
+	if _, err := s.MergeDepartment(ctx, src.Id, src.Id, 0, ""); !errors.Is(err, models.ErrMergeIntoSelf) {
+		t.Fatalf("merge into self: got %v", err)
+	}
+	if _, err := s.MergeDepartment(ctx, root.Id, dst.Id, 0, ""); !errors.Is(err, models.ErrMergeIntoDescendant) {
+		t.Fatalf("merge into descendant: got %v", err)
+	}
+	if _, err := s.MergeDepartment(ctx, src.Id, dst.Id, 0, "bogus"); !errors.Is(err, models.ErrInvalidMergeStrategy) {
+		t.Fatalf("invalid strategy: got %v", err)
+	}
+	if _, err := s.MergeDepartment(ctx, src.Id, 999999, 0, ""); !errors.Is(err, models.ErrTargetNotFound) {
+		t.Fatalf("missing target: got %v", err)
+	}
+
+	summary, err := s.MergeDepartment(ctx, src.Id, dst.Id, 0, models.MergeFail)
+	if err != nil {
+		t.Fatalf("MergeDepartment: %v", err)
+	}
+	if summary.EmployeesMoved != 2 || len(summary.DepartmentsMoved) != 1 {
+		t.Fatalf("unexpected summary: %+v", summary)
+	}
+	if _, err := s.GetWithTree(ctx, src.Id, 1, nil); !errors.Is(err, models.ErrDepartmentNotFound) {
+		t.Fatalf("source not removed: %v", err)
+	}
+	tree, err := s.GetWithTree(ctx, dst.Id, 1, &models.EmployeeFilter{})
+	if err != nil || len(tree.Employees) != 2 || len(tree.Children) != 1 {
+		t.Fatalf("unexpected target: %+v, %v", tree, err)
+	}
+}
+
+// Совпадения среди соседей, политика siblings
+func testMergeStrategies(t *testing.T, s Store) {
+	setup := func(t *testing.T) (src, dst, srcSales, dstSales *models.Department) {
+		src = mustCreate(t, s, "Source "+t.Name(), nil)
+		dst = mustCreate(t, s, "Target "+t.Name(), nil)
+		srcSales = mustCreate(t, s, "Sales", &src.Id)
+		dstSales = mustCreate(t, s, "SALES", &dst.Id)
+		mustCreate(t, s, "East", &srcSales.Id)
+		mustCreate(t, s, "East", &dstSales.Id)
+		mustCreate(t, s, "West", &srcSales.Id)
+		mustHire(t, s, srcSales.Id, "Ivan")
+		return
+	}
+
+	t.Run(models.MergeFail, func(t *testing.T) {
+		src, dst, srcSales, _ := setup(t)
+		if _, err := s.MergeDepartment(ctx, src.Id, dst.Id, 0, models.MergeFail); !errors.Is(err, models.ErrMergeConflict) {
+			t.Fatalf("got %v, want ErrMergeConflict", err)
+		}
+		// Транзакция откатилась
+		tree, err := s.GetWithTree(ctx, src.Id, 2, &models.EmployeeFilter{})
+		if err != nil || len(tree.Children) != 1 || tree.Children[0].Id != srcSales.Id || len(tree.Children[0].Children) != 2 {
+			t.Fatalf("source changed after failed merge: %+v, %v", tree, err)
+		}
+	})
+
+	t.Run(models.MergeSuffix, func(t *testing.T) {
+		src, dst, srcSales, _ := setup(t)
+		summary, err := s.MergeDepartment(ctx, src.Id, dst.Id, 0, models.MergeSuffix)
+		if err != nil {
+			t.Fatalf("MergeDepartment: %v", err)
+		}
+		if len(summary.Renamed) != 1 || summary.Renamed[0].ID != srcSales.Id || summary.Renamed[0].To != "Sales (2)" {
+			t.Fatalf("unexpected summary: %+v", summary)
+		}
+		tree, err := s.GetWithTree(ctx, dst.Id, 1, nil)
+		if err != nil || len(tree.Children) != 2 {
+			t.Fatalf("unexpected target: %+v, %v", tree, err)
+		}
+	})
+
+	t.Run(models.MergeRecursive, func(t *testing.T) {
+		src, dst, srcSales, dstSales := setup(t)
+		summary, err := s.MergeDepartment(ctx, src.Id, dst.Id, 0, models.MergeRecursive)
+		if err != nil {
+			t.Fatalf("MergeDepartment: %v", err)
+		}
+		// Sales -> SALES, внутри East -> East, West переносится
+		if len(summary.Merged) != 2 || summary.EmployeesMoved != 1 || len(summary.DepartmentsMoved) != 1 {
+			t.Fatalf("unexpected summary: %+v", summary)
+		}
+		if last := summary.Merged[len(summary.Merged)-1]; last.SourceID != srcSales.Id || last.TargetID != dstSales.Id {
+			t.Fatalf("unexpected merge order: %+v", summary.Merged)
+		}
+		tree, err := s.GetWithTree(ctx, dstSales.Id, 1, &models.EmployeeFilter{})
+		if err != nil || len(tree.Employees) != 1 || len(tree.Children) != 2 {
+			t.Fatalf("unexpected merged tree: %+v, %v", tree, err)
+		}
+	})
+}
+
+// Политика branch по умолчанию: переносимое поддерево совпадает с веткой target
+// не только среди соседей (A/X/Deep и B/Y/Deep)
+func testMergeStrategiesInBranch(t *testing.T, s Store) {
+	setup := func(t *testing.T) (a, b, x, deep, bDeep *models.Department) {
+		a = mustCreate(t, s, "A "+t.Name(), nil)
+		b = mustCreate(t, s, "B "+t.Name(), nil)
+		x = mustCreate(t, s, "X", &a.Id)
+		deep = mustCreate(t, s, "Deep", &x.Id)
+		mustCreate(t, s, "Inner", &deep.Id)
+		y := mustCreate(t, s, "Y", &b.Id)
+		bDeep = mustCreate(t, s, "DEEP", &y.Id)
+		mustHire(t, s, deep.Id, "Ivan")
+		return
+	}
+
+	t.Run(models.MergeFail, func(t *testing.T) {
+		a, b, x, deep, _ := setup(t)
+		if _, err := s.MergeDepartment(ctx, a.Id, b.Id, 0, models.MergeFail); !errors.Is(err, models.ErrMergeConflict) {
+			t.Fatalf("got %v, want ErrMergeConflict", err)
+		}
+		tree, err := s.GetWithTree(ctx, x.Id, 1, nil)
+		if err != nil || len(tree.Children) != 1 || tree.Children[0].Id != deep.Id || *tree.ParentId != a.Id {
+			t.Fatalf("source changed after failed merge: %+v, %v", tree, err)
+		}
+	})
+
+	t.Run(models.MergeSuffix, func(t *testing.T) {
+		a, b, x, deep, _ := setup(t)
+		summary, err := s.MergeDepartment(ctx, a.Id, b.Id, 0, models.MergeSuffix)
+		if err != nil {
+			t.Fatalf("MergeDepartment: %v", err)
+		}
+		if len(summary.Renamed) != 1 || summary.Renamed[0].ID != deep.Id || summary.Renamed[0].To != "Deep (2)" {
+			t.Fatalf("unexpected summary: %+v", summary)
+		}
+		if len(summary.DepartmentsMoved) != 1 || summary.DepartmentsMoved[0] != x.Id {
+			t.Fatalf("unexpected moves: %+v", summary)
+		}
+		tree, err := s.GetWithTree(ctx, x.Id, 2, nil)
+		if err != nil || *tree.ParentId != b.Id || len(tree.Children) != 1 || tree.Children[0].Name != "Deep (2)" || len(tree.Children[0].Children) != 1 {
+			t.Fatalf("unexpected moved tree: %+v, %v", tree, err)
+		}
+	})
+
+	t.Run(models.MergeRecursive, func(t *testing.T) {
+		a, b, x, deep, bDeep := setup(t)
+		summary, err := s.MergeDepartment(ctx, a.Id, b.Id, 0, models.MergeRecursive)
+		if err != nil {
+			t.Fatalf("MergeDepartment: %v", err)
+		}
+		if len(summary.Merged) != 1 || summary.Merged[0].SourceID != deep.Id || summary.Merged[0].TargetID != bDeep.Id || summary.EmployeesMoved != 1 {
+			t.Fatalf("unexpected summary: %+v", summary)
+		}
+		tree, err := s.GetWithTree(ctx, bDeep.Id, 1, &models.EmployeeFilter{})
+		if err != nil || len(tree.Employees) != 1 || len(tree.Children) != 1 || tree.Children[0].Name != "Inner" {
+			t.Fatalf("unexpected merged tree: %+v, %v", tree, err)
+		}
+		tree, err = s.GetWithTree(ctx, x.Id, 1, nil)
+		if err != nil || *tree.ParentId != b.Id || len(tree.Children) != 0 {
+			t.Fatalf("unexpected moved tree: %+v, %v", tree, err)
+		}
+	})
+}
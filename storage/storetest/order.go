@@ -0,0 +1,131 @@
+package storetest
+
+import (
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/kroulersama/goProject/models"
+)
+
+func testSiblingOrder(t *testing.T, s Store) {
+	root := mustCreate(t, s, "Root", nil)
+	a := mustCreate(t, s, "A", &root.Id)
+	b := mustCreate(t, s, "B", &root.Id)
+	c := mustCreate(t, s, "C", &root.Id)
+	if a.SortOrder != 0 || b.SortOrder != 1 || c.SortOrder != 2 {
+		t.Fatalf("new departments are not appended: %d, %d, %d", a.SortOrder, b.SortOrder, c.SortOrder)
+	}
+
+	first := 0
+	d, err := s.CreateDepartment(ctx, &models.DepartmentRequest{Name: "D", ParentID: &root.Id, Position: &first})
+	if err != nil {
+		t.Fatalf("CreateDepartment at position: %v", err)
+	}
+	if d.SortOrder != 0 {
+		t.Fatalf("unexpected sort order: %d", d.SortOrder)
+	}
+	expectChildren(t, s, root.Id, d.Id, a.Id, b.Id, c.Id)
+
+	// Порядок задается полным списком детей
+	tree, err := s.GetWithTree(ctx, root.Id, 0, nil)
+	if err != nil {
+		t.Fatalf("GetWithTree: %v", err)
+	}
+	for _, ids := range [][]uint{{c.Id, a.Id, d.Id}, {c.Id, a.Id, d.Id, d.Id}, {c.Id, a.Id, d.Id, root.Id}} {
+		if _, err := s.ReorderChildren(ctx, root.Id, tree.Version, &models.ReorderRequest{IDs: ids}); !errors.Is(err, models.ErrInvalidChildOrder) {
+			t.Fatalf("reorder %v: got %v", ids, err)
+		}
+	}
+	if _, err := s.ReorderChildren(ctx, root.Id, tree.Version+1, &models.ReorderRequest{IDs: []uint{c.Id, a.Id, d.Id, b.Id}}); !errors.Is(err, models.ErrVersionMismatch) {
+		t.Fatalf("reorder stale version: got %v", err)
+	}
+	reordered, err := s.ReorderChildren(ctx, root.Id, tree.Version, &models.ReorderRequest{IDs: []uint{c.Id, a.Id, d.Id, b.Id}})
+	if err != nil {
+		t.Fatalf("ReorderChildren: %v", err)
+	}
+	if reordered.Version != tree.Version+1 || len(reordered.Children) != 4 || reordered.Children[0].Id != c.Id {
+		t.Fatalf("unexpected reorder response: %+v", reordered)
+	}
+	expectChildren(t, s, root.Id, c.Id, a.Id, d.Id, b.Id)
+
+	// Перенос на позицию, без позиции - в конец
+	other := mustCreate(t, s, "Other", nil)
+	x := mustCreate(t, s, "X", &other.Id)
+	second := 1
+	if _, err := s.UpdateDepartment(ctx, x.Id, 0, &models.DepartmentRequest{ParentID: &root.Id, Position: &second}); err != nil {
+		t.Fatalf("move to position: %v", err)
+	}
+	expectChildren(t, s, root.Id, c.Id, x.Id, a.Id, d.Id, b.Id)
+
+	if _, err := s.UpdateDepartment(ctx, a.Id, 0, &models.DepartmentRequest{ParentID: &other.Id}); err != nil {
+		t.Fatalf("move: %v", err)
+	}
+	y := mustCreate(t, s, "Y", &other.Id)
+	expectChildren(t, s, other.Id, a.Id, y.Id)
+
+	// Перестановка внутри родителя; позиция за концом - в конец
+	last := 100
+	if _, err := s.UpdateDepartment(ctx, c.Id, 0, &models.DepartmentRequest{Position: &last}); err != nil {
+		t.Fatalf("reorder by position: %v", err)
+	}
+	expectChildren(t, s, root.Id, x.Id, d.Id, b.Id, c.Id)
+
+	// Плоский список: корни первыми, дети одного родителя - в порядке дерева
+	listed, err := s.ListDepartments(ctx, &models.DepartmentFilter{})
+	if err != nil {
+		t.Fatalf("ListDepartments: %v", err)
+	}
+	var roots, children []uint
+	for i, dep := range listed {
+		if dep.ParentId == nil {
+			if i != len(roots) {
+				t.Fatalf("root %d listed after children: %+v", dep.Id, listed)
+			}
+			roots = append(roots, dep.Id)
+		} else if *dep.ParentId == root.Id {
+			children = append(children, dep.Id)
+		}
+	}
+	if !slices.Equal(children, []uint{x.Id, d.Id, b.Id, c.Id}) {
+		t.Fatalf("ListDepartments children of %d: got %v", root.Id, children)
+	}
+
+	negative := -1
+	if _, err := s.UpdateDepartment(ctx, c.Id, 0, &models.DepartmentRequest{Position: &negative}); !errors.Is(err, models.ErrInvalidPosition) {
+		t.Fatalf("negative position: got %v", err)
+	}
+
+	// Копия сохраняет порядок
+	copied, err := s.CopyDepartment(ctx, root.Id, &models.CopyRequest{Name: "Root 2"})
+	if err != nil {
+		t.Fatalf("CopyDepartment: %v", err)
+	}
+	tree, err = s.GetWithTree(ctx, copied.Root.Id, 1, nil)
+	if err != nil {
+		t.Fatalf("GetWithTree: %v", err)
+	}
+	var names []string
+	for _, child := range tree.Children {
+		names = append(names, child.Name)
+	}
+	if !slices.Equal(names, []string{"X", "D", "B", "C"}) {
+		t.Fatalf("copy changed order: %v", names)
+	}
+}
+
+// Дети parentID в порядке вывода
+func expectChildren(t *testing.T, s Store, parentID uint, want ...uint) {
+	t.Helper()
+	tree, err := s.GetWithTree(ctx, parentID, 1, nil)
+	if err != nil {
+		t.Fatalf("GetWithTree: %v", err)
+	}
+	var got []uint
+	for _, child := range tree.Children {
+		got = append(got, child.Id)
+	}
+	if !slices.Equal(got, want) {
+		t.Fatalf("children of %d: got %v, want %v", parentID, got, want)
+	}
+}
@@ -0,0 +1,182 @@
+package storetest
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/kroulersama/goProject/models"
+)
+
+func testPlans(t *testing.T, s Store) {
+	root := mustCreate(t, s, "Root", nil)
+	sales := mustCreate(t, s, "Sales", &root.Id)
+	east := mustCreate(t, s, "East", &sales.Id)
+	legacy := mustCreate(t, s, "Legacy", &root.Id)
+	ivan := mustHire(t, s, east.Id, "Ivan")
+	anna := mustHire(t, s, legacy.Id, "Anna")
+
+	if _, err := s.CreatePlan(ctx, &models.PlanRequest{Name: "  "}); !errors.Is(err, models.ErrPlanNameEmpty) {
+		t.Fatalf("empty name: got %v", err)
+	}
+
+	// Ошибки собираются по всем операциям сразу
+	missing := uint(999999)
+	_, err := s.CreatePlan(ctx, &models.PlanRequest{Name: "Bad", Operations: []models.PlanOperation{
+		{Op: models.OpMove, DepartmentID: &sales.Id, ParentID: &east.Id},
+		{Op: models.OpRename, DepartmentID: &missing, Name: "X"},
+		{Op: "explode"},
+	}})
+	var invalid *models.PlanValidationError
+	if !errors.As(err, &invalid) || !errors.Is(err, models.ErrPlanInvalid) || len(invalid.Errors) != 3 {
+		t.Fatalf("invalid plan: got %v", err)
+	}
+	if !errors.Is(invalid.Errors[0].Err, models.ErrCycleDetected) || !errors.Is(invalid.Errors[1].Err, models.ErrDepartmentNotFound) {
+		t.Fatalf("unexpected operation errors: %+v", invalid.Errors)
+	}
+
+	plan, err := s.CreatePlan(ctx, &models.PlanRequest{Name: "Q3", Operations: []models.PlanOperation{
+		{Op: models.OpCreate, Ref: "north", ParentID: &sales.Id, Name: "North"},
+		{Op: models.OpTransfer, EmployeeIDs: []uint{ivan.ID}, ToDepartmentRef: "north"},
+	}})
+	if err != nil {
+		t.Fatalf("CreatePlan: %v", err)
+	}
+	if plan.Status != models.PlanDraft || plan.Version != 1 {
+		t.Fatalf("unexpected plan: %+v", plan)
+	}
+
+	// Операции проверяются вместе с уже добавленными
+	_, err = s.AddPlanOperations(ctx, plan.ID, plan.Version, []models.PlanOperation{
+		{Op: models.OpCreate, ParentID: &sales.Id, Name: "north"},
+	})
+	if !errors.As(err, &invalid) || !errors.Is(invalid.Errors[0].Err, models.ErrNameExists) || invalid.Errors[0].Index != 2 {
+		t.Fatalf("name clash inside plan: got %v", err)
+	}
+	plan, err = s.AddPlanOperations(ctx, plan.ID, plan.Version, []models.PlanOperation{
+		{Op: models.OpRename, DepartmentID: &east.Id, Name: "South"},
+		{Op: models.OpDelete, DepartmentID: &legacy.Id, Mode: "reassign", ToDepartmentRef: "north"},
+	})
+	if err != nil {
+		t.Fatalf("AddPlanOperations: %v", err)
+	}
+	if len(plan.Operations) != 4 || plan.Version != 2 {
+		t.Fatalf("unexpected plan: %+v", plan)
+	}
+	if _, err := s.AddPlanOperations(ctx, plan.ID, 1, []models.PlanOperation{{Op: models.OpRename, DepartmentID: &east.Id, Name: "West"}}); !errors.Is(err, models.ErrVersionMismatch) {
+		t.Fatalf("stale version: got %v", err)
+	}
+
+	diff, err := s.DiffPlan(ctx, plan.ID)
+	if err != nil {
+		t.Fatalf("DiffPlan: %v", err)
+	}
+	if len(diff.Departments) != 3 || len(diff.Employees) != 2 {
+		t.Fatalf("unexpected diff: %+v", diff)
+	}
+	if change := diff.Employees[0]; change.ID != ivan.ID || change.From != "Root / Sales / East" || change.To == nil || *change.To != "Root / Sales / North" {
+		t.Fatalf("unexpected employee change: %+v", change)
+	}
+
+	// Изменение затронутого подразделения делает план устаревшим
+	if _, err := s.UpdateDepartment(ctx, east.Id, 0, &models.DepartmentRequest{Name: "East 2"}); err != nil {
+		t.Fatalf("UpdateDepartment: %v", err)
+	}
+	if _, err := s.ApplyPlan(ctx, plan.ID, plan.Version); !errors.Is(err, models.ErrPlanStale) {
+		t.Fatalf("stale plan: got %v", err)
+	}
+	if err := s.DeletePlan(ctx, plan.ID, plan.Version); err != nil {
+		t.Fatalf("DeletePlan: %v", err)
+	}
+	if _, err := s.GetPlan(ctx, plan.ID); !errors.Is(err, models.ErrPlanNotFound) {
+		t.Fatalf("deleted plan: got %v", err)
+	}
+
+	plan, err = s.CreatePlan(ctx, &models.PlanRequest{Name: "Q4", Operations: []models.PlanOperation{
+		{Op: models.OpCreate, Ref: "north", ParentID: &sales.Id, Name: "North"},
+		{Op: models.OpMove, DepartmentID: &east.Id, ParentRef: "north"},
+		{Op: models.OpDelete, DepartmentID: &legacy.Id, Mode: "reassign", ToDepartmentRef: "north"},
+	}})
+	if err != nil {
+		t.Fatalf("CreatePlan: %v", err)
+	}
+	if plan, err = s.ApplyPlan(ctx, plan.ID, plan.Version); err != nil {
+		t.Fatalf("ApplyPlan: %v", err)
+	}
+	if plan.Status != models.PlanApplied || plan.AppliedAt == nil {
+		t.Fatalf("unexpected applied plan: %+v", plan)
+	}
+
+	tree, err := s.GetWithTree(ctx, sales.Id, 3, &models.EmployeeFilter{})
+	if err != nil || len(tree.Children) != 1 || tree.Children[0].Name != "North" {
+		t.Fatalf("unexpected tree: %+v, %v", tree, err)
+	}
+	north := tree.Children[0]
+	if len(north.Children) != 1 || north.Children[0].Id != east.Id || len(north.Employees) != 1 || north.Employees[0].ID != anna.ID {
+		t.Fatalf("unexpected north: %+v", north)
+	}
+	if _, err := s.GetWithTree(ctx, legacy.Id, 1, nil); !errors.Is(err, models.ErrDepartmentNotFound) {
+		t.Fatalf("legacy not deleted: %v", err)
+	}
+
+	if _, err := s.ApplyPlan(ctx, plan.ID, plan.Version); !errors.Is(err, models.ErrPlanApplied) {
+		t.Fatalf("apply twice: got %v", err)
+	}
+	plans, err := s.ListPlans(ctx)
+	if err != nil || len(plans) != 1 || plans[0].ID != plan.ID {
+		t.Fatalf("unexpected plans: %+v, %v", plans, err)
+	}
+}
+
+func testPlanEmployees(t *testing.T, s Store) {
+	root := mustCreate(t, s, "Root", nil)
+	sales := mustCreate(t, s, "Sales", &root.Id)
+	support := mustCreate(t, s, "Support", &root.Id)
+	ivan := mustHire(t, s, sales.Id, "Ivan")
+	petr := mustHire(t, s, sales.Id, "Petr")
+	if _, err := s.TerminateEmployee(ctx, petr.ID, 0, &models.TerminationRequest{Reason: "resigned"}); err != nil {
+		t.Fatalf("TerminateEmployee: %v", err)
+	}
+
+	// Уволенного сотрудника перевести нельзя
+	_, err := s.CreatePlan(ctx, &models.PlanRequest{Name: "Terminated", Operations: []models.PlanOperation{
+		{Op: models.OpTransfer, EmployeeIDs: []uint{petr.ID}, ToDepartmentID: &support.Id},
+	}})
+	var invalid *models.PlanValidationError
+	if !errors.As(err, &invalid) || !errors.Is(invalid.Errors[0].Err, models.ErrEmployeeTerminated) {
+		t.Fatalf("transfer terminated: got %v", err)
+	}
+
+	// Изменение сотрудника из плана делает план устаревшим
+	plan, err := s.CreatePlan(ctx, &models.PlanRequest{Name: "Stale", Operations: []models.PlanOperation{
+		{Op: models.OpTransfer, EmployeeIDs: []uint{ivan.ID}, ToDepartmentID: &support.Id},
+	}})
+	if err != nil {
+		t.Fatalf("CreatePlan: %v", err)
+	}
+	if version, ok := plan.EmployeeSnapshot[ivan.ID]; !ok || version != ivan.Version {
+		t.Fatalf("unexpected employee snapshot: %+v", plan.EmployeeSnapshot)
+	}
+	if _, err := s.TerminateEmployee(ctx, ivan.ID, 0, &models.TerminationRequest{Reason: "resigned"}); err != nil {
+		t.Fatalf("TerminateEmployee: %v", err)
+	}
+	if _, err := s.ApplyPlan(ctx, plan.ID, plan.Version); !errors.Is(err, models.ErrPlanStale) {
+		t.Fatalf("stale plan: got %v", err)
+	}
+	if emp, err := s.GetEmployee(ctx, ivan.ID); err != nil || emp.DepartmentId != sales.Id {
+		t.Fatalf("terminated employee moved: %+v, %v", emp, err)
+	}
+
+	// У примененного плана нет изменений для сравнения
+	plan, err = s.CreatePlan(ctx, &models.PlanRequest{Name: "Rename", Operations: []models.PlanOperation{
+		{Op: models.OpRename, DepartmentID: &support.Id, Name: "Helpdesk"},
+	}})
+	if err != nil {
+		t.Fatalf("CreatePlan: %v", err)
+	}
+	if _, err := s.ApplyPlan(ctx, plan.ID, plan.Version); err != nil {
+		t.Fatalf("ApplyPlan: %v", err)
+	}
+	if _, err := s.DiffPlan(ctx, plan.ID); !errors.Is(err, models.ErrPlanApplied) {
+		t.Fatalf("diff applied plan: got %v", err)
+	}
+}
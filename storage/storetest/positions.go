@@ -0,0 +1,137 @@
+package storetest
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/kroulersama/goProject/models"
+)
+
+func testPositions(t *testing.T, s Store) {
+	if _, err := s.CreatePosition(ctx, &models.PositionRequest{Title: "  "}); !errors.Is(err, models.ErrPositionTitleEmpty) {
+		t.Fatalf("empty title: got %v", err)
+	}
+
+	engineer, err := s.CreatePosition(ctx, &models.PositionRequest{Title: " Engineer ", Grade: "L2", JobFamily: "engineering"})
+	if err != nil || engineer.Title != "Engineer" || engineer.Version != 1 {
+		t.Fatalf("CreatePosition: %+v, %v", engineer, err)
+	}
+	if _, err := s.CreatePosition(ctx, &models.PositionRequest{Title: "ENGINEER"}); !errors.Is(err, models.ErrPositionExists) {
+		t.Fatalf("duplicate title: got %v", err)
+	}
+	senior, err := s.CreatePosition(ctx, &models.PositionRequest{Title: "Sr. Engineer"})
+	if err != nil {
+		t.Fatalf("CreatePosition: %v", err)
+	}
+	if _, err := s.CreatePosition(ctx, &models.PositionRequest{Title: "senior engineer"}); !errors.Is(err, models.ErrPositionExists) {
+		t.Fatalf("spelling of existing title: got %v", err)
+	}
+	if _, err := s.CreatePosition(ctx, &models.PositionRequest{Title: "--"}); !errors.Is(err, models.ErrPositionTitleEmpty) {
+		t.Fatalf("punctuation title: got %v", err)
+	}
+	if _, err := s.CreatePosition(ctx, &models.PositionRequest{Title: "Accountant", JobFamily: "finance"}); err != nil {
+		t.Fatalf("CreatePosition: %v", err)
+	}
+
+	positions, err := s.ListPositions(ctx, "engineering")
+	if err != nil || len(positions) != 1 || positions[0].ID != engineer.ID {
+		t.Fatalf("ListPositions(engineering): %+v, %v", positions, err)
+	}
+
+	// Сотрудник по ссылке на каталог; текст должности берется из названия
+	dept := mustCreate(t, s, "Dept", nil)
+	missing := uint(999999)
+	if _, err := s.CreateEmployee(ctx, dept.Id, &models.EmployeeRequest{FullName: "X", PositionID: &missing}); !errors.Is(err, models.ErrUnknownPosition) {
+		t.Fatalf("missing position: got %v", err)
+	}
+	emp, err := s.CreateEmployee(ctx, dept.Id, &models.EmployeeRequest{FullName: "Ivan", PositionID: &engineer.ID})
+	if err != nil || emp.Position != "Engineer" || emp.PositionID == nil || *emp.PositionID != engineer.ID {
+		t.Fatalf("CreateEmployee with position_id: %+v, %v", emp, err)
+	}
+
+	// Заданный текст остается, только если это написание той же должности
+	_, err = s.CreateEmployee(ctx, dept.Id, &models.EmployeeRequest{FullName: "Petr", Position: "Accountant", PositionID: &engineer.ID})
+	if !errors.Is(err, models.ErrPositionMismatch) {
+		t.Fatalf("conflicting position text: got %v", err)
+	}
+	spelled, err := s.CreateEmployee(ctx, dept.Id, &models.EmployeeRequest{FullName: "Anna", Position: "Senior Engineer", PositionID: &senior.ID})
+	if err != nil || spelled.Position != "Senior Engineer" || spelled.PositionID == nil || *spelled.PositionID != senior.ID {
+		t.Fatalf("spelling of position title: %+v, %v", spelled, err)
+	}
+
+	// Переименование меняет текст должности сотрудников
+	taken := "SENIOR ENG"
+	if _, err := s.UpdatePosition(ctx, engineer.ID, 1, &models.PositionUpdate{Title: &taken}); !errors.Is(err, models.ErrPositionExists) {
+		t.Fatalf("rename to spelling of existing title: got %v", err)
+	}
+	title := "Software Engineer"
+	if _, err := s.UpdatePosition(ctx, engineer.ID, 2, &models.PositionUpdate{Title: &title}); !errors.Is(err, models.ErrVersionMismatch) {
+		t.Fatalf("stale update: got %v", err)
+	}
+	updated, err := s.UpdatePosition(ctx, engineer.ID, 1, &models.PositionUpdate{Title: &title})
+	if err != nil || updated.Title != title || updated.Grade != "L2" || updated.Version != 2 {
+		t.Fatalf("UpdatePosition: %+v, %v", updated, err)
+	}
+	emp, err = s.GetEmployee(ctx, emp.ID)
+	if err != nil || emp.Position != title || emp.Version != 2 {
+		t.Fatalf("employee after rename: %+v, %v", emp, err)
+	}
+	history, err := s.EmployeeHistory(ctx, emp.ID)
+	if err != nil || len(history) != 2 || history[0].Position != "Engineer" || history[0].To == nil ||
+		history[1].Position != title || history[1].To != nil {
+		t.Fatalf("history after rename: %+v, %v", history, err)
+	}
+
+	if err := s.DeletePosition(ctx, engineer.ID, 0); !errors.Is(err, models.ErrPositionInUse) {
+		t.Fatalf("delete position in use: got %v", err)
+	}
+	if _, err := s.GetPosition(ctx, missing); !errors.Is(err, models.ErrPositionNotFound) {
+		t.Fatalf("missing position: got %v", err)
+	}
+}
+
+func testPositionClusters(t *testing.T, s Store) {
+	dept := mustCreate(t, s, "Dept", nil)
+	for _, position := range []string{"Senior Engineer", "Senior Engineer", "Sr. Engineer", "senior engineer", "Accountant", "accountant"} {
+		if _, err := s.CreateEmployee(ctx, dept.Id, &models.EmployeeRequest{FullName: "X", Position: position}); err != nil {
+			t.Fatalf("CreateEmployee(%q): %v", position, err)
+		}
+	}
+	accountant, err := s.CreatePosition(ctx, &models.PositionRequest{Title: "Accountant", JobFamily: "finance"})
+	if err != nil {
+		t.Fatalf("CreatePosition: %v", err)
+	}
+
+	// Без apply ничего не меняется
+	result, err := s.ClusterPositionTitles(ctx, false)
+	if err != nil || len(result.Clusters) != 2 || result.Linked != 0 {
+		t.Fatalf("dry run: %+v, %v", result, err)
+	}
+	senior := result.Clusters[0]
+	if senior.Key != "senior engineer" || senior.Title != "Senior Engineer" || senior.Employees != 4 || len(senior.Variants) != 3 || senior.PositionID != 0 {
+		t.Fatalf("senior cluster: %+v", senior)
+	}
+	if result.Clusters[1].PositionID != accountant.ID || result.Clusters[1].Employees != 2 {
+		t.Fatalf("accountant cluster: %+v", result.Clusters[1])
+	}
+
+	result, err = s.ClusterPositionTitles(ctx, true)
+	if err != nil || result.Created != 1 || result.Linked != 6 {
+		t.Fatalf("apply: %+v, %v", result, err)
+	}
+	for _, emp := range mustList(t, s, dept.Id) {
+		if emp.PositionID == nil || emp.Version != 2 {
+			t.Fatalf("employee not linked: %+v", emp)
+		}
+	}
+
+	// Повторный запуск: все уже привязаны
+	result, err = s.ClusterPositionTitles(ctx, true)
+	if err != nil || len(result.Clusters) != 0 || result.Created != 0 {
+		t.Fatalf("second apply: %+v, %v", result, err)
+	}
+	positions, err := s.ListPositions(ctx, "")
+	if err != nil || len(positions) != 2 {
+		t.Fatalf("catalog after apply: %+v, %v", positions, err)
+	}
+}
@@ -0,0 +1,106 @@
+package storetest
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/kroulersama/goProject/models"
+)
+
+func testReportingLines(t *testing.T, s Store) {
+	root := mustCreate(t, s, "Root", nil)
+	a := mustCreate(t, s, "A", &root.Id)
+	b := mustCreate(t, s, "B", &root.Id)
+	ceo := mustHire(t, s, root.Id, "Ceo")
+	lead := mustHire(t, s, a.Id, "Lead")
+	dev := mustHire(t, s, a.Id, "Dev")
+	other := mustHire(t, s, b.Id, "Other")
+
+	setManager := func(id uint, version int, managerID *uint) (*models.Employee, error) {
+		return s.SetManager(ctx, id, version, &models.ManagerRequest{ManagerID: managerID})
+	}
+	for _, line := range []struct{ id, managerID uint }{{lead.ID, ceo.ID}, {dev.ID, lead.ID}, {other.ID, lead.ID}} {
+		emp, err := setManager(line.id, 1, &line.managerID)
+		if err != nil || emp.ManagerID == nil || *emp.ManagerID != line.managerID || emp.Version != 2 {
+			t.Fatalf("SetManager(%d, %d): %+v, %v", line.id, line.managerID, emp, err)
+		}
+	}
+
+	// Самоподчинение, цикл, несуществующий руководитель, устаревшая версия
+	if _, err := setManager(ceo.ID, 0, &ceo.ID); !errors.Is(err, models.ErrSelfManager) {
+		t.Fatalf("self manager: got %v", err)
+	}
+	if _, err := setManager(ceo.ID, 0, &dev.ID); !errors.Is(err, models.ErrManagerCycle) {
+		t.Fatalf("manager cycle: got %v", err)
+	}
+	missing := uint(999999)
+	if _, err := setManager(dev.ID, 0, &missing); !errors.Is(err, models.ErrManagerNotFound) {
+		t.Fatalf("missing manager: got %v", err)
+	}
+	if _, err := setManager(dev.ID, 1, nil); !errors.Is(err, models.ErrVersionMismatch) {
+		t.Fatalf("stale version: got %v", err)
+	}
+
+	reports, err := s.DirectReports(ctx, lead.ID, &models.EmployeeFilter{})
+	if err != nil || len(reports) != 2 || reports[0].ID != dev.ID || reports[1].ID != other.ID {
+		t.Fatalf("DirectReports: %+v, %v", reports, err)
+	}
+	chain, err := s.ReportingChain(ctx, dev.ID)
+	if err != nil || len(chain) != 2 || chain[0].ID != lead.ID || chain[1].ID != ceo.ID {
+		t.Fatalf("ReportingChain: %+v, %v", chain, err)
+	}
+	if chain, err = s.ReportingChain(ctx, ceo.ID); err != nil || len(chain) != 0 {
+		t.Fatalf("ReportingChain of top: %+v, %v", chain, err)
+	}
+	span, err := s.GetSpanOfControl(ctx, ceo.ID)
+	if err != nil || *span != (models.SpanOfControl{EmployeeID: ceo.ID, Direct: 1, Total: 3, Depth: 2}) {
+		t.Fatalf("GetSpanOfControl: %+v, %v", span, err)
+	}
+
+	// Руководитель из соседнего подразделения - нарушение, из предка - нет
+	issues, err := s.GetReportingIssues(ctx)
+	if err != nil || len(issues) != 1 || issues[0].Type != models.ReportingOutsideAncestry ||
+		issues[0].EmployeeID != other.ID || issues[0].ManagerDepartmentID != a.Id {
+		t.Fatalf("GetReportingIssues: %+v, %v", issues, err)
+	}
+
+	// Увольнение снимает руководителя с подчиненных; уволенного нельзя назначить
+	if _, err := s.TerminateEmployee(ctx, lead.ID, 0, &models.TerminationRequest{Reason: "resigned"}); err != nil {
+		t.Fatalf("TerminateEmployee: %v", err)
+	}
+	for _, id := range []uint{dev.ID, other.ID} {
+		if emp, err := s.GetEmployee(ctx, id); err != nil || emp.ManagerID != nil || emp.Version != 3 {
+			t.Fatalf("report after manager termination: %+v, %v", emp, err)
+		}
+	}
+	if reports, err = s.DirectReports(ctx, lead.ID, &models.EmployeeFilter{}); err != nil || len(reports) != 0 {
+		t.Fatalf("reports of terminated: %+v, %v", reports, err)
+	}
+	issues, err = s.GetReportingIssues(ctx)
+	if err != nil || len(issues) != 0 {
+		t.Fatalf("issues after termination: %+v, %v", issues, err)
+	}
+	if _, err := setManager(ceo.ID, 0, &lead.ID); !errors.Is(err, models.ErrManagerTerminated) {
+		t.Fatalf("terminated manager: got %v", err)
+	}
+	if span, err = s.GetSpanOfControl(ctx, ceo.ID); err != nil || span.Total != 0 {
+		t.Fatalf("span after termination: %+v, %v", span, err)
+	}
+
+	// Снятие руководителя и ON DELETE SET NULL
+	if emp, err := setManager(other.ID, 0, &dev.ID); err != nil || emp.ManagerID == nil {
+		t.Fatalf("SetManager: %+v, %v", emp, err)
+	}
+	if emp, err := setManager(ceo.ID, 0, &dev.ID); err != nil || emp.ManagerID == nil {
+		t.Fatalf("SetManager: %+v, %v", emp, err)
+	}
+	if emp, err := setManager(ceo.ID, 0, nil); err != nil || emp.ManagerID != nil {
+		t.Fatalf("clear manager: %+v, %v", emp, err)
+	}
+	if err := s.DeleteDepartment(ctx, a.Id, 0, "cascade", nil); err != nil {
+		t.Fatalf("DeleteDepartment: %v", err)
+	}
+	if emp, err := s.GetEmployee(ctx, other.ID); err != nil || emp.ManagerID != nil {
+		t.Fatalf("manager after delete: %+v, %v", emp, err)
+	}
+}
@@ -0,0 +1,185 @@
+package storetest
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/kroulersama/goProject/models"
+)
+
+func testHierarchyRules(t *testing.T, s Store) {
+	set, err := s.GetHierarchyRules(ctx)
+	if err != nil || !set.Rules.Empty() {
+		t.Fatalf("GetHierarchyRules: %+v, %v", set, err)
+	}
+
+	// До правил подразделения без типа создаются как раньше, тип нужно описать
+	legacy := mustCreate(t, s, "Legacy", nil)
+	mustCreate(t, s, "Legacy child", &legacy.Id)
+	division := "division"
+	if _, err := s.CreateDepartment(ctx, &models.DepartmentRequest{Name: "X", Type: &division}); !errors.Is(err, models.ErrUnknownDepartmentType) {
+		t.Fatalf("undefined type: got %v", err)
+	}
+
+	// Проверка самих правил
+	if _, err := s.UpdateHierarchyRules(ctx, set.Version, &models.HierarchyRules{
+		MaxDepth: -1,
+		Types:    map[string]models.DepartmentTypeRule{"Team": {}, "unit": {AllowedChildren: []string{"nope"}, NamePattern: "("}},
+	}); !errors.Is(err, models.ErrInvalidHierarchyRules) {
+		t.Fatalf("invalid rules: got %v", err)
+	}
+
+	rules := &models.HierarchyRules{
+		MaxDepth:    3,
+		MaxChildren: 2,
+		Types: map[string]models.DepartmentTypeRule{
+			"division":   {AllowedChildren: []string{"division", "department", "team"}, MaxChildren: 3},
+			"department": {AllowedChildren: []string{"team"}},
+			"team":       {AllowedChildren: []string{}, NamePattern: `Team [A-Z][a-z]+`},
+		},
+	}
+	if _, err := s.UpdateHierarchyRules(ctx, set.Version+1, rules); !errors.Is(err, models.ErrVersionMismatch) {
+		t.Fatalf("stale rules version: got %v", err)
+	}
+	set, err = s.UpdateHierarchyRules(ctx, set.Version, rules)
+	if err != nil || set.Version != 2 || len(set.Rules.Types) != 3 {
+		t.Fatalf("UpdateHierarchyRules: %+v, %v", set, err)
+	}
+
+	// Пары типов родитель - потомок
+	sales := mustCreateTyped(t, s, "Sales", nil, "division")
+	dept := "department"
+	team := "team"
+	retail := mustCreateTyped(t, s, "Retail", &sales.Id, dept)
+	if _, err := s.CreateDepartment(ctx, &models.DepartmentRequest{Name: "Sub", ParentID: &retail.Id, Type: &division}); !errors.Is(err, models.ErrTypeNotAllowed) {
+		t.Fatalf("division under department: got %v", err)
+	}
+	if _, err := s.CreateDepartment(ctx, &models.DepartmentRequest{Name: "Plain", ParentID: &retail.Id}); !errors.Is(err, models.ErrTypeNotAllowed) {
+		t.Fatalf("untyped under department: got %v", err)
+	}
+
+	// Имя по шаблону типа
+	if _, err := s.CreateDepartment(ctx, &models.DepartmentRequest{Name: "Alpha", ParentID: &retail.Id, Type: &team}); !errors.Is(err, models.ErrNamePatternMismatch) {
+		t.Fatalf("team name pattern: got %v", err)
+	}
+	alpha := mustCreateTyped(t, s, "Team Alpha", &retail.Id, team)
+	if _, err := s.UpdateDepartment(ctx, alpha.Id, 0, &models.DepartmentRequest{Name: "alpha"}); !errors.Is(err, models.ErrNamePatternMismatch) {
+		t.Fatalf("rename against pattern: got %v", err)
+	}
+
+	// У команды нет дочерних, глубина не больше 3
+	if _, err := s.CreateDepartment(ctx, &models.DepartmentRequest{Name: "Team Sub", ParentID: &alpha.Id, Type: &team}); !errors.Is(err, models.ErrTypeNotAllowed) {
+		t.Fatalf("child under team: got %v", err)
+	}
+	north := mustCreateTyped(t, s, "North", &sales.Id, division)
+	wholesale := mustCreateTyped(t, s, "Wholesale", &north.Id, dept)
+	if _, err := s.CreateDepartment(ctx, &models.DepartmentRequest{Name: "Team Deep", ParentID: &wholesale.Id, Type: &team}); !errors.Is(err, models.ErrMaxDepthExceeded) {
+		t.Fatalf("max depth: got %v", err)
+	}
+	if _, err := s.UpdateDepartment(ctx, alpha.Id, 0, &models.DepartmentRequest{ParentID: &wholesale.Id}); !errors.Is(err, models.ErrMaxDepthExceeded) {
+		t.Fatalf("move below max depth: got %v", err)
+	}
+
+	// Ограничение детей: у division свое (3), у остальных общее (2)
+	mustCreateTyped(t, s, "Team Beta", &sales.Id, team)
+	if _, err := s.CreateDepartment(ctx, &models.DepartmentRequest{Name: "Team Delta", ParentID: &sales.Id, Type: &team}); !errors.Is(err, models.ErrMaxChildrenExceeded) {
+		t.Fatalf("division children limit: got %v", err)
+	}
+	mustCreateTyped(t, s, "Team Omega", &retail.Id, team)
+	if _, err := s.CreateDepartment(ctx, &models.DepartmentRequest{Name: "Team Zeta", ParentID: &retail.Id, Type: &team}); !errors.Is(err, models.ErrMaxChildrenExceeded) {
+		t.Fatalf("global children limit: got %v", err)
+	}
+
+	// Смена типа проверяется по существующим детям
+	if _, err := s.UpdateDepartment(ctx, retail.Id, 0, &models.DepartmentRequest{Type: &team}); err == nil {
+		t.Fatal("department with children cannot become a team")
+	}
+
+	// Отложенно тип не меняется
+	future := retail.CreatedAt.AddDate(1, 0, 0)
+	if _, err := s.ScheduleDepartmentChange(ctx, retail.Id, 0, &models.DepartmentRequest{Type: &team}, future); !errors.Is(err, models.ErrNotSchedulable) {
+		t.Fatalf("scheduled type change: got %v", err)
+	}
+
+	// Нарушения, бывшие до правил, не мешают изменениям, но видны в проверке дерева
+	if _, err := s.UpdateDepartment(ctx, legacy.Id, 0, &models.DepartmentRequest{Name: "Legacy 2"}); err != nil {
+		t.Fatalf("rename legacy: %v", err)
+	}
+	violations, err := s.CheckHierarchy(ctx)
+	if err != nil || len(violations) != 0 {
+		t.Fatalf("CheckHierarchy: %+v, %v", violations, err)
+	}
+
+	rules.MaxChildren = 1
+	if set, err = s.UpdateHierarchyRules(ctx, set.Version, rules); err != nil {
+		t.Fatalf("tighten rules: %v", err)
+	}
+	violations, err = s.CheckHierarchy(ctx)
+	if err != nil || len(violations) != 1 || violations[0].DepartmentID != retail.Id || violations[0].Rule != models.RuleMaxChildren {
+		t.Fatalf("violations after tightening: %+v, %v", violations, err)
+	}
+
+	// Копия сохраняет типы и тоже проверяется
+	if _, err := s.CopyDepartment(ctx, alpha.Id, &models.CopyRequest{ParentID: &retail.Id, Name: "Team Copy"}); !errors.Is(err, models.ErrMaxChildrenExceeded) {
+		t.Fatalf("copy over children limit: got %v", err)
+	}
+	copied, err := s.CopyDepartment(ctx, alpha.Id, &models.CopyRequest{Name: "Team Copy"})
+	if err != nil || copied.Root.Type != team {
+		t.Fatalf("copy keeps type: %+v, %v", copied, err)
+	}
+
+	// План проверяется по правилам до применения
+	_, err = s.CreatePlan(ctx, &models.PlanRequest{Name: "Bad", Operations: []models.PlanOperation{
+		{Op: models.OpCreate, ParentID: &alpha.Id, Name: "Sub", Type: division},
+	}})
+	var invalid *models.PlanValidationError
+	if !errors.As(err, &invalid) || !errors.Is(invalid.Errors[0].Err, models.ErrTypeNotAllowed) {
+		t.Fatalf("plan against rules: got %v", err)
+	}
+}
+
+func testHierarchyRulesOnMove(t *testing.T, s Store) {
+	// Ветка глубиной 4 появилась до правил
+	a1 := mustCreate(t, s, "A1", nil)
+	a2 := mustCreate(t, s, "A2", &a1.Id)
+	a3 := mustCreate(t, s, "A3", &a2.Id)
+	a4 := mustCreate(t, s, "A4", &a3.Id)
+	b1 := mustCreate(t, s, "B1", nil)
+	b2 := mustCreate(t, s, "B2", &b1.Id)
+	b3 := mustCreate(t, s, "B3", &b2.Id)
+	set, err := s.GetHierarchyRules(ctx)
+	if err != nil {
+		t.Fatalf("GetHierarchyRules: %v", err)
+	}
+	if _, err := s.UpdateHierarchyRules(ctx, set.Version, &models.HierarchyRules{MaxDepth: 3, MaxChildren: 1}); err != nil {
+		t.Fatalf("UpdateHierarchyRules: %v", err)
+	}
+
+	// Перенос на ту же глубину не усиливает прежнее нарушение
+	if _, err := s.UpdateDepartment(ctx, a4.Id, 0, &models.DepartmentRequest{ParentID: &b3.Id}); err != nil {
+		t.Fatalf("move at the same depth: %v", err)
+	}
+	if _, err := s.CreateDepartment(ctx, &models.DepartmentRequest{Name: "A5", ParentID: &a4.Id}); !errors.Is(err, models.ErrMaxDepthExceeded) {
+		t.Fatalf("deeper than before: got %v", err)
+	}
+
+	// Число детей нового родителя считается по всем его детям
+	if _, err := s.UpdateDepartment(ctx, a3.Id, 0, &models.DepartmentRequest{ParentID: &b1.Id}); !errors.Is(err, models.ErrMaxChildrenExceeded) {
+		t.Fatalf("children limit of new parent: got %v", err)
+	}
+	if _, err := s.UpdateDepartment(ctx, a3.Id, 0, &models.DepartmentRequest{ParentID: &a1.Id}); !errors.Is(err, models.ErrMaxChildrenExceeded) {
+		t.Fatalf("children limit of ancestor: got %v", err)
+	}
+}
+
+func mustCreateTyped(t *testing.T, s Store, name string, parentID *uint, typ string) *models.Department {
+	t.Helper()
+	dept, err := s.CreateDepartment(ctx, &models.DepartmentRequest{Name: name, ParentID: parentID, Type: &typ})
+	if err != nil {
+		t.Fatalf("CreateDepartment(%q, %s): %v", name, typ, err)
+	}
+	if dept.Type != typ {
+		t.Fatalf("department type: got %q, want %q", dept.Type, typ)
+	}
+	return dept
+}
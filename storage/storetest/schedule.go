@@ -0,0 +1,133 @@
+package storetest
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/kroulersama/goProject/models"
+)
+
+func testTransferEmployee(t *testing.T, s Store) {
+	a := mustCreate(t, s, "A", nil)
+	b := mustCreate(t, s, "B", nil)
+	ivan := mustHire(t, s, a.Id, "Ivan")
+
+	if _, err := s.TransferEmployee(ctx, ivan.ID, ivan.Version+1, &models.TransferRequest{DepartmentID: b.Id}); !errors.Is(err, models.ErrVersionMismatch) {
+		t.Fatalf("stale version: got %v", err)
+	}
+	if _, err := s.TransferEmployee(ctx, ivan.ID, 0, &models.TransferRequest{DepartmentID: 999999}); !errors.Is(err, models.ErrUnknownDepartment) {
+		t.Fatalf("missing department: got %v", err)
+	}
+	if _, err := s.TransferEmployee(ctx, 999999, 0, &models.TransferRequest{DepartmentID: b.Id}); !errors.Is(err, models.ErrEmployeeNotFound) {
+		t.Fatalf("missing employee: got %v", err)
+	}
+
+	moved, err := s.TransferEmployee(ctx, ivan.ID, ivan.Version, &models.TransferRequest{DepartmentID: b.Id})
+	if err != nil {
+		t.Fatalf("TransferEmployee: %v", err)
+	}
+	if moved.DepartmentId != b.Id || moved.Version != ivan.Version+1 {
+		t.Fatalf("unexpected employee: %+v", moved)
+	}
+}
+
+func testScheduledChanges(t *testing.T, s Store) {
+	root := mustCreate(t, s, "Root", nil)
+	sales := mustCreate(t, s, "Sales", &root.Id)
+	east := mustCreate(t, s, "East", &root.Id)
+	ivan := mustHire(t, s, sales.Id, "Ivan")
+
+	now := time.Now().UTC().Truncate(time.Second)
+	first := now.Add(24 * time.Hour)
+	second := now.Add(48 * time.Hour)
+
+	// Проверки при постановке в очередь
+	if _, err := s.ScheduleDepartmentChange(ctx, sales.Id, 0, &models.DepartmentRequest{}, first); !errors.Is(err, models.ErrEmptyChange) {
+		t.Fatalf("empty change: got %v", err)
+	}
+	if _, err := s.ScheduleDepartmentChange(ctx, sales.Id, sales.Version+1, &models.DepartmentRequest{Name: "X"}, first); !errors.Is(err, models.ErrVersionMismatch) {
+		t.Fatalf("stale version: got %v", err)
+	}
+	missing := uint(999999)
+	if _, err := s.ScheduleDepartmentChange(ctx, sales.Id, 0, &models.DepartmentRequest{ParentID: &missing}, first); !errors.Is(err, models.ErrParentNotFound) {
+		t.Fatalf("missing parent: got %v", err)
+	}
+
+	rename, err := s.ScheduleDepartmentChange(ctx, sales.Id, sales.Version, &models.DepartmentRequest{Name: " Sales EMEA "}, first)
+	if err != nil {
+		t.Fatalf("ScheduleDepartmentChange: %v", err)
+	}
+	if rename.Status != models.ChangePending || rename.Name != "Sales EMEA" || !rename.EffectiveAt.Equal(first) {
+		t.Fatalf("unexpected change: %+v", rename)
+	}
+	// Цикл обнаружится только при применении: к тому времени Sales будет под East
+	move, err := s.ScheduleDepartmentChange(ctx, east.Id, 0, &models.DepartmentRequest{ParentID: &sales.Id}, second)
+	if err != nil {
+		t.Fatalf("ScheduleDepartmentChange: %v", err)
+	}
+	underEast, err := s.ScheduleDepartmentChange(ctx, sales.Id, 0, &models.DepartmentRequest{ParentID: &east.Id}, first)
+	if err != nil {
+		t.Fatalf("ScheduleDepartmentChange: %v", err)
+	}
+	transfer, err := s.ScheduleTransfer(ctx, ivan.ID, ivan.Version, &models.TransferRequest{DepartmentID: east.Id}, first)
+	if err != nil {
+		t.Fatalf("ScheduleTransfer: %v", err)
+	}
+	canceled, err := s.ScheduleTransfer(ctx, ivan.ID, 0, &models.TransferRequest{DepartmentID: root.Id}, first)
+	if err != nil {
+		t.Fatalf("ScheduleTransfer: %v", err)
+	}
+
+	if _, err := s.CancelScheduledChange(ctx, canceled.ID, canceled.Version+1); !errors.Is(err, models.ErrVersionMismatch) {
+		t.Fatalf("cancel stale version: got %v", err)
+	}
+	if canceled, err = s.CancelScheduledChange(ctx, canceled.ID, canceled.Version); err != nil || canceled.Status != models.ChangeCanceled {
+		t.Fatalf("CancelScheduledChange: %+v, %v", canceled, err)
+	}
+	if _, err := s.CancelScheduledChange(ctx, canceled.ID, 0); !errors.Is(err, models.ErrChangeNotPending) {
+		t.Fatalf("cancel twice: got %v", err)
+	}
+
+	// До срока ничего не применяется
+	if applied, err := s.ApplyDueChanges(ctx, now); err != nil || applied != 0 {
+		t.Fatalf("ApplyDueChanges before due: %d, %v", applied, err)
+	}
+	pending, err := s.ListScheduledChanges(ctx, models.ChangePending)
+	if err != nil || len(pending) != 4 || pending[len(pending)-1].ID != move.ID {
+		t.Fatalf("unexpected pending changes: %+v, %v", pending, err)
+	}
+	if _, err := s.ListScheduledChanges(ctx, "bogus"); !errors.Is(err, models.ErrInvalidChangeStatus) {
+		t.Fatalf("invalid status: got %v", err)
+	}
+
+	if applied, err := s.ApplyDueChanges(ctx, first); err != nil || applied != 3 {
+		t.Fatalf("ApplyDueChanges first: %d, %v", applied, err)
+	}
+	tree, err := s.GetWithTree(ctx, east.Id, 2, &models.EmployeeFilter{})
+	if err != nil || len(tree.Children) != 1 || tree.Children[0].Name != "Sales EMEA" || len(tree.Employees) != 1 || tree.Employees[0].ID != ivan.ID {
+		t.Fatalf("unexpected tree after first batch: %+v, %v", tree, err)
+	}
+
+	// Ошибка применения не блокирует очередь, а помечает изменение failed
+	if applied, err := s.ApplyDueChanges(ctx, second); err != nil || applied != 0 {
+		t.Fatalf("ApplyDueChanges second: %d, %v", applied, err)
+	}
+	failed, err := s.GetScheduledChange(ctx, move.ID)
+	if err != nil || failed.Status != models.ChangeFailed || failed.Error == "" || failed.AppliedAt != nil {
+		t.Fatalf("unexpected failed change: %+v, %v", failed, err)
+	}
+	if tree, err = s.GetWithTree(ctx, east.Id, 1, nil); err != nil || tree.ParentId == nil || *tree.ParentId != root.Id {
+		t.Fatalf("failed change modified the tree: %+v, %v", tree, err)
+	}
+
+	for _, id := range []uint{rename.ID, underEast.ID, transfer.ID} {
+		change, err := s.GetScheduledChange(ctx, id)
+		if err != nil || change.Status != models.ChangeApplied || change.AppliedAt == nil {
+			t.Fatalf("unexpected applied change: %+v, %v", change, err)
+		}
+	}
+	if _, err := s.GetScheduledChange(ctx, 999999); !errors.Is(err, models.ErrScheduledChangeNotFound) {
+		t.Fatalf("missing change: got %v", err)
+	}
+}
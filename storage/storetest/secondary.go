@@ -0,0 +1,240 @@
+package storetest
+
+import (
+	"errors"
+	"maps"
+	"testing"
+
+	"github.com/kroulersama/goProject/models"
+)
+
+func testSecondaryAssignments(t *testing.T, s Store) {
+	root := mustCreate(t, s, "Root", nil)
+	a := mustCreate(t, s, "A", &root.Id)
+	b := mustCreate(t, s, "B", &root.Id)
+	lead := mustHire(t, s, a.Id, "Lead")
+	mustHire(t, s, b.Id, "Dev")
+	mustHire(t, s, root.Id, "Head")
+
+	add := func(empID, deptID uint, role string, allocation int) (*models.SecondaryAssignment, error) {
+		return s.AddSecondaryAssignment(ctx, empID, &models.SecondaryAssignmentRequest{DepartmentID: deptID, Role: role, Allocation: allocation})
+	}
+
+	inB, err := add(lead.ID, b.Id, " Tech lead ", 40)
+	if err != nil || inB.Role != "Tech lead" || inB.Version != 1 {
+		t.Fatalf("AddSecondaryAssignment: %+v, %v", inB, err)
+	}
+	if _, err := add(lead.ID, root.Id, "Architect", 50); err != nil {
+		t.Fatalf("AddSecondaryAssignment second: %v", err)
+	}
+
+	// Ограничения: сумма долей, основное подразделение, повтор, валидация
+	if _, err := add(lead.ID, mustCreate(t, s, "C", &root.Id).Id, "Mentor", 20); !errors.Is(err, models.ErrAllocationExceeded) {
+		t.Fatalf("allocation over 100%%: got %v", err)
+	}
+	if _, err := add(lead.ID, a.Id, "Mentor", 5); !errors.Is(err, models.ErrSecondaryInPrimary) {
+		t.Fatalf("secondary in primary department: got %v", err)
+	}
+	if _, err := add(lead.ID, b.Id, "Mentor", 5); !errors.Is(err, models.ErrSecondaryExists) {
+		t.Fatalf("duplicate secondary assignment: got %v", err)
+	}
+	if _, err := add(lead.ID, b.Id, "", 0); !errors.Is(err, models.ErrRoleEmpty) || !errors.Is(err, models.ErrInvalidAllocation) {
+		t.Fatalf("invalid request: got %v", err)
+	}
+	if _, err := add(lead.ID, 999999, "Mentor", 5); !errors.Is(err, models.ErrUnknownDepartment) {
+		t.Fatalf("missing department: got %v", err)
+	}
+
+	// Численность: основному месту работы остается 10% ставки
+	headcount, err := s.GetHeadcount(ctx, b.Id)
+	if err != nil || headcount.Direct != (models.HeadcountFigures{Heads: 1, SecondaryHeads: 1, FTE: 1.4}) {
+		t.Fatalf("headcount of B: %+v, %v", headcount, err)
+	}
+	headcount, err = s.GetHeadcount(ctx, root.Id)
+	if err != nil || headcount.Direct != (models.HeadcountFigures{Heads: 1, SecondaryHeads: 1, FTE: 1.5}) ||
+		headcount.Total != (models.HeadcountFigures{Heads: 3, SecondaryHeads: 0, FTE: 3}) {
+		t.Fatalf("headcount of Root: %+v, %v", headcount, err)
+	}
+	if headcount, err = s.GetHeadcount(ctx, a.Id); err != nil || headcount.Direct.FTE != 0.1 {
+		t.Fatalf("headcount of A: %+v, %v", headcount, err)
+	}
+
+	// Изменение с проверкой версии и суммы
+	allocation := 60
+	if _, err := s.UpdateSecondaryAssignment(ctx, lead.ID, inB.ID, 1, &models.SecondaryAssignmentUpdate{Allocation: &allocation}); !errors.Is(err, models.ErrAllocationExceeded) {
+		t.Fatalf("update over 100%%: got %v", err)
+	}
+	allocation = 30
+	updated, err := s.UpdateSecondaryAssignment(ctx, lead.ID, inB.ID, 1, &models.SecondaryAssignmentUpdate{Allocation: &allocation})
+	if err != nil || updated.Allocation != 30 || updated.Role != "Tech lead" || updated.Version != 2 {
+		t.Fatalf("UpdateSecondaryAssignment: %+v, %v", updated, err)
+	}
+	if _, err := s.UpdateSecondaryAssignment(ctx, lead.ID, inB.ID, 1, &models.SecondaryAssignmentUpdate{}); !errors.Is(err, models.ErrVersionMismatch) {
+		t.Fatalf("stale update: got %v", err)
+	}
+
+	// Совместители в дереве только по флагу
+	tree, err := s.GetWithTree(ctx, b.Id, 0, &models.EmployeeFilter{Secondary: true})
+	if err != nil || len(tree.Employees) != 1 || len(tree.Secondary) != 1 ||
+		tree.Secondary[0].ID != lead.ID || tree.Secondary[0].AssignmentID != inB.ID || tree.Secondary[0].Allocation != 30 {
+		t.Fatalf("tree with secondary: %+v, %v", tree, err)
+	}
+	if tree, err = s.GetWithTree(ctx, b.Id, 0, &models.EmployeeFilter{}); err != nil || tree.Secondary != nil {
+		t.Fatalf("tree without secondary: %+v, %v", tree, err)
+	}
+
+	// Снятие совмещения и каскад при удалении подразделения
+	if err := s.RemoveSecondaryAssignment(ctx, 999999, inB.ID, 0); !errors.Is(err, models.ErrSecondaryNotFound) {
+		t.Fatalf("remove foreign assignment: got %v", err)
+	}
+	if err := s.RemoveSecondaryAssignment(ctx, lead.ID, inB.ID, 2); err != nil {
+		t.Fatalf("RemoveSecondaryAssignment: %v", err)
+	}
+	if _, err := add(lead.ID, b.Id, "Tech lead", 40); err != nil {
+		t.Fatalf("AddSecondaryAssignment again: %v", err)
+	}
+	if err := s.DeleteDepartment(ctx, b.Id, 0, "cascade", nil); err != nil {
+		t.Fatalf("DeleteDepartment: %v", err)
+	}
+	list, err := s.ListSecondaryAssignments(ctx, lead.ID)
+	if err != nil || len(list) != 1 || list[0].DepartmentID != root.Id {
+		t.Fatalf("assignments after delete: %+v, %v", list, err)
+	}
+
+	// Уволенные не совмещают и не учитываются
+	if _, err := s.TerminateEmployee(ctx, lead.ID, 0, &models.TerminationRequest{Reason: "resigned"}); err != nil {
+		t.Fatalf("TerminateEmployee: %v", err)
+	}
+	if _, err := add(lead.ID, a.Id, "Mentor", 5); !errors.Is(err, models.ErrEmployeeTerminated) {
+		t.Fatalf("terminated employee: got %v", err)
+	}
+	headcount, err = s.GetHeadcount(ctx, root.Id)
+	if err != nil || headcount.Direct != (models.HeadcountFigures{Heads: 1, FTE: 1}) || headcount.Total.Heads != 1 {
+		t.Fatalf("headcount after termination: %+v, %v", headcount, err)
+	}
+	if _, err := s.GetHeadcount(ctx, 999999); !errors.Is(err, models.ErrDepartmentNotFound) {
+		t.Fatalf("headcount of missing department: got %v", err)
+	}
+}
+
+// Перевод основного места в подразделение совмещения снимает совмещение:
+// сотрудник не должен считаться там дважды
+func testSecondaryOnPrimaryChange(t *testing.T, s Store) {
+	root := mustCreate(t, s, "Root", nil)
+	a := mustCreate(t, s, "A", &root.Id)
+	b := mustCreate(t, s, "B", &root.Id)
+	c := mustCreate(t, s, "C", &root.Id)
+	ann := mustHire(t, s, a.Id, "Ann")
+	bob := mustHire(t, s, a.Id, "Bob")
+	cid := mustHire(t, s, c.Id, "Cid")
+	for _, line := range []struct{ empID, deptID uint }{{ann.ID, b.Id}, {bob.ID, c.Id}, {cid.ID, b.Id}} {
+		if _, err := s.AddSecondaryAssignment(ctx, line.empID, &models.SecondaryAssignmentRequest{DepartmentID: line.deptID, Role: "Mentor", Allocation: 20}); err != nil {
+			t.Fatalf("AddSecondaryAssignment(%d, %d): %v", line.empID, line.deptID, err)
+		}
+	}
+	secondaryIn := func(empID uint) []uint {
+		t.Helper()
+		list, err := s.ListSecondaryAssignments(ctx, empID)
+		if err != nil {
+			t.Fatalf("ListSecondaryAssignments: %v", err)
+		}
+		var ids []uint
+		for _, a := range list {
+			ids = append(ids, a.DepartmentID)
+		}
+		return ids
+	}
+
+	// Одиночный перевод
+	if _, err := s.TransferEmployee(ctx, ann.ID, 0, &models.TransferRequest{DepartmentID: b.Id}); err != nil {
+		t.Fatalf("TransferEmployee: %v", err)
+	}
+	if ids := secondaryIn(ann.ID); len(ids) != 0 {
+		t.Fatalf("secondary after transfer: %v", ids)
+	}
+	headcount, err := s.GetHeadcount(ctx, b.Id)
+	if err != nil || headcount.Direct != (models.HeadcountFigures{Heads: 1, SecondaryHeads: 1, FTE: 1.2}) {
+		t.Fatalf("headcount after transfer: %+v, %v", headcount, err)
+	}
+
+	// Массовый перевод; совмещение в другом подразделении остается
+	if err := s.MoveEmployees(ctx, a.Id, c.Id); err != nil {
+		t.Fatalf("MoveEmployees: %v", err)
+	}
+	if ids := secondaryIn(bob.ID); len(ids) != 0 {
+		t.Fatalf("secondary after bulk move: %v", ids)
+	}
+
+	// Слияние
+	if _, err := s.MergeDepartment(ctx, c.Id, b.Id, 0, ""); err != nil {
+		t.Fatalf("MergeDepartment: %v", err)
+	}
+	if ids := secondaryIn(cid.ID); len(ids) != 0 {
+		t.Fatalf("secondary after merge: %v", ids)
+	}
+	headcount, err = s.GetHeadcount(ctx, b.Id)
+	if err != nil || headcount.Direct != (models.HeadcountFigures{Heads: 3, FTE: 3}) {
+		t.Fatalf("headcount after merge: %+v, %v", headcount, err)
+	}
+}
+
+// Слияние и удаление с переводом переносят совмещения в целевое подразделение
+func testSecondaryOnMerge(t *testing.T, s Store) {
+	root := mustCreate(t, s, "Root", nil)
+	a := mustCreate(t, s, "A", &root.Id)
+	b := mustCreate(t, s, "B", &root.Id)
+	c := mustCreate(t, s, "C", &root.Id)
+	d := mustCreate(t, s, "D", &root.Id)
+	x := mustHire(t, s, d.Id, "X")
+	y := mustHire(t, s, d.Id, "Y")
+	z := mustHire(t, s, b.Id, "Z")
+	for _, line := range []struct {
+		empID, deptID uint
+		allocation    int
+	}{{x.ID, a.Id, 20}, {x.ID, b.Id, 30}, {x.ID, c.Id, 10}, {y.ID, a.Id, 25}, {z.ID, a.Id, 10}} {
+		if _, err := s.AddSecondaryAssignment(ctx, line.empID, &models.SecondaryAssignmentRequest{DepartmentID: line.deptID, Role: "Mentor", Allocation: line.allocation}); err != nil {
+			t.Fatalf("AddSecondaryAssignment(%d, %d): %v", line.empID, line.deptID, err)
+		}
+	}
+	allocations := func(empID uint) map[uint]int {
+		t.Helper()
+		list, err := s.ListSecondaryAssignments(ctx, empID)
+		if err != nil {
+			t.Fatalf("ListSecondaryAssignments: %v", err)
+		}
+		got := make(map[uint]int)
+		for _, a := range list {
+			got[a.DepartmentID] = a.Allocation
+		}
+		return got
+	}
+	expect := func(empID uint, want map[uint]int) {
+		t.Helper()
+		if got := allocations(empID); !maps.Equal(got, want) {
+			t.Fatalf("secondary of %d: got %v, want %v", empID, got, want)
+		}
+	}
+
+	// Слияние A в B: совпавшее совмещение складывается, в основном месте - снимается
+	if _, err := s.MergeDepartment(ctx, a.Id, b.Id, 0, ""); err != nil {
+		t.Fatalf("MergeDepartment: %v", err)
+	}
+	expect(x.ID, map[uint]int{b.Id: 50, c.Id: 10})
+	expect(y.ID, map[uint]int{b.Id: 25})
+	expect(z.ID, map[uint]int{})
+	headcount, err := s.GetHeadcount(ctx, b.Id)
+	if err != nil || headcount.Direct != (models.HeadcountFigures{Heads: 1, SecondaryHeads: 2, FTE: 1.75}) {
+		t.Fatalf("headcount after merge: %+v, %v", headcount, err)
+	}
+
+	// Удаление B с переводом в C
+	if err := s.DeleteDepartment(ctx, b.Id, 0, "reassign", &c.Id); err != nil {
+		t.Fatalf("DeleteDepartment: %v", err)
+	}
+	expect(x.ID, map[uint]int{c.Id: 60})
+	expect(y.ID, map[uint]int{c.Id: 25})
+	headcount, err = s.GetHeadcount(ctx, c.Id)
+	if err != nil || headcount.Direct != (models.HeadcountFigures{Heads: 1, SecondaryHeads: 2, FTE: 1.85}) {
+		t.Fatalf("headcount after reassign: %+v, %v", headcount, err)
+	}
+}
@@ -0,0 +1,146 @@
+package storetest
+
+import (
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/kroulersama/goProject/models"
+)
+
+func testSplit(t *testing.T, s Store) {
+	root := mustCreate(t, s, "Root", nil)
+	src := mustCreate(t, s, "Source", &root.Id)
+	keep := mustCreate(t, s, "Keep", &src.Id)
+	move := mustCreate(t, s, "Move", &src.Id)
+	ivan := mustHire(t, s, src.Id, "Ivan")
+	anna := mustHire(t, s, src.Id, "Anna")
+	other := mustCreate(t, s, "Other", &root.Id)
+	stranger := mustHire(t, s, other.Id, "Petr")
+
+	// Чужие сотрудники и не прямые потомки отклоняются без изменений
+	_, err := s.SplitDepartment(ctx, src.Id, 0, &models.SplitRequest{Name: "New", EmployeeIDs: []uint{ivan.ID, stranger.ID}})
+	if !errors.Is(err, models.ErrEmployeeNotInDepartment) {
+		t.Fatalf("foreign employee: got %v", err)
+	}
+	_, err = s.SplitDepartment(ctx, src.Id, 0, &models.SplitRequest{Name: "New", EmployeeIDs: []uint{ivan.ID, 999999}})
+	if !errors.Is(err, models.ErrEmployeeNotFound) {
+		t.Fatalf("missing employee: got %v", err)
+	}
+	_, err = s.SplitDepartment(ctx, src.Id, 0, &models.SplitRequest{Name: "New", ChildIDs: []uint{other.Id}})
+	if !errors.Is(err, models.ErrNotChildDepartment) {
+		t.Fatalf("not a child: got %v", err)
+	}
+	if unique, err := s.CheckNameUnique(ctx, "New", &root.Id); err != nil || !unique {
+		t.Fatalf("failed split left a department: %v, %v", unique, err)
+	}
+	if _, err := s.SplitDepartment(ctx, src.Id, 0, &models.SplitRequest{Name: "Other"}); !errors.Is(err, models.ErrNameExists) {
+		t.Fatalf("duplicate name: got %v", err)
+	}
+
+	result, err := s.SplitDepartment(ctx, src.Id, 0, &models.SplitRequest{
+		Name:        "New",
+		EmployeeIDs: []uint{ivan.ID, ivan.ID},
+		ChildIDs:    []uint{move.Id},
+	})
+	if err != nil {
+		t.Fatalf("SplitDepartment: %v", err)
+	}
+	if result.Department.ParentId == nil || *result.Department.ParentId != root.Id {
+		t.Fatalf("new department is not a sibling: %+v", result.Department)
+	}
+	if len(result.EmployeesMoved) != 1 || len(result.DepartmentsMoved) != 1 {
+		t.Fatalf("unexpected result: %+v", result)
+	}
+
+	tree, err := s.GetWithTree(ctx, result.Department.Id, 1, &models.EmployeeFilter{})
+	if err != nil || len(tree.Employees) != 1 || tree.Employees[0].ID != ivan.ID || len(tree.Children) != 1 || tree.Children[0].Id != move.Id {
+		t.Fatalf("unexpected new department: %+v, %v", tree, err)
+	}
+	tree, err = s.GetWithTree(ctx, src.Id, 1, &models.EmployeeFilter{})
+	if err != nil || len(tree.Employees) != 1 || tree.Employees[0].ID != anna.ID || len(tree.Children) != 1 || tree.Children[0].Id != keep.Id {
+		t.Fatalf("unexpected source: %+v, %v", tree, err)
+	}
+}
+
+func testCopy(t *testing.T, s Store) {
+	root := mustCreate(t, s, "Root", nil)
+	src := mustCreate(t, s, "Sales", &root.Id)
+	east := mustCreate(t, s, "East", &src.Id)
+	mustCreate(t, s, "Retail", &east.Id)
+	mustHire(t, s, east.Id, "Ivan")
+	anna := mustHire(t, s, east.Id, "Anna")
+	if _, err := s.TerminateEmployee(ctx, anna.ID, 0, &models.TerminationRequest{Reason: "resigned"}); err != nil {
+		t.Fatalf("TerminateEmployee: %v", err)
+	}
+	if _, err := s.CreateVacancy(ctx, &models.VacancyRequest{DepartmentID: east.Id, Position: "Analyst"}); err != nil {
+		t.Fatalf("CreateVacancy: %v", err)
+	}
+
+	missing := uint(999999)
+	if _, err := s.CopyDepartment(ctx, src.Id, &models.CopyRequest{ParentID: &missing}); !errors.Is(err, models.ErrParentNotFound) {
+		t.Fatalf("missing parent: got %v", err)
+	}
+	if _, err := s.CopyDepartment(ctx, missing, &models.CopyRequest{}); !errors.Is(err, models.ErrDepartmentNotFound) {
+		t.Fatalf("missing source: got %v", err)
+	}
+	// Явно заданное имя не меняется
+	if _, err := s.CopyDepartment(ctx, src.Id, &models.CopyRequest{ParentID: &root.Id, Name: "SALES"}); !errors.Is(err, models.ErrNameExists) {
+		t.Fatalf("copy with taken name: got %v", err)
+	}
+
+	result, err := s.CopyDepartment(ctx, src.Id, &models.CopyRequest{Name: "Template", IncludePositions: true})
+	if err != nil {
+		t.Fatalf("CopyDepartment: %v", err)
+	}
+	if result.Root.Name != "Template" || result.Root.ParentId != nil || len(result.Departments) != 3 || len(result.Renamed) != 0 {
+		t.Fatalf("unexpected result: %+v", result)
+	}
+
+	// Места копируются открытыми вакансиями: работающий сотрудник и вакансия, уволенный - нет
+	for _, copied := range result.Departments {
+		var positions []string
+		for _, vacancy := range copied.Vacancies {
+			if vacancy.DepartmentID != copied.ID || vacancy.Status != models.VacancyOpen {
+				t.Fatalf("unexpected copied vacancy: %+v", vacancy)
+			}
+			positions = append(positions, vacancy.Position)
+		}
+		if copied.SourceID == east.Id && !slices.Equal(positions, []string{"Engineer", "Analyst"}) || copied.SourceID != east.Id && len(positions) != 0 {
+			t.Fatalf("unexpected vacancies of %d: %v", copied.SourceID, positions)
+		}
+	}
+	copiedEast := result.Departments[1].ID
+	if vacancies, err := s.ListVacancies(ctx, &models.VacancyFilter{DepartmentID: &copiedEast}); err != nil || len(vacancies) != 2 {
+		t.Fatalf("copied vacancies not stored: %+v, %v", vacancies, err)
+	}
+
+	// Структура скопирована, сотрудники нет
+	tree, err := s.GetWithTree(ctx, result.Root.Id, 3, &models.EmployeeFilter{})
+	if err != nil || len(tree.Children) != 1 || tree.Children[0].Name != "East" || len(tree.Children[0].Children) != 1 {
+		t.Fatalf("unexpected copy: %+v, %v", tree, err)
+	}
+	if len(tree.Children[0].Employees) != 0 {
+		t.Fatalf("employees were copied: %+v", tree.Children[0].Employees)
+	}
+
+	// Политика branch: шаблон копируется в свою же ветку, занятые имена получают суффикс
+	result, err = s.CopyDepartment(ctx, src.Id, &models.CopyRequest{ParentID: &root.Id, Name: "Sales 2"})
+	if err != nil {
+		t.Fatalf("copy into same branch: %v", err)
+	}
+	var names []string
+	for _, copied := range result.Departments {
+		names = append(names, copied.Name)
+	}
+	if !slices.Equal(names, []string{"Sales 2", "East (2)", "Retail (2)"}) || len(result.Renamed) != 2 ||
+		result.Renamed[0] != (models.RenamedDepartment{ID: result.Departments[1].ID, From: "East", To: "East (2)"}) {
+		t.Fatalf("unexpected copy into same branch: %v, %+v", names, result.Renamed)
+	}
+
+	// Без имени суффикс получает и корень копии
+	result, err = s.CopyDepartment(ctx, east.Id, &models.CopyRequest{ParentID: &src.Id})
+	if err != nil || result.Root.Name != "East (3)" || result.Departments[1].Name != "Retail (3)" {
+		t.Fatalf("copy without name: %+v, %v", result, err)
+	}
+}
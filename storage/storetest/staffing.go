@@ -0,0 +1,225 @@
+package storetest
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/kroulersama/goProject/models"
+)
+
+func testStaffing(t *testing.T, s Store) {
+	root := mustCreate(t, s, "Root", nil)
+	a := mustCreate(t, s, "A", &root.Id)
+	b := mustCreate(t, s, "B", &root.Id)
+	mustHire(t, s, root.Id, "Ceo")
+	mustHire(t, s, a.Id, "Dev")
+	gone := mustHire(t, s, a.Id, "Gone")
+	prev, period, next := periods()
+
+	// Уволен в прошлом месяце: в текущем уже не учитывается
+	now := time.Now().UTC()
+	left := now.AddDate(0, 0, -now.Day())
+	if _, err := s.TerminateEmployee(ctx, gone.ID, 0, &models.TerminationRequest{Reason: "resigned", TerminatedAt: &left}); err != nil {
+		t.Fatalf("TerminateEmployee: %v", err)
+	}
+
+	// Планы: валидация, уникальность по подразделению и месяцу
+	for _, req := range []models.HeadcountPlanRequest{
+		{DepartmentID: root.Id, Period: period, Planned: 1},
+		{DepartmentID: a.Id, Period: period, Planned: 4},
+		{DepartmentID: a.Id, Period: next, Planned: 9},
+	} {
+		if _, err := s.CreateHeadcountPlan(ctx, &req); err != nil {
+			t.Fatalf("CreateHeadcountPlan(%+v): %v", req, err)
+		}
+	}
+	if _, err := s.CreateHeadcountPlan(ctx, &models.HeadcountPlanRequest{DepartmentID: a.Id, Period: period}); !errors.Is(err, models.ErrHeadcountPlanExists) {
+		t.Fatalf("duplicate plan: got %v", err)
+	}
+	if _, err := s.CreateHeadcountPlan(ctx, &models.HeadcountPlanRequest{DepartmentID: a.Id, Period: "2026-13", Planned: -1}); !errors.Is(err, models.ErrInvalidPeriod) || !errors.Is(err, models.ErrInvalidPlanned) {
+		t.Fatalf("invalid plan: got %v", err)
+	}
+	if _, err := s.CreateHeadcountPlan(ctx, &models.HeadcountPlanRequest{DepartmentID: 999999, Period: period}); !errors.Is(err, models.ErrUnknownDepartment) {
+		t.Fatalf("plan for missing department: got %v", err)
+	}
+	plans, err := s.ListHeadcountPlans(ctx, &models.HeadcountPlanFilter{DepartmentID: &a.Id})
+	if err != nil || len(plans) != 2 || plans[0].Period != period || plans[1].Period != next {
+		t.Fatalf("ListHeadcountPlans: %+v, %v", plans, err)
+	}
+	plan, err := s.UpdateHeadcountPlan(ctx, plans[0].ID, 1, &models.HeadcountPlanUpdate{Planned: 3})
+	if err != nil || plan.Planned != 3 || plan.Version != 2 {
+		t.Fatalf("UpdateHeadcountPlan: %+v, %v", plan, err)
+	}
+	if err := s.DeleteHeadcountPlan(ctx, plans[1].ID, 2); !errors.Is(err, models.ErrVersionMismatch) {
+		t.Fatalf("stale delete: got %v", err)
+	}
+	if err := s.DeleteHeadcountPlan(ctx, plans[1].ID, 1); err != nil {
+		t.Fatalf("DeleteHeadcountPlan: %v", err)
+	}
+
+	// Вакансии: должность из каталога, отмена, закрытие
+	position, err := s.CreatePosition(ctx, &models.PositionRequest{Title: "Analyst", Grade: "L2", JobFamily: "Data"})
+	if err != nil {
+		t.Fatalf("CreatePosition: %v", err)
+	}
+	open, err := s.CreateVacancy(ctx, &models.VacancyRequest{DepartmentID: a.Id, PositionID: &position.ID})
+	if err != nil || open.Position != "Analyst" || open.Status != models.VacancyOpen {
+		t.Fatalf("CreateVacancy: %+v, %v", open, err)
+	}
+	if _, err := s.CreateVacancy(ctx, &models.VacancyRequest{DepartmentID: a.Id, Position: "Tester", PositionID: &position.ID}); !errors.Is(err, models.ErrPositionMismatch) {
+		t.Fatalf("conflicting vacancy position: got %v", err)
+	}
+	if _, err := s.CreateVacancy(ctx, &models.VacancyRequest{DepartmentID: a.Id}); !errors.Is(err, models.ErrPositionEmpty) {
+		t.Fatalf("vacancy without position: got %v", err)
+	}
+	toFill, err := s.CreateVacancy(ctx, &models.VacancyRequest{DepartmentID: b.Id, Position: "Tester"})
+	if err != nil {
+		t.Fatalf("CreateVacancy: %v", err)
+	}
+	canceled, err := s.CreateVacancy(ctx, &models.VacancyRequest{DepartmentID: b.Id, Position: "Designer"})
+	if err != nil {
+		t.Fatalf("CreateVacancy: %v", err)
+	}
+	status := models.VacancyCanceled
+	if canceled, err = s.UpdateVacancy(ctx, canceled.ID, 1, &models.VacancyUpdate{Status: &status}); err != nil || canceled.Status != status {
+		t.Fatalf("cancel vacancy: %+v, %v", canceled, err)
+	}
+	status = models.VacancyFilled
+	if _, err := s.UpdateVacancy(ctx, open.ID, 0, &models.VacancyUpdate{Status: &status}); !errors.Is(err, models.ErrInvalidVacancyStatus) {
+		t.Fatalf("status filled via update: got %v", err)
+	}
+	if _, _, err := s.FillVacancy(ctx, canceled.ID, 0, &models.EmployeeRequest{FullName: "X"}); !errors.Is(err, models.ErrVacancyNotOpen) {
+		t.Fatalf("fill canceled: got %v", err)
+	}
+
+	// Ошибка создания сотрудника не закрывает вакансию
+	if _, _, err := s.FillVacancy(ctx, toFill.ID, 1, &models.EmployeeRequest{}); !errors.Is(err, models.ErrFullNameEmpty) {
+		t.Fatalf("fill with invalid employee: got %v", err)
+	}
+	filled, hired, err := s.FillVacancy(ctx, toFill.ID, 1, &models.EmployeeRequest{FullName: "Tess"})
+	if err != nil || filled.Status != models.VacancyFilled || filled.EmployeeID == nil || *filled.EmployeeID != hired.ID ||
+		filled.FilledAt == nil || filled.Version != 2 || hired.DepartmentId != b.Id || hired.Position != "Tester" {
+		t.Fatalf("FillVacancy: %+v, %+v, %v", filled, hired, err)
+	}
+	if _, err := s.UpdateVacancy(ctx, filled.ID, 0, &models.VacancyUpdate{}); !errors.Is(err, models.ErrVacancyFilled) {
+		t.Fatalf("update filled: got %v", err)
+	}
+	if history, err := s.EmployeeHistory(ctx, hired.ID); err != nil || len(history) != 1 {
+		t.Fatalf("history of hired: %+v, %v", history, err)
+	}
+	vacancies, err := s.ListVacancies(ctx, &models.VacancyFilter{Status: models.VacancyOpen})
+	if err != nil || len(vacancies) != 1 || vacancies[0].ID != open.ID {
+		t.Fatalf("ListVacancies: %+v, %v", vacancies, err)
+	}
+
+	// Сводка: уволенные и закрытые/отмененные вакансии не учитываются
+	rollup, err := s.GetStaffing(ctx, root.Id, period)
+	if err != nil || len(rollup.Departments) != 3 {
+		t.Fatalf("GetStaffing: %+v, %v", rollup, err)
+	}
+	want := map[uint][2]models.StaffingFigures{
+		root.Id: {{Planned: 1, Actual: 1, Gap: 0}, {Planned: 4, Actual: 3, Open: 1, Gap: 0}},
+		a.Id:    {{Planned: 3, Actual: 1, Open: 1, Gap: 1}, {Planned: 3, Actual: 1, Open: 1, Gap: 1}},
+		b.Id:    {{Actual: 1, Gap: -1}, {Actual: 1, Gap: -1}},
+	}
+	for _, line := range rollup.Departments {
+		if w := want[line.DepartmentID]; line.Own != w[0] || line.Total != w[1] {
+			t.Fatalf("staffing of %d: own %+v, total %+v, want %+v", line.DepartmentID, line.Own, line.Total, w)
+		}
+	}
+	if rollup.Departments[0].DepartmentID != root.Id || rollup.Total != want[root.Id][1] {
+		t.Fatalf("staffing total: %+v", rollup)
+	}
+	if rollup, err = s.GetStaffing(ctx, a.Id, next); err != nil || rollup.Total.Planned != 0 || len(rollup.Departments) != 1 {
+		t.Fatalf("staffing after plan delete: %+v, %v", rollup, err)
+	}
+
+	// Прошлый месяц: уволенный еще работал, вакансий еще не было
+	if rollup, err = s.GetStaffing(ctx, a.Id, prev); err != nil || rollup.Total.Actual != 2 || rollup.Total.Open != 0 {
+		t.Fatalf("staffing of previous month: %+v, %v", rollup, err)
+	}
+	if _, err := s.GetStaffing(ctx, root.Id, "March"); !errors.Is(err, models.ErrInvalidPeriod) {
+		t.Fatalf("invalid period: got %v", err)
+	}
+
+	// Удаление подразделения удаляет его планы и вакансии
+	if err := s.DeleteDepartment(ctx, a.Id, 0, "cascade", nil); err != nil {
+		t.Fatalf("DeleteDepartment: %v", err)
+	}
+	if _, err := s.GetVacancy(ctx, open.ID); !errors.Is(err, models.ErrVacancyNotFound) {
+		t.Fatalf("vacancy after delete: got %v", err)
+	}
+	if plans, err = s.ListHeadcountPlans(ctx, &models.HeadcountPlanFilter{Period: period}); err != nil || len(plans) != 1 {
+		t.Fatalf("plans after delete: %+v, %v", plans, err)
+	}
+}
+
+func testStaffingOnMerge(t *testing.T, s Store) {
+	root := mustCreate(t, s, "Root", nil)
+	a := mustCreate(t, s, "A", &root.Id)
+	b := mustCreate(t, s, "B", &root.Id)
+	c := mustCreate(t, s, "C", &root.Id)
+	_, period, next := periods()
+	for _, req := range []models.HeadcountPlanRequest{
+		{DepartmentID: a.Id, Period: period, Planned: 2},
+		{DepartmentID: a.Id, Period: next, Planned: 5},
+		{DepartmentID: b.Id, Period: period, Planned: 3},
+		{DepartmentID: c.Id, Period: period, Planned: 1},
+	} {
+		if _, err := s.CreateHeadcountPlan(ctx, &req); err != nil {
+			t.Fatalf("CreateHeadcountPlan(%+v): %v", req, err)
+		}
+	}
+	open, err := s.CreateVacancy(ctx, &models.VacancyRequest{DepartmentID: a.Id, Position: "Dev"})
+	if err != nil {
+		t.Fatalf("CreateVacancy: %v", err)
+	}
+	canceled, err := s.CreateVacancy(ctx, &models.VacancyRequest{DepartmentID: a.Id, Position: "QA"})
+	if err != nil {
+		t.Fatalf("CreateVacancy: %v", err)
+	}
+	status := models.VacancyCanceled
+	if _, err := s.UpdateVacancy(ctx, canceled.ID, 0, &models.VacancyUpdate{Status: &status}); err != nil {
+		t.Fatalf("cancel vacancy: %v", err)
+	}
+
+	// Слияние: планы на один месяц складываются, открытая вакансия переходит в цель
+	if _, err := s.MergeDepartment(ctx, a.Id, b.Id, 0, ""); err != nil {
+		t.Fatalf("MergeDepartment: %v", err)
+	}
+	plans, err := s.ListHeadcountPlans(ctx, &models.HeadcountPlanFilter{DepartmentID: &b.Id})
+	if err != nil || len(plans) != 2 || plans[0].Period != period || plans[0].Planned != 5 ||
+		plans[1].Period != next || plans[1].Planned != 5 {
+		t.Fatalf("plans after merge: %+v, %v", plans, err)
+	}
+	if vacancy, err := s.GetVacancy(ctx, open.ID); err != nil || vacancy.DepartmentID != b.Id || vacancy.Status != models.VacancyOpen {
+		t.Fatalf("open vacancy after merge: %+v, %v", vacancy, err)
+	}
+	if _, err := s.GetVacancy(ctx, canceled.ID); !errors.Is(err, models.ErrVacancyNotFound) {
+		t.Fatalf("canceled vacancy after merge: got %v", err)
+	}
+
+	// Удаление с переводом ведет себя так же
+	if err := s.DeleteDepartment(ctx, b.Id, 0, "reassign", &c.Id); err != nil {
+		t.Fatalf("DeleteDepartment: %v", err)
+	}
+	plans, err = s.ListHeadcountPlans(ctx, &models.HeadcountPlanFilter{DepartmentID: &c.Id})
+	if err != nil || len(plans) != 2 || plans[0].Planned != 6 || plans[1].Planned != 5 {
+		t.Fatalf("plans after reassign: %+v, %v", plans, err)
+	}
+	if vacancy, err := s.GetVacancy(ctx, open.ID); err != nil || vacancy.DepartmentID != c.Id {
+		t.Fatalf("open vacancy after reassign: %+v, %v", vacancy, err)
+	}
+	rollup, err := s.GetStaffing(ctx, c.Id, period)
+	if err != nil || rollup.Total.Planned != 6 || rollup.Total.Open != 1 {
+		t.Fatalf("staffing after reassign: %+v, %v", rollup, err)
+	}
+}
+
+// Прошлый, текущий и следующий месяцы в формате периода
+func periods() (string, string, string) {
+	now := time.Now().UTC()
+	month := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	return month.AddDate(0, -1, 0).Format("2006-01"), month.Format("2006-01"), month.AddDate(0, 1, 0).Format("2006-01")
+}
@@ -0,0 +1,74 @@
+package storetest
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/kroulersama/goProject/models"
+)
+
+func testDepartmentStats(t *testing.T, s Store) {
+	root := mustCreate(t, s, "Root", nil)
+	a := mustCreate(t, s, "A", &root.Id)
+	b := mustCreate(t, s, "B", &root.Id)
+	mustCreate(t, s, "A1", &a.Id)
+
+	now := time.Now().UTC()
+	hire := func(deptID uint, name, position string, daysAgo int) *models.Employee {
+		t.Helper()
+		hiredAt := now.AddDate(0, 0, -daysAgo)
+		emp, err := s.CreateEmployee(ctx, deptID, &models.EmployeeRequest{FullName: name, Position: position, HiredAt: &hiredAt})
+		if err != nil {
+			t.Fatalf("CreateEmployee(%q): %v", name, err)
+		}
+		return emp
+	}
+	hire(root.Id, "Ceo", "Director", 40)
+	hire(a.Id, "Dev 1", "Engineer", 20)
+	hire(b.Id, "Dev 2", "Engineer", 10)
+	gone := hire(b.Id, "Gone", "Analyst", 5)
+	if _, err := s.TerminateEmployee(ctx, gone.ID, 0, &models.TerminationRequest{Reason: "resigned"}); err != nil {
+		t.Fatalf("TerminateEmployee: %v", err)
+	}
+
+	stats, err := s.GetDepartmentStats(ctx, root.Id)
+	if err != nil {
+		t.Fatalf("GetDepartmentStats: %v", err)
+	}
+	if stats.Headcount != (models.StatsHeadcount{Direct: 1, Total: 3}) {
+		t.Fatalf("headcount: %+v", stats.Headcount)
+	}
+	if stats.Tree != (models.TreeShape{Departments: 4, Depth: 3, Breadth: 2}) {
+		t.Fatalf("tree: %+v", stats.Tree)
+	}
+	if stats.Tenure != (models.TenureStats{Employees: 3, AverageDays: 23.3, MedianDays: 20}) {
+		t.Fatalf("tenure: %+v", stats.Tenure)
+	}
+	if len(stats.Positions) != 2 || stats.Positions[0] != (models.PositionVariant{Title: "Engineer", Employees: 2}) ||
+		stats.Positions[1].Title != "Director" {
+		t.Fatalf("positions: %+v", stats.Positions)
+	}
+
+	// Найм по месяцам учитывает и уволенных
+	hires := 0
+	for i, m := range stats.HiresByMonth {
+		if i > 0 && m.Month <= stats.HiresByMonth[i-1].Month {
+			t.Fatalf("hires by month not sorted: %+v", stats.HiresByMonth)
+		}
+		hires += m.Hires
+	}
+	if hires != 4 || stats.HiresByMonth[len(stats.HiresByMonth)-1].Month != now.AddDate(0, 0, -5).Format("2006-01") {
+		t.Fatalf("hires by month: %+v", stats.HiresByMonth)
+	}
+
+	// Лист дерева
+	leaf, err := s.GetDepartmentStats(ctx, b.Id)
+	if err != nil || leaf.Tree != (models.TreeShape{Departments: 1, Depth: 1, Breadth: 1}) || leaf.Headcount.Total != 1 ||
+		leaf.Tenure.MedianDays != 10 {
+		t.Fatalf("stats of B: %+v, %v", leaf, err)
+	}
+	if _, err := s.GetDepartmentStats(ctx, 999999); !errors.Is(err, models.ErrDepartmentNotFound) {
+		t.Fatalf("stats of missing department: got %v", err)
+	}
+}
@@ -0,0 +1,391 @@
+// Package storetest содержит общий набор проверок для реализаций
+// хранилищ из models: подразделения, сотрудники, планы, отложенные изменения,
+// пользовательские атрибуты, каталог должностей, правила иерархии, проверка целостности,
+// статус занятости, история назначений, совмещения и линии подчинения сотрудников, показатели подразделений,
+// плановая численность, вакансии и ключи идемпотентности.
+package storetest
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/kroulersama/goProject/models"
+)
+
+// Хранилище под проверкой
+type Store interface {
+	models.DepartmentStore
+	models.EmployeeStore
+	models.PlanStore
+	models.ScheduleStore
+	models.AttributeStore
+	models.PositionStore
+	models.AssignmentStore
+	models.SecondaryStore
+	models.ReportingStore
+	models.StatsStore
+	models.StaffingStore
+	models.RulesStore
+	models.IntegrityStore
+	models.IdempotencyStore
+}
+
+var ctx = context.Background()
+
+// Run запускает проверки; newStore должен возвращать пустое хранилище
+// с заданной политикой уникальности имен
+func Run(t *testing.T, newStore func(t *testing.T, policy models.UniquenessPolicy) Store) {
+	tests := []struct {
+		name string
+		fn   func(t *testing.T, s Store)
+	}{
+		{"CreateDepartment", testCreateDepartment},
+		{"NameUniqueInBranch", testNameUniqueInBranch},
+		{"UpdateDepartment", testUpdateDepartment},
+		{"CycleDetection", testCycleDetection},
+		{"Versioning", testVersioning},
+		{"DeleteCascade", testDeleteCascade},
+		{"DeleteReassign", testDeleteReassign},
+		{"GetWithTree", testGetWithTree},
+		{"Employees", testEmployees},
+		{"Merge", testMerge},
+		{"MergeStrategiesInBranch", testMergeStrategiesInBranch},
+		{"Split", testSplit},
+		{"Copy", testCopy},
+		{"SiblingOrder", testSiblingOrder},
+		{"Identifiers", testIdentifiers},
+		{"Attributes", testAttributes},
+		{"Positions", testPositions},
+		{"PositionClusters", testPositionClusters},
+		{"HierarchyRules", testHierarchyRules},
+		{"HierarchyRulesOnMove", testHierarchyRulesOnMove},
+		{"Integrity", testIntegrity},
+		{"Plans", testPlans},
+		{"PlanEmployees", testPlanEmployees},
+		{"TransferEmployee", testTransferEmployee},
+		{"EmploymentStatus", testEmploymentStatus},
+		{"AssignmentHistory", testAssignmentHistory},
+		{"SecondaryAssignments", testSecondaryAssignments},
+		{"SecondaryOnPrimaryChange", testSecondaryOnPrimaryChange},
+		{"SecondaryOnMerge", testSecondaryOnMerge},
+		{"ReportingLines", testReportingLines},
+		{"DepartmentStats", testDepartmentStats},
+		{"Staffing", testStaffing},
+		{"StaffingOnMerge", testStaffingOnMerge},
+		{"ScheduledChanges", testScheduledChanges},
+		{"IdempotencyKeys", testIdempotencyKeys},
+		{"ConcurrentCreate", testConcurrentCreate},
+		{"ConcurrentMoves", testConcurrentMoves},
+		{"ConcurrentAllocation", testConcurrentAllocation},
+		{"ConcurrentManagerTermination", testConcurrentManagerTermination},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.fn(t, newStore(t, models.UniqueInBranch))
+		})
+	}
+
+	// Проверки, зависящие от политики уникальности
+	policies := []struct {
+		name   string
+		policy models.UniquenessPolicy
+		fn     func(t *testing.T, s Store)
+	}{
+		{"Uniqueness/siblings", models.UniqueAmongSiblings, testUniqueAmongSiblings},
+		{"Uniqueness/branch", models.UniqueInBranch, testUniqueInBranch},
+		{"Uniqueness/global", models.UniqueGlobal, testUniqueGlobal},
+		{"MergeStrategies", models.UniqueAmongSiblings, testMergeStrategies},
+	}
+
+	for _, tt := range policies {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.fn(t, newStore(t, tt.policy))
+		})
+	}
+}
+
+func testCreateDepartment(t *testing.T, s Store) {
+	root := mustCreate(t, s, "Root", nil)
+	if root.Id == 0 || root.ParentId != nil {
+		t.Fatalf("unexpected root: %+v", root)
+	}
+
+	child := mustCreate(t, s, "  Child  ", &root.Id)
+	if child.Name != "Child" || child.ParentId == nil || *child.ParentId != root.Id {
+		t.Fatalf("unexpected child: %+v", child)
+	}
+
+	if _, err := s.CreateDepartment(ctx, &models.DepartmentRequest{Name: "   "}); !errors.Is(err, models.ErrNameEmpty) {
+		t.Fatalf("empty name: got %v", err)
+	}
+
+	missing := uint(999999)
+	_, err := s.CreateDepartment(ctx, &models.DepartmentRequest{Name: "Orphan", ParentID: &missing})
+	if !errors.Is(err, models.ErrParentNotFound) {
+		t.Fatalf("missing parent: got %v", err)
+	}
+}
+
+func testNameUniqueInBranch(t *testing.T, s Store) {
+	root := mustCreate(t, s, "Root", nil)
+	a := mustCreate(t, s, "A", &root.Id)
+	mustCreate(t, s, "Deep", &a.Id)
+
+	// Дубликат среди корневых
+	if _, err := s.CreateDepartment(ctx, &models.DepartmentRequest{Name: "Root"}); !errors.Is(err, models.ErrNameExists) {
+		t.Fatalf("duplicate root: got %v", err)
+	}
+
+	// Имя потомка занято во всей ветке
+	_, err := s.CreateDepartment(ctx, &models.DepartmentRequest{Name: "Deep", ParentID: &root.Id})
+	if !errors.Is(err, models.ErrNameExists) {
+		t.Fatalf("duplicate in branch: got %v", err)
+	}
+
+	unique, err := s.CheckNameUnique(ctx, "Deep", &root.Id)
+	if err != nil || unique {
+		t.Fatalf("CheckNameUnique(Deep) = %v, %v", unique, err)
+	}
+	unique, err = s.CheckNameUnique(ctx, "Other", &root.Id)
+	if err != nil || !unique {
+		t.Fatalf("CheckNameUnique(Other) = %v, %v", unique, err)
+	}
+
+	// В другой ветке имя свободно
+	other := mustCreate(t, s, "Other root", nil)
+	mustCreate(t, s, "Deep", &other.Id)
+}
+
+func testUpdateDepartment(t *testing.T, s Store) {
+	root := mustCreate(t, s, "Root", nil)
+	a := mustCreate(t, s, "A", &root.Id)
+	b := mustCreate(t, s, "B", &root.Id)
+	other := mustCreate(t, s, "Other", nil)
+
+	updated, err := s.UpdateDepartment(ctx, a.Id, 0, &models.DepartmentRequest{Name: " Renamed "})
+	if err != nil || updated.Name != "Renamed" {
+		t.Fatalf("rename: %+v, %v", updated, err)
+	}
+
+	updated, err = s.UpdateDepartment(ctx, b.Id, 0, &models.DepartmentRequest{ParentID: &other.Id})
+	if err != nil || updated.ParentId == nil || *updated.ParentId != other.Id {
+		t.Fatalf("move: %+v, %v", updated, err)
+	}
+
+	// Переименование проверяется так же, как создание
+	if _, err := s.UpdateDepartment(ctx, a.Id, 0, &models.DepartmentRequest{Name: "Root"}); !errors.Is(err, models.ErrNameExists) {
+		t.Fatalf("rename to parent name: got %v", err)
+	}
+	mustCreate(t, s, "C", &root.Id)
+	if _, err := s.UpdateDepartment(ctx, a.Id, 0, &models.DepartmentRequest{Name: "C"}); !errors.Is(err, models.ErrNameExists) {
+		t.Fatalf("sibling clash: got %v", err)
+	}
+
+	if _, err := s.UpdateDepartment(ctx, 999999, 0, &models.DepartmentRequest{Name: "X"}); !errors.Is(err, models.ErrDepartmentNotFound) {
+		t.Fatalf("missing department: got %v", err)
+	}
+
+	missing := uint(999999)
+	if _, err := s.UpdateDepartment(ctx, a.Id, 0, &models.DepartmentRequest{ParentID: &missing}); !errors.Is(err, models.ErrParentNotFound) {
+		t.Fatalf("missing parent: got %v", err)
+	}
+
+	long := make([]byte, 201)
+	for i := range long {
+		long[i] = 'x'
+	}
+	if _, err := s.UpdateDepartment(ctx, a.Id, 0, &models.DepartmentRequest{Name: string(long)}); !errors.Is(err, models.ErrNameTooLong) {
+		t.Fatalf("long name: got %v", err)
+	}
+}
+
+func testCycleDetection(t *testing.T, s Store) {
+	root := mustCreate(t, s, "Root", nil)
+	a := mustCreate(t, s, "A", &root.Id)
+	b := mustCreate(t, s, "B", &a.Id)
+
+	if _, err := s.UpdateDepartment(ctx, a.Id, 0, &models.DepartmentRequest{ParentID: &a.Id}); !errors.Is(err, models.ErrSelfParent) {
+		t.Fatalf("self parent: got %v", err)
+	}
+	if _, err := s.UpdateDepartment(ctx, root.Id, 0, &models.DepartmentRequest{ParentID: &b.Id}); !errors.Is(err, models.ErrCycleDetected) {
+		t.Fatalf("cycle: got %v", err)
+	}
+}
+
+func testVersioning(t *testing.T, s Store) {
+	root := mustCreate(t, s, "Root", nil)
+	if root.Version != 1 {
+		t.Fatalf("initial version = %d", root.Version)
+	}
+
+	updated, err := s.UpdateDepartment(ctx, root.Id, 1, &models.DepartmentRequest{Name: "Renamed"})
+	if err != nil || updated.Version != 2 {
+		t.Fatalf("versioned update: %+v, %v", updated, err)
+	}
+
+	// Устаревшая версия
+	if _, err := s.UpdateDepartment(ctx, root.Id, 1, &models.DepartmentRequest{Name: "Stale"}); !errors.Is(err, models.ErrVersionMismatch) {
+		t.Fatalf("stale update: got %v", err)
+	}
+	if err := s.DeleteDepartment(ctx, root.Id, 1, "cascade", nil); !errors.Is(err, models.ErrVersionMismatch) {
+		t.Fatalf("stale delete: got %v", err)
+	}
+
+	tree, err := s.GetWithTree(ctx, root.Id, 0, nil)
+	if err != nil || tree.Name != "Renamed" || tree.Version != 2 {
+		t.Fatalf("after stale writes: %+v, %v", tree, err)
+	}
+
+	if err := s.DeleteDepartment(ctx, root.Id, 2, "cascade", nil); err != nil {
+		t.Fatalf("versioned delete: %v", err)
+	}
+}
+
+func testDeleteCascade(t *testing.T, s Store) {
+	root := mustCreate(t, s, "Root", nil)
+	a := mustCreate(t, s, "A", &root.Id)
+	b := mustCreate(t, s, "B", &a.Id)
+	mustHire(t, s, b.Id, "Ivan")
+
+	if err := s.DeleteDepartment(ctx, a.Id, 0, "cascade", nil); err != nil {
+		t.Fatalf("cascade: %v", err)
+	}
+	for _, id := range []uint{a.Id, b.Id} {
+		if _, err := s.GetWithTree(ctx, id, 1, &models.EmployeeFilter{}); !errors.Is(err, models.ErrDepartmentNotFound) {
+			t.Fatalf("department %d still exists: %v", id, err)
+		}
+	}
+	if employees := mustList(t, s, b.Id); len(employees) != 0 {
+		t.Fatalf("employees survived cascade: %+v", employees)
+	}
+
+	if err := s.DeleteDepartment(ctx, a.Id, 0, "cascade", nil); !errors.Is(err, models.ErrDepartmentNotFound) {
+		t.Fatalf("repeat delete: got %v", err)
+	}
+	if err := s.DeleteDepartment(ctx, root.Id, 0, "drop", nil); !errors.Is(err, models.ErrInvalidMode) {
+		t.Fatalf("invalid mode: got %v", err)
+	}
+}
+
+func testDeleteReassign(t *testing.T, s Store) {
+	root := mustCreate(t, s, "Root", nil)
+	a := mustCreate(t, s, "A", &root.Id)
+	b := mustCreate(t, s, "B", &root.Id)
+	child := mustCreate(t, s, "A child", &a.Id)
+	mustHire(t, s, a.Id, "Ivan")
+	mustHire(t, s, child.Id, "Petr")
+
+	if err := s.DeleteDepartment(ctx, a.Id, 0, "reassign", &a.Id); !errors.Is(err, models.ErrReassignToSame) {
+		t.Fatalf("same target: got %v", err)
+	}
+	missing := uint(999999)
+	if err := s.DeleteDepartment(ctx, a.Id, 0, "reassign", &missing); !errors.Is(err, models.ErrTargetNotFound) {
+		t.Fatalf("missing target: got %v", err)
+	}
+
+	if err := s.DeleteDepartment(ctx, a.Id, 0, "reassign", &b.Id); err != nil {
+		t.Fatalf("reassign: %v", err)
+	}
+
+	employees := mustList(t, s, b.Id)
+	if len(employees) != 1 || employees[0].FullName != "Ivan" {
+		t.Fatalf("reassigned employees: %+v", employees)
+	}
+	if _, err := s.GetWithTree(ctx, child.Id, 0, nil); !errors.Is(err, models.ErrDepartmentNotFound) {
+		t.Fatalf("child survived reassign: %v", err)
+	}
+}
+
+func testGetWithTree(t *testing.T, s Store) {
+	root := mustCreate(t, s, "Root", nil)
+	a := mustCreate(t, s, "A", &root.Id)
+	b := mustCreate(t, s, "B", &a.Id)
+	mustHire(t, s, root.Id, "Ivan")
+
+	tree, err := s.GetWithTree(ctx, root.Id, 1, &models.EmployeeFilter{})
+	if err != nil {
+		t.Fatalf("GetWithTree: %v", err)
+	}
+	if tree.Id != root.Id || len(tree.Employees) != 1 || len(tree.Children) != 1 {
+		t.Fatalf("unexpected tree: %+v", tree)
+	}
+	if tree.Children[0].Id != a.Id || len(tree.Children[0].Children) != 0 {
+		t.Fatalf("depth not respected: %+v", tree.Children[0])
+	}
+
+	tree, err = s.GetWithTree(ctx, root.Id, 2, nil)
+	if err != nil {
+		t.Fatalf("GetWithTree: %v", err)
+	}
+	if len(tree.Employees) != 0 || len(tree.Children[0].Children) != 1 || tree.Children[0].Children[0].Id != b.Id {
+		t.Fatalf("unexpected deep tree: %+v", tree)
+	}
+
+	if _, err := s.GetWithTree(ctx, 999999, 1, &models.EmployeeFilter{}); !errors.Is(err, models.ErrDepartmentNotFound) {
+		t.Fatalf("missing department: got %v", err)
+	}
+}
+
+func testEmployees(t *testing.T, s Store) {
+	from := mustCreate(t, s, "From", nil)
+	to := mustCreate(t, s, "To", nil)
+
+	if _, err := s.CreateEmployee(ctx, 999999, &models.EmployeeRequest{FullName: "X", Position: "Y"}); !errors.Is(err, models.ErrDepartmentNotFound) {
+		t.Fatalf("missing department: got %v", err)
+	}
+	if _, err := s.CreateEmployee(ctx, from.Id, &models.EmployeeRequest{FullName: " ", Position: "Dev"}); !errors.Is(err, models.ErrFullNameEmpty) {
+		t.Fatalf("empty name: got %v", err)
+	}
+	if _, err := s.CreateEmployee(ctx, from.Id, &models.EmployeeRequest{FullName: "X"}); !errors.Is(err, models.ErrPositionEmpty) {
+		t.Fatalf("empty position: got %v", err)
+	}
+	future := time.Now().Add(48 * time.Hour)
+	if _, err := s.CreateEmployee(ctx, from.Id, &models.EmployeeRequest{FullName: "X", Position: "Dev", HiredAt: &future}); !errors.Is(err, models.ErrHiredAtFuture) {
+		t.Fatalf("future hire: got %v", err)
+	}
+
+	mustHire(t, s, from.Id, "Petr")
+	mustHire(t, s, from.Id, "Anna")
+
+	employees, err := s.GetEmployeesByDepartment(ctx, from.Id, &models.EmployeeFilter{SortBy: "name"})
+	if err != nil || len(employees) != 2 || employees[0].FullName != "Anna" {
+		t.Fatalf("sorted by name: %+v, %v", employees, err)
+	}
+
+	if err := s.MoveEmployees(ctx, from.Id, to.Id); err != nil {
+		t.Fatalf("MoveEmployees: %v", err)
+	}
+	if len(mustList(t, s, from.Id)) != 0 || len(mustList(t, s, to.Id)) != 2 {
+		t.Fatal("employees were not moved")
+	}
+}
+
+func mustCreate(t *testing.T, s Store, name string, parentID *uint) *models.Department {
+	t.Helper()
+	dept, err := s.CreateDepartment(ctx, &models.DepartmentRequest{Name: name, ParentID: parentID})
+	if err != nil {
+		t.Fatalf("CreateDepartment(%q): %v", name, err)
+	}
+	return dept
+}
+
+func mustHire(t *testing.T, s Store, departmentID uint, name string) *models.Employee {
+	t.Helper()
+	emp, err := s.CreateEmployee(ctx, departmentID, &models.EmployeeRequest{FullName: name, Position: "Engineer"})
+	if err != nil {
+		t.Fatalf("CreateEmployee(%q): %v", name, err)
+	}
+	return emp
+}
+
+func mustList(t *testing.T, s Store, departmentID uint) []models.Employee {
+	t.Helper()
+	employees, err := s.GetEmployeesByDepartment(ctx, departmentID, &models.EmployeeFilter{})
+	if err != nil {
+		t.Fatalf("GetEmployeesByDepartment: %v", err)
+	}
+	return employees
+}
@@ -0,0 +1,179 @@
+package storetest
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"sync"
+	"testing"
+
+	"github.com/kroulersama/goProject/models"
+)
+
+// Параметры нагрузочных проверок
+const (
+	stressWorkers = 8
+	stressNodes   = 12
+	stressMoves   = 40
+)
+
+// testConcurrentCreate: одно имя создается конкурентно среди корневых и в ветке,
+// в каждом месте выжить должно одно
+func testConcurrentCreate(t *testing.T, s Store) {
+	root := mustCreate(t, s, "Root", nil)
+	a := mustCreate(t, s, "A", &root.Id)
+	parents := []*uint{nil, &a.Id}
+
+	var (
+		wg      sync.WaitGroup
+		mu      sync.Mutex
+		created int
+	)
+	for i := 0; i < stressWorkers*len(parents); i++ {
+		wg.Add(1)
+		go func(parentID *uint) {
+			defer wg.Done()
+			_, err := s.CreateDepartment(ctx, &models.DepartmentRequest{Name: "Same", ParentID: parentID})
+			switch {
+			case err == nil:
+				mu.Lock()
+				created++
+				mu.Unlock()
+			case !errors.Is(err, models.ErrNameExists):
+				t.Errorf("CreateDepartment: %v", err)
+			}
+		}(parents[i%len(parents)])
+	}
+	wg.Wait()
+
+	if created != len(parents) {
+		t.Fatalf("created %d departments named Same, want %d", created, len(parents))
+	}
+}
+
+// testConcurrentMoves: случайные конкурентные переносы не должны создать цикл
+func testConcurrentMoves(t *testing.T, s Store) {
+	root := mustCreate(t, s, "Root", nil)
+	ids := make([]uint, stressNodes)
+	for i := range ids {
+		ids[i] = mustCreate(t, s, fmt.Sprintf("Node %d", i), &root.Id).Id
+	}
+
+	var wg sync.WaitGroup
+	for w := 0; w < stressWorkers; w++ {
+		wg.Add(1)
+		go func(seed int64) {
+			defer wg.Done()
+			rnd := rand.New(rand.NewSource(seed))
+			for i := 0; i < stressMoves; i++ {
+				node := ids[rnd.Intn(len(ids))]
+				parent := ids[rnd.Intn(len(ids))]
+				_, err := s.UpdateDepartment(ctx, node, 0, &models.DepartmentRequest{ParentID: &parent})
+				if err != nil && !errors.Is(err, models.ErrCycleDetected) && !errors.Is(err, models.ErrSelfParent) {
+					t.Errorf("UpdateDepartment(%d -> %d): %v", node, parent, err)
+				}
+			}
+		}(int64(w))
+	}
+	wg.Wait()
+
+	// Узел в цикле отрывается от корня и пропадает из дерева
+	tree, err := s.GetWithTree(ctx, root.Id, stressNodes+1, nil)
+	if err != nil {
+		t.Fatalf("GetWithTree: %v", err)
+	}
+	if got := countNodes(tree); got != stressNodes+1 {
+		t.Fatalf("tree has %d nodes, want %d: cycle detected", got, stressNodes+1)
+	}
+}
+
+func countNodes(tree *models.DepartmentResponse) int {
+	n := 1
+	for i := range tree.Children {
+		n += countNodes(&tree.Children[i])
+	}
+	return n
+}
+
+// testConcurrentAllocation: конкурентные совмещения по 60% в разных подразделениях,
+// сумма долей не превышает 100% - выжить должно одно
+func testConcurrentAllocation(t *testing.T, s Store) {
+	root := mustCreate(t, s, "Root", nil)
+	emp := mustHire(t, s, root.Id, "Busy")
+	depts := make([]uint, stressWorkers)
+	for i := range depts {
+		depts[i] = mustCreate(t, s, fmt.Sprintf("Dept %d", i), &root.Id).Id
+	}
+
+	var (
+		wg    sync.WaitGroup
+		mu    sync.Mutex
+		added int
+	)
+	for _, deptID := range depts {
+		wg.Add(1)
+		go func(deptID uint) {
+			defer wg.Done()
+			req := &models.SecondaryAssignmentRequest{DepartmentID: deptID, Role: "Helper", Allocation: 60}
+			_, err := s.AddSecondaryAssignment(ctx, emp.ID, req)
+			switch {
+			case err == nil:
+				mu.Lock()
+				added++
+				mu.Unlock()
+			case !errors.Is(err, models.ErrAllocationExceeded):
+				t.Errorf("AddSecondaryAssignment: %v", err)
+			}
+		}(deptID)
+	}
+	wg.Wait()
+
+	if added != 1 {
+		t.Fatalf("added %d assignments of 60%%, want 1", added)
+	}
+}
+
+// testConcurrentManagerTermination: назначения руководителем идут вместе с его
+// увольнением - в итоге у уволенного не остается подчиненных
+func testConcurrentManagerTermination(t *testing.T, s Store) {
+	root := mustCreate(t, s, "Root", nil)
+	for round := range stressWorkers {
+		boss := mustHire(t, s, root.Id, fmt.Sprintf("Boss %d", round))
+		reports := make([]uint, stressWorkers)
+		for i := range reports {
+			reports[i] = mustHire(t, s, root.Id, fmt.Sprintf("Report %d.%d", round, i)).ID
+		}
+
+		var wg sync.WaitGroup
+		for i, id := range reports {
+			wg.Add(1)
+			go func(id uint) {
+				defer wg.Done()
+				_, err := s.SetManager(ctx, id, 0, &models.ManagerRequest{ManagerID: &boss.ID})
+				if err != nil && !errors.Is(err, models.ErrManagerTerminated) {
+					t.Errorf("SetManager: %v", err)
+				}
+			}(id)
+			if i == len(reports)/2 {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					if _, err := s.TerminateEmployee(ctx, boss.ID, 0, &models.TerminationRequest{Reason: "resigned"}); err != nil {
+						t.Errorf("TerminateEmployee: %v", err)
+					}
+				}()
+			}
+		}
+		wg.Wait()
+
+		for _, id := range reports {
+			emp, err := s.GetEmployee(ctx, id)
+			if err != nil {
+				t.Fatalf("GetEmployee: %v", err)
+			}
+			if emp.ManagerID != nil && *emp.ManagerID == boss.ID {
+				t.Fatalf("employee %d reports to terminated manager %d", id, boss.ID)
+			}
+		}
+	}
+}
@@ -0,0 +1,109 @@
+package storetest
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/kroulersama/goProject/models"
+)
+
+// Варианты одного имени: регистр и полноширинные символы
+var sameNames = []string{"SALES", "sales", "Ｓａｌｅｓ"}
+
+func testUniqueAmongSiblings(t *testing.T, s Store) {
+	root := mustCreate(t, s, "Root", nil)
+	a := mustCreate(t, s, "A", &root.Id)
+	mustCreate(t, s, "Sales", &root.Id)
+
+	for _, name := range sameNames {
+		expectNameExists(t, "create "+name, createErr(s, name, &root.Id))
+	}
+	mustCreate(t, s, "Straße", &root.Id)
+	expectNameExists(t, "create STRASSE", createErr(s, "STRASSE", &root.Id))
+
+	// У другого родителя и у потомка имя свободно
+	mustCreate(t, s, "Root", &a.Id)
+	sales := mustCreate(t, s, "sales", &a.Id)
+
+	// Переименование и перенос
+	expectNameExists(t, "rename", updateErr(s, a.Id, &models.DepartmentRequest{Name: "SALES"}))
+	expectNameExists(t, "move", updateErr(s, sales.Id, &models.DepartmentRequest{ParentID: &root.Id}))
+	if err := updateErr(s, sales.Id, &models.DepartmentRequest{Name: "Sales A"}); err != nil {
+		t.Fatalf("rename to free name: %v", err)
+	}
+}
+
+func testUniqueInBranch(t *testing.T, s Store) {
+	r1 := mustCreate(t, s, "R1", nil)
+	a := mustCreate(t, s, "A", &r1.Id)
+	mustCreate(t, s, "Sales", &a.Id)
+	r2 := mustCreate(t, s, "R2", nil)
+	sales2 := mustCreate(t, s, "SALES", &r2.Id)
+
+	// Вся ветка, включая корень
+	for _, name := range sameNames {
+		expectNameExists(t, "create "+name, createErr(s, name, &r1.Id))
+	}
+	expectNameExists(t, "create root name", createErr(s, "r1", &a.Id))
+	expectNameExists(t, "duplicate root", createErr(s, "r2", nil))
+
+	// Переименование внутри ветки и корня
+	expectNameExists(t, "rename", updateErr(s, a.Id, &models.DepartmentRequest{Name: "sales"}))
+	expectNameExists(t, "rename root", updateErr(s, r2.Id, &models.DepartmentRequest{Name: "Sales"}))
+
+	// Перенос узла и целой ветки
+	expectNameExists(t, "move", updateErr(s, sales2.Id, &models.DepartmentRequest{ParentID: &a.Id}))
+	expectNameExists(t, "move branch", updateErr(s, r2.Id, &models.DepartmentRequest{ParentID: &r1.Id}))
+
+	// Неудачные операции ничего не меняют
+	if unique, err := s.CheckNameUnique(ctx, "Sales", &r2.Id); err != nil || unique {
+		t.Fatalf("CheckNameUnique after failed move = %v, %v", unique, err)
+	}
+
+	// Переименованный корень меняет область всей ветки
+	if err := updateErr(s, r2.Id, &models.DepartmentRequest{Name: "R3"}); err != nil {
+		t.Fatalf("rename root: %v", err)
+	}
+	expectNameExists(t, "create in renamed branch", createErr(s, "sales", &r2.Id))
+	if err := updateErr(s, sales2.Id, &models.DepartmentRequest{Name: "Other"}); err != nil {
+		t.Fatalf("rename: %v", err)
+	}
+	if err := updateErr(s, r2.Id, &models.DepartmentRequest{ParentID: &r1.Id}); err != nil {
+		t.Fatalf("move branch: %v", err)
+	}
+	expectNameExists(t, "create after move", createErr(s, "OTHER", &a.Id))
+}
+
+func testUniqueGlobal(t *testing.T, s Store) {
+	r1 := mustCreate(t, s, "R1", nil)
+	r2 := mustCreate(t, s, "R2", nil)
+	sales := mustCreate(t, s, "Sales", &r1.Id)
+
+	for _, name := range sameNames {
+		expectNameExists(t, "create "+name, createErr(s, name, &r2.Id))
+	}
+	expectNameExists(t, "create root", createErr(s, "sales", nil))
+	expectNameExists(t, "rename", updateErr(s, r2.Id, &models.DepartmentRequest{Name: "SALES"}))
+
+	// Перенос не меняет набор имен
+	if err := updateErr(s, sales.Id, &models.DepartmentRequest{ParentID: &r2.Id}); err != nil {
+		t.Fatalf("move: %v", err)
+	}
+}
+
+func createErr(s Store, name string, parentID *uint) error {
+	_, err := s.CreateDepartment(ctx, &models.DepartmentRequest{Name: name, ParentID: parentID})
+	return err
+}
+
+func updateErr(s Store, id uint, req *models.DepartmentRequest) error {
+	_, err := s.UpdateDepartment(ctx, id, 0, req)
+	return err
+}
+
+func expectNameExists(t *testing.T, op string, err error) {
+	t.Helper()
+	if !errors.Is(err, models.ErrNameExists) {
+		t.Fatalf("%s: got %v, want ErrNameExists", op, err)
+	}
+}